@@ -16,6 +16,8 @@ type Config struct {
 
 	UserConfigDir  string
 	UserConfigName string
+	// BlobDir 图标、缩略图等二进制文件的存放目录
+	BlobDir string
 
 	// 服务器配置
 	ServerAddress string
@@ -74,6 +76,7 @@ func LoadConfig() (*Config, error) {
 	viper.SetDefault("info.productName", "Devboard")
 	viper.SetDefault("UserConfigDir", data_dir)
 	viper.SetDefault("UserConfigName", "settings.json")
+	viper.SetDefault("BLOB_DIR", filepath.Join(data_dir, "blobs"))
 	// 设置默认值
 	viper.SetDefault("SERVER_ADDRESS", ":8389")
 	viper.SetDefault("ENVIRONMENT", "development")
@@ -95,6 +98,7 @@ func LoadConfig() (*Config, error) {
 		ProductVersion: viper.GetString("info.version"),
 		UserConfigDir:  viper.GetString("UserConfigDir"),
 		UserConfigName: viper.GetString("UserConfigName"),
+		BlobDir:        viper.GetString("BLOB_DIR"),
 		ServerAddress:  viper.GetString("SERVER_ADDRESS"),
 		Environment:    viper.GetString("ENVIRONMENT"),
 		LogLevel:       viper.GetString("LOG_LEVEL"),
@@ -0,0 +1,73 @@
+package db
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"gorm.io/gorm"
+
+	"devboard/models"
+	_html "devboard/pkg/html"
+)
+
+type data_migration struct {
+	Name       string
+	Status     int
+	FinishedAt string
+}
+
+func (data_migration) TableName() string {
+	return "data_migration"
+}
+
+// data_migration_handlers 需要在程序中执行的数据迁移，名称由 sql 迁移写入 data_migration 表
+var data_migration_handlers = map[string]func(db *gorm.DB) error{
+	"html_sanitize":        resanitize_paste_event_html,
+	"html_sanitize_synced": resanitize_paste_event_html,
+}
+
+// RunDataMigrations 执行还没完成的数据迁移，失败时下次启动重试
+func RunDataMigrations(db *gorm.DB) error {
+	var pending []data_migration
+	if err := db.Where("status = ?", 0).Find(&pending).Error; err != nil {
+		return err
+	}
+	for _, m := range pending {
+		handler, ok := data_migration_handlers[m.Name]
+		if !ok {
+			continue
+		}
+		if err := handler(db); err != nil {
+			return fmt.Errorf("data migration %s failed, %w", m.Name, err)
+		}
+		if err := db.Model(&data_migration{}).Where("name = ?", m.Name).Updates(map[string]interface{}{
+			"status":      1,
+			"finished_at": strconv.FormatInt(time.Now().UnixMilli(), 10),
+		}).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// resanitize_paste_event_html 只更新 html 字段，不修改更新时间，避免记录顺序变化
+func resanitize_paste_event_html(db *gorm.DB) error {
+	var records []models.PasteEvent
+	return db.Unscoped().
+		Model(&models.PasteEvent{}).
+		Select("id", "html").
+		Where("html IS NOT NULL AND html != ''").
+		FindInBatches(&records, 200, func(_ *gorm.DB, batch int) error {
+			for _, r := range records {
+				cleaned := _html.Sanitize(r.Html, _html.ProfileKeepLayout)
+				if cleaned == r.Html {
+					continue
+				}
+				if err := db.Unscoped().Model(&models.PasteEvent{}).Where("id = ?", r.Id).UpdateColumn("html", cleaned).Error; err != nil {
+					return err
+				}
+			}
+			return nil
+		}).Error
+}
@@ -27,10 +27,11 @@ export function fetchPasteEventList(body: Partial<FetchParams> & Partial<{ types
     ListResponse<{
       id: string;
       content_type: PasteContentType;
+      title?: string;
+      pinned?: boolean;
+      /** 文本的前 800 个字符，html 记录是去掉标签后的文本 */
       text?: string;
-      image_base64?: string;
-      file_list_json?: string;
-      html?: string;
+      text_length?: number;
       details: string;
       created_at: string;
       updated_at: string;
@@ -39,6 +40,12 @@ export function fetchPasteEventList(body: Partial<FetchParams> & Partial<{ types
         id: string;
         label: string;
       }[];
+      file_count?: number;
+      stale?: boolean;
+      image?: PasteImage;
+      /** 还没有生成缩略图时读取原图的地址 */
+      image_url?: string;
+      remark_count?: number;
     }>
   >(FetchPasteEventList, {
     ...body,
@@ -61,6 +68,18 @@ export type PasteContentImage = {
   size_for_humans: string;
 };
 export type PasteContentFile = {};
+export type PasteImage = {
+  format: string;
+  width: number;
+  height: number;
+  thumbnails: {
+    small: string;
+    medium: string;
+    large: string;
+  };
+  group_id?: string;
+  similar_count?: number;
+};
 export type PasteContentDetails = MutableRecord<{
   [PasteContentType.Text]: PasteContentText;
   [PasteContentType.HTML]: PasteContentHTML;
@@ -70,7 +89,9 @@ export type PasteContentDetails = MutableRecord<{
 const PasteCardHeightCache = new Map<string, number>();
 
 export function processPartialPasteEvent(
-  v: UnpackedRequestPayload<ReturnType<typeof fetchPasteEventList>>["list"][number]
+  v: UnpackedRequestPayload<ReturnType<typeof fetchPasteEventList>>["list"][number] &
+    // 列表中只有摘要，完整的内容只在详情中返回
+    Partial<{ html: string; image_base64: string; file_list_json: string }>
 ) {
   const types = (v.categories ?? []).map((cate) => cate.label);
   const text = (() => {
@@ -180,7 +201,12 @@ export function processPartialPasteEvent(
       }
       return null;
     })(),
-    image_url: v.image_base64 ? `data:image/png;base64,${v.image_base64}` : null,
+    image_url: (() => {
+      if (v.image_base64) {
+        return `data:image/png;base64,${v.image_base64}`;
+      }
+      return v.image?.thumbnails.medium || v.image_url || null;
+    })(),
     details,
     operations: (() => {
       const r: string[] = [];
@@ -306,33 +306,43 @@ const PasteEventPayloadContent = (props: {
       }}
     >
       <Switch fallback={<div class="p-2 text-w-fg-0 break-all">{v.text}</div>}>
-        <Match when={v.type === "file" && v.files}>
+        <Match when={v.type === "file" && (v.files || v.file_count)}>
           <div class="w-full p-2 overflow-auto whitespace-nowrap scroll--hidden">
-            <For each={v.files}>
-              {(f: any) => {
-                return (
-                  <div>
-                    <div
-                      class="inline-flex items-center gap-1 cursor-pointer hover:underline"
-                      onClick={(event) => {
-                        event.stopPropagation();
-                        vm.methods.handleClickFile(f);
-                      }}
-                    >
-                      <Switch>
-                        <Match when={f.mime_type === "folder"}>
-                          <Folder class="w-4 h-4 text-w-fg-1" />
-                        </Match>
-                        <Match when={f.mime_type !== "folder"}>
-                          <File class="w-4 h-4 text-w-fg-1" />
-                        </Match>
-                      </Switch>
-                      <div class="text-w-fg-0">{f.name}</div>
+            <Show
+              when={v.files}
+              fallback={
+                <div class="inline-flex items-center gap-1">
+                  <File class="w-4 h-4 text-w-fg-1" />
+                  <div class="text-w-fg-0">{v.file_count} 个文件</div>
+                </div>
+              }
+            >
+              <For each={v.files}>
+                {(f: any) => {
+                  return (
+                    <div>
+                      <div
+                        class="inline-flex items-center gap-1 cursor-pointer hover:underline"
+                        onClick={(event) => {
+                          event.stopPropagation();
+                          vm.methods.handleClickFile(f);
+                        }}
+                      >
+                        <Switch>
+                          <Match when={f.mime_type === "folder"}>
+                            <Folder class="w-4 h-4 text-w-fg-1" />
+                          </Match>
+                          <Match when={f.mime_type !== "folder"}>
+                            <File class="w-4 h-4 text-w-fg-1" />
+                          </Match>
+                        </Switch>
+                        <div class="text-w-fg-0">{f.name}</div>
+                      </div>
                     </div>
-                  </div>
-                );
-              }}
-            </For>
+                  );
+                }}
+              </For>
+            </Show>
           </div>
         </Match>
         <Match when={v.types.includes("url") && v.text}>
@@ -360,8 +370,8 @@ const PasteEventPayloadContent = (props: {
             <div class="text-w-fg-1">{v.text}</div>
           </div>
         </Match>
-        <Match when={v.type === "html" && v.text}>
-          <HTMLCard html={v.text!} />
+        <Match when={v.type === "html" && v.html}>
+          <HTMLCard html={v.html!} />
         </Match>
         <Match when={v.type === "image" && v.image_url}>
           <Show
@@ -0,0 +1,205 @@
+package ask
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"devboard/pkg/llm"
+)
+
+// Query 从问题中解析出的检索条件
+type Query struct {
+	Keywords []string
+	StartAt  time.Time // 为零值表示不限制
+	EndAt    time.Time
+}
+
+// Document 参与回答的一条记录
+type Document struct {
+	Id        string
+	Title     string
+	Text      string
+	CreatedAt time.Time
+}
+
+var stop_words = map[string]bool{
+	"a": true, "an": true, "the": true, "i": true, "me": true, "my": true, "we": true, "our": true, "you": true,
+	"what": true, "which": true, "who": true, "when": true, "where": true, "how": true, "why": true,
+	"was": true, "were": true, "is": true, "are": true, "be": true, "been": true, "do": true, "did": true, "does": true,
+	"to": true, "for": true, "of": true, "in": true, "on": true, "at": true, "by": true, "with": true, "from": true,
+	"and": true, "or": true, "that": true, "this": true, "it": true, "used": true, "use": true, "copied": true,
+	"copy": true, "paste": true, "pasted": true, "find": true, "show": true, "give": true, "about": true,
+	"last": true, "week": true, "month": true, "year": true, "today": true, "yesterday": true, "ago": true,
+	"days": true, "day": true, "weeks": true, "months": true, "hours": true, "hour": true, "recently": true,
+}
+
+var relative_time_regexp = regexp.MustCompile(`(?i)\b(\d+)\s+(hour|day|week|month)s?\s+ago\b`)
+var word_regexp = regexp.MustCompile(`[\p{L}\p{N}_.:/-]+`)
+
+func start_of_day(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+}
+
+// ParseQuestion 提取关键词和时间范围，时间只支持常见的相对表达
+func ParseQuestion(question string, now time.Time) Query {
+	var q Query
+	lower := strings.ToLower(question)
+	today := start_of_day(now)
+	switch {
+	case strings.Contains(lower, "today"):
+		q.StartAt = today
+	case strings.Contains(lower, "yesterday"):
+		q.StartAt = today.AddDate(0, 0, -1)
+		q.EndAt = today
+	case strings.Contains(lower, "last week") || strings.Contains(lower, "past week"):
+		q.StartAt = today.AddDate(0, 0, -7)
+	case strings.Contains(lower, "this week"):
+		q.StartAt = today.AddDate(0, 0, -int(today.Weekday()))
+	case strings.Contains(lower, "last month") || strings.Contains(lower, "past month"):
+		q.StartAt = today.AddDate(0, -1, 0)
+	case strings.Contains(lower, "this month"):
+		q.StartAt = time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location())
+	case strings.Contains(lower, "last year") || strings.Contains(lower, "past year"):
+		q.StartAt = today.AddDate(-1, 0, 0)
+	}
+	if m := relative_time_regexp.FindStringSubmatch(lower); m != nil {
+		n, _ := strconv.Atoi(m[1])
+		switch m[2] {
+		case "hour":
+			q.StartAt = now.Add(-time.Duration(n) * time.Hour)
+		case "day":
+			q.StartAt = today.AddDate(0, 0, -n)
+		case "week":
+			q.StartAt = today.AddDate(0, 0, -7*n)
+		case "month":
+			q.StartAt = today.AddDate(0, -n, 0)
+		}
+		q.EndAt = time.Time{}
+	}
+	seen := make(map[string]bool)
+	for _, w := range word_regexp.FindAllString(lower, -1) {
+		w = strings.Trim(w, ".:/-")
+		if len([]rune(w)) < 2 || stop_words[w] || seen[w] {
+			continue
+		}
+		if _, err := strconv.Atoi(w); err == nil && len(w) < 3 {
+			continue
+		}
+		seen[w] = true
+		q.Keywords = append(q.Keywords, w)
+	}
+	return q
+}
+
+// Score 关键词命中次数，命中的不同关键词越多分数越高
+func Score(doc Document, keywords []string) float64 {
+	content := strings.ToLower(doc.Title + "\n" + doc.Text)
+	score := 0.0
+	for _, k := range keywords {
+		n := strings.Count(content, k)
+		if n == 0 {
+			continue
+		}
+		score += 1 + float64(min(n, 5))*0.1
+	}
+	return score
+}
+
+// Rank 按关键词得分排序，分数相同时较新的在前
+func Rank(docs []Document, keywords []string) []Document {
+	scores := make(map[string]float64)
+	for _, d := range docs {
+		scores[d.Id] = Score(d, keywords)
+	}
+	sorted := append([]Document{}, docs...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		a, b := scores[sorted[i].Id], scores[sorted[j].Id]
+		if a != b {
+			return a > b
+		}
+		return sorted[i].CreatedAt.After(sorted[j].CreatedAt)
+	})
+	return sorted
+}
+
+// Cosine 两个向量的余弦相似度
+func Cosine(a []float64, b []float64) float64 {
+	if len(a) == 0 || len(a) != len(b) {
+		return 0
+	}
+	var dot, na, nb float64
+	for i := range a {
+		dot += a[i] * b[i]
+		na += a[i] * a[i]
+		nb += b[i] * b[i]
+	}
+	if na == 0 || nb == 0 {
+		return 0
+	}
+	return dot / (math.Sqrt(na) * math.Sqrt(nb))
+}
+
+func truncate(text string, n int) string {
+	r := []rune(text)
+	if len(r) <= n {
+		return text
+	}
+	return string(r[:n]) + "…"
+}
+
+// BuildMessages 把检索到的记录编号后放进提示词，要求模型用 [n] 的方式引用
+func BuildMessages(question string, docs []Document, max_doc_length int, now time.Time) []llm.LLMChatMessage {
+	var b strings.Builder
+	for i, d := range docs {
+		fmt.Fprintf(&b, "[%d] copied at %s", i+1, d.CreatedAt.Format("2006-01-02 15:04"))
+		if d.Title != "" {
+			fmt.Fprintf(&b, ", title: %s", d.Title)
+		}
+		b.WriteString("\n")
+		b.WriteString(truncate(d.Text, max_doc_length))
+		b.WriteString("\n\n")
+	}
+	system := "You answer questions about the user's own clipboard history. " +
+		"Use only the numbered clipboard entries below. " +
+		"Cite the entries you rely on with their number in square brackets, like [1] or [2][3]. " +
+		"If the entries do not contain the answer, say that you could not find it. " +
+		fmt.Sprintf("Current time is %s.\n\n", now.Format("2006-01-02 15:04")) +
+		"Clipboard entries:\n\n" + b.String()
+	return []llm.LLMChatMessage{
+		{Role: "system", Content: system},
+		{Role: "user", Content: question},
+	}
+}
+
+var citation_regexp = regexp.MustCompile(`\[(\d+)\]`)
+
+// Citations 返回回答中引用到的记录，按首次出现顺序
+func Citations(answer string, docs []Document) []Document {
+	var result []Document
+	seen := make(map[int]bool)
+	for _, m := range citation_regexp.FindAllStringSubmatch(answer, -1) {
+		n, err := strconv.Atoi(m[1])
+		if err != nil || n < 1 || n > len(docs) || seen[n] {
+			continue
+		}
+		seen[n] = true
+		result = append(result, docs[n-1])
+	}
+	return result
+}
+
+// IsMeaningful 过滤掉只有空白或符号的内容
+func IsMeaningful(text string) bool {
+	for _, r := range text {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,131 @@
+package ask_test
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"devboard/internal/ask"
+)
+
+func TestParseQuestion(t *testing.T) {
+	now := time.Date(2026, 10, 19, 15, 30, 0, 0, time.UTC)
+	day := func(d int) time.Time {
+		return time.Date(2026, 10, d, 0, 0, 0, 0, time.UTC)
+	}
+	cases := []struct {
+		question string
+		keywords []string
+		start_at time.Time
+		end_at   time.Time
+	}{
+		{"What docker command did I use yesterday?", []string{"docker", "command"}, day(18), day(19)},
+		{"the api key I copied last week", []string{"api", "key"}, day(12), time.Time{}},
+		{"redis url from 3 days ago", []string{"redis", "url"}, day(16), time.Time{}},
+		{"anything from today", []string{"anything"}, day(19), time.Time{}},
+		{"nginx nginx config", []string{"nginx", "config"}, time.Time{}, time.Time{}},
+		{"port 8080 and 42", []string{"port", "8080"}, time.Time{}, time.Time{}},
+		{"see example.com.", []string{"see", "example.com"}, time.Time{}, time.Time{}},
+	}
+	for _, c := range cases {
+		q := ask.ParseQuestion(c.question, now)
+		if !reflect.DeepEqual(q.Keywords, c.keywords) {
+			t.Errorf("%q: keywords got %v, want %v", c.question, q.Keywords, c.keywords)
+		}
+		if !q.StartAt.Equal(c.start_at) || !q.EndAt.Equal(c.end_at) {
+			t.Errorf("%q: range got %v - %v, want %v - %v", c.question, q.StartAt, q.EndAt, c.start_at, c.end_at)
+		}
+	}
+}
+
+func TestRank(t *testing.T) {
+	now := time.Date(2026, 10, 19, 15, 30, 0, 0, time.UTC)
+	docs := []ask.Document{
+		{Id: "a", Title: "docker", Text: "docker run docker ps", CreatedAt: now.Add(-3 * time.Hour)},
+		{Id: "b", Text: "docker compose up", CreatedAt: now.Add(-2 * time.Hour)},
+		{Id: "c", Text: "unrelated", CreatedAt: now.Add(-1 * time.Hour)},
+		{Id: "d", Text: "nothing", CreatedAt: now},
+	}
+	var ids []string
+	for _, d := range ask.Rank(docs, []string{"docker", "compose"}) {
+		ids = append(ids, d.Id)
+	}
+	if want := []string{"b", "a", "d", "c"}; !reflect.DeepEqual(ids, want) {
+		t.Errorf("got %v, want %v", ids, want)
+	}
+	if docs[0].Id != "a" {
+		t.Errorf("the input should not be reordered")
+	}
+}
+
+func TestCosine(t *testing.T) {
+	cases := []struct {
+		a    []float64
+		b    []float64
+		want float64
+	}{
+		{[]float64{1, 0}, []float64{2, 0}, 1},
+		{[]float64{1, 0}, []float64{0, 1}, 0},
+		{[]float64{1, 0}, []float64{1}, 0},
+		{[]float64{0, 0}, []float64{1, 1}, 0},
+		{nil, nil, 0},
+	}
+	for _, c := range cases {
+		if got := ask.Cosine(c.a, c.b); got != c.want {
+			t.Errorf("Cosine(%v, %v) got %v, want %v", c.a, c.b, got, c.want)
+		}
+	}
+}
+
+func TestBuildMessages(t *testing.T) {
+	now := time.Date(2026, 10, 19, 15, 30, 0, 0, time.UTC)
+	docs := []ask.Document{
+		{Id: "a", Title: "deploy", Text: "kubectl apply", CreatedAt: time.Date(2026, 10, 18, 9, 0, 0, 0, time.UTC)},
+		{Id: "b", Text: "abc", CreatedAt: time.Date(2026, 10, 18, 10, 0, 0, 0, time.UTC)},
+	}
+	messages := ask.BuildMessages("how did I deploy?", docs, 7, now)
+	if len(messages) != 2 {
+		t.Fatalf("got %d messages", len(messages))
+	}
+	if messages[1].Role != "user" || messages[1].Content != "how did I deploy?" {
+		t.Errorf("unexpected user message %+v", messages[1])
+	}
+	system := messages[0].Content
+	for _, want := range []string{
+		"[1] copied at 2026-10-18 09:00, title: deploy\nkubectl…",
+		"[2] copied at 2026-10-18 10:00\nabc\n",
+		"Current time is 2026-10-19 15:30.",
+	} {
+		if !strings.Contains(system, want) {
+			t.Errorf("system prompt missing %q:\n%s", want, system)
+		}
+	}
+}
+
+func TestCitations(t *testing.T) {
+	docs := []ask.Document{{Id: "a"}, {Id: "b"}, {Id: "c"}}
+	var ids []string
+	for _, d := range ask.Citations("See [2] and [1][2], not [5], [0] or [x]", docs) {
+		ids = append(ids, d.Id)
+	}
+	if want := []string{"b", "a"}; !reflect.DeepEqual(ids, want) {
+		t.Errorf("got %v, want %v", ids, want)
+	}
+}
+
+func TestIsMeaningful(t *testing.T) {
+	cases := map[string]bool{
+		"":     false,
+		"  \n": false,
+		"---":  false,
+		"a":    true,
+		"中文":   true,
+		"42":   true,
+	}
+	for text, want := range cases {
+		if got := ask.IsMeaningful(text); got != want {
+			t.Errorf("IsMeaningful(%q) got %v, want %v", text, got, want)
+		}
+	}
+}
@@ -11,7 +11,10 @@ import (
 
 	"devboard/config"
 	"devboard/internal/controller"
+	"devboard/internal/job"
 	"devboard/models"
+	"devboard/pkg/blob"
+	"devboard/pkg/llm"
 	"devboard/pkg/system"
 	// "devboard/internal/service"
 )
@@ -22,6 +25,9 @@ type ControllerMap struct {
 	Remark   *controller.PasteEventRemarkController
 	App      *controller.AppController
 	Device   *controller.DeviceController
+	Recipe   *controller.RecipeController
+	URLMeta  *controller.URLMetaController
+	Blob     *controller.BlobController
 }
 
 type BizApp struct {
@@ -38,6 +44,12 @@ type BizApp struct {
 	CommandHotKeyMap           map[string]*hotkey.Hotkey // 以 Command 为 key，kh 实例为值
 	ManuallyWriteClipboardTime time.Time
 	ControllerMap              *ControllerMap
+	LLM                        *llm.Registry
+	AutoTitleWorker            *job.Worker
+	URLMetaWorker              *job.Worker
+	ImageWorker                *job.Worker
+	CategoryRuleWorker         *job.Worker
+	Blob                       *blob.Store
 	Ready                      bool
 
 	prev_app *system.ForegroundProcess
@@ -72,12 +84,25 @@ func (a *BizApp) SetMachineId(id string) *BizApp {
 }
 func (a *BizApp) SetConfig(config *config.Config) *BizApp {
 	a.Config = config
+	store, err := blob.NewStore(config.BlobDir)
+	if err != nil {
+		fmt.Println("[ERROR]initialize blob store failed", err.Error())
+		return a
+	}
+	a.Blob = store
 	return a
 }
 func (a *BizApp) InitializeUserConfig(cfg *config.Config) *BizApp {
 	biz_config := NewBizConfig(cfg.UserConfigDir, cfg.UserConfigName)
 	biz_config.InitializeConfig()
 	a.Perferences = biz_config
+	a.LLM = llm.NewRegistry(func() ([]llm.Provider, string) {
+		v := a.Perferences.Value
+		if v == nil {
+			return nil, ""
+		}
+		return v.LLM.Providers, v.LLM.DefaultProvider
+	})
 	return a
 }
 func (a *BizApp) SetUserConfig(config *UserSettings) *BizApp {
@@ -86,11 +111,14 @@ func (a *BizApp) SetUserConfig(config *UserSettings) *BizApp {
 }
 func (a *BizApp) InitializeControllerMap() *BizApp {
 	a.ControllerMap = &ControllerMap{
-		Paste:    controller.NewPasteController(a.DB, a.MachineId),
-		Remark:   controller.NewRemarkController(a.DB),
+		Paste:    controller.NewPasteController(a.DB, a.MachineId).SetBlobStore(a.Blob),
+		Remark:   controller.NewRemarkController(a.DB).SetBlobStore(a.Blob),
 		Category: controller.NewCategoryController(a.DB),
 		Device:   controller.NewDeviceController(a.DB),
 		App:      controller.NewAppController(a.DB),
+		Recipe:   controller.NewRecipeController(a.DB),
+		URLMeta:  controller.NewURLMetaController(a.DB),
+		Blob:     controller.NewBlobController(a.DB),
 	}
 	return a
 }
@@ -111,16 +139,47 @@ func (a *BizApp) Ensure() error {
 }
 
 func (a *BizApp) HandlePasteText(text string, extra *controller.PasteExtraInfo) (*models.PasteEvent, error) {
-	return a.ControllerMap.Paste.HandlePasteText(text, extra)
+	if cleaned, ok := a.clean_url_text(text); ok {
+		extra.OriginalText = text
+		text = cleaned
+		a.write_back_cleaned_url(cleaned)
+	}
+	created, err := a.ControllerMap.Paste.HandlePasteText(text, extra)
+	if err == nil {
+		a.after_paste_created(created)
+	}
+	return created, err
+}
+
+// HTMLProfile 设置中保存 html 时使用的清理规则
+func (a *BizApp) HTMLProfile() string {
+	if a.Perferences != nil && a.Perferences.Value != nil {
+		return a.Perferences.Value.HTML.SanitizeProfile
+	}
+	return ""
 }
 func (a *BizApp) HandlePasteHTML(text string, extra *controller.PasteExtraInfo) (*models.PasteEvent, error) {
-	return a.ControllerMap.Paste.HandlePasteHTML(text, extra)
+	extra.HTMLProfile = a.HTMLProfile()
+	created, err := a.ControllerMap.Paste.HandlePasteHTML(text, extra)
+	if err == nil {
+		a.after_paste_created(created)
+	}
+	return created, err
 }
 func (a *BizApp) HandlePastePNG(img []byte, extra *controller.PasteExtraInfo) (*models.PasteEvent, error) {
-	return a.ControllerMap.Paste.HandlePastePNG(img, extra)
+	created, err := a.ControllerMap.Paste.HandlePastePNG(img, extra)
+	if err == nil {
+		a.after_paste_created(created)
+	}
+	return created, err
 }
 func (a *BizApp) HandlePasteFile(files []string, extra *controller.PasteExtraInfo) (*models.PasteEvent, error) {
-	return a.ControllerMap.Paste.HandlePasteFile(files, extra)
+	extra.FileSnapshot = a.file_snapshot_policy()
+	created, err := a.ControllerMap.Paste.HandlePasteFile(files, extra)
+	if err == nil {
+		a.after_paste_created(created)
+	}
+	return created, err
 }
 
 func (a *BizApp) FindWindow(url string) *application.WebviewWindow {
@@ -0,0 +1,55 @@
+package biz
+
+import (
+	"fmt"
+	"os"
+
+	"devboard/internal/controller"
+)
+
+func (a *BizApp) emit_archive_progress(name string) func(done int, total int) {
+	return func(done int, total int) {
+		if a.app == nil {
+			return
+		}
+		a.app.Event.Emit(name, map[string]interface{}{
+			"done":  done,
+			"total": total,
+		})
+	}
+}
+
+// ExportArchive 先写入临时文件，完成后再移动到 path，失败时不会留下不完整的归档。
+// 通过 archive:export_progress 事件通知进度
+func (a *BizApp) ExportArchive(body controller.ArchiveExportBody, path string) (*controller.ArchiveManifest, error) {
+	if a.ControllerMap == nil {
+		return nil, fmt.Errorf("The controllers are not initialized")
+	}
+	tmp := path + ".tmp"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return nil, err
+	}
+	manifest, err := a.ControllerMap.Paste.ExportArchive(body, f, a.emit_archive_progress("archive:export_progress"))
+	if e := f.Close(); err == nil {
+		err = e
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return nil, err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return nil, err
+	}
+	return manifest, nil
+}
+
+// ImportArchive 通过 archive:import_progress 事件通知进度
+func (a *BizApp) ImportArchive(body controller.ArchiveImportBody) (*controller.ArchiveImportResult, error) {
+	if a.ControllerMap == nil {
+		return nil, fmt.Errorf("The controllers are not initialized")
+	}
+	body.HTMLProfile = a.HTMLProfile()
+	return a.ControllerMap.Paste.ImportArchive(body, a.emit_archive_progress("archive:import_progress"))
+}
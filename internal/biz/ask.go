@@ -0,0 +1,205 @@
+package biz
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"devboard/internal/ask"
+	"devboard/internal/controller"
+	"devboard/internal/sensitive"
+	"devboard/pkg/llm"
+)
+
+const (
+	ask_candidate_limit  = 200
+	ask_semantic_limit   = 50
+	ask_default_top_k    = 8
+	ask_max_doc_length   = 1500
+	ask_excerpt_length   = 200
+	ask_max_question_len = 2000
+)
+
+type AskClipboardBody struct {
+	Question string `json:"question"`
+	Provider string `json:"provider"`
+	// StartAt/EndAt 毫秒时间戳，传入时覆盖从问题中解析出的时间范围
+	StartAt int64 `json:"start_at"`
+	EndAt   int64 `json:"end_at"`
+	TopK    int   `json:"top_k"`
+}
+
+type AskClipboardSource struct {
+	Id        string `json:"id"`
+	Title     string `json:"title"`
+	Excerpt   string `json:"excerpt"`
+	CreatedAt string `json:"created_at"`
+	Cited     bool   `json:"cited"`
+}
+
+type AskClipboardResp struct {
+	Answer        string               `json:"answer"`
+	PasteEventIds []string             `json:"paste_event_ids"`
+	Sources       []AskClipboardSource `json:"sources"`
+	Semantic      bool                 `json:"semantic"`
+}
+
+func parse_millis(v string) time.Time {
+	n, err := strconv.ParseInt(v, 10, 64)
+	if err != nil {
+		return time.Time{}
+	}
+	return time.UnixMilli(n)
+}
+
+func excerpt(text string) string {
+	text = strings.Join(strings.Fields(text), " ")
+	r := []rune(text)
+	if len(r) > ask_excerpt_length {
+		return string(r[:ask_excerpt_length]) + "…"
+	}
+	return text
+}
+
+// semantic_rerank 厂商配置了向量模型时，按与问题的相似度重新排序，失败时保持原顺序
+func semantic_rerank(ctx context.Context, provider *llm.Provider, question string, docs []ask.Document) ([]ask.Document, bool) {
+	if provider.EmbeddingModel == "" || len(docs) == 0 {
+		return docs, false
+	}
+	if len(docs) > ask_semantic_limit {
+		docs = docs[:ask_semantic_limit]
+	}
+	inputs := []string{question}
+	for _, d := range docs {
+		inputs = append(inputs, d.Title+"\n"+excerpt(d.Text))
+	}
+	vectors, err := provider.Embed(ctx, inputs)
+	if err != nil {
+		fmt.Println("[LOG]embedding failed, fallback to keyword search,", err.Error())
+		return docs, false
+	}
+	scores := make(map[string]float64)
+	for i, d := range docs {
+		scores[d.Id] = ask.Cosine(vectors[0], vectors[i+1])
+	}
+	sorted := append([]ask.Document{}, docs...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return scores[sorted[i].Id] > scores[sorted[j].Id]
+	})
+	return sorted, true
+}
+
+// AskClipboard 检索相关记录后让 LLM 基于这些记录回答问题
+func (a *BizApp) AskClipboard(ctx context.Context, body AskClipboardBody) (*AskClipboardResp, error) {
+	question := strings.TrimSpace(body.Question)
+	if question == "" {
+		return nil, fmt.Errorf("Missing the question")
+	}
+	if len([]rune(question)) > ask_max_question_len {
+		return nil, fmt.Errorf("The question is too long")
+	}
+	provider, err := a.LLM.Resolve(body.Provider)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	q := ask.ParseQuestion(question, now)
+	search := controller.PasteSearchBody{
+		Keywords: q.Keywords,
+		Limit:    ask_candidate_limit,
+	}
+	if !q.StartAt.IsZero() {
+		search.StartAt = q.StartAt.UnixMilli()
+	}
+	if !q.EndAt.IsZero() {
+		search.EndAt = q.EndAt.UnixMilli()
+	}
+	if body.StartAt > 0 {
+		search.StartAt = body.StartAt
+	}
+	if body.EndAt > 0 {
+		search.EndAt = body.EndAt
+	}
+	records, err := a.ControllerMap.Paste.SearchPasteEvents(search)
+	if err != nil {
+		return nil, err
+	}
+	if len(records) == 0 && len(search.Keywords) != 0 {
+		// 关键词没有命中时退化为只按时间范围检索，交给语义排序或模型自行判断
+		search.Keywords = nil
+		records, err = a.ControllerMap.Paste.SearchPasteEvents(search)
+		if err != nil {
+			return nil, err
+		}
+	}
+	var docs []ask.Document
+	for _, r := range records {
+		if !ask.IsMeaningful(r.Text) || sensitive.ContainsSecret(r.Text) {
+			continue
+		}
+		// 备注也参与检索，一起交给模型，包含密钥的备注不发送
+		text := r.Text
+		for _, remark := range r.Remarks {
+			if sensitive.ContainsSecret(remark.Content) {
+				continue
+			}
+			text += "\n\nRemark: " + remark.Content
+		}
+		docs = append(docs, ask.Document{
+			Id:        r.Id,
+			Title:     r.Title,
+			Text:      text,
+			CreatedAt: parse_millis(r.CreatedAt),
+		})
+	}
+	docs = ask.Rank(docs, q.Keywords)
+	docs, semantic := semantic_rerank(ctx, provider, question, docs)
+	top_k := body.TopK
+	if top_k <= 0 {
+		top_k = ask_default_top_k
+	}
+	if len(docs) > top_k {
+		docs = docs[:top_k]
+	}
+	resp := &AskClipboardResp{
+		PasteEventIds: []string{},
+		Sources:       []AskClipboardSource{},
+		Semantic:      semantic,
+	}
+	if len(docs) == 0 {
+		resp.Answer = "No related clipboard history was found."
+		return resp, nil
+	}
+	temperature := 0.2
+	answer, err := provider.Chat(ctx, ask.BuildMessages(question, docs, ask_max_doc_length, now), llm.ChatOptions{
+		Temperature: &temperature,
+	})
+	if err != nil {
+		return nil, err
+	}
+	resp.Answer = answer
+	cited := make(map[string]bool)
+	for _, d := range ask.Citations(answer, docs) {
+		cited[d.Id] = true
+		resp.PasteEventIds = append(resp.PasteEventIds, d.Id)
+	}
+	for _, d := range docs {
+		resp.Sources = append(resp.Sources, AskClipboardSource{
+			Id:        d.Id,
+			Title:     d.Title,
+			Excerpt:   excerpt(d.Text),
+			CreatedAt: strconv.FormatInt(d.CreatedAt.UnixMilli(), 10),
+			Cited:     cited[d.Id],
+		})
+	}
+	// 模型没有按要求引用时，认为所有提供的记录都参与了回答
+	if len(resp.PasteEventIds) == 0 {
+		for _, d := range docs {
+			resp.PasteEventIds = append(resp.PasteEventIds, d.Id)
+		}
+	}
+	return resp, nil
+}
@@ -0,0 +1,179 @@
+package biz
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"devboard/internal/controller"
+	"devboard/internal/job"
+	"devboard/internal/sensitive"
+	"devboard/models"
+	"devboard/pkg/llm"
+)
+
+const (
+	auto_title_default_max_input_length = 4000
+	auto_title_default_max_per_minute   = 6
+	auto_title_min_input_length         = 8
+	auto_title_max_title_length         = 50
+	auto_title_max_categories           = 3
+)
+
+// 这些分类根据内容类型自动添加，不需要 LLM 推荐
+var auto_title_ignored_categories = map[string]bool{
+	"text":  true,
+	"html":  true,
+	"image": true,
+	"file":  true,
+}
+
+func (a *BizApp) StartBackgroundJobs() *BizApp {
+	ctx := context.Background()
+	a.AutoTitleWorker = job.NewWorker("auto_title", 32, func() time.Duration {
+		n := a.Perferences.Value.LLM.AutoTitle.MaxPerMinute
+		if n <= 0 {
+			n = auto_title_default_max_per_minute
+		}
+		return job.PerMinute(n)
+	}).Start(ctx)
+	a.start_url_meta_jobs(ctx)
+	a.start_image_jobs(ctx)
+	a.start_category_rule_jobs(ctx)
+	go a.empty_trash_loop(ctx)
+	return a
+}
+
+// after_paste_created 新记录创建后触发的后台任务
+func (a *BizApp) after_paste_created(created *models.PasteEvent) {
+	if created == nil {
+		return
+	}
+	a.enqueue_auto_title(created)
+	a.enqueue_url_meta(created)
+	a.enqueue_paste_image(created)
+}
+
+func (a *BizApp) enqueue_auto_title(created *models.PasteEvent) {
+	if a.AutoTitleWorker == nil || a.Perferences == nil || !a.Perferences.Value.LLM.AutoTitle.Enabled {
+		return
+	}
+	if created.ContentType != "text" && created.ContentType != "html" {
+		return
+	}
+	if controller.IsSecretPasteEvent(created) {
+		return
+	}
+	id := created.Id
+	a.AutoTitleWorker.Push(job.Task{
+		Name: id,
+		Run: func(ctx context.Context) error {
+			return a.AutoTitle(ctx, id)
+		},
+	})
+}
+
+type auto_title_result struct {
+	Title      string   `json:"title"`
+	Categories []string `json:"categories"`
+}
+
+func build_auto_title_messages(text string, labels []controller.CategoryLabel) []llm.LLMChatMessage {
+	var names []string
+	for _, l := range labels {
+		names = append(names, l.Label)
+	}
+	system := "You name clipboard snippets for a developer's clipboard history. " +
+		"Reply with JSON only, in the form {\"title\": string, \"categories\": string[]}. " +
+		fmt.Sprintf("The title must be at most %d characters, in the same language as the snippet. ", auto_title_max_title_length) +
+		fmt.Sprintf("Choose at most %d categories, only from this list: %s. ", auto_title_max_categories, strings.Join(names, ", ")) +
+		"Use an empty array when none fits."
+	return []llm.LLMChatMessage{
+		{Role: "system", Content: system},
+		{Role: "user", Content: text},
+	}
+}
+
+// AutoTitle 请求 LLM 为记录生成标题和推荐分类
+func (a *BizApp) AutoTitle(ctx context.Context, paste_event_id string) error {
+	cfg := a.Perferences.Value.LLM.AutoTitle
+	record, err := a.ControllerMap.Paste.FetchPasteEventProfile(controller.PasteProfileBody{EventId: paste_event_id})
+	if err != nil {
+		return err
+	}
+	text := strings.TrimSpace(record.Text)
+	if controller.IsSecretPasteEvent(record) || sensitive.ContainsSecret(text) {
+		return nil
+	}
+	r := []rune(text)
+	if len(r) < auto_title_min_input_length {
+		return nil
+	}
+	max_input_length := cfg.MaxInputLength
+	if max_input_length <= 0 {
+		max_input_length = auto_title_default_max_input_length
+	}
+	if len(r) > max_input_length {
+		text = string(r[:max_input_length])
+	}
+	all_labels, err := a.ControllerMap.Paste.FetchCategoryLabelList()
+	if err != nil {
+		return err
+	}
+	var labels []controller.CategoryLabel
+	label_to_id := make(map[string]string)
+	for _, l := range all_labels {
+		if auto_title_ignored_categories[l.Id] {
+			continue
+		}
+		labels = append(labels, l)
+		label_to_id[strings.ToLower(l.Label)] = l.Id
+	}
+	provider, err := a.LLM.Resolve(cfg.Provider)
+	if err != nil {
+		return err
+	}
+	temperature := 0.2
+	content, err := provider.Chat(ctx, build_auto_title_messages(text, labels), llm.ChatOptions{
+		Temperature: &temperature,
+		MaxTokens:   200,
+	})
+	if err != nil {
+		return err
+	}
+	var result auto_title_result
+	if err := llm.ExtractJSON(content, &result); err != nil {
+		return err
+	}
+	title := strings.TrimSpace(result.Title)
+	if t := []rune(title); len(t) > auto_title_max_title_length {
+		title = string(t[:auto_title_max_title_length])
+	}
+	var category_ids []string
+	for _, c := range result.Categories {
+		id, ok := label_to_id[strings.ToLower(strings.TrimSpace(c))]
+		if !ok {
+			continue
+		}
+		category_ids = append(category_ids, id)
+		if len(category_ids) >= auto_title_max_categories {
+			break
+		}
+	}
+	created, err := a.ControllerMap.Paste.SavePasteEventSuggestion(controller.PasteEventSuggestionBody{
+		PasteEventId: paste_event_id,
+		Title:        title,
+		CategoryIds:  category_ids,
+		Source:       "llm",
+	})
+	if err != nil {
+		return err
+	}
+	a.app.Event.Emit("paste_event:suggestion", map[string]interface{}{
+		"paste_event_id": paste_event_id,
+		"title":          title,
+		"suggestions":    created,
+	})
+	return nil
+}
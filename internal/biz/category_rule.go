@@ -0,0 +1,70 @@
+package biz
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/uuid"
+
+	"devboard/internal/controller"
+	"devboard/internal/job"
+)
+
+func (a *BizApp) start_category_rule_jobs(ctx context.Context) {
+	// 同一时间只处理一个重新应用的任务，避免重复添加分类
+	a.CategoryRuleWorker = job.NewWorker("category_rule", 4, nil).Start(ctx)
+}
+
+// PreviewCategoryRules 统计重新应用规则后会添加分类的记录数量，不修改记录
+func (a *BizApp) PreviewCategoryRules(body controller.CategoryRuleApplyBody) (*controller.CategoryRuleApplyResult, error) {
+	if a.ControllerMap == nil {
+		return nil, fmt.Errorf("The controllers are not initialized")
+	}
+	body.DryRun = true
+	return a.ControllerMap.Paste.ApplyCategoryRules(body, nil)
+}
+
+// ApplyCategoryRules 在后台对历史记录重新应用规则，返回任务 id。
+// 通过 category_rule:apply_progress 事件通知进度，完成后发送 category_rule:applied 事件
+func (a *BizApp) ApplyCategoryRules(body controller.CategoryRuleApplyBody) (string, error) {
+	if a.ControllerMap == nil {
+		return "", fmt.Errorf("The controllers are not initialized")
+	}
+	if a.CategoryRuleWorker == nil {
+		return "", fmt.Errorf("The background jobs are not started")
+	}
+	body.DryRun = false
+	task_id := uuid.New().String()
+	emit := func(name string, data map[string]interface{}) {
+		if a.app == nil {
+			return
+		}
+		data["task_id"] = task_id
+		a.app.Event.Emit(name, data)
+	}
+	ok := a.CategoryRuleWorker.Push(job.Task{
+		Name: task_id,
+		Run: func(ctx context.Context) error {
+			result, err := a.ControllerMap.Paste.ApplyCategoryRules(body, func(done int, total int) {
+				emit("category_rule:apply_progress", map[string]interface{}{
+					"done":  done,
+					"total": total,
+				})
+			})
+			if err != nil {
+				emit("category_rule:applied", map[string]interface{}{
+					"error": err.Error(),
+				})
+				return err
+			}
+			emit("category_rule:applied", map[string]interface{}{
+				"result": result,
+			})
+			return nil
+		},
+	})
+	if !ok {
+		return "", fmt.Errorf("Too many pending tasks, please try again later")
+	}
+	return task_id, nil
+}
@@ -0,0 +1,26 @@
+package biz
+
+import (
+	"strings"
+
+	"devboard/internal/transformer"
+	"devboard/models"
+)
+
+// color_paste_actions 把颜色复制为其他格式
+func color_paste_actions(a *BizApp, record *models.PasteEvent) []PasteAction {
+	c, ok := transformer.ParseColor(record.Text)
+	if !ok {
+		return nil
+	}
+	var actions []PasteAction
+	for _, format := range transformer.ColorFormats {
+		actions = append(actions, PasteAction{
+			Id:    "color:" + format,
+			Group: "color",
+			Label: "Copy as " + strings.ToUpper(format),
+			Value: c.Format(format),
+		})
+	}
+	return actions
+}
@@ -0,0 +1,24 @@
+package biz
+
+import "devboard/internal/controller"
+
+const default_file_snapshot_max_size = 20 * 1024 * 1024
+
+// file_snapshot_policy 未开启保存文件内容时返回 nil
+func (a *BizApp) file_snapshot_policy() *controller.FileSnapshotPolicy {
+	if a.Perferences == nil || a.Perferences.Value == nil {
+		return nil
+	}
+	cfg := a.Perferences.Value.FileSnapshot
+	if !cfg.Enabled {
+		return nil
+	}
+	policy := &controller.FileSnapshotPolicy{
+		MaxSize:   cfg.MaxSize,
+		MimeTypes: cfg.MimeTypes,
+	}
+	if policy.MaxSize <= 0 {
+		policy.MaxSize = default_file_snapshot_max_size
+	}
+	return policy
+}
@@ -0,0 +1,76 @@
+package biz
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/ltaoo/clipboard-go"
+
+	"devboard/internal/controller"
+	"devboard/models"
+)
+
+// PasteAction 针对某条记录可以执行的操作，执行时把 Value 写入剪贴板
+type PasteAction struct {
+	Id    string `json:"id"`
+	Group string `json:"group"`
+	Label string `json:"label"`
+	Value string `json:"value"`
+}
+
+type paste_action_provider func(a *BizApp, record *models.PasteEvent) []PasteAction
+
+// 按顺序收集每个 provider 返回的操作
+var paste_action_providers = []paste_action_provider{
+	time_paste_actions,
+	color_paste_actions,
+	url_paste_actions,
+}
+
+func parse_details(record *models.PasteEvent) map[string]interface{} {
+	details := map[string]interface{}{}
+	if record.Details != "" {
+		json.Unmarshal([]byte(record.Details), &details)
+	}
+	return details
+}
+
+func (a *BizApp) FetchPasteEventActionList(body controller.PasteProfileBody) ([]PasteAction, error) {
+	record, err := a.ControllerMap.Paste.FetchPasteEventProfile(body)
+	if err != nil {
+		return nil, err
+	}
+	list := []PasteAction{}
+	for _, p := range paste_action_providers {
+		list = append(list, p(a, record)...)
+	}
+	return list, nil
+}
+
+type PasteActionRunBody struct {
+	EventId  string `json:"paste_event_id"`
+	ActionId string `json:"action_id"`
+}
+
+// RunPasteAction 重新计算操作列表后执行对应的操作
+func (a *BizApp) RunPasteAction(body PasteActionRunBody) (*PasteAction, error) {
+	if body.ActionId == "" {
+		return nil, fmt.Errorf("Missing the action_id")
+	}
+	list, err := a.FetchPasteEventActionList(controller.PasteProfileBody{EventId: body.EventId})
+	if err != nil {
+		return nil, err
+	}
+	for _, action := range list {
+		if action.Id != body.ActionId {
+			continue
+		}
+		a.ManuallyWriteClipboardTime = time.Now()
+		if err := clipboard.WriteText(action.Value); err != nil {
+			return nil, err
+		}
+		return &action, nil
+	}
+	return nil, fmt.Errorf("The action %s not found", body.ActionId)
+}
@@ -0,0 +1,49 @@
+package biz
+
+import (
+	"fmt"
+
+	"github.com/google/uuid"
+
+	"devboard/internal/controller"
+)
+
+func (a *BizApp) emit_paste_bulk_progress(body controller.PasteBulkBody) func(done int, total int) {
+	return func(done int, total int) {
+		if a.app == nil {
+			return
+		}
+		a.app.Event.Emit("paste_event:bulk_progress", map[string]interface{}{
+			"task_id": body.TaskId,
+			"action":  body.Action,
+			"done":    done,
+			"total":   total,
+		})
+	}
+}
+
+// BulkUpdatePasteEvents 执行批量操作，处理过程中通过 paste_event:bulk_progress 事件通知进度
+func (a *BizApp) BulkUpdatePasteEvents(body controller.PasteBulkBody) (*controller.PasteBulkResult, error) {
+	if a.ControllerMap == nil {
+		return nil, fmt.Errorf("The controllers are not initialized")
+	}
+	if body.Action == controller.PasteBulkExport {
+		return nil, fmt.Errorf("Please use the export method")
+	}
+	if body.TaskId == "" {
+		body.TaskId = uuid.New().String()
+	}
+	return a.ControllerMap.Paste.BulkUpdatePasteEvents(body, a.emit_paste_bulk_progress(body))
+}
+
+// BulkExportPasteEvents 读取需要导出的完整记录
+func (a *BizApp) BulkExportPasteEvents(body controller.PasteBulkBody) ([]controller.PasteEventExportItem, *controller.PasteBulkResult, error) {
+	if a.ControllerMap == nil {
+		return nil, nil, fmt.Errorf("The controllers are not initialized")
+	}
+	body.Action = controller.PasteBulkExport
+	if body.TaskId == "" {
+		body.TaskId = uuid.New().String()
+	}
+	return a.ControllerMap.Paste.ExportPasteEvents(body, a.emit_paste_bulk_progress(body))
+}
@@ -0,0 +1,123 @@
+package biz
+
+import (
+	"fmt"
+
+	"devboard/internal/controller"
+	"devboard/internal/transformer"
+	"devboard/models"
+	_html "devboard/pkg/html"
+)
+
+// PasteExtractItem 从 html 记录中提取出的内容，可以保存为新记录
+type PasteExtractItem struct {
+	Id       string `json:"id"`
+	Type     string `json:"type"` // code、table、links
+	Label    string `json:"label"`
+	Language string `json:"language,omitempty"`
+	Text     string `json:"text"`
+}
+
+func build_paste_extract_items(record *models.PasteEvent) ([]PasteExtractItem, error) {
+	items := []PasteExtractItem{}
+	if record.Html == "" {
+		return items, nil
+	}
+	source_url, _ := parse_details(record)["source_url"].(string)
+	extracted, err := _html.Extract(record.Html, source_url)
+	if err != nil {
+		return nil, err
+	}
+	for i, c := range extracted.CodeBlocks {
+		label := fmt.Sprintf("Code block %d", i+1)
+		if c.Language != "" {
+			label += " · " + c.Language
+		}
+		items = append(items, PasteExtractItem{
+			Id:       fmt.Sprintf("code:%d", i),
+			Type:     "code",
+			Label:    label,
+			Language: c.Language,
+			Text:     c.Code,
+		})
+	}
+	for i, t := range extracted.Tables {
+		items = append(items, PasteExtractItem{
+			Id:    fmt.Sprintf("table:%d:csv", i),
+			Type:  "table",
+			Label: fmt.Sprintf("Table %d as CSV", i+1),
+			Text:  t.CSV(),
+		}, PasteExtractItem{
+			Id:    fmt.Sprintf("table:%d:json", i),
+			Type:  "table",
+			Label: fmt.Sprintf("Table %d as JSON", i+1),
+			Text:  t.JSON(),
+		})
+	}
+	if len(extracted.Links) != 0 {
+		items = append(items, PasteExtractItem{
+			Id:    "links",
+			Type:  "links",
+			Label: fmt.Sprintf("Links (%d)", len(extracted.Links)),
+			Text:  extracted.LinksMarkdown(),
+		})
+	}
+	return items, nil
+}
+
+// FetchPasteEventExtractList 列出 html 记录中的代码块、表格和链接
+func (a *BizApp) FetchPasteEventExtractList(body controller.PasteProfileBody) ([]PasteExtractItem, error) {
+	record, err := a.ControllerMap.Paste.FetchPasteEventProfile(body)
+	if err != nil {
+		return nil, err
+	}
+	return build_paste_extract_items(record)
+}
+
+type PasteExtractBody struct {
+	EventId string `json:"paste_event_id"`
+	ItemId  string `json:"item_id"`
+}
+
+// CreateExtractedPasteEvent 把提取出的内容保存为新记录
+func (a *BizApp) CreateExtractedPasteEvent(body PasteExtractBody) (*models.PasteEvent, error) {
+	if body.ItemId == "" {
+		return nil, fmt.Errorf("Missing the item_id")
+	}
+	items, err := a.FetchPasteEventExtractList(controller.PasteProfileBody{EventId: body.EventId})
+	if err != nil {
+		return nil, err
+	}
+	for _, item := range items {
+		if item.Id != body.ItemId {
+			continue
+		}
+		details := map[string]interface{}{
+			"extracted_type": item.Type,
+		}
+		var categories []string
+		if item.Type == "code" {
+			if item.Language != "" {
+				details["language"] = item.Language
+			}
+			categories = append(categories, "code")
+			if lang := transformer.LanguageFromHint(item.Language); lang != "" {
+				categories = append(categories, lang)
+			}
+		}
+		created, is_new, err := a.ControllerMap.Paste.CreateDerivedPasteEvent(controller.DerivedPasteEventBody{
+			OriginId:   body.EventId,
+			Text:       item.Text,
+			Details:    details,
+			Categories: categories,
+		})
+		if err != nil {
+			return nil, err
+		}
+		if is_new {
+			a.after_paste_created(created)
+		}
+		return created, nil
+	}
+	return nil, fmt.Errorf("The item %s not found", body.ItemId)
+}
@@ -0,0 +1,83 @@
+package biz
+
+import (
+	"context"
+	"fmt"
+
+	"devboard/internal/job"
+	"devboard/models"
+)
+
+// 每次补充处理的旧图片数量，处理完一批后再加入下一批
+const image_backfill_batch_size = 20
+
+func (a *BizApp) start_image_jobs(ctx context.Context) {
+	a.ImageWorker = job.NewWorker("paste_image", 64, nil).Start(ctx)
+	a.push_image_backfill_task(nil)
+}
+
+func (a *BizApp) enqueue_paste_image(created *models.PasteEvent) {
+	if a.ImageWorker == nil || created.ContentType != "image" {
+		return
+	}
+	id := created.Id
+	a.ImageWorker.Push(job.Task{
+		Name: id,
+		Run: func(ctx context.Context) error {
+			return a.process_paste_image(id)
+		},
+	})
+}
+
+func (a *BizApp) process_paste_image(id string) error {
+	if a.ControllerMap == nil {
+		return fmt.Errorf("The controllers are not initialized")
+	}
+	if _, err := a.ControllerMap.Paste.ProcessPasteImage(id); err != nil {
+		return err
+	}
+	image, err := a.ControllerMap.Paste.FetchPasteImage(id)
+	if err != nil {
+		return err
+	}
+	if a.app != nil && image != nil {
+		a.app.Event.Emit("paste_event:image_updated", map[string]interface{}{
+			"id":    id,
+			"image": image,
+		})
+	}
+	return nil
+}
+
+// push_image_backfill_task 为升级前保存的图片生成缩略图
+// 处理失败的图片放到 failed 中，后面的批次跳过，避免没有保存失败记录的图片被反复处理
+func (a *BizApp) push_image_backfill_task(failed []string) {
+	a.ImageWorker.Push(job.Task{
+		Name: "backfill",
+		Run: func(ctx context.Context) error {
+			if a.ControllerMap == nil || a.Blob == nil {
+				return nil
+			}
+			ids, err := a.ControllerMap.Paste.FetchUnprocessedImageIdList(image_backfill_batch_size, failed)
+			if err != nil {
+				return err
+			}
+			failures := 0
+			for _, id := range ids {
+				if ctx.Err() != nil {
+					return ctx.Err()
+				}
+				if err := a.process_paste_image(id); err != nil {
+					fmt.Println("[ERROR]process paste image failed", id, err.Error())
+					failed = append(failed, id)
+					failures += 1
+				}
+			}
+			// 整批都失败时不再继续，等下次启动再处理
+			if len(ids) == image_backfill_batch_size && failures < len(ids) {
+				a.push_image_backfill_task(failed)
+			}
+			return nil
+		},
+	})
+}
@@ -8,6 +8,9 @@ import (
 	"reflect"
 	"strconv"
 	"strings"
+
+	"devboard/internal/transformer"
+	"devboard/pkg/llm"
 )
 
 // user preferences
@@ -41,9 +44,72 @@ type UserSettingsValue struct {
 			RootDir  string `json:"root_dir"`
 		} `json:"webdav"`
 	} `json:"synchronize"`
+	LLM struct {
+		DefaultProvider string         `json:"default_provider"`
+		Providers       []llm.Provider `json:"providers"`
+		AutoTitle       struct {
+			Enabled        bool   `json:"enabled"`          // 新记录创建后自动生成标题和推荐分类
+			Provider       string `json:"provider"`         // 为空时使用默认厂商
+			MaxInputLength int    `json:"max_input_length"` // 发送给 LLM 的最大字符数
+			MaxPerMinute   int    `json:"max_per_minute"`   // 每分钟最多请求次数
+		} `json:"auto_title"`
+	} `json:"llm"`
+	Time struct {
+		TimeZones []string `json:"time_zones"` // 时间记录转换的目标时区，例如 Asia/Shanghai、UTC
+		Formats   []string `json:"formats"`    // 输出格式，支持 transformer.TimeFormats 或 Go layout
+	} `json:"time"`
+	URLMeta struct {
+		Disabled        bool     `json:"disabled"`         // 不在后台获取链接的标题、图标
+		DisabledDomains []string `json:"disabled_domains"` // 不获取这些域名及其子域名的链接
+	} `json:"url_meta"`
+	URLClean struct {
+		Disabled           bool                       `json:"disabled"`             // 不清理链接中的跟踪参数
+		WriteBack          bool                       `json:"write_back"`           // 清理后自动把链接写回剪贴板
+		KeepQueryOrder     bool                       `json:"keep_query_order"`     // 不对查询参数排序
+		IgnoreDefaultRules bool                       `json:"ignore_default_rules"` // 只使用 rules 中的规则
+		Rules              []transformer.URLCleanRule `json:"rules"`                // 在内置规则之外追加的规则
+	} `json:"url_clean"`
+	HTML struct {
+		SanitizeProfile string `json:"sanitize_profile"` // 保存 html 时使用的清理规则 strict、keep_layout、text_only，默认 keep_layout
+	} `json:"html"`
+	FileSnapshot struct {
+		Enabled   bool     `json:"enabled"`    // 复制文件时保存文件内容，原文件被移动、删除后仍然可以粘贴
+		MaxSize   int64    `json:"max_size"`   // 超过该大小的文件不保存，单位字节，默认 20MB
+		MimeTypes []string `json:"mime_types"` // 只保存这些类型的文件，支持 image/* 这种写法，为空时不限制
+	} `json:"file_snapshot"`
+	Trash struct {
+		AutoEmptyDays int `json:"auto_empty_days"` // 回收站中的记录保留天数，默认为 0 不自动清空
+	} `json:"trash"`
 	AutoStart bool `json:"auto_start"` // 开机自启
 }
 
+// Public 返回可以给前端展示的配置，去掉了 LLM 厂商的 api key
+func (v *UserSettingsValue) Public() *UserSettingsValue {
+	vv := *v
+	vv.LLM.Providers = make([]llm.Provider, len(v.LLM.Providers))
+	for i, p := range v.LLM.Providers {
+		p.APIKey = ""
+		vv.LLM.Providers[i] = p
+	}
+	return &vv
+}
+
+// keep_llm_api_keys 前端拿不到 api key，写回配置时 key 为空的厂商沿用原来的 key
+func keep_llm_api_keys(prev *UserSettingsValue, next *UserSettingsValue) {
+	if prev == nil || next == nil {
+		return
+	}
+	keys := make(map[string]string)
+	for _, p := range prev.LLM.Providers {
+		keys[p.Name] = p.APIKey
+	}
+	for i, p := range next.LLM.Providers {
+		if p.APIKey == "" {
+			next.LLM.Providers[i].APIKey = keys[p.Name]
+		}
+	}
+}
+
 func NewBizConfig(dir string, filename string) *UserSettings {
 	c := UserSettings{
 		config_dir:      dir,
@@ -119,11 +185,26 @@ func (c *UserSettings) WriteValueWithPath(path string, data interface{}) error {
 	if err != nil {
 		return err
 	}
-	if err := json.Unmarshal(b, c.Value); err != nil {
+	var next UserSettingsValue
+	if err := json.Unmarshal(b, &next); err != nil {
 		return err
 	}
-	c.WriteConfig(c.Value)
-	return nil
+	keep_llm_api_keys(c.Value, &next)
+	return c.WriteConfig(&next)
+}
+
+// WriteValues 用前端提交的完整配置覆盖当前配置
+func (c *UserSettings) WriteValues(data map[string]interface{}) error {
+	b, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	var next UserSettingsValue
+	if err := json.Unmarshal(b, &next); err != nil {
+		return err
+	}
+	keep_llm_api_keys(c.Value, &next)
+	return c.WriteConfig(&next)
 }
 
 func updateField(v reflect.Value, path []string, data interface{}) error {
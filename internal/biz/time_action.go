@@ -0,0 +1,80 @@
+package biz
+
+import (
+	"time"
+	// Windows 上没有系统时区数据库，打包一份保证 LoadLocation 可用
+	_ "time/tzdata"
+
+	"devboard/internal/transformer"
+	"devboard/models"
+)
+
+var default_time_zones = []string{"Local", "UTC"}
+var default_time_formats = []string{"RFC3339", "DateTime", "unix", "unix_ms", "relative"}
+
+// paste_event_instant 优先使用创建记录时保存的时间，旧记录没有时重新解析文本
+func paste_event_instant(record *models.PasteEvent) (time.Time, bool) {
+	details := parse_details(record)
+	if v, ok := details["time"].(map[string]interface{}); ok {
+		if s, ok := v["instant"].(string); ok {
+			if t, err := time.Parse(time.RFC3339Nano, s); err == nil {
+				return t, true
+			}
+		}
+	}
+	if record.Text == "" {
+		return time.Time{}, false
+	}
+	now := time.Now()
+	if created := parse_millis(record.CreatedAt); !created.IsZero() {
+		now = created
+	}
+	t, ok := transformer.ParseTime(record.Text, now)
+	if !ok {
+		return time.Time{}, false
+	}
+	return t.Instant, true
+}
+
+// time_paste_actions 把时间转换为设置中的每个时区和格式
+func time_paste_actions(a *BizApp, record *models.PasteEvent) []PasteAction {
+	instant, ok := paste_event_instant(record)
+	if !ok {
+		return nil
+	}
+	zones := default_time_zones
+	formats := default_time_formats
+	if a.Perferences != nil && a.Perferences.Value != nil {
+		if v := a.Perferences.Value.Time.TimeZones; len(v) != 0 {
+			zones = v
+		}
+		if v := a.Perferences.Value.Time.Formats; len(v) != 0 {
+			formats = v
+		}
+	}
+	now := time.Now()
+	var actions []PasteAction
+	seen := make(map[string]bool)
+	for _, zone := range zones {
+		loc, err := time.LoadLocation(zone)
+		if err != nil {
+			continue
+		}
+		t := instant.In(loc)
+		for _, format := range formats {
+			value := transformer.FormatTime(t, format, now)
+			// unix 时间戳和相对时间与时区无关，只保留一个
+			if seen[format+"\n"+value] {
+				continue
+			}
+			seen[format+"\n"+value] = true
+			actions = append(actions, PasteAction{
+				Id:    "time:" + zone + ":" + format,
+				Group: "time",
+				Label: zone + " · " + format,
+				Value: value,
+			})
+		}
+	}
+	return actions
+}
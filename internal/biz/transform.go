@@ -0,0 +1,89 @@
+package biz
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/ltaoo/clipboard-go"
+
+	"devboard/internal/controller"
+	"devboard/internal/transformer"
+	"devboard/models"
+)
+
+type RunRecipeBody struct {
+	PasteEventId string `json:"paste_event_id"`
+	// RecipeId 和 Steps 二选一，Steps 用于运行尚未保存的组合
+	RecipeId       string                   `json:"recipe_id"`
+	Steps          []transformer.RecipeStep `json:"steps"`
+	WriteClipboard bool                     `json:"write_clipboard"`
+	SaveAsEvent    bool                     `json:"save_as_event"`
+}
+
+type RunRecipeResp struct {
+	Output     string             `json:"output"`
+	PasteEvent *models.PasteEvent `json:"paste_event,omitempty"`
+}
+
+// RunRecipe 对记录的文本内容执行组合转换，可选写入剪贴板或保存为新记录
+func (a *BizApp) RunRecipe(body RunRecipeBody) (*RunRecipeResp, error) {
+	if body.PasteEventId == "" {
+		return nil, fmt.Errorf("Missing the paste_event_id")
+	}
+	steps := body.Steps
+	recipe_name := ""
+	if body.RecipeId != "" {
+		recipe, err := a.ControllerMap.Recipe.FetchRecipe(controller.RecipeBody{Id: body.RecipeId})
+		if err != nil {
+			return nil, err
+		}
+		steps = recipe.Steps
+		recipe_name = recipe.Name
+	}
+	if err := transformer.ValidateRecipe(steps); err != nil {
+		return nil, err
+	}
+	record, err := a.ControllerMap.Paste.FetchPasteEventProfile(controller.PasteProfileBody{EventId: body.PasteEventId})
+	if err != nil {
+		return nil, err
+	}
+	if record.Text == "" {
+		return nil, fmt.Errorf("The record has no text content")
+	}
+	output, err := transformer.RunRecipe(steps, record.Text)
+	if err != nil {
+		return nil, err
+	}
+	resp := &RunRecipeResp{
+		Output: output,
+	}
+	if body.SaveAsEvent {
+		details := map[string]interface{}{
+			"recipe_steps": steps,
+		}
+		if body.RecipeId != "" {
+			details["recipe_id"] = body.RecipeId
+			details["recipe_name"] = recipe_name
+		}
+		created, is_new, err := a.ControllerMap.Paste.CreateDerivedPasteEvent(controller.DerivedPasteEventBody{
+			OriginId: record.Id,
+			Text:     output,
+			Details:  details,
+		})
+		if err != nil {
+			return nil, err
+		}
+		if is_new {
+			a.after_paste_created(created)
+		}
+		resp.PasteEvent = created
+	}
+	if body.WriteClipboard {
+		// 避免剪贴板监听把写入的内容再记录一次，需要记录时使用 SaveAsEvent
+		a.ManuallyWriteClipboardTime = time.Now()
+		if err := clipboard.WriteText(output); err != nil {
+			return nil, err
+		}
+	}
+	return resp, nil
+}
@@ -0,0 +1,98 @@
+package biz
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"devboard/internal/controller"
+)
+
+const (
+	trash_auto_empty_interval = time.Hour
+	// 每次永久删除的记录数量
+	trash_purge_batch_size = 200
+)
+
+// trash_auto_empty_days 返回 0 时不自动清空，需要用户在设置中开启
+func (a *BizApp) trash_auto_empty_days() int {
+	if a.Perferences == nil || a.Perferences.Value == nil {
+		return 0
+	}
+	return max(a.Perferences.Value.Trash.AutoEmptyDays, 0)
+}
+
+// SyncEnabled 是否配置了 WebDAV 同步，开启同步时只能永久删除已经推送到远端的删除操作
+func (a *BizApp) SyncEnabled() bool {
+	if a.Perferences == nil || a.Perferences.Value == nil {
+		return false
+	}
+	return a.Perferences.Value.Synchronize.Webdav.Url != ""
+}
+
+// empty_trash_loop 启动时和之后每小时清理一次超过保留天数的记录
+func (a *BizApp) empty_trash_loop(ctx context.Context) {
+	ticker := time.NewTicker(trash_auto_empty_interval)
+	defer ticker.Stop()
+	for {
+		if days := a.trash_auto_empty_days(); days > 0 {
+			if _, err := a.EmptyTrash(time.Now().AddDate(0, 0, -days)); err != nil {
+				fmt.Println("[ERROR]empty trash failed", err.Error())
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+type TrashEmptyResult struct {
+	PasteEvents int `json:"paste_events"`
+	Remarks     int `json:"remarks"`
+}
+
+// EmptyTrash 永久删除在 before 之前删除的记录和备注
+func (a *BizApp) EmptyTrash(before time.Time) (*TrashEmptyResult, error) {
+	if a.ControllerMap == nil {
+		return nil, fmt.Errorf("The controllers are not initialized")
+	}
+	synced_only := a.SyncEnabled()
+	result := &TrashEmptyResult{}
+	for {
+		ids, err := a.ControllerMap.Paste.FetchExpiredPasteEventIdList(before, trash_purge_batch_size, synced_only)
+		if err != nil {
+			return result, err
+		}
+		if len(ids) == 0 {
+			break
+		}
+		r, err := a.ControllerMap.Paste.PurgePasteEvents(controller.PastePurgeBody{Ids: ids, SyncedOnly: synced_only})
+		if err != nil {
+			return result, err
+		}
+		result.PasteEvents += r.Total
+		if len(ids) < trash_purge_batch_size {
+			break
+		}
+	}
+	for {
+		ids, err := a.ControllerMap.Remark.FetchExpiredRemarkIdList(before, trash_purge_batch_size, synced_only)
+		if err != nil {
+			return result, err
+		}
+		if len(ids) == 0 {
+			break
+		}
+		r, err := a.ControllerMap.Remark.PurgeRemarks(controller.RemarkPurgeBody{Ids: ids, SyncedOnly: synced_only})
+		if err != nil {
+			return result, err
+		}
+		result.Remarks += r.Total
+		if len(ids) < trash_purge_batch_size {
+			break
+		}
+	}
+	return result, nil
+}
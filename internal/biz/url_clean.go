@@ -0,0 +1,81 @@
+package biz
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/ltaoo/clipboard-go"
+
+	"devboard/internal/transformer"
+	"devboard/models"
+)
+
+func (a *BizApp) url_normalize_options() (transformer.URLNormalizeOptions, bool) {
+	opts := transformer.URLNormalizeOptions{
+		Rules:     transformer.DefaultURLCleanRules,
+		SortQuery: true,
+	}
+	if a.Perferences == nil || a.Perferences.Value == nil {
+		return opts, true
+	}
+	cfg := a.Perferences.Value.URLClean
+	if cfg.Disabled {
+		return opts, false
+	}
+	if cfg.IgnoreDefaultRules {
+		opts.Rules = nil
+	}
+	opts.Rules = append(append([]transformer.URLCleanRule{}, opts.Rules...), cfg.Rules...)
+	opts.SortQuery = !cfg.KeepQueryOrder
+	return opts, true
+}
+
+// clean_url_text 文本是链接时返回清理后的链接，相同链接只保存一条记录
+func (a *BizApp) clean_url_text(text string) (string, bool) {
+	opts, enabled := a.url_normalize_options()
+	if !enabled {
+		return text, false
+	}
+	cleaned, ok := transformer.NormalizeURL(text, opts)
+	if !ok || cleaned == text {
+		return text, false
+	}
+	return cleaned, true
+}
+
+// write_back_cleaned_url 开启后把清理后的链接写回剪贴板，并忽略这次写入触发的剪贴板变化
+func (a *BizApp) write_back_cleaned_url(cleaned string) {
+	if a.Perferences == nil || a.Perferences.Value == nil || !a.Perferences.Value.URLClean.WriteBack {
+		return
+	}
+	a.ManuallyWriteClipboardTime = time.Now()
+	if err := clipboard.WriteText(cleaned); err != nil {
+		fmt.Println("[ERROR]write cleaned url to clipboard failed", err.Error())
+	}
+}
+
+// url_paste_actions 复制原始链接或清理后的链接
+func url_paste_actions(a *BizApp, record *models.PasteEvent) []PasteAction {
+	if record.ContentType != "text" {
+		return nil
+	}
+	var actions []PasteAction
+	details := parse_details(record)
+	if original, ok := details["original_text"].(string); ok && original != "" {
+		actions = append(actions, PasteAction{
+			Id:    "url:original",
+			Group: "url",
+			Label: "Copy original URL",
+			Value: original,
+		})
+	}
+	if cleaned, ok := a.clean_url_text(record.Text); ok {
+		actions = append(actions, PasteAction{
+			Id:    "url:clean",
+			Group: "url",
+			Label: "Copy cleaned URL",
+			Value: cleaned,
+		})
+	}
+	return actions
+}
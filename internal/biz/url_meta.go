@@ -0,0 +1,305 @@
+package biz
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"devboard/internal/controller"
+	"devboard/internal/job"
+	"devboard/models"
+	"devboard/pkg/urlmeta"
+)
+
+const (
+	url_meta_max_attempts     = 5
+	url_meta_max_asset_size   = 1024 * 1024
+	url_meta_max_per_minute   = 30
+	url_meta_retry_interval   = 5 * time.Minute
+	url_meta_offline_delay    = time.Minute
+	url_meta_fetch_timeout    = 20 * time.Second
+	url_meta_retry_batch_size = 20
+)
+
+// 第 n 次失败后等待的时间
+var url_meta_backoff = []time.Duration{time.Minute, 5 * time.Minute, 30 * time.Minute, 2 * time.Hour}
+
+func url_meta_next_retry(attempts int) string {
+	i := attempts - 1
+	if i < 0 {
+		i = 0
+	}
+	if i >= len(url_meta_backoff) {
+		i = len(url_meta_backoff) - 1
+	}
+	return strconv.FormatInt(time.Now().Add(url_meta_backoff[i]).UnixMilli(), 10)
+}
+
+type URLMetaResp struct {
+	URL          string `json:"url"`
+	CanonicalURL string `json:"canonical_url"`
+	Title        string `json:"title"`
+	Description  string `json:"description"`
+	SiteName     string `json:"site_name"`
+	Icon         string `json:"icon"`
+	Image        string `json:"image"`
+	Status       int    `json:"status"`
+	FetchedAt    string `json:"fetched_at"`
+}
+
+// to_url_meta_resp 图标和图片已下载时返回本地地址，离线也能展示
+func to_url_meta_resp(record *models.URLMeta) URLMetaResp {
+	resp := URLMetaResp{
+		URL:          record.URL,
+		CanonicalURL: record.CanonicalURL,
+		Title:        record.Title,
+		Description:  record.Description,
+		SiteName:     record.SiteName,
+		Icon:         record.IconURL,
+		Image:        record.ImageURL,
+		Status:       record.Status,
+		FetchedAt:    record.FetchedAt,
+	}
+	if record.IconBlob != "" {
+		resp.Icon = controller.BlobURL(record.IconBlob)
+	}
+	if record.ImageBlob != "" {
+		resp.Image = controller.BlobURL(record.ImageBlob)
+	}
+	return resp
+}
+
+func (a *BizApp) start_url_meta_jobs(ctx context.Context) {
+	a.URLMetaWorker = job.NewWorker("url_meta", 64, func() time.Duration {
+		return job.PerMinute(url_meta_max_per_minute)
+	}).Start(ctx)
+	go a.retry_url_meta_loop(ctx)
+}
+
+// retry_url_meta_loop 定时把失败、未完成的链接重新加入队列，应用重启后也能继续
+func (a *BizApp) retry_url_meta_loop(ctx context.Context) {
+	ticker := time.NewTicker(url_meta_retry_interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if a.ControllerMap == nil {
+				continue
+			}
+			list, err := a.ControllerMap.URLMeta.FetchDueURLMetaList(url_meta_max_attempts, url_meta_retry_batch_size)
+			if err != nil {
+				fmt.Println("[ERROR]fetch url meta list failed", err.Error())
+				continue
+			}
+			for _, record := range list {
+				a.push_url_meta_task(record.URL)
+			}
+		}
+	}
+}
+
+// url_meta_domain_disabled 用户关闭了链接信息获取，或者域名（包括子域名）在排除列表中
+func (a *BizApp) url_meta_domain_disabled(domain string) bool {
+	if a.Perferences == nil || a.Perferences.Value == nil {
+		return false
+	}
+	cfg := a.Perferences.Value.URLMeta
+	if cfg.Disabled {
+		return true
+	}
+	for _, d := range cfg.DisabledDomains {
+		d = strings.TrimPrefix(strings.ToLower(strings.TrimSpace(d)), ".")
+		if d == "" {
+			continue
+		}
+		if domain == d || strings.HasSuffix(domain, "."+d) {
+			return true
+		}
+	}
+	return false
+}
+
+func (a *BizApp) enqueue_url_meta(created *models.PasteEvent) {
+	if a.URLMetaWorker == nil || created.ContentType != "text" {
+		return
+	}
+	if controller.IsSecretPasteEvent(created) {
+		return
+	}
+	is_url := false
+	for _, c := range created.Categories {
+		if c.Id == "url" {
+			is_url = true
+			break
+		}
+	}
+	if !is_url {
+		return
+	}
+	a.push_url_meta_task(strings.TrimSpace(created.Text))
+}
+
+func (a *BizApp) push_url_meta_task(u string) {
+	if a.URLMetaWorker == nil {
+		return
+	}
+	a.URLMetaWorker.Push(job.Task{
+		Name: u,
+		Run: func(ctx context.Context) error {
+			ctx, cancel := context.WithTimeout(ctx, url_meta_fetch_timeout)
+			defer cancel()
+			// 后台自动获取时不请求本机和局域网的地址
+			_, err := a.EnrichURL(ctx, u, false, true)
+			return err
+		},
+	})
+}
+
+// save_url_asset 下载图标或图片保存到 blob 目录，返回哈希
+func (a *BizApp) save_url_asset(ctx context.Context, u string, kind string, block_private bool) (string, error) {
+	if a.Blob == nil {
+		return "", fmt.Errorf("The blob store is not initialized")
+	}
+	buf, mime_type, err := urlmeta.FetchAsset(ctx, u, url_meta_max_asset_size, block_private)
+	if err != nil {
+		return "", err
+	}
+	hash, err := a.Blob.Put(buf)
+	if err != nil {
+		return "", err
+	}
+	if _, err := a.ControllerMap.Blob.SaveBlob(hash, kind, mime_type, int64(len(buf))); err != nil {
+		return "", err
+	}
+	return hash, nil
+}
+
+// EnrichURL 获取链接的标题、描述、图标等信息并缓存
+// force 为 false 时已成功获取过的链接直接返回缓存，block_private 为 true 时跳过本机和局域网的地址
+func (a *BizApp) EnrichURL(ctx context.Context, raw string, force bool, block_private bool) (*models.URLMeta, error) {
+	parsed, err := urlmeta.Normalize(raw)
+	if err != nil {
+		return nil, err
+	}
+	u := parsed.String()
+	domain := strings.ToLower(parsed.Hostname())
+	record, err := a.ControllerMap.URLMeta.EnsureURLMeta(u, domain)
+	if err != nil {
+		return nil, err
+	}
+	if a.url_meta_domain_disabled(domain) {
+		if record.Status != controller.URLMetaStatusSkipped && record.FetchedAt == "" {
+			record.Status = controller.URLMetaStatusSkipped
+			if err := a.ControllerMap.URLMeta.SaveURLMeta(record); err != nil {
+				return nil, err
+			}
+		}
+		return record, nil
+	}
+	if !force && record.Status == controller.URLMetaStatusOk {
+		return record, nil
+	}
+	if force {
+		record.Attempts = 0
+	}
+	meta, err := urlmeta.Fetch(ctx, u, block_private)
+	if errors.Is(err, urlmeta.ErrPrivateAddress) {
+		// 本机和局域网的地址不获取信息
+		record.Status = controller.URLMetaStatusSkipped
+		record.LastError = err.Error()
+		record.NextRetryAt = ""
+		if err := a.ControllerMap.URLMeta.SaveURLMeta(record); err != nil {
+			return nil, err
+		}
+		return record, nil
+	}
+	if err != nil {
+		record.LastError = err.Error()
+		switch {
+		case record.FetchedAt != "":
+			// 之前获取成功过，保留原来的信息
+			record.Status = controller.URLMetaStatusOk
+			record.NextRetryAt = ""
+		case urlmeta.IsOffline(err):
+			// 网络不可用，不计入失败次数
+			record.Status = controller.URLMetaStatusFailed
+			record.NextRetryAt = strconv.FormatInt(time.Now().Add(url_meta_offline_delay).UnixMilli(), 10)
+		case urlmeta.IsPermanent(err):
+			record.Status = controller.URLMetaStatusFailed
+			record.Attempts = url_meta_max_attempts
+			record.NextRetryAt = ""
+		default:
+			record.Status = controller.URLMetaStatusFailed
+			record.Attempts += 1
+			record.NextRetryAt = url_meta_next_retry(record.Attempts)
+		}
+		if err2 := a.ControllerMap.URLMeta.SaveURLMeta(record); err2 != nil {
+			return nil, err2
+		}
+		return record, err
+	}
+	record.CanonicalURL = meta.CanonicalURL
+	record.Title = meta.Title
+	record.Description = meta.Description
+	record.SiteName = meta.SiteName
+	// 图标、图片下载失败时不影响其他信息，沿用之前下载的文件
+	if meta.Icon != "" && (meta.Icon != record.IconURL || record.IconBlob == "") {
+		if hash, err := a.save_url_asset(ctx, meta.Icon, "favicon", block_private); err == nil {
+			record.IconBlob = hash
+		} else {
+			fmt.Println("[ERROR]download favicon failed", meta.Icon, err.Error())
+		}
+	}
+	if meta.Image != "" && (meta.Image != record.ImageURL || record.ImageBlob == "") {
+		if hash, err := a.save_url_asset(ctx, meta.Image, "og_image", block_private); err == nil {
+			record.ImageBlob = hash
+		} else {
+			fmt.Println("[ERROR]download og:image failed", meta.Image, err.Error())
+		}
+	}
+	record.IconURL = meta.Icon
+	record.ImageURL = meta.Image
+	record.Status = controller.URLMetaStatusOk
+	record.Attempts = 0
+	record.LastError = ""
+	record.NextRetryAt = ""
+	record.FetchedAt = strconv.FormatInt(time.Now().UnixMilli(), 10)
+	if err := a.ControllerMap.URLMeta.SaveURLMeta(record); err != nil {
+		return nil, err
+	}
+	if a.app != nil {
+		a.app.Event.Emit("url_meta:updated", to_url_meta_resp(record))
+	}
+	return record, nil
+}
+
+// FetchURLMeta 优先返回缓存，没有缓存时立即获取，用户主动获取时允许本机和局域网的地址
+func (a *BizApp) FetchURLMeta(ctx context.Context, raw string) (*URLMetaResp, error) {
+	record, err := a.EnrichURL(ctx, raw, false, false)
+	if record == nil {
+		return nil, err
+	}
+	if record.Status != controller.URLMetaStatusOk && record.Status != controller.URLMetaStatusSkipped {
+		if err == nil {
+			err = fmt.Errorf("Failed to fetch the url meta, %s", record.LastError)
+		}
+		return nil, err
+	}
+	resp := to_url_meta_resp(record)
+	return &resp, nil
+}
+
+// RefreshURLMeta 忽略缓存重新获取
+func (a *BizApp) RefreshURLMeta(ctx context.Context, raw string) (*URLMetaResp, error) {
+	record, err := a.EnrichURL(ctx, raw, true, false)
+	if err != nil {
+		return nil, err
+	}
+	resp := to_url_meta_resp(record)
+	return &resp, nil
+}
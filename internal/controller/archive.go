@@ -0,0 +1,809 @@
+package controller
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"devboard/models"
+	"devboard/pkg/blob"
+	_html "devboard/pkg/html"
+)
+
+// 归档文件的结构：
+//
+//	manifest.json            ArchiveManifest
+//	tables/<table>.ndjson    每行一条记录，字段和数据库中的列相同
+//	blobs/<hash>             文件快照和备注附件
+const (
+	ArchiveFormat  = "devboard-archive"
+	ArchiveVersion = 1
+
+	archive_manifest_name = "manifest.json"
+	archive_tables_dir    = "tables/"
+	archive_blobs_dir     = "blobs/"
+)
+
+// 按 id 批量查询时每批的数量，避免超过 SQLite 的参数数量限制
+const archive_batch_size = 500
+
+// 导出和导入的顺序，被引用的表在前面
+var archive_tables = []string{
+	"app",
+	"device",
+	"category_node",
+	"category_hierarchy",
+	"paste_event",
+	"paste_event_category_mapping",
+	"remark",
+	"remark_revision",
+	"remark_attachment",
+	"blob",
+}
+
+type ArchiveManifest struct {
+	Format  string `json:"format"`
+	Version int    `json:"version"`
+	// SchemaVersion 导出时数据库迁移的版本
+	SchemaVersion uint           `json:"schema_version"`
+	MachineId     string         `json:"machine_id"`
+	CreatedAt     string         `json:"created_at"`
+	Tables        map[string]int `json:"tables"`
+	Blobs         int            `json:"blobs"`
+}
+
+// schema_version 读取 golang-migrate 记录的版本，读取失败时返回 0
+func schema_version(db *gorm.DB) uint {
+	var versions []uint
+	if err := db.Raw("SELECT version FROM schema_migrations LIMIT 1").Scan(&versions).Error; err != nil || len(versions) == 0 {
+		return 0
+	}
+	return versions[0]
+}
+
+// 导出时去掉关联和读取时生成的字段，外层的字段会覆盖内嵌结构中同名的字段
+type archive_paste_event struct {
+	models.PasteEvent
+	Device      *struct{} `json:"device,omitempty"`
+	App         *struct{} `json:"app,omitempty"`
+	Categories  *struct{} `json:"categories,omitempty"`
+	Remarks     *struct{} `json:"remarks,omitempty"`
+	RemarkCount *struct{} `json:"remark_count,omitempty"`
+}
+
+type archive_category_node struct {
+	models.CategoryNode
+	Parents     *struct{} `json:"parents,omitempty"`
+	Children    *struct{} `json:"children,omitempty"`
+	PasteEvents *struct{} `json:"paste_events,omitempty"`
+}
+
+type archive_remark struct {
+	models.Remark
+	HTML        *struct{} `json:"html,omitempty"`
+	Attachments *struct{} `json:"attachments,omitempty"`
+}
+
+type archive_remark_attachment struct {
+	models.RemarkAttachment
+	URL *struct{} `json:"url,omitempty"`
+}
+
+func chunk_ids(ids []string, fn func(chunk []string) error) error {
+	for start := 0; start < len(ids); start += archive_batch_size {
+		end := start + archive_batch_size
+		if end > len(ids) {
+			end = len(ids)
+		}
+		if err := fn(ids[start:end]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type archive_writer struct {
+	zw       *zip.Writer
+	manifest *ArchiveManifest
+	progress func(done int, total int)
+	done     int
+	total    int
+}
+
+func (w *archive_writer) tick() {
+	w.done += 1
+	if w.progress != nil && (w.done%archive_batch_size == 0 || w.done == w.total) {
+		w.progress(w.done, w.total)
+	}
+}
+
+// write_archive_rows ids 为 nil 时 load 只调用一次
+func write_archive_rows[T any](w *archive_writer, name string, ids []string, load func(ids []string) ([]T, error)) error {
+	f, err := w.zw.Create(archive_tables_dir + name + ".ndjson")
+	if err != nil {
+		return err
+	}
+	enc := json.NewEncoder(f)
+	write := func(chunk []string) error {
+		rows, err := load(chunk)
+		if err != nil {
+			return err
+		}
+		for i := range rows {
+			if err := enc.Encode(&rows[i]); err != nil {
+				return err
+			}
+			w.manifest.Tables[name] += 1
+			if name == "paste_event" || name == "remark" {
+				w.tick()
+			}
+		}
+		return nil
+	}
+	w.manifest.Tables[name] = 0
+	if ids == nil {
+		return write(nil)
+	}
+	return chunk_ids(ids, write)
+}
+
+type ArchiveExportBody struct {
+	// Ids 和 Filter 都为空时导出所有记录
+	Ids    []string       `json:"ids"`
+	Filter *PasteListBody `json:"filter"`
+}
+
+// ExportArchive 把记录和相关的分类、备注、应用、设备以及引用的文件写入 zip，只包括没有删除的数据
+func (s *PasteController) ExportArchive(body ArchiveExportBody, dst io.Writer, progress func(done int, total int)) (*ArchiveManifest, error) {
+	var ids []string
+	if len(body.Ids) == 0 && body.Filter == nil {
+		if err := s.db.Model(&models.PasteEvent{}).Order("created_at ASC").Pluck("id", &ids).Error; err != nil {
+			return nil, err
+		}
+	} else {
+		var err error
+		// 导出不会修改数据，没有条件的 filter 表示导出所有记录
+		ids, err = s.ResolvePasteBulkIds(PasteBulkBody{Ids: body.Ids, Filter: body.Filter, All: true})
+		if err != nil {
+			return nil, err
+		}
+	}
+	if ids == nil {
+		ids = []string{}
+	}
+	var remark_ids []string
+	if err := chunk_ids(ids, func(chunk []string) error {
+		var v []string
+		if err := s.db.Model(&models.Remark{}).Where("paste_event_id IN ?", chunk).Pluck("id", &v).Error; err != nil {
+			return err
+		}
+		remark_ids = append(remark_ids, v...)
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	if remark_ids == nil {
+		remark_ids = []string{}
+	}
+	manifest := &ArchiveManifest{
+		Format:        ArchiveFormat,
+		Version:       ArchiveVersion,
+		SchemaVersion: schema_version(s.db),
+		MachineId:     s.machine_id,
+		CreatedAt:     strconv.FormatInt(time.Now().UnixMilli(), 10),
+		Tables:        make(map[string]int),
+	}
+	zw := zip.NewWriter(dst)
+	w := &archive_writer{
+		zw:       zw,
+		manifest: manifest,
+		progress: progress,
+		// 进度只计算记录和备注，分类等其他表的数量较少或者跟随记录
+		total: len(ids) + len(remark_ids),
+	}
+	hashes := make(map[string]bool)
+	var hash_list []string
+	add_hash := func(hash string) {
+		if hash != "" && !hashes[hash] {
+			hashes[hash] = true
+			hash_list = append(hash_list, hash)
+		}
+	}
+	steps := []func() error{
+		func() error {
+			return write_archive_rows(w, "app", nil, func([]string) ([]models.App, error) {
+				var rows []models.App
+				return rows, s.db.Order("created_at ASC").Find(&rows).Error
+			})
+		},
+		func() error {
+			return write_archive_rows(w, "device", nil, func([]string) ([]models.Device, error) {
+				var rows []models.Device
+				return rows, s.db.Order("created_at ASC").Find(&rows).Error
+			})
+		},
+		func() error {
+			return write_archive_rows(w, "category_node", nil, func([]string) ([]archive_category_node, error) {
+				var records []models.CategoryNode
+				if err := s.db.Order("created_at ASC").Find(&records).Error; err != nil {
+					return nil, err
+				}
+				rows := make([]archive_category_node, 0, len(records))
+				for _, r := range records {
+					rows = append(rows, archive_category_node{CategoryNode: r})
+				}
+				return rows, nil
+			})
+		},
+		func() error {
+			return write_archive_rows(w, "category_hierarchy", nil, func([]string) ([]models.CategoryHierarchy, error) {
+				var rows []models.CategoryHierarchy
+				return rows, s.db.Order("created_at ASC").Find(&rows).Error
+			})
+		},
+		func() error {
+			return write_archive_rows(w, "paste_event", ids, func(chunk []string) ([]archive_paste_event, error) {
+				var records []models.PasteEvent
+				if err := s.db.Where("id IN ?", chunk).Order("created_at ASC").Find(&records).Error; err != nil {
+					return nil, err
+				}
+				rows := make([]archive_paste_event, 0, len(records))
+				for _, r := range records {
+					if r.ContentType == "file" {
+						var files []FileInPasteEvent
+						json.Unmarshal([]byte(r.FileListJSON), &files)
+						for _, f := range files {
+							add_hash(f.BlobHash)
+						}
+					}
+					rows = append(rows, archive_paste_event{PasteEvent: r})
+				}
+				return rows, nil
+			})
+		},
+		func() error {
+			return write_archive_rows(w, "paste_event_category_mapping", ids, func(chunk []string) ([]models.PasteEventCategoryMapping, error) {
+				var rows []models.PasteEventCategoryMapping
+				return rows, s.db.Where("paste_event_id IN ?", chunk).Order("created_at ASC").Find(&rows).Error
+			})
+		},
+		func() error {
+			return write_archive_rows(w, "remark", remark_ids, func(chunk []string) ([]archive_remark, error) {
+				var records []models.Remark
+				if err := s.db.Where("id IN ?", chunk).Order("created_at ASC").Find(&records).Error; err != nil {
+					return nil, err
+				}
+				rows := make([]archive_remark, 0, len(records))
+				for _, r := range records {
+					rows = append(rows, archive_remark{Remark: r})
+				}
+				return rows, nil
+			})
+		},
+		func() error {
+			return write_archive_rows(w, "remark_revision", remark_ids, func(chunk []string) ([]models.RemarkRevision, error) {
+				var rows []models.RemarkRevision
+				return rows, s.db.Where("remark_id IN ?", chunk).Order("created_at ASC").Find(&rows).Error
+			})
+		},
+		func() error {
+			return write_archive_rows(w, "remark_attachment", remark_ids, func(chunk []string) ([]archive_remark_attachment, error) {
+				var records []models.RemarkAttachment
+				if err := s.db.Where("remark_id IN ?", chunk).Order("created_at ASC").Find(&records).Error; err != nil {
+					return nil, err
+				}
+				rows := make([]archive_remark_attachment, 0, len(records))
+				for _, r := range records {
+					add_hash(r.BlobHash)
+					rows = append(rows, archive_remark_attachment{RemarkAttachment: r})
+				}
+				return rows, nil
+			})
+		},
+	}
+	for _, step := range steps {
+		if err := step(); err != nil {
+			return nil, err
+		}
+	}
+	// 快照文件已经被删除时跳过，导入后和原来一样无法恢复
+	var available []string
+	for _, hash := range hash_list {
+		if s.blob != nil && s.blob.Has(hash) {
+			available = append(available, hash)
+		}
+	}
+	if available == nil {
+		available = []string{}
+	}
+	if err := write_archive_rows(w, "blob", available, func(chunk []string) ([]models.Blob, error) {
+		var rows []models.Blob
+		return rows, s.db.Where("hash IN ?", chunk).Find(&rows).Error
+	}); err != nil {
+		return nil, err
+	}
+	for _, hash := range available {
+		if err := s.write_archive_blob(zw, hash); err != nil {
+			return nil, err
+		}
+		manifest.Blobs += 1
+	}
+	f, err := zw.Create(archive_manifest_name)
+	if err != nil {
+		return nil, err
+	}
+	enc := json.NewEncoder(f)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(manifest); err != nil {
+		return nil, err
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	if progress != nil && w.done != w.total {
+		progress(w.total, w.total)
+	}
+	return manifest, nil
+}
+
+// write_archive_blob 直接从 blob 目录复制文件，不把内容读到内存中
+func (s *PasteController) write_archive_blob(zw *zip.Writer, hash string) error {
+	p, err := s.blob.Path(hash)
+	if err != nil {
+		return err
+	}
+	src, err := os.Open(p)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	// 快照大多是已经压缩过的文件，不再压缩
+	f, err := zw.CreateHeader(&zip.FileHeader{
+		Name:   archive_blobs_dir + hash,
+		Method: zip.Store,
+	})
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(f, src)
+	return err
+}
+
+const (
+	// ArchiveImportMerge 保留已有的数据，和归档中的数据合并
+	ArchiveImportMerge = "merge"
+	// ArchiveImportReplace 先把已有的记录、分类关联和备注移到回收站，再导入归档中的数据
+	ArchiveImportReplace = "replace"
+)
+
+const (
+	// ArchiveConflictSkip id 已经存在时保留本地的数据
+	ArchiveConflictSkip = "skip"
+	// ArchiveConflictOverwrite id 已经存在时使用归档中的数据
+	ArchiveConflictOverwrite = "overwrite"
+	// ArchiveConflictNewer id 已经存在时保留更新时间较晚的数据
+	ArchiveConflictNewer = "newer"
+)
+
+type ArchiveImportBody struct {
+	Path string `json:"path"`
+	// Mode 见 ArchiveImportMerge、ArchiveImportReplace，默认为 merge
+	Mode string `json:"mode"`
+	// Conflict 见 ArchiveConflictSkip 等，默认为 skip，replace 模式下总是使用归档中的数据
+	Conflict string `json:"conflict"`
+	// DryRun 只统计导入的结果，不修改数据
+	DryRun bool `json:"dry_run"`
+	// HTMLProfile 清理导入的 html 时使用的规则，见 _html.FindSanitizeProfile
+	HTMLProfile string `json:"-"`
+}
+
+type ArchiveTableStats struct {
+	Created int `json:"created"`
+	Updated int `json:"updated"`
+	Skipped int `json:"skipped"`
+}
+
+type ArchiveImportResult struct {
+	Manifest *ArchiveManifest              `json:"manifest"`
+	Tables   map[string]*ArchiveTableStats `json:"tables"`
+	// Replaced replace 模式下移到回收站的记录数量
+	Replaced int `json:"replaced"`
+	// BlobsCreated 新增的文件数量，BlobsExisting 本地已经存在的文件数量
+	BlobsCreated  int  `json:"blobs_created"`
+	BlobsExisting int  `json:"blobs_existing"`
+	DryRun        bool `json:"dry_run"`
+}
+
+var errArchiveDryRun = errors.New("dry run")
+
+func read_archive_manifest(zr *zip.Reader) (*ArchiveManifest, error) {
+	f, err := zr.Open(archive_manifest_name)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid archive, missing %s", archive_manifest_name)
+	}
+	defer f.Close()
+	var manifest ArchiveManifest
+	if err := json.NewDecoder(f).Decode(&manifest); err != nil {
+		return nil, fmt.Errorf("Invalid archive manifest, %v", err)
+	}
+	if manifest.Format != ArchiveFormat {
+		return nil, fmt.Errorf("Invalid archive format %s", manifest.Format)
+	}
+	if manifest.Version > ArchiveVersion {
+		return nil, fmt.Errorf("The archive version %d is not supported, please upgrade the app", manifest.Version)
+	}
+	return &manifest, nil
+}
+
+type archive_importer struct {
+	tx       *gorm.DB
+	zr       *zip.Reader
+	conflict string
+	result   *ArchiveImportResult
+	progress func(done int, total int)
+	done     int
+	total    int
+	// html_profile 清理导入的 html 时使用的规则
+	html_profile string
+	// 新增或覆盖的记录，导入后重新提取实体
+	paste_event_ids []string
+}
+
+// sanitize_imported_html 归档中的 html 可能被修改过，和复制时一样清理后再保存，Markdown 从清理后的 html 重新生成
+func sanitize_imported_html(r *models.PasteEvent, profile string) {
+	if r.Html == "" {
+		return
+	}
+	r.Html = _html.Sanitize(r.Html, _html.FindSanitizeProfile(profile))
+	r.Markdown, _ = _html.ToMarkdown(r.Html)
+}
+
+func (im *archive_importer) tick() {
+	im.done += 1
+	if im.progress != nil && (im.done%archive_batch_size == 0 || im.done == im.total) {
+		im.progress(im.done, im.total)
+	}
+}
+
+// timestamp_of 比较新旧时使用的时间，毫秒
+func timestamp_of(b *models.BaseModel) int64 {
+	for _, v := range []string{b.UpdatedAt, b.LastOperationTime, b.CreatedAt} {
+		if n, err := strconv.ParseInt(v, 10, 64); err == nil {
+			return n
+		}
+	}
+	return 0
+}
+
+// import_archive_rows find 查找本地冲突的记录，返回的记录会被归档中的数据覆盖
+// prepare 在写入前修改归档中的数据
+func import_archive_rows[T any](im *archive_importer, name string, base func(*T) *models.BaseModel, prepare func(row *T), find func(tx *gorm.DB, row *T) ([]T, error), after func(row *T)) error {
+	stats := &ArchiveTableStats{}
+	im.result.Tables[name] = stats
+	f, err := im.zr.Open(archive_tables_dir + name + ".ndjson")
+	if err != nil {
+		// 旧版本的归档可能没有某些表
+		return nil
+	}
+	defer f.Close()
+	dec := json.NewDecoder(f)
+	for {
+		var row T
+		if err := dec.Decode(&row); err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return fmt.Errorf("Invalid rows of %s, %v", name, err)
+		}
+		im.tick()
+		b := base(&row)
+		if b.Id == "" {
+			stats.Skipped += 1
+			continue
+		}
+		if prepare != nil {
+			prepare(&row)
+		}
+		existing, err := find(im.tx, &row)
+		if err != nil {
+			return err
+		}
+		if len(existing) == 0 {
+			if err := im.tx.Omit(clause.Associations).Create(&row).Error; err != nil {
+				return fmt.Errorf("Create %s %s failed, %v", name, b.Id, err)
+			}
+			stats.Created += 1
+			if after != nil {
+				after(&row)
+			}
+			continue
+		}
+		local := base(&existing[0])
+		overwrite := false
+		switch im.conflict {
+		case ArchiveConflictOverwrite:
+			overwrite = true
+		case ArchiveConflictNewer:
+			overwrite = timestamp_of(b) > timestamp_of(local)
+		}
+		// 本地的记录已经删除时总是恢复
+		if local.DeletedAt.Valid && !b.DeletedAt.Valid {
+			overwrite = true
+		}
+		if !overwrite {
+			stats.Skipped += 1
+			continue
+		}
+		// 按照本地记录的 id 覆盖，关联表中 id 不同但关联相同的记录也算作冲突
+		b.Id = local.Id
+		if err := im.tx.Unscoped().Omit(clause.Associations).Save(&row).Error; err != nil {
+			return fmt.Errorf("Update %s %s failed, %v", name, b.Id, err)
+		}
+		stats.Updated += 1
+		if after != nil {
+			after(&row)
+		}
+	}
+}
+
+func find_by_id[T any](tx *gorm.DB, id string) ([]T, error) {
+	var existing []T
+	if err := tx.Unscoped().Where("id = ?", id).Limit(1).Find(&existing).Error; err != nil {
+		return nil, err
+	}
+	return existing, nil
+}
+
+// replace_history 把已有的记录、分类关联和备注移到回收站，返回移动的记录数量
+func replace_history(tx *gorm.DB) (int, error) {
+	now := time.Now()
+	r := tx.Model(&models.PasteEvent{}).Where("deleted_at IS NULL").UpdateColumns(sync_columns(map[string]interface{}{"deleted_at": now}, 3))
+	if r.Error != nil {
+		return 0, r.Error
+	}
+	for _, m := range []interface{}{&models.PasteEventCategoryMapping{}, &models.Remark{}} {
+		if err := tx.Model(m).Where("deleted_at IS NULL").UpdateColumns(sync_columns(map[string]interface{}{"deleted_at": now}, 3)).Error; err != nil {
+			return 0, err
+		}
+	}
+	return int(r.RowsAffected), nil
+}
+
+// archive_blob_hashes 归档中所有文件的哈希
+func archive_blob_hashes(zr *zip.Reader) []string {
+	var hashes []string
+	for _, f := range zr.File {
+		if strings.HasPrefix(f.Name, archive_blobs_dir) && !f.FileInfo().IsDir() {
+			hashes = append(hashes, path.Base(f.Name))
+		}
+	}
+	return hashes
+}
+
+// 归档中单个文件的最大大小，和文件快照的上限一致
+const archive_blob_max_size = 256 * 1024 * 1024
+
+// import_archive_blobs 文件在数据库事务之前写入，内容相同的文件只保存一份
+func (s *PasteController) import_archive_blobs(zr *zip.Reader, result *ArchiveImportResult, dry_run bool) ([]string, error) {
+	var created []string
+	for _, f := range zr.File {
+		if !strings.HasPrefix(f.Name, archive_blobs_dir) || f.FileInfo().IsDir() {
+			continue
+		}
+		hash := path.Base(f.Name)
+		if s.blob != nil && s.blob.Has(hash) {
+			result.BlobsExisting += 1
+			continue
+		}
+		if f.UncompressedSize64 > archive_blob_max_size {
+			return created, fmt.Errorf("The file %s in the archive is larger than %d bytes", f.Name, archive_blob_max_size)
+		}
+		result.BlobsCreated += 1
+		if dry_run {
+			continue
+		}
+		if s.blob == nil {
+			return created, fmt.Errorf("The blob store is not initialized")
+		}
+		r, err := f.Open()
+		if err != nil {
+			return created, err
+		}
+		// 实际内容超过上限时会被截断，哈希校验不会通过
+		_, _, err = s.blob.PutReader(io.LimitReader(r, archive_blob_max_size+1), hash)
+		r.Close()
+		if errors.Is(err, blob.ErrHashMismatch) {
+			return created, fmt.Errorf("The file %s in the archive is corrupted", f.Name)
+		}
+		if err != nil {
+			return created, err
+		}
+		created = append(created, hash)
+	}
+	return created, nil
+}
+
+// ImportArchive 在一个事务中导入归档，dry_run 时执行完后回滚，只返回统计结果
+func (s *PasteController) ImportArchive(body ArchiveImportBody, progress func(done int, total int)) (*ArchiveImportResult, error) {
+	if body.Path == "" {
+		return nil, fmt.Errorf("Missing the path")
+	}
+	if body.Mode == "" {
+		body.Mode = ArchiveImportMerge
+	}
+	if body.Mode != ArchiveImportMerge && body.Mode != ArchiveImportReplace {
+		return nil, fmt.Errorf("Unknown mode %s", body.Mode)
+	}
+	if body.Conflict == "" {
+		body.Conflict = ArchiveConflictSkip
+	}
+	if body.Conflict != ArchiveConflictSkip && body.Conflict != ArchiveConflictOverwrite && body.Conflict != ArchiveConflictNewer {
+		return nil, fmt.Errorf("Unknown conflict strategy %s", body.Conflict)
+	}
+	if body.Mode == ArchiveImportReplace {
+		body.Conflict = ArchiveConflictOverwrite
+	}
+	zrc, err := zip.OpenReader(body.Path)
+	if err != nil {
+		return nil, err
+	}
+	defer zrc.Close()
+	zr := &zrc.Reader
+	manifest, err := read_archive_manifest(zr)
+	if err != nil {
+		return nil, err
+	}
+	if current := schema_version(s.db); current != 0 && manifest.SchemaVersion > current {
+		return nil, fmt.Errorf("The archive is created by a newer version (schema %d > %d), please upgrade the app", manifest.SchemaVersion, current)
+	}
+	result := &ArchiveImportResult{
+		Manifest: manifest,
+		Tables:   make(map[string]*ArchiveTableStats),
+		DryRun:   body.DryRun,
+	}
+	created_blobs, err := s.import_archive_blobs(zr, result, body.DryRun)
+	if err != nil {
+		if e := delete_unused_blobs(s.db, s.blob, created_blobs); e != nil {
+			fmt.Println("[ERROR]delete unused blobs failed", e.Error())
+		}
+		return nil, err
+	}
+	total := 0
+	for _, n := range manifest.Tables {
+		total += n
+	}
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if body.Mode == ArchiveImportReplace {
+			n, err := replace_history(tx)
+			if err != nil {
+				return err
+			}
+			result.Replaced = n
+		}
+		im := &archive_importer{
+			tx:           tx,
+			zr:           zr,
+			conflict:     body.Conflict,
+			result:       result,
+			html_profile: body.HTMLProfile,
+			progress:     progress,
+			total:        total,
+		}
+		if err := import_archive_tables(im); err != nil {
+			return err
+		}
+		// 实体和分类层级可以从导入的数据重新计算，不保存在归档中
+		if err := chunk_ids(im.paste_event_ids, func(chunk []string) error {
+			var records []models.PasteEvent
+			if err := tx.Select("id", "text").Where("id IN ?", chunk).Find(&records).Error; err != nil {
+				return err
+			}
+			for _, r := range records {
+				if err := save_paste_event_entities(tx, r.Id, r.Text); err != nil {
+					return err
+				}
+			}
+			return nil
+		}); err != nil {
+			return err
+		}
+		if err := recompute_category_levels(tx); err != nil {
+			return err
+		}
+		if body.DryRun {
+			return errArchiveDryRun
+		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, errArchiveDryRun) {
+		if e := delete_unused_blobs(s.db, s.blob, created_blobs); e != nil {
+			fmt.Println("[ERROR]delete unused blobs failed", e.Error())
+		}
+		return nil, err
+	}
+	if progress != nil {
+		progress(total, total)
+	}
+	return result, nil
+}
+
+func import_archive_tables(im *archive_importer) error {
+	steps := map[string]func() error{
+		"app": func() error {
+			return import_archive_rows(im, "app", func(r *models.App) *models.BaseModel { return &r.BaseModel }, nil, func(tx *gorm.DB, r *models.App) ([]models.App, error) {
+				return find_by_id[models.App](tx, r.Id)
+			}, nil)
+		},
+		"device": func() error {
+			return import_archive_rows(im, "device", func(r *models.Device) *models.BaseModel { return &r.BaseModel }, nil, func(tx *gorm.DB, r *models.Device) ([]models.Device, error) {
+				return find_by_id[models.Device](tx, r.Id)
+			}, nil)
+		},
+		"category_node": func() error {
+			return import_archive_rows(im, "category_node", func(r *models.CategoryNode) *models.BaseModel { return &r.BaseModel }, nil, func(tx *gorm.DB, r *models.CategoryNode) ([]models.CategoryNode, error) {
+				return find_by_id[models.CategoryNode](tx, r.Id)
+			}, nil)
+		},
+		"category_hierarchy": func() error {
+			return import_archive_rows(im, "category_hierarchy", func(r *models.CategoryHierarchy) *models.BaseModel { return &r.BaseModel }, nil, func(tx *gorm.DB, r *models.CategoryHierarchy) ([]models.CategoryHierarchy, error) {
+				var existing []models.CategoryHierarchy
+				err := tx.Unscoped().Where("id = ? OR (parent_id = ? AND child_id = ?)", r.Id, r.ParentId, r.ChildId).Limit(1).Find(&existing).Error
+				return existing, err
+			}, nil)
+		},
+		"paste_event": func() error {
+			return import_archive_rows(im, "paste_event", func(r *models.PasteEvent) *models.BaseModel { return &r.BaseModel }, func(r *models.PasteEvent) {
+				sanitize_imported_html(r, im.html_profile)
+			}, func(tx *gorm.DB, r *models.PasteEvent) ([]models.PasteEvent, error) {
+				return find_by_id[models.PasteEvent](tx, r.Id)
+			}, func(r *models.PasteEvent) {
+				im.paste_event_ids = append(im.paste_event_ids, r.Id)
+			})
+		},
+		"paste_event_category_mapping": func() error {
+			return import_archive_rows(im, "paste_event_category_mapping", func(r *models.PasteEventCategoryMapping) *models.BaseModel { return &r.BaseModel }, nil, func(tx *gorm.DB, r *models.PasteEventCategoryMapping) ([]models.PasteEventCategoryMapping, error) {
+				var existing []models.PasteEventCategoryMapping
+				err := tx.Unscoped().Where("id = ? OR (paste_event_id = ? AND category_id = ?)", r.Id, r.PasteEventId, r.CategoryId).Limit(1).Find(&existing).Error
+				return existing, err
+			}, nil)
+		},
+		"remark": func() error {
+			return import_archive_rows(im, "remark", func(r *models.Remark) *models.BaseModel { return &r.BaseModel }, nil, func(tx *gorm.DB, r *models.Remark) ([]models.Remark, error) {
+				return find_by_id[models.Remark](tx, r.Id)
+			}, nil)
+		},
+		"remark_revision": func() error {
+			return import_archive_rows(im, "remark_revision", func(r *models.RemarkRevision) *models.BaseModel { return &r.BaseModel }, nil, func(tx *gorm.DB, r *models.RemarkRevision) ([]models.RemarkRevision, error) {
+				return find_by_id[models.RemarkRevision](tx, r.Id)
+			}, nil)
+		},
+		"remark_attachment": func() error {
+			return import_archive_rows(im, "remark_attachment", func(r *models.RemarkAttachment) *models.BaseModel { return &r.BaseModel }, nil, func(tx *gorm.DB, r *models.RemarkAttachment) ([]models.RemarkAttachment, error) {
+				return find_by_id[models.RemarkAttachment](tx, r.Id)
+			}, nil)
+		},
+		"blob": func() error {
+			return import_archive_rows(im, "blob", func(r *models.Blob) *models.BaseModel { return &r.BaseModel }, nil, func(tx *gorm.DB, r *models.Blob) ([]models.Blob, error) {
+				var existing []models.Blob
+				err := tx.Unscoped().Where("id = ? OR hash = ?", r.Id, r.Hash).Limit(1).Find(&existing).Error
+				return existing, err
+			}, nil)
+		},
+	}
+	for _, name := range archive_tables {
+		if err := steps[name](); err != nil {
+			return err
+		}
+	}
+	return nil
+}
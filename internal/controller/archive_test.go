@@ -0,0 +1,246 @@
+package controller_test
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+
+	"devboard/internal/controller"
+	"devboard/models"
+)
+
+// open_test_db 使用 migrations 目录中的迁移创建数据库
+func open_test_db(t *testing.T) *gorm.DB {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "test.db")), &gorm.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	files, err := filepath.Glob("../../migrations/*.up.sql")
+	if err != nil || len(files) == 0 {
+		t.Fatal("no migrations found", err)
+	}
+	sort.Strings(files)
+	for _, f := range files {
+		data, err := os.ReadFile(f)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := db.Exec(string(data)).Error; err != nil {
+			t.Fatal(f, err)
+		}
+	}
+	version, err := strconv.Atoi(strings.SplitN(filepath.Base(files[len(files)-1]), "_", 2)[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Exec("CREATE TABLE schema_migrations (version bigint NOT NULL PRIMARY KEY, dirty boolean NOT NULL)").Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Exec("INSERT INTO schema_migrations (version, dirty) VALUES (?, ?)", version, false).Error; err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+func paste_texts(t *testing.T, s *controller.PasteController, texts ...string) []string {
+	t.Helper()
+	var ids []string
+	for _, text := range texts {
+		record, err := s.HandlePasteText(text, &controller.PasteExtraInfo{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, record.Id)
+	}
+	return ids
+}
+
+// export_test_archive 导出两条记录和一条备注，返回归档文件的路径和记录的 id
+func export_test_archive(t *testing.T) (string, []string) {
+	t.Helper()
+	db := open_test_db(t)
+	ids := paste_texts(t, controller.NewPasteController(db, "source"), "docker ps -a", "kubectl get pods")
+	if _, err := controller.NewRemarkController(db).CreateRemark(controller.RemarkCreateBody{PasteEventId: ids[0], Content: "list containers"}); err != nil {
+		t.Fatal(err)
+	}
+	p, manifest := write_test_archive(t, db)
+	if manifest.Tables["paste_event"] != 2 || manifest.Tables["remark"] != 1 {
+		t.Fatalf("unexpected manifest tables %v", manifest.Tables)
+	}
+	return p, ids
+}
+
+func write_test_archive(t *testing.T, db *gorm.DB) (string, *controller.ArchiveManifest) {
+	t.Helper()
+	var buf bytes.Buffer
+	manifest, err := controller.NewPasteController(db, "source").ExportArchive(controller.ArchiveExportBody{}, &buf, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := filepath.Join(t.TempDir(), "archive.zip")
+	if err := os.WriteFile(p, buf.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return p, manifest
+}
+
+func import_test_archive(t *testing.T, s *controller.PasteController, body controller.ArchiveImportBody) *controller.ArchiveImportResult {
+	t.Helper()
+	result, err := s.ImportArchive(body, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return result
+}
+
+func expect_stats(t *testing.T, result *controller.ArchiveImportResult, table string, want controller.ArchiveTableStats) {
+	t.Helper()
+	got := result.Tables[table]
+	if got == nil || *got != want {
+		t.Errorf("%s: got stats %+v, want %+v", table, got, want)
+	}
+}
+
+func paste_event_text(t *testing.T, db *gorm.DB, id string) string {
+	t.Helper()
+	var record models.PasteEvent
+	if err := db.Unscoped().Where("id = ?", id).First(&record).Error; err != nil {
+		t.Fatal(err)
+	}
+	return record.Text
+}
+
+func TestImportArchiveMerge(t *testing.T) {
+	p, ids := export_test_archive(t)
+	db := open_test_db(t)
+	s := controller.NewPasteController(db, "target")
+	result := import_test_archive(t, s, controller.ArchiveImportBody{Path: p})
+	expect_stats(t, result, "paste_event", controller.ArchiveTableStats{Created: 2})
+	expect_stats(t, result, "remark", controller.ArchiveTableStats{Created: 1})
+	if got := paste_event_text(t, db, ids[1]); got != "kubectl get pods" {
+		t.Errorf("unexpected text %q", got)
+	}
+	// 再次导入时所有记录都已经存在
+	result = import_test_archive(t, s, controller.ArchiveImportBody{Path: p})
+	expect_stats(t, result, "paste_event", controller.ArchiveTableStats{Skipped: 2})
+	expect_stats(t, result, "remark", controller.ArchiveTableStats{Skipped: 1})
+}
+
+func TestImportArchiveOverwrite(t *testing.T) {
+	p, ids := export_test_archive(t)
+	db := open_test_db(t)
+	s := controller.NewPasteController(db, "target")
+	import_test_archive(t, s, controller.ArchiveImportBody{Path: p})
+	if err := db.Model(&models.PasteEvent{}).Where("id IN ?", ids).UpdateColumn("text", "changed").Error; err != nil {
+		t.Fatal(err)
+	}
+	result := import_test_archive(t, s, controller.ArchiveImportBody{Path: p, Conflict: controller.ArchiveConflictOverwrite})
+	expect_stats(t, result, "paste_event", controller.ArchiveTableStats{Updated: 2})
+	if got := paste_event_text(t, db, ids[0]); got != "docker ps -a" {
+		t.Errorf("unexpected text %q", got)
+	}
+}
+
+func TestImportArchiveNewer(t *testing.T) {
+	p, ids := export_test_archive(t)
+	db := open_test_db(t)
+	s := controller.NewPasteController(db, "target")
+	import_test_archive(t, s, controller.ArchiveImportBody{Path: p})
+	// 第一条本地记录更新，第二条归档中的记录更新
+	if err := db.Model(&models.PasteEvent{}).Where("id = ?", ids[0]).UpdateColumns(map[string]interface{}{"text": "local newer", "updated_at": "99999999999999"}).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Model(&models.PasteEvent{}).Where("id = ?", ids[1]).UpdateColumns(map[string]interface{}{"text": "local older", "updated_at": "1"}).Error; err != nil {
+		t.Fatal(err)
+	}
+	result := import_test_archive(t, s, controller.ArchiveImportBody{Path: p, Conflict: controller.ArchiveConflictNewer})
+	expect_stats(t, result, "paste_event", controller.ArchiveTableStats{Updated: 1, Skipped: 1})
+	if got := paste_event_text(t, db, ids[0]); got != "local newer" {
+		t.Errorf("the newer local record should be kept, got %q", got)
+	}
+	if got := paste_event_text(t, db, ids[1]); got != "kubectl get pods" {
+		t.Errorf("the older local record should be overwritten, got %q", got)
+	}
+}
+
+func TestImportArchiveReplace(t *testing.T) {
+	p, ids := export_test_archive(t)
+	db := open_test_db(t)
+	s := controller.NewPasteController(db, "target")
+	local := paste_texts(t, s, "local only")
+	result := import_test_archive(t, s, controller.ArchiveImportBody{Path: p, Mode: controller.ArchiveImportReplace})
+	if result.Replaced != 1 {
+		t.Errorf("got replaced %d, want 1", result.Replaced)
+	}
+	expect_stats(t, result, "paste_event", controller.ArchiveTableStats{Created: 2})
+	var trashed models.PasteEvent
+	if err := db.Unscoped().Where("id = ?", local[0]).First(&trashed).Error; err != nil {
+		t.Fatal(err)
+	}
+	if !trashed.DeletedAt.Valid {
+		t.Errorf("the local record should be moved to the trash")
+	}
+	var count int64
+	if err := db.Model(&models.PasteEvent{}).Where("id IN ?", ids).Count(&count).Error; err != nil {
+		t.Fatal(err)
+	}
+	if count != 2 {
+		t.Errorf("got %d imported records, want 2", count)
+	}
+}
+
+func TestImportArchiveDryRun(t *testing.T) {
+	p, _ := export_test_archive(t)
+	db := open_test_db(t)
+	s := controller.NewPasteController(db, "target")
+	result := import_test_archive(t, s, controller.ArchiveImportBody{Path: p, DryRun: true})
+	if !result.DryRun {
+		t.Errorf("the result should be marked as dry run")
+	}
+	expect_stats(t, result, "paste_event", controller.ArchiveTableStats{Created: 2})
+	for _, m := range []interface{}{&models.PasteEvent{}, &models.Remark{}} {
+		var count int64
+		if err := db.Unscoped().Model(m).Count(&count).Error; err != nil {
+			t.Fatal(err)
+		}
+		if count != 0 {
+			t.Errorf("dry run should not change the database, got %d rows of %T", count, m)
+		}
+	}
+}
+
+func TestImportArchiveSanitizeHTML(t *testing.T) {
+	source := open_test_db(t)
+	ids := paste_texts(t, controller.NewPasteController(source, "source"), "hi")
+	// 模拟被修改过的归档
+	if err := source.Model(&models.PasteEvent{}).Where("id = ?", ids[0]).UpdateColumns(map[string]interface{}{
+		"content_type": "html",
+		"html":         `<p onclick="alert(1)">hi</p><script>alert(2)</script><a href="javascript:alert(3)">link</a>`,
+		"markdown":     "[link](javascript:alert(3))",
+	}).Error; err != nil {
+		t.Fatal(err)
+	}
+	p, _ := write_test_archive(t, source)
+	db := open_test_db(t)
+	import_test_archive(t, controller.NewPasteController(db, "target"), controller.ArchiveImportBody{Path: p})
+	var record models.PasteEvent
+	if err := db.Where("id = ?", ids[0]).First(&record).Error; err != nil {
+		t.Fatal(err)
+	}
+	for _, v := range []string{record.Html, record.Markdown} {
+		if strings.Contains(v, "alert") || strings.Contains(v, "onclick") {
+			t.Errorf("the imported html is not sanitized: %q", v)
+		}
+	}
+	if !strings.Contains(record.Html, "hi") {
+		t.Errorf("the content should be kept, got %q", record.Html)
+	}
+}
@@ -0,0 +1,121 @@
+package controller
+
+import (
+	"errors"
+
+	"gorm.io/gorm"
+
+	"devboard/models"
+	"devboard/pkg/blob"
+)
+
+type BlobController struct {
+	db *gorm.DB
+}
+
+// BlobURL 前端通过该地址读取 blob 文件，见 service.BlobService
+func BlobURL(hash string) string {
+	return "/blob?hash=" + hash
+}
+
+func NewBlobController(db *gorm.DB) *BlobController {
+	return &BlobController{
+		db: db,
+	}
+}
+
+// SaveBlob 记录 blob 文件的信息，哈希相同时沿用已有的记录
+func (s *BlobController) SaveBlob(hash string, kind string, mime_type string, size int64) (*models.Blob, error) {
+	return save_blob(s.db, hash, kind, mime_type, size)
+}
+
+func save_blob(db *gorm.DB, hash string, kind string, mime_type string, size int64) (*models.Blob, error) {
+	var record models.Blob
+	err := db.Where("hash = ?", hash).First(&record).Error
+	if err == nil {
+		return &record, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+	record = models.Blob{
+		Hash:     hash,
+		Kind:     kind,
+		MimeType: mime_type,
+		Size:     size,
+	}
+	if err := db.Create(&record).Error; err != nil {
+		return nil, err
+	}
+	return &record, nil
+}
+
+func (s *BlobController) FetchBlob(hash string) (*models.Blob, error) {
+	var record models.Blob
+	if err := s.db.Where("hash = ?", hash).First(&record).Error; err != nil {
+		return nil, err
+	}
+	return &record, nil
+}
+
+// blob_referenced 缩略图、链接图标、文件快照和备注附件都可能引用同一个 blob，软删除的记录还可以恢复，也算作引用
+func blob_referenced(db *gorm.DB, hash string) (bool, error) {
+	var count int64
+	if err := db.Unscoped().Model(&models.PasteEventImage{}).
+		Where("thumb_small = ? OR thumb_medium = ? OR thumb_large = ?", hash, hash, hash).
+		Count(&count).Error; err != nil {
+		return false, err
+	}
+	if count > 0 {
+		return true, nil
+	}
+	if err := db.Unscoped().Model(&models.URLMeta{}).
+		Where("icon_blob = ? OR image_blob = ?", hash, hash).
+		Count(&count).Error; err != nil {
+		return false, err
+	}
+	if count > 0 {
+		return true, nil
+	}
+	if err := db.Unscoped().Model(&models.RemarkAttachment{}).
+		Where("blob_hash = ?", hash).
+		Count(&count).Error; err != nil {
+		return false, err
+	}
+	if count > 0 {
+		return true, nil
+	}
+	if err := db.Unscoped().Model(&models.PasteEvent{}).
+		Where("content_type = ? AND file_list_json LIKE ?", "file", "%\"blob_hash\":\""+hash+"\"%").
+		Count(&count).Error; err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// delete_unused_blobs 删除不再被任何记录引用的 blob 文件和记录
+func delete_unused_blobs(db *gorm.DB, store *blob.Store, hashes []string) error {
+	checked := make(map[string]bool)
+	for _, hash := range hashes {
+		if hash == "" || checked[hash] {
+			continue
+		}
+		checked[hash] = true
+		referenced, err := blob_referenced(db, hash)
+		if err != nil {
+			return err
+		}
+		if referenced {
+			continue
+		}
+		if store != nil {
+			if err := store.Delete(hash); err != nil {
+				return err
+			}
+		}
+		if err := db.Unscoped().Where("hash = ?", hash).Delete(&models.Blob{}).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
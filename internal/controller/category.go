@@ -2,6 +2,7 @@ package controller
 
 import (
 	"fmt"
+	"strings"
 
 	"gorm.io/gorm"
 
@@ -18,251 +19,186 @@ func NewCategoryController(db *gorm.DB) *CategoryController {
 	}
 }
 
-func GetCategoryTreeOptimized(db *gorm.DB) ([]models.CategoryNode, error) {
-	var allNodes []models.CategoryNode
-	if err := db.Preload("Children").Preload("Parents").Find(&allNodes).Error; err != nil {
-		return nil, err
-	}
-
-	nodeMap := make(map[string]*models.CategoryNode)
-	for i := range allNodes {
-		nodeMap[allNodes[i].Id] = &allNodes[i]
-	}
-
-	var roots []*models.CategoryNode
-	for _, node := range nodeMap {
-		if len(node.Parents) == 0 {
-			roots = append(roots, node)
-		}
-	}
-
-	var trees []models.CategoryNode
-	visited := make(map[string]bool)
-	nodeCache := make(map[string]*models.CategoryNode) // 缓存已构建的子树
-
-	for _, root := range roots {
-		if visited[root.Id] {
-			continue
-		}
-
-		tree := buildTreeOptimized(root, nodeMap, visited, nodeCache, 0)
-		trees = append(trees, *tree)
-	}
-
-	return trees, nil
-}
-
-// func buildTreeOptimized(node *models.CategoryNode, nodeMap map[string]*models.CategoryNode, visited map[string]bool, nodeCache map[string]*models.CategoryNode, depth int) *models.CategoryNode {
-// 	if depth > 100 {
-// 		return node
-// 	}
-
-// 	if cached, exists := nodeCache[node.Id]; exists {
-// 		return cached
-// 	}
-
-// 	visited[node.Id] = true
-
-// 	newNode := *node
-// 	newNode.Children = nil
-
-// 	for _, child := range node.Children {
-// 		if visited[child.Id] {
-// 			continue
-// 		}
-
-// 		childNode := nodeMap[child.Id]
-// 		if childNode != nil {
-// 			childTree := buildTreeOptimized(childNode, nodeMap, visited, nodeCache, depth+1)
-// 			newNode.Children = append(newNode.Children, *childTree)
-// 		}
-// 	}
-
-// 	nodeCache[node.Id] = &newNode
-// 	return &newNode
-// }
-
 type CategoryCreateBody struct {
 	Label         string               `json:"label"`
 	Type          string               `json:"type"`
 	Description   string               `json:"description"`
+	Color         string               `json:"color"`
+	Icon          string               `json:"icon"`
 	ParentId      *string              `json:"parent_id"`
 	SubCategories []CategoryCreateBody `json:"children"`
 }
 
 func (s *CategoryController) CreateCategory(body CategoryCreateBody) (*models.CategoryNode, error) {
-	var parentLevel int
-	if body.ParentId != nil {
+	var parent *models.CategoryNode
+	if body.ParentId != nil && *body.ParentId != "" {
 		// 检查父节点是否存在
-		var parent models.CategoryNode
-		if err := s.db.First(&parent, *body.ParentId).Error; err != nil {
+		var existing models.CategoryNode
+		if err := s.db.Where("id = ?", *body.ParentId).First(&existing).Error; err != nil {
 			return nil, fmt.Errorf("父节点不存在: %v", err)
 		}
-		parentLevel = parent.Level
+		parent = &existing
+	}
+	var created *models.CategoryNode
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		created, err = create_category(tx, parent, body)
+		return err
+	})
+	if err != nil {
+		return nil, err
 	}
-	// 开始事务
-	tx := s.db.Begin()
-	defer func() {
-		if r := recover(); r != nil {
-			tx.Rollback()
-		}
-	}()
+	return created, nil
+}
 
-	// 创建主类目
-	main_category := models.CategoryNode{
-		Label: body.Label,
-		// NodeType:    body.NodeType,
+// create_category 创建分类和层级关系，新分类排在同级分类的最后，然后递归创建子分类
+func create_category(tx *gorm.DB, parent *models.CategoryNode, body CategoryCreateBody) (*models.CategoryNode, error) {
+	label := strings.TrimSpace(body.Label)
+	if label == "" {
+		return nil, fmt.Errorf("The label can't be empty")
+	}
+	if err := validate_category_style(body.Color, body.Icon); err != nil {
+		return nil, err
+	}
+	level := 0
+	siblings := tx.Model(&models.CategoryNode{})
+	if parent != nil {
+		level = parent.Level + 1
+		siblings = siblings.Where("id IN (SELECT child_id FROM category_hierarchy WHERE parent_id = ? AND deleted_at IS NULL)", parent.Id)
+	} else {
+		siblings = siblings.Where("id NOT IN (SELECT child_id FROM category_hierarchy WHERE deleted_at IS NULL)")
+	}
+	var sort_order int
+	if err := siblings.Select("COALESCE(MAX(sort_order) + 1, 0)").Scan(&sort_order).Error; err != nil {
+		return nil, err
+	}
+	created := models.CategoryNode{
+		Label:       label,
 		Description: body.Description,
-		Level:       parentLevel + 1,
+		Color:       body.Color,
+		Icon:        body.Icon,
+		Level:       level,
+		SortOrder:   sort_order,
 		IsActive:    true,
 	}
-
-	if err := tx.Create(&main_category).Error; err != nil {
-		tx.Rollback()
+	if err := tx.Create(&created).Error; err != nil {
 		return nil, fmt.Errorf("创建主类目失败: %v", err)
 	}
-
-	// 如果有子类目，递归创建
-	// if len(body.SubCategories) > 0 {
-	// 	if err := createSubCategories(tx, mainCategory.Id, body.SubCategories, mainCategory.Level+1); err != nil {
-	// 		tx.Rollback()
-	// 		return Error(err)
-	// 	}
-	// }
-
-	// 提交事务
-	if err := tx.Commit().Error; err != nil {
-		return nil, fmt.Errorf("提交事务失败: %v", err)
+	if parent != nil {
+		if err := ensure_category_hierarchy(tx, parent.Id, created.Id); err != nil {
+			return nil, err
+		}
 	}
-
-	// 重新查询以获取完整的关联数据
-	var result models.CategoryNode
-	if err := s.db.Preload("Children").First(&result, main_category.Id).Error; err != nil {
-		return nil, fmt.Errorf("查询创建结果失败: %v", err)
+	for _, sub := range body.SubCategories {
+		child, err := create_category(tx, &created, sub)
+		if err != nil {
+			return nil, err
+		}
+		created.Children = append(created.Children, *child)
 	}
-
-	return &result, nil
+	return &created, nil
 }
 
-func (s *CategoryController) FetchCategoryTree() ([]models.CategoryNode, error) {
-	var roots []models.CategoryNode
-	err := s.db.Where("parent_id IS NULL").Preload("Children", recursivePreload).Find(&roots).Error
+// FetchCategoryTree 按层级关系返回所有分类和记录数量，有多个父分类的分类会出现在每个父分类下
+func (s *CategoryController) FetchCategoryTree() ([]CategoryTreeNode, error) {
+	g, counts, err := load_category_tree(s.db)
 	if err != nil {
 		return nil, fmt.Errorf("获取分类树失败: %v", err)
 	}
+	var build func(id string, depth int) CategoryTreeNode
+	build = func(id string, depth int) CategoryTreeNode {
+		node := CategoryTreeNode{
+			CategoryNode:  *g.nodes[id],
+			CategoryCount: counts[id],
+			Children:      []CategoryTreeNode{},
+		}
+		if depth >= category_max_level {
+			return node
+		}
+		for _, c := range g.children[id] {
+			node.Children = append(node.Children, build(c, depth+1))
+		}
+		return node
+	}
+	roots := make([]CategoryTreeNode, 0)
+	for _, id := range g.roots() {
+		roots = append(roots, build(id, 0))
+	}
 	return roots, nil
 }
 
-func recursivePreload(db *gorm.DB) *gorm.DB {
-	return db.Preload("Children", recursivePreload)
+type CategoryListBody struct {
+	models.Pagination
+
+	Keyword string `json:"keyword"`
 }
 
-func (s *CategoryController) PreloadAll() func(db *gorm.DB) *gorm.DB {
-	return func(db *gorm.DB) *gorm.DB {
-		return db.Preload("Children", s.PreloadAll())
+// FetchCategoryList 按 sort_order 从小到大分页返回分类，不包含层级关系
+func (s *CategoryController) FetchCategoryList(body CategoryListBody) (*ListResp[models.CategoryNode], error) {
+	query := s.db.Model(&models.CategoryNode{})
+	if body.Keyword != "" {
+		query = query.Where("category_node.label LIKE ?", "%"+body.Keyword+"%")
+	}
+	pb := models.NewPaginationBuilder[models.CategoryNode](query).
+		SetLimit(body.PageSize).
+		SetPage(body.Page).
+		SetNextMarker(body.NextMarker).
+		SetOrderBy("category_node.sort_order ASC")
+	var list1 []models.CategoryNode
+	if err := pb.Build().Find(&list1).Error; err != nil {
+		return nil, err
 	}
+	list2, has_more, next_marker := pb.ProcessResults(list1)
+	return &ListResp[models.CategoryNode]{
+		List:       list2,
+		Page:       body.Page,
+		PageSize:   pb.GetLimit(),
+		HasMore:    has_more,
+		NextMarker: next_marker,
+	}, nil
 }
 
 type CategoryTree struct {
 	models.CategoryNode
+	CategoryCount
 	Parents []CategoryTree `json:"parents"`
 }
 
+// GetCategoryTreeOptimized 从叶子分类开始，向上列出所有父分类
 func (s *CategoryController) GetCategoryTreeOptimized() ([]CategoryTree, error) {
-	// 获取所有叶子节点
-	var leafNodes []models.CategoryNode
-	s.db.Where("NOT EXISTS (SELECT 1 FROM category_hierarchy WHERE category_hierarchy.parent_id = category_node.id)").
-		Find(&leafNodes)
-
-	var results []CategoryTree
-
-	for _, leaf := range leafNodes {
-		tree := CategoryTree{CategoryNode: leaf}
-		// 递归获取父节点
-		var buildTree func(node *CategoryTree) error
-		buildTree = func(node *CategoryTree) error {
-			var parents []models.CategoryNode
-			err := s.db.Table("category_node").
-				Joins("JOIN category_hierarchy ON category_node.id = category_hierarchy.parent_id").
-				Where("category_hierarchy.child_id = ?", node.Id).
-				Find(&parents).Error
-			if err != nil {
-				return err
-			}
-
-			for _, parent := range parents {
-				parentTree := CategoryTree{CategoryNode: parent}
-				if err := buildTree(&parentTree); err != nil {
-					return err
-				}
-				node.Parents = append(node.Parents, parentTree)
-			}
-			return nil
-		}
-
-		if err := buildTree(&tree); err != nil {
-			return nil, err
-		}
-		results = append(results, tree)
-	}
-	return results, nil
-}
-
-func (s *CategoryController) GetCategoryTreeOptimized2() ([]models.CategoryNode, error) {
-	var all_nodes []models.CategoryNode
-	if err := s.db.Find(&all_nodes).Error; err != nil {
+	g, counts, err := load_category_tree(s.db)
+	if err != nil {
 		return nil, err
 	}
-	node_map := make(map[string]*models.CategoryNode)
-	for i := range all_nodes {
-		node_map[all_nodes[i].Id] = &all_nodes[i]
-	}
-	var roots []*models.CategoryNode
-	for _, node := range node_map {
-		if len(node.Parents) == 0 {
-			roots = append(roots, node)
+	var build func(id string, depth int) CategoryTree
+	build = func(id string, depth int) CategoryTree {
+		node := CategoryTree{
+			CategoryNode:  *g.nodes[id],
+			CategoryCount: counts[id],
 		}
-	}
-	var trees []models.CategoryNode
-	visited := make(map[string]bool)
-	node_cache := make(map[string]*models.CategoryNode) // 缓存已构建的子树
-
-	for _, root := range roots {
-		if visited[root.Id] {
-			continue
+		if depth >= category_max_level {
+			return node
+		}
+		for _, p := range g.parents[id] {
+			node.Parents = append(node.Parents, build(p, depth+1))
 		}
-		tree := buildTreeOptimized(root, node_map, visited, node_cache, 0)
-		trees = append(trees, *tree)
-	}
-	return trees, nil
-}
-
-func buildTreeOptimized(node *models.CategoryNode, nodeMap map[string]*models.CategoryNode, visited map[string]bool, nodeCache map[string]*models.CategoryNode, depth int) *models.CategoryNode {
-	if depth > 100 {
 		return node
 	}
-
-	if cached, exists := nodeCache[node.Id]; exists {
-		return cached
-	}
-
-	visited[node.Id] = true
-
-	newNode := *node
-	newNode.Children = nil
-
-	for _, child := range node.Children {
-		if visited[child.Id] {
-			continue
-		}
-
-		childNode := nodeMap[child.Id]
-		if childNode != nil {
-			childTree := buildTreeOptimized(childNode, nodeMap, visited, nodeCache, depth+1)
-			newNode.Children = append(newNode.Children, *childTree)
+	var leaves []string
+	for id := range g.nodes {
+		if len(g.children[id]) == 0 {
+			leaves = append(leaves, id)
 		}
 	}
+	g.sort(leaves)
+	results := make([]CategoryTree, 0, len(leaves))
+	for _, id := range leaves {
+		results = append(results, build(id, 0))
+	}
+	return results, nil
+}
 
-	nodeCache[node.Id] = &newNode
-	return &newNode
+// GetCategoryTreeOptimized2 和 FetchCategoryTree 相同
+func (s *CategoryController) GetCategoryTreeOptimized2() ([]CategoryTreeNode, error) {
+	return s.FetchCategoryTree()
 }
@@ -0,0 +1,477 @@
+package controller
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+
+	"devboard/models"
+)
+
+// 删除分类时如何处理子分类
+const (
+	// CategoryDeleteReparent 子分类移动到被删除分类的父分类下，没有父分类时成为根分类
+	CategoryDeleteReparent = "reparent"
+	// CategoryDeleteCascade 一起删除只属于该分类的子分类，还有其他父分类的子分类只移除关联
+	CategoryDeleteCascade = "cascade"
+)
+
+// 层级深度的上限，超过时认为数据有问题
+const category_max_level = 100
+
+var category_color_regexp = regexp.MustCompile(`^#[0-9a-fA-F]{6}$`)
+
+// category_graph 未删除的分类和层级关系，分类数量不多，直接全部读取后在内存中处理
+type category_graph struct {
+	nodes    map[string]*models.CategoryNode
+	parents  map[string][]string
+	children map[string][]string
+}
+
+func load_category_graph(db *gorm.DB) (*category_graph, error) {
+	var nodes []models.CategoryNode
+	if err := db.Order("sort_order ASC").Order("created_at ASC").Find(&nodes).Error; err != nil {
+		return nil, err
+	}
+	var edges []models.CategoryHierarchy
+	if err := db.Find(&edges).Error; err != nil {
+		return nil, err
+	}
+	g := &category_graph{
+		nodes:    make(map[string]*models.CategoryNode),
+		parents:  make(map[string][]string),
+		children: make(map[string][]string),
+	}
+	for i := range nodes {
+		g.nodes[nodes[i].Id] = &nodes[i]
+	}
+	for _, e := range edges {
+		if g.nodes[e.ParentId] == nil || g.nodes[e.ChildId] == nil {
+			continue
+		}
+		g.parents[e.ChildId] = append(g.parents[e.ChildId], e.ParentId)
+		g.children[e.ParentId] = append(g.children[e.ParentId], e.ChildId)
+	}
+	for id := range g.children {
+		g.sort(g.children[id])
+	}
+	return g, nil
+}
+
+// sort 按 sort_order、创建时间排序
+func (g *category_graph) sort(ids []string) {
+	sort.SliceStable(ids, func(i, j int) bool {
+		a, b := g.nodes[ids[i]], g.nodes[ids[j]]
+		if a.SortOrder != b.SortOrder {
+			return a.SortOrder < b.SortOrder
+		}
+		return a.CreatedAt < b.CreatedAt
+	})
+}
+
+func (g *category_graph) roots() []string {
+	var ids []string
+	for id := range g.nodes {
+		if len(g.parents[id]) == 0 {
+			ids = append(ids, id)
+		}
+	}
+	g.sort(ids)
+	return ids
+}
+
+// descendants 所有子孙分类，不包括自己
+func (g *category_graph) descendants(id string) map[string]bool {
+	result := make(map[string]bool)
+	queue := append([]string{}, g.children[id]...)
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+		if result[current] {
+			continue
+		}
+		result[current] = true
+		queue = append(queue, g.children[current]...)
+	}
+	return result
+}
+
+// levels 根分类为 0，有多个父分类时取最深的一条路径
+func (g *category_graph) levels() map[string]int {
+	levels := make(map[string]int)
+	var visit func(id string, depth int)
+	visit = func(id string, depth int) {
+		if depth > category_max_level {
+			return
+		}
+		if v, ok := levels[id]; ok && v >= depth {
+			return
+		}
+		levels[id] = depth
+		for _, c := range g.children[id] {
+			visit(c, depth+1)
+		}
+	}
+	for _, id := range g.roots() {
+		visit(id, 0)
+	}
+	return levels
+}
+
+// recompute_category_levels 层级关系变化后重新计算所有分类的 level，只更新有变化的分类
+func recompute_category_levels(tx *gorm.DB) error {
+	g, err := load_category_graph(tx)
+	if err != nil {
+		return err
+	}
+	for id, level := range g.levels() {
+		node := g.nodes[id]
+		if node.Level == level {
+			continue
+		}
+		node.Level = level
+		if err := tx.Save(node).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ensure_category_hierarchy 添加层级关系，之前的关系被软删除了就恢复它，避免违反唯一约束
+func ensure_category_hierarchy(tx *gorm.DB, parent_id string, child_id string) error {
+	var existing []models.CategoryHierarchy
+	if err := tx.Unscoped().Where("parent_id = ? AND child_id = ?", parent_id, child_id).Limit(1).Find(&existing).Error; err != nil {
+		return err
+	}
+	if len(existing) == 0 {
+		return tx.Create(&models.CategoryHierarchy{
+			BaseModel: models.BaseModel{
+				Id: parent_id + "_" + child_id,
+			},
+			ParentId: parent_id,
+			ChildId:  child_id,
+		}).Error
+	}
+	edge := existing[0]
+	if !edge.DeletedAt.Valid {
+		return nil
+	}
+	edge.DeletedAt = gorm.DeletedAt{}
+	return tx.Unscoped().Save(&edge).Error
+}
+
+// remove_category_hierarchy parent_id 为空时移除 child_id 的所有父分类关系
+func remove_category_hierarchy(tx *gorm.DB, parent_id string, child_id string) error {
+	query := tx.Where("child_id = ?", child_id)
+	if parent_id != "" {
+		query = query.Where("parent_id = ?", parent_id)
+	}
+	var existing []models.CategoryHierarchy
+	if err := query.Find(&existing).Error; err != nil {
+		return err
+	}
+	for _, edge := range existing {
+		edge.DeletedAt = gorm.DeletedAt{Time: time.Now(), Valid: true}
+		if err := tx.Save(&edge).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func validate_category_style(color string, icon string) error {
+	if color != "" && !category_color_regexp.MatchString(color) {
+		return fmt.Errorf("The color should be like #RRGGBB")
+	}
+	if len([]rune(icon)) > 64 {
+		return fmt.Errorf("The icon is too long")
+	}
+	return nil
+}
+
+type CategoryUpdateBody struct {
+	Id          string  `json:"id"`
+	Label       *string `json:"label"`
+	Description *string `json:"description"`
+	Color       *string `json:"color"`
+	Icon        *string `json:"icon"`
+}
+
+// UpdateCategory 只修改传入的字段
+func (s *CategoryController) UpdateCategory(body CategoryUpdateBody) (*models.CategoryNode, error) {
+	if body.Id == "" {
+		return nil, fmt.Errorf("Missing the id")
+	}
+	var existing models.CategoryNode
+	if err := s.db.Where("id = ?", body.Id).First(&existing).Error; err != nil {
+		return nil, err
+	}
+	if body.Label != nil {
+		label := strings.TrimSpace(*body.Label)
+		if label == "" {
+			return nil, fmt.Errorf("The label can't be empty")
+		}
+		existing.Label = label
+	}
+	if body.Description != nil {
+		existing.Description = *body.Description
+	}
+	if body.Color != nil {
+		existing.Color = strings.TrimSpace(*body.Color)
+	}
+	if body.Icon != nil {
+		existing.Icon = strings.TrimSpace(*body.Icon)
+	}
+	if err := validate_category_style(existing.Color, existing.Icon); err != nil {
+		return nil, err
+	}
+	if err := s.db.Save(&existing).Error; err != nil {
+		return nil, err
+	}
+	return &existing, nil
+}
+
+type CategoryMoveBody struct {
+	Id string `json:"id"`
+	// ParentId 为空时移动为根分类
+	ParentId string `json:"parent_id"`
+	// FromParentId 分类有多个父分类时只替换这一个，为空时移除所有父分类
+	FromParentId string `json:"from_parent_id"`
+}
+
+// MoveCategory 把分类移动到另一个分类下，不能移动到自己或自己的子孙分类下
+func (s *CategoryController) MoveCategory(body CategoryMoveBody) (*models.CategoryNode, error) {
+	if body.Id == "" {
+		return nil, fmt.Errorf("Missing the id")
+	}
+	g, err := load_category_graph(s.db)
+	if err != nil {
+		return nil, err
+	}
+	node := g.nodes[body.Id]
+	if node == nil {
+		return nil, gorm.ErrRecordNotFound
+	}
+	if body.ParentId != "" {
+		if g.nodes[body.ParentId] == nil {
+			return nil, fmt.Errorf("The parent category doesn't exist")
+		}
+		if body.ParentId == body.Id || g.descendants(body.Id)[body.ParentId] {
+			return nil, fmt.Errorf("Can't move the category under itself or its descendants")
+		}
+	}
+	if body.FromParentId != "" && !slices.Contains(g.parents[body.Id], body.FromParentId) {
+		return nil, fmt.Errorf("The category isn't under %s", body.FromParentId)
+	}
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if body.FromParentId == "" || body.FromParentId != body.ParentId {
+			if err := remove_category_hierarchy(tx, body.FromParentId, body.Id); err != nil {
+				return err
+			}
+		}
+		if body.ParentId != "" {
+			if err := ensure_category_hierarchy(tx, body.ParentId, body.Id); err != nil {
+				return err
+			}
+		}
+		return recompute_category_levels(tx)
+	})
+	if err != nil {
+		return nil, err
+	}
+	var result models.CategoryNode
+	if err := s.db.Where("id = ?", body.Id).First(&result).Error; err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+type CategoryReorderBody struct {
+	// ParentId 为空时调整根分类的顺序
+	ParentId string `json:"parent_id"`
+	// Ids 排序后的同级分类 id，没有传入的同级分类排在后面
+	Ids []string `json:"ids"`
+}
+
+// ReorderCategories 按传入的顺序重新设置同级分类的 sort_order
+func (s *CategoryController) ReorderCategories(body CategoryReorderBody) ([]models.CategoryNode, error) {
+	g, err := load_category_graph(s.db)
+	if err != nil {
+		return nil, err
+	}
+	siblings := g.roots()
+	if body.ParentId != "" {
+		if g.nodes[body.ParentId] == nil {
+			return nil, fmt.Errorf("The parent category doesn't exist")
+		}
+		siblings = g.children[body.ParentId]
+	}
+	is_sibling := make(map[string]bool)
+	for _, id := range siblings {
+		is_sibling[id] = true
+	}
+	ordered := make([]string, 0, len(siblings))
+	seen := make(map[string]bool)
+	for _, id := range body.Ids {
+		if !is_sibling[id] {
+			return nil, fmt.Errorf("The category %s isn't under the parent", id)
+		}
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+		ordered = append(ordered, id)
+	}
+	for _, id := range siblings {
+		if !seen[id] {
+			ordered = append(ordered, id)
+		}
+	}
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		for i, id := range ordered {
+			node := g.nodes[id]
+			if node.SortOrder == i {
+				continue
+			}
+			node.SortOrder = i
+			if err := tx.Save(node).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	list := make([]models.CategoryNode, 0, len(ordered))
+	for _, id := range ordered {
+		list = append(list, *g.nodes[id])
+	}
+	return list, nil
+}
+
+type CategoryActiveBody struct {
+	Id       string `json:"id"`
+	IsActive bool   `json:"is_active"`
+}
+
+// SetCategoryActive 停用的分类不会出现在推荐和可选的分类列表中，已有的关联不受影响
+func (s *CategoryController) SetCategoryActive(body CategoryActiveBody) (*models.CategoryNode, error) {
+	if body.Id == "" {
+		return nil, fmt.Errorf("Missing the id")
+	}
+	var existing models.CategoryNode
+	if err := s.db.Where("id = ?", body.Id).First(&existing).Error; err != nil {
+		return nil, err
+	}
+	if existing.IsActive == body.IsActive {
+		return &existing, nil
+	}
+	existing.IsActive = body.IsActive
+	if err := s.db.Save(&existing).Error; err != nil {
+		return nil, err
+	}
+	return &existing, nil
+}
+
+type CategoryDeleteBody struct {
+	Id string `json:"id"`
+	// Mode reparent 或 cascade，默认 reparent
+	Mode string `json:"mode"`
+}
+
+// DeleteCategory 软删除分类以及相关的层级关系和记录关联，返回被删除的分类 id
+func (s *CategoryController) DeleteCategory(body CategoryDeleteBody) ([]string, error) {
+	if body.Id == "" {
+		return nil, fmt.Errorf("Missing the id")
+	}
+	if body.Mode == "" {
+		body.Mode = CategoryDeleteReparent
+	}
+	if body.Mode != CategoryDeleteReparent && body.Mode != CategoryDeleteCascade {
+		return nil, fmt.Errorf("Unknown mode %s", body.Mode)
+	}
+	g, err := load_category_graph(s.db)
+	if err != nil {
+		return nil, err
+	}
+	if g.nodes[body.Id] == nil {
+		return nil, gorm.ErrRecordNotFound
+	}
+	deleted := map[string]bool{body.Id: true}
+	if body.Mode == CategoryDeleteCascade {
+		// 所有父分类都被删除的子分类也一起删除，直到没有新的分类加入
+		for changed := true; changed; {
+			changed = false
+			for id := range g.descendants(body.Id) {
+				if deleted[id] {
+					continue
+				}
+				orphan := true
+				for _, p := range g.parents[id] {
+					if !deleted[p] {
+						orphan = false
+						break
+					}
+				}
+				if orphan {
+					deleted[id] = true
+					changed = true
+				}
+			}
+		}
+	}
+	var ids []string
+	for id := range deleted {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if body.Mode == CategoryDeleteReparent {
+			for _, c := range g.children[body.Id] {
+				for _, p := range g.parents[body.Id] {
+					if err := ensure_category_hierarchy(tx, p, c); err != nil {
+						return err
+					}
+				}
+			}
+		}
+		var edges []models.CategoryHierarchy
+		if err := tx.Where("parent_id IN ? OR child_id IN ?", ids, ids).Find(&edges).Error; err != nil {
+			return err
+		}
+		for _, edge := range edges {
+			edge.DeletedAt = gorm.DeletedAt{Time: time.Now(), Valid: true}
+			if err := tx.Save(&edge).Error; err != nil {
+				return err
+			}
+		}
+		var mappings []models.PasteEventCategoryMapping
+		if err := tx.Where("category_id IN ?", ids).Find(&mappings).Error; err != nil {
+			return err
+		}
+		for _, mapping := range mappings {
+			mapping.DeletedAt = gorm.DeletedAt{Time: time.Now(), Valid: true}
+			if err := tx.Save(&mapping).Error; err != nil {
+				return err
+			}
+		}
+		for _, id := range ids {
+			node := g.nodes[id]
+			node.DeletedAt = gorm.DeletedAt{Time: time.Now(), Valid: true}
+			if err := tx.Save(node).Error; err != nil {
+				return err
+			}
+		}
+		return recompute_category_levels(tx)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return ids, nil
+}
@@ -0,0 +1,137 @@
+package controller_test
+
+import (
+	"reflect"
+	"testing"
+
+	"gorm.io/gorm"
+
+	"devboard/internal/controller"
+	"devboard/models"
+)
+
+func create_test_category(t *testing.T, s *controller.CategoryController, label string, parent_id string) string {
+	t.Helper()
+	created, err := s.CreateCategory(controller.CategoryCreateBody{Label: label, ParentId: &parent_id})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return created.Id
+}
+
+// add_test_category_parent 给分类再加一个父分类
+func add_test_category_parent(t *testing.T, db *gorm.DB, parent_id string, child_id string) {
+	t.Helper()
+	if err := db.Create(&models.CategoryHierarchy{
+		BaseModel: models.BaseModel{Id: parent_id + "_" + child_id},
+		ParentId:  parent_id,
+		ChildId:   child_id,
+	}).Error; err != nil {
+		t.Fatal(err)
+	}
+}
+
+func category_levels(t *testing.T, db *gorm.DB, ids ...string) []int {
+	t.Helper()
+	var levels []int
+	for _, id := range ids {
+		var node models.CategoryNode
+		if err := db.Where("id = ?", id).First(&node).Error; err != nil {
+			t.Fatal(err)
+		}
+		levels = append(levels, node.Level)
+	}
+	return levels
+}
+
+func TestMoveCategoryCycle(t *testing.T) {
+	s := controller.NewCategoryController(open_test_db(t))
+	a := create_test_category(t, s, "a", "")
+	b := create_test_category(t, s, "b", a)
+	c := create_test_category(t, s, "c", b)
+	for _, parent_id := range []string{a, b, c} {
+		if _, err := s.MoveCategory(controller.CategoryMoveBody{Id: a, ParentId: parent_id}); err == nil {
+			t.Errorf("moving the category under %s should fail", parent_id)
+		}
+	}
+	if _, err := s.MoveCategory(controller.CategoryMoveBody{Id: b, ParentId: "not_exists"}); err == nil {
+		t.Errorf("moving the category under a missing parent should fail")
+	}
+}
+
+func TestMoveCategoryLevel(t *testing.T) {
+	db := open_test_db(t)
+	s := controller.NewCategoryController(db)
+	x := create_test_category(t, s, "x", "")
+	y := create_test_category(t, s, "y", "")
+	c := create_test_category(t, s, "c", x)
+	d := create_test_category(t, s, "d", c)
+	if _, err := s.MoveCategory(controller.CategoryMoveBody{Id: y, ParentId: d}); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := category_levels(t, db, x, c, d, y), []int{0, 1, 2, 3}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got levels %v, want %v", got, want)
+	}
+	// 移动为根分类后子孙分类的 level 一起更新
+	moved, err := s.MoveCategory(controller.CategoryMoveBody{Id: c})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if moved.Level != 0 {
+		t.Errorf("got level %d, want 0", moved.Level)
+	}
+	if got, want := category_levels(t, db, x, c, d, y), []int{0, 0, 1, 2}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got levels %v, want %v", got, want)
+	}
+}
+
+func TestDeleteCategoryCascade(t *testing.T) {
+	db := open_test_db(t)
+	s := controller.NewCategoryController(db)
+	a := create_test_category(t, s, "a", "")
+	b := create_test_category(t, s, "b", "")
+	shared := create_test_category(t, s, "shared", a)
+	add_test_category_parent(t, db, b, shared)
+	only := create_test_category(t, s, "only", a)
+	grandchild := create_test_category(t, s, "grandchild", only)
+	ids, err := s.DeleteCategory(controller.CategoryDeleteBody{Id: a, Mode: controller.CategoryDeleteCascade})
+	if err != nil {
+		t.Fatal(err)
+	}
+	deleted := map[string]bool{}
+	for _, id := range ids {
+		deleted[id] = true
+	}
+	if want := map[string]bool{a: true, only: true, grandchild: true}; !reflect.DeepEqual(deleted, want) {
+		t.Errorf("got deleted %v, want %v", deleted, want)
+	}
+	// 还有其他父分类的子分类保留，只移除和被删除分类的关联
+	var parents []string
+	if err := db.Model(&models.CategoryHierarchy{}).Where("child_id = ?", shared).Pluck("parent_id", &parents).Error; err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(parents, []string{b}) {
+		t.Errorf("got parents %v, want [%s]", parents, b)
+	}
+	if got, want := category_levels(t, db, b, shared), []int{0, 1}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got levels %v, want %v", got, want)
+	}
+}
+
+func TestDeleteCategoryReparent(t *testing.T) {
+	db := open_test_db(t)
+	s := controller.NewCategoryController(db)
+	a := create_test_category(t, s, "a", "")
+	b := create_test_category(t, s, "b", a)
+	c := create_test_category(t, s, "c", b)
+	ids, err := s.DeleteCategory(controller.CategoryDeleteBody{Id: b})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(ids, []string{b}) {
+		t.Errorf("got deleted %v, want [%s]", ids, b)
+	}
+	if got, want := category_levels(t, db, a, c), []int{0, 1}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got levels %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,70 @@
+package controller
+
+import (
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+
+	"devboard/models"
+)
+
+// ensure_category_mapping 为记录添加分类，如果之前的关联被软删除了就恢复它，避免违反唯一约束
+func ensure_category_mapping(tx *gorm.DB, paste_event_id string, category_id string) (*models.PasteEventCategoryMapping, error) {
+	var existing []models.PasteEventCategoryMapping
+	if err := tx.Unscoped().Where("paste_event_id = ? AND category_id = ?", paste_event_id, category_id).Limit(1).Find(&existing).Error; err != nil {
+		return nil, err
+	}
+	if len(existing) == 0 {
+		created := models.PasteEventCategoryMapping{
+			PasteEventId: paste_event_id,
+			CategoryId:   category_id,
+		}
+		if err := tx.Create(&created).Error; err != nil {
+			return nil, err
+		}
+		return &created, nil
+	}
+	mapping := existing[0]
+	if !mapping.DeletedAt.Valid {
+		return &mapping, nil
+	}
+	mapping.DeletedAt = gorm.DeletedAt{}
+	if err := tx.Unscoped().Save(&mapping).Error; err != nil {
+		return nil, err
+	}
+	return &mapping, nil
+}
+
+// remove_category_mapping 软删除关联，便于同步到其他设备
+func remove_category_mapping(tx *gorm.DB, paste_event_id string, category_id string) error {
+	var existing []models.PasteEventCategoryMapping
+	if err := tx.Where("paste_event_id = ? AND category_id = ?", paste_event_id, category_id).Find(&existing).Error; err != nil {
+		return err
+	}
+	for _, mapping := range existing {
+		mapping.DeletedAt = gorm.DeletedAt{Time: time.Now(), Valid: true}
+		if err := tx.Save(&mapping).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validate_category_ids 所有分类都存在并且没有被删除
+func validate_category_ids(db *gorm.DB, ids []string) error {
+	var existing []string
+	if err := db.Model(&models.CategoryNode{}).Where("id IN ?", ids).Pluck("id", &existing).Error; err != nil {
+		return err
+	}
+	found := make(map[string]bool)
+	for _, id := range existing {
+		found[id] = true
+	}
+	for _, id := range ids {
+		if !found[id] {
+			return fmt.Errorf("The category %s doesn't exist", id)
+		}
+	}
+	return nil
+}
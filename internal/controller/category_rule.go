@@ -0,0 +1,393 @@
+package controller
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+
+	"devboard/internal/transformer"
+	"devboard/models"
+)
+
+// 重新应用规则时每批处理的记录数量
+const category_rule_apply_batch_size = 200
+
+type CategoryRuleResp struct {
+	Id          string                              `json:"id"`
+	Name        string                              `json:"name"`
+	Conditions  []transformer.CategoryRuleCondition `json:"conditions"`
+	CategoryIds []string                            `json:"category_ids"`
+	Enabled     bool                                `json:"enabled"`
+	SortOrder   int                                 `json:"sort_order"`
+	CreatedAt   string                              `json:"created_at"`
+}
+
+func to_category_rule_resp(record models.CategoryRule) CategoryRuleResp {
+	conditions := []transformer.CategoryRuleCondition{}
+	json.Unmarshal([]byte(record.ConditionsJSON), &conditions)
+	category_ids := []string{}
+	json.Unmarshal([]byte(record.CategoryIdsJSON), &category_ids)
+	return CategoryRuleResp{
+		Id:          record.Id,
+		Name:        record.Name,
+		Conditions:  conditions,
+		CategoryIds: category_ids,
+		Enabled:     record.Enabled,
+		SortOrder:   record.SortOrder,
+		CreatedAt:   record.CreatedAt,
+	}
+}
+
+type CategoryRuleSaveBody struct {
+	Id          string                              `json:"id"` // 为空表示新增
+	Name        string                              `json:"name"`
+	Conditions  []transformer.CategoryRuleCondition `json:"conditions"`
+	CategoryIds []string                            `json:"category_ids"`
+	Enabled     bool                                `json:"enabled"`
+	SortOrder   int                                 `json:"sort_order"`
+}
+
+func (s *CategoryController) SaveCategoryRule(body CategoryRuleSaveBody) (*CategoryRuleResp, error) {
+	body.Name = strings.TrimSpace(body.Name)
+	if body.Name == "" {
+		return nil, fmt.Errorf("Missing the name")
+	}
+	if _, err := transformer.CompileCategoryRule(body.Conditions); err != nil {
+		return nil, err
+	}
+	if len(body.CategoryIds) == 0 {
+		return nil, fmt.Errorf("Missing the category_ids")
+	}
+	if err := validate_category_ids(s.db, body.CategoryIds); err != nil {
+		return nil, err
+	}
+	conditions, err := json.Marshal(&body.Conditions)
+	if err != nil {
+		return nil, err
+	}
+	category_ids, err := json.Marshal(&body.CategoryIds)
+	if err != nil {
+		return nil, err
+	}
+	if body.Id == "" {
+		created := models.CategoryRule{
+			Name:            body.Name,
+			ConditionsJSON:  string(conditions),
+			CategoryIdsJSON: string(category_ids),
+			Enabled:         body.Enabled,
+			SortOrder:       body.SortOrder,
+		}
+		if err := s.db.Create(&created).Error; err != nil {
+			return nil, err
+		}
+		r := to_category_rule_resp(created)
+		return &r, nil
+	}
+	var record models.CategoryRule
+	if err := s.db.Where("id = ?", body.Id).First(&record).Error; err != nil {
+		return nil, err
+	}
+	record.Name = body.Name
+	record.ConditionsJSON = string(conditions)
+	record.CategoryIdsJSON = string(category_ids)
+	record.Enabled = body.Enabled
+	record.SortOrder = body.SortOrder
+	if err := s.db.Save(&record).Error; err != nil {
+		return nil, err
+	}
+	r := to_category_rule_resp(record)
+	return &r, nil
+}
+
+func (s *CategoryController) FetchCategoryRuleList() ([]CategoryRuleResp, error) {
+	var records []models.CategoryRule
+	if err := s.db.Order("sort_order ASC").Order("created_at ASC").Find(&records).Error; err != nil {
+		return nil, err
+	}
+	list := make([]CategoryRuleResp, 0, len(records))
+	for _, r := range records {
+		list = append(list, to_category_rule_resp(r))
+	}
+	return list, nil
+}
+
+type CategoryRuleBody struct {
+	Id string `json:"id"`
+}
+
+func (s *CategoryController) DeleteCategoryRule(body CategoryRuleBody) (*models.CategoryRule, error) {
+	if body.Id == "" {
+		return nil, fmt.Errorf("Missing the id")
+	}
+	var existing models.CategoryRule
+	if err := s.db.Where("id = ?", body.Id).First(&existing).Error; err != nil {
+		return nil, err
+	}
+	existing.DeletedAt = gorm.DeletedAt{Time: time.Now(), Valid: true}
+	if err := s.db.Save(&existing).Error; err != nil {
+		return nil, err
+	}
+	return &existing, nil
+}
+
+type category_rule struct {
+	id           string
+	matcher      *transformer.CategoryRuleMatcher
+	category_ids []string
+}
+
+// load_category_rules rule_ids 为空时读取所有启用的规则，无法解析的规则会被跳过
+func load_category_rules(db *gorm.DB, rule_ids []string) ([]category_rule, error) {
+	query := db.Model(&models.CategoryRule{})
+	if len(rule_ids) != 0 {
+		query = query.Where("id IN ?", rule_ids)
+	} else {
+		query = query.Where("enabled = ?", true)
+	}
+	var records []models.CategoryRule
+	if err := query.Order("sort_order ASC").Order("created_at ASC").Find(&records).Error; err != nil {
+		return nil, err
+	}
+	var rules []category_rule
+	for _, record := range records {
+		r := to_category_rule_resp(record)
+		matcher, err := transformer.CompileCategoryRule(r.Conditions)
+		if err != nil {
+			fmt.Println("[ERROR]compile category rule failed", record.Id, err.Error())
+			continue
+		}
+		rules = append(rules, category_rule{
+			id:           record.Id,
+			matcher:      matcher,
+			category_ids: r.CategoryIds,
+		})
+	}
+	return rules, nil
+}
+
+// match_category_rules 所有匹配的规则对应的分类，去重后按规则的顺序返回
+func match_category_rules(rules []category_rule, input transformer.CategoryRuleInput) []string {
+	var result []string
+	for _, r := range rules {
+		if !r.matcher.Match(input) {
+			continue
+		}
+		result = append_categories(result, r.category_ids...)
+	}
+	return result
+}
+
+// append_categories 添加没有出现过的分类
+func append_categories(categories []string, ids ...string) []string {
+	for _, id := range ids {
+		if id != "" && !slices.Contains(categories, id) {
+			categories = append(categories, id)
+		}
+	}
+	return categories
+}
+
+// existing_category_ids 过滤掉已经删除的分类，避免规则引用的分类被删除后无法保存记录
+func existing_category_ids(db *gorm.DB, ids []string) ([]string, error) {
+	if len(ids) == 0 {
+		return nil, nil
+	}
+	var existing []string
+	if err := db.Model(&models.CategoryNode{}).Where("id IN ?", ids).Pluck("id", &existing).Error; err != nil {
+		return nil, err
+	}
+	var result []string
+	for _, id := range ids {
+		if slices.Contains(existing, id) {
+			result = append(result, id)
+		}
+	}
+	return result, nil
+}
+
+// rule_categories 新记录匹配到的自定义分类，规则出错时只打印日志，不影响记录的保存
+func (s *PasteController) rule_categories(input transformer.CategoryRuleInput) []string {
+	rules, err := load_category_rules(s.db, nil)
+	if err != nil {
+		fmt.Println("[ERROR]load category rules failed", err.Error())
+		return nil
+	}
+	if len(rules) == 0 {
+		return nil
+	}
+	ids, err := existing_category_ids(s.db, match_category_rules(rules, input))
+	if err != nil {
+		fmt.Println("[ERROR]check rule categories failed", err.Error())
+		return nil
+	}
+	return ids
+}
+
+// file_list_rule_text 文件记录用所有文件路径匹配文本条件
+func file_list_rule_text(file_list_json string) string {
+	var files []FileInPasteEvent
+	json.Unmarshal([]byte(file_list_json), &files)
+	var paths []string
+	for _, f := range files {
+		paths = append(paths, f.AbsolutePath)
+	}
+	return strings.Join(paths, "\n")
+}
+
+// html_rule_urls 文本中的链接和复制时所在网页的地址
+func html_rule_urls(text string, source_url string) []string {
+	var urls []string
+	for _, e := range transformer.ExtractEntities(text) {
+		if e.Type == transformer.EntityURL {
+			urls = append(urls, e.Value)
+		}
+	}
+	if source_url != "" {
+		urls = append(urls, source_url)
+	}
+	return urls
+}
+
+type paste_event_rule_record struct {
+	Id           string
+	ContentType  string
+	Text         string
+	FileListJSON string
+	Details      string
+	AppName      string
+}
+
+func (r *paste_event_rule_record) input() transformer.CategoryRuleInput {
+	details := map[string]interface{}{}
+	json.Unmarshal([]byte(r.Details), &details)
+	window_title, _ := details["window_title"].(string)
+	language, _ := details["language"].(string)
+	input := transformer.CategoryRuleInput{
+		Text:        r.Text,
+		AppName:     r.AppName,
+		WindowTitle: window_title,
+		ContentType: r.ContentType,
+		Language:    language,
+	}
+	switch r.ContentType {
+	case "file":
+		input.Text = file_list_rule_text(r.FileListJSON)
+	case "html":
+		source_url, _ := details["source_url"].(string)
+		input.URLs = html_rule_urls(r.Text, source_url)
+	}
+	return input
+}
+
+type CategoryRuleApplyBody struct {
+	// RuleIds 为空时使用所有启用的规则
+	RuleIds []string `json:"rule_ids"`
+	// DryRun 只统计会添加的分类数量，不修改记录
+	DryRun bool `json:"dry_run"`
+}
+
+type CategoryRuleApplyResult struct {
+	Scanned int `json:"scanned"`
+	// Matched 会添加至少一个分类的记录数量
+	Matched int `json:"matched"`
+	// Added 新增的分类关联数量
+	Added  int  `json:"added"`
+	DryRun bool `json:"dry_run"`
+}
+
+// ApplyCategoryRules 对已有的记录重新应用规则，只添加缺少的分类，不会移除已有的分类
+func (s *PasteController) ApplyCategoryRules(body CategoryRuleApplyBody, progress func(done int, total int)) (*CategoryRuleApplyResult, error) {
+	rules, err := load_category_rules(s.db, body.RuleIds)
+	if err != nil {
+		return nil, err
+	}
+	result := &CategoryRuleApplyResult{DryRun: body.DryRun}
+	if len(rules) == 0 {
+		return result, nil
+	}
+	var total int64
+	if err := s.db.Model(&models.PasteEvent{}).Count(&total).Error; err != nil {
+		return nil, err
+	}
+	last_id := ""
+	for {
+		var records []paste_event_rule_record
+		if err := s.db.Model(&models.PasteEvent{}).
+			Select("paste_event.id, paste_event.content_type, paste_event.text, paste_event.file_list_json, paste_event.details, app.name AS app_name").
+			Joins("LEFT JOIN app ON app.id = paste_event.app_id").
+			Where("paste_event.id > ?", last_id).
+			Order("paste_event.id ASC").
+			Limit(category_rule_apply_batch_size).
+			Scan(&records).Error; err != nil {
+			return nil, err
+		}
+		if len(records) == 0 {
+			break
+		}
+		last_id = records[len(records)-1].Id
+		var ids []string
+		for _, r := range records {
+			ids = append(ids, r.Id)
+		}
+		assigned, err := s.fetch_paste_categories_map(ids)
+		if err != nil {
+			return nil, err
+		}
+		pending := make(map[string][]string)
+		var matched []string
+		for i := range records {
+			r := &records[i]
+			for _, c := range match_category_rules(rules, r.input()) {
+				exists := false
+				for _, a := range assigned[r.Id] {
+					if a.Id == c {
+						exists = true
+						break
+					}
+				}
+				if !exists {
+					pending[r.Id] = append(pending[r.Id], c)
+					matched = append_categories(matched, c)
+				}
+			}
+		}
+		valid, err := existing_category_ids(s.db, matched)
+		if err != nil {
+			return nil, err
+		}
+		err = s.db.Transaction(func(tx *gorm.DB) error {
+			for _, r := range records {
+				added := 0
+				for _, c := range pending[r.Id] {
+					if !slices.Contains(valid, c) {
+						continue
+					}
+					added += 1
+					if body.DryRun {
+						continue
+					}
+					if _, err := ensure_category_mapping(tx, r.Id, c); err != nil {
+						return err
+					}
+				}
+				if added != 0 {
+					result.Matched += 1
+					result.Added += added
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		result.Scanned += len(records)
+		if progress != nil {
+			progress(result.Scanned, int(total))
+		}
+	}
+	return result, nil
+}
@@ -0,0 +1,88 @@
+package controller
+
+import (
+	"gorm.io/gorm"
+
+	"devboard/models"
+)
+
+// category_tree_sql 一次查询返回所有分类、父分类 id 和记录数量，有多个父分类时一个分类对应多行。
+// category_closure 是递归得到的 (祖先, 子孙) 关系，包括分类自己，UNION 去重后出现环也能结束。
+// 同一条记录属于多个子孙分类时只计算一次。SQLite、Postgres 和 MySQL 8 都支持这种写法
+const category_tree_sql = `
+WITH RECURSIVE category_closure(ancestor_id, descendant_id) AS (
+  SELECT id, id FROM category_node WHERE deleted_at IS NULL
+  UNION
+  SELECT category_closure.ancestor_id, category_hierarchy.child_id FROM category_closure
+  JOIN category_hierarchy ON category_hierarchy.parent_id = category_closure.descendant_id AND category_hierarchy.deleted_at IS NULL
+  JOIN category_node ON category_node.id = category_hierarchy.child_id AND category_node.deleted_at IS NULL
+),
+category_count(category_id, direct_count, total_count) AS (
+  SELECT category_closure.ancestor_id,
+    COUNT(DISTINCT CASE WHEN category_closure.ancestor_id = category_closure.descendant_id THEN paste_event.id END),
+    COUNT(DISTINCT paste_event.id)
+  FROM category_closure
+  JOIN paste_event_category_mapping ON paste_event_category_mapping.category_id = category_closure.descendant_id AND paste_event_category_mapping.deleted_at IS NULL
+  JOIN paste_event ON paste_event.id = paste_event_category_mapping.paste_event_id AND paste_event.deleted_at IS NULL
+  GROUP BY category_closure.ancestor_id
+)
+SELECT category_node.*, category_hierarchy.parent_id AS parent_id,
+  COALESCE(category_count.direct_count, 0) AS direct_count,
+  COALESCE(category_count.total_count, 0) AS total_count
+FROM category_node
+LEFT JOIN category_hierarchy ON category_hierarchy.child_id = category_node.id AND category_hierarchy.deleted_at IS NULL
+LEFT JOIN category_count ON category_count.category_id = category_node.id
+WHERE category_node.deleted_at IS NULL
+ORDER BY category_node.sort_order ASC, category_node.created_at ASC`
+
+type category_tree_row struct {
+	models.CategoryNode
+	ParentId    *string
+	DirectCount int
+	TotalCount  int
+}
+
+// CategoryCount DirectCount 直接关联的记录数量，TotalCount 包括所有子孙分类的记录，不重复计算
+type CategoryCount struct {
+	DirectCount int `json:"direct_count"`
+	TotalCount  int `json:"total_count"`
+}
+
+type CategoryTreeNode struct {
+	models.CategoryNode
+	CategoryCount
+	Children []CategoryTreeNode `json:"children"`
+}
+
+// load_category_tree 和 load_category_graph 一样返回分类和层级关系，同时带上每个分类的记录数量
+func load_category_tree(db *gorm.DB) (*category_graph, map[string]CategoryCount, error) {
+	var rows []category_tree_row
+	if err := db.Raw(category_tree_sql).Scan(&rows).Error; err != nil {
+		return nil, nil, err
+	}
+	g := &category_graph{
+		nodes:    make(map[string]*models.CategoryNode),
+		parents:  make(map[string][]string),
+		children: make(map[string][]string),
+	}
+	counts := make(map[string]CategoryCount)
+	for i := range rows {
+		r := &rows[i]
+		if g.nodes[r.Id] == nil {
+			g.nodes[r.Id] = &r.CategoryNode
+			counts[r.Id] = CategoryCount{
+				DirectCount: r.DirectCount,
+				TotalCount:  r.TotalCount,
+			}
+		}
+	}
+	// 行已经按 sort_order 排序，子分类按出现的顺序加入
+	for _, r := range rows {
+		if r.ParentId == nil || g.nodes[*r.ParentId] == nil {
+			continue
+		}
+		g.parents[r.Id] = append(g.parents[r.Id], *r.ParentId)
+		g.children[*r.ParentId] = append(g.children[*r.ParentId], r.Id)
+	}
+	return g, counts, nil
+}
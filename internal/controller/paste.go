@@ -4,18 +4,24 @@ import (
 	"encoding/base64"
 	"encoding/json"
 	"fmt"
+	"html"
 	"os"
+	"strings"
 	"time"
 
 	"github.com/ltaoo/clipboard-go"
 	"gorm.io/gorm"
 
 	"devboard/models"
+	"devboard/pkg/blob"
+	_html "devboard/pkg/html"
 )
 
 type PasteController struct {
 	db         *gorm.DB
 	machine_id string
+	// blob 保存文件快照，为空时不保存也无法恢复
+	blob *blob.Store
 }
 
 func NewPasteController(db *gorm.DB, machine_id string) *PasteController {
@@ -30,68 +36,116 @@ type PasteListBody struct {
 
 	Types   []string `json:"types"`
 	Keyword string   `json:"keyword"`
+	// EntityTypes 只返回包含这些实体的记录，例如 ["ip"]
+	EntityTypes []string `json:"entity_types"`
+	// Pinned 只返回置顶的记录
+	Pinned bool `json:"pinned"`
 }
+
+// empty 没有任何筛选条件
+func (b PasteListBody) empty() bool {
+	return b.Keyword == "" && len(b.Types) == 0 && len(b.EntityTypes) == 0 && !b.Pinned
+}
+
+// remark_keyword_condition 备注的内容包含关键词
+const remark_keyword_condition = "EXISTS (SELECT 1 FROM remark WHERE remark.paste_event_id = paste_event.id AND remark.deleted_at IS NULL AND remark.content LIKE ?)"
+
+// apply_paste_list_filter 列表和批量操作共用的筛选条件
+func apply_paste_list_filter(query *gorm.DB, body PasteListBody) *gorm.DB {
+	if body.Keyword != "" {
+		// 备注的内容也参与搜索
+		keyword := "%" + body.Keyword + "%"
+		query = query.Where("(paste_event.text LIKE ? OR "+remark_keyword_condition+")", keyword, keyword)
+	}
+	if len(body.Types) != 0 {
+		query = query.Where("EXISTS (SELECT 1 FROM paste_event_category_mapping WHERE paste_event_category_mapping.paste_event_id = paste_event.id AND paste_event_category_mapping.category_id IN ? AND paste_event_category_mapping.deleted_at IS NULL)", body.Types)
+	}
+	if len(body.EntityTypes) != 0 {
+		query = query.Where("EXISTS (SELECT 1 FROM paste_event_entity WHERE paste_event_entity.paste_event_id = paste_event.id AND paste_event_entity.type IN ?)", body.EntityTypes)
+	}
+	if body.Pinned {
+		query = query.Where("paste_event.pinned = ?", true)
+	}
+	return query
+}
+
 type PasteCategoryResp struct {
 	Id    string `json:"id"`
 	Label string `json:"label"`
 }
+
+// PasteListItemResp 列表中的摘要，完整内容通过 FetchPasteEventProfile 或 /paste_content 读取
 type PasteListItemResp struct {
-	Id           string              `json:"id"`
-	ContentType  string              `json:"content_type"`
-	Text         string              `json:"text,omitempty"`
-	HTML         string              `json:"html,omitempty"`
-	ImageBase64  string              `json:"image_base64,omitempty"`
-	FileListJSON string              `json:"file_list_json,omitempty"`
-	Details      string              `json:"details,omitempty"`
-	CreatedAt    string              `json:"created_at"`
-	UpdatedAt    string              `json:"updated_at"`
-	Categories   []PasteCategoryResp `json:"categories"`
+	Id          string `json:"id"`
+	ContentType string `json:"content_type"`
+	Title       string `json:"title,omitempty"`
+	Pinned      bool   `json:"pinned,omitempty"`
+	// Text 文本的前 800 个字符，html 记录没有纯文本时为去掉标签后的文本
+	Text string `json:"text,omitempty"`
+	// TextLength 完整文本的字符数，大于 Text 的长度时说明内容被截断
+	TextLength int                 `json:"text_length,omitempty"`
+	Details    string              `json:"details,omitempty"`
+	CreatedAt  string              `json:"created_at"`
+	UpdatedAt  string              `json:"updated_at"`
+	Categories []PasteCategoryResp `json:"categories"`
+	// FileCount 文件记录中的文件数量
+	FileCount int `json:"file_count,omitempty"`
+	// Stale 文件记录中有文件被移动、删除或修改
+	Stale bool `json:"stale,omitempty"`
+	// Image 图片的格式信息和缩略图
+	Image *PasteImageResp `json:"image,omitempty"`
+	// ImageURL 还没有生成缩略图时读取原图的地址
+	ImageURL string `json:"image_url,omitempty"`
+	// RemarkCount 没有删除的备注数量
+	RemarkCount int `json:"remark_count,omitempty"`
+}
+
+// 列表中的文本最多返回的字符数
+const paste_preview_length = 800
+
+// html 记录没有纯文本时，截取这部分 html 生成预览
+const paste_preview_html_length = 16 * 1024
+
+// paste_event_summary 列表只查询需要的字段，不读取图片和完整的 html
+type paste_event_summary struct {
+	Id           string
+	ContentType  string
+	Title        string
+	Pinned       bool
+	Text         string
+	TextLength   int
+	Html         string
+	FileListJSON string
+	Details      string
+	CreatedAt    string
+	UpdatedAt    string
+}
+
+func (s *PasteController) paste_summary_query() *gorm.DB {
+	return s.db.Model(&models.PasteEvent{}).Select(
+		"paste_event.id, paste_event.content_type, paste_event.title, paste_event.pinned, paste_event.details, paste_event.created_at, paste_event.updated_at, "+
+			"substr(paste_event.text, 1, ?) AS text, length(paste_event.text) AS text_length, "+
+			"CASE WHEN paste_event.content_type = 'html' AND (paste_event.text IS NULL OR paste_event.text = '') THEN substr(paste_event.html, 1, ?) ELSE '' END AS html, "+
+			"CASE WHEN paste_event.content_type = 'file' THEN paste_event.file_list_json ELSE '' END AS file_list_json",
+		paste_preview_length, paste_preview_html_length,
+	)
 }
 
 func (s *PasteController) FetchPasteEventList(body PasteListBody) (*ListResp[PasteListItemResp], error) {
-	query := s.db.Model(&models.PasteEvent{})
-	if body.Keyword != "" {
-		query = query.Where("paste_event.text LIKE ?", "%"+body.Keyword+"%")
-	}
-	if len(body.Types) != 0 {
-		query = query.Joins("JOIN paste_event_category_mapping ON paste_event_category_mapping.paste_event_id = paste_event.id").Where("paste_event_category_mapping.category_id IN ?", body.Types).Distinct("paste_event.*")
-	}
-    pb := models.NewPaginationBuilder[models.PasteEvent](query).
-        SetLimit(body.PageSize).
-        SetPage(body.Page).
-        SetOrderBy("paste_event.updated_at DESC")
-	var list1 []models.PasteEvent
-	if err := pb.Build().Preload("Categories").Find(&list1).Error; err != nil {
+	query := apply_paste_list_filter(s.paste_summary_query(), body)
+	pb := models.NewPaginationBuilder[paste_event_summary](query).
+		SetLimit(body.PageSize).
+		SetPage(body.Page).
+		SetNextMarker(body.NextMarker).
+		SetOrderBy("paste_event.updated_at DESC")
+	var list1 []paste_event_summary
+	if err := pb.Build().Find(&list1).Error; err != nil {
 		return nil, err
 	}
 	list2, has_more, next_marker := pb.ProcessResults(list1)
-	list := make([]PasteListItemResp, 0)
-	for _, v := range list2 {
-		text := v.Text
-		r := []rune(text)
-		if len(r) > 800 {
-			text = string(r[:800])
-		}
-		vv := PasteListItemResp{
-			Id:           v.Id,
-			ContentType:  v.ContentType,
-			Text:         text,
-			HTML:         v.Html,
-			ImageBase64:  v.ImageBase64,
-			FileListJSON: v.FileListJSON,
-			Details:      v.Details,
-			CreatedAt:    v.CreatedAt,
-			UpdatedAt:    v.UpdatedAt,
-		}
-		var categories []PasteCategoryResp
-		for _, c := range v.Categories {
-			categories = append(categories, PasteCategoryResp{
-				Id:    c.Id,
-				Label: c.Label,
-			})
-		}
-		vv.Categories = categories
-		list = append(list, vv)
+	list, err := s.to_paste_list_items(list2)
+	if err != nil {
+		return nil, err
 	}
 	return &ListResp[PasteListItemResp]{
 		List:       list,
@@ -102,6 +156,123 @@ func (s *PasteController) FetchPasteEventList(body PasteListBody) (*ListResp[Pas
 	}, nil
 }
 
+// active_category_mapping_condition 预加载 Categories 时使用，many2many 预加载不会检查中间表的 deleted_at，取消关联的分类仍会出现
+const active_category_mapping_condition = "category_node.id IN (SELECT category_id FROM paste_event_category_mapping WHERE paste_event_id = ? AND deleted_at IS NULL)"
+
+// fetch_paste_categories_map 批量查询记录的分类，key 为记录 id
+func (s *PasteController) fetch_paste_categories_map(ids []string) (map[string][]PasteCategoryResp, error) {
+	result := make(map[string][]PasteCategoryResp)
+	if len(ids) == 0 {
+		return result, nil
+	}
+	var rows []struct {
+		PasteEventId string
+		Id           string
+		Label        string
+	}
+	if err := s.db.Table("paste_event_category_mapping").
+		Select("paste_event_category_mapping.paste_event_id, category_node.id, category_node.label").
+		Joins("JOIN category_node ON category_node.id = paste_event_category_mapping.category_id").
+		Where("paste_event_category_mapping.paste_event_id IN ? AND paste_event_category_mapping.deleted_at IS NULL AND category_node.deleted_at IS NULL", ids).
+		Order("paste_event_category_mapping.created_at").
+		Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+	for _, r := range rows {
+		result[r.PasteEventId] = append(result[r.PasteEventId], PasteCategoryResp{
+			Id:    r.Id,
+			Label: r.Label,
+		})
+	}
+	return result, nil
+}
+
+// fetch_remark_count_map 批量查询记录的备注数量，key 为记录 id
+func (s *PasteController) fetch_remark_count_map(ids []string) (map[string]int, error) {
+	result := make(map[string]int)
+	if len(ids) == 0 {
+		return result, nil
+	}
+	var rows []struct {
+		PasteEventId string
+		Count        int
+	}
+	if err := s.db.Model(&models.Remark{}).
+		Select("paste_event_id, COUNT(*) AS count").
+		Where("paste_event_id IN ?", ids).
+		Group("paste_event_id").
+		Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+	for _, r := range rows {
+		result[r.PasteEventId] = r.Count
+	}
+	return result, nil
+}
+
+// to_paste_list_items 列表中的图片返回缩略图地址，还没有生成缩略图时返回读取原图的地址
+func (s *PasteController) to_paste_list_items(records []paste_event_summary) ([]PasteListItemResp, error) {
+	var ids []string
+	var image_ids []string
+	for _, v := range records {
+		ids = append(ids, v.Id)
+		if v.ContentType == "image" {
+			image_ids = append(image_ids, v.Id)
+		}
+	}
+	images, err := s.fetch_paste_image_map(image_ids)
+	if err != nil {
+		return nil, err
+	}
+	categories, err := s.fetch_paste_categories_map(ids)
+	if err != nil {
+		return nil, err
+	}
+	remark_counts, err := s.fetch_remark_count_map(ids)
+	if err != nil {
+		return nil, err
+	}
+	list := make([]PasteListItemResp, 0)
+	for _, v := range records {
+		vv := PasteListItemResp{
+			Id:          v.Id,
+			ContentType: v.ContentType,
+			Title:       v.Title,
+			Pinned:      v.Pinned,
+			Text:        v.Text,
+			TextLength:  v.TextLength,
+			Details:     v.Details,
+			CreatedAt:   v.CreatedAt,
+			UpdatedAt:   v.UpdatedAt,
+			Categories:  categories[v.Id],
+			RemarkCount: remark_counts[v.Id],
+		}
+		if vv.Text == "" && v.Html != "" {
+			// 清理后的文本中 & < > 等字符仍然是转义的，前端按纯文本展示
+			text := []rune(html.UnescapeString(_html.Sanitize(v.Html, _html.ProfileTextOnly)))
+			vv.TextLength = len(text)
+			if len(text) > paste_preview_length {
+				text = text[:paste_preview_length]
+			}
+			vv.Text = string(text)
+		}
+		switch v.ContentType {
+		case "file":
+			var files []FileInPasteEvent
+			json.Unmarshal([]byte(v.FileListJSON), &files)
+			vv.FileCount = len(files)
+			_, vv.Stale = mark_stale_files(v.FileListJSON)
+		case "image":
+			vv.Image = images[v.Id]
+			if vv.Image == nil {
+				vv.ImageURL = PasteContentURL(v.Id, PasteContentImage)
+			}
+		}
+		list = append(list, vv)
+	}
+	return list, nil
+}
+
 type PasteProfileBody struct {
 	EventId string `json:"paste_event_id"`
 }
@@ -114,13 +285,21 @@ func (s *PasteController) FetchPasteEventProfile(body PasteProfileBody) (*models
 	if err := s.db.Where("id = ?", body.EventId).
 		Preload("App").
 		Preload("Device").
-		// 	Preload("Remarks", func(db *gorm.DB) *gorm.DB {
-		// 	return db.Order("remark.created_at DESC")
-		// }).
-		Preload("Categories").First(&record).Error; err != nil {
+		Preload("Remarks", func(db *gorm.DB) *gorm.DB {
+			return db.Order("remark.created_at DESC")
+		}).
+		Preload("Remarks.Attachments", func(db *gorm.DB) *gorm.DB {
+			return db.Order("remark_attachment.created_at ASC")
+		}).
+		Preload("Categories", active_category_mapping_condition, body.EventId).First(&record).Error; err != nil {
 		return nil, err
 	}
+	render_remarks(record.Remarks)
+	record.RemarkCount = len(record.Remarks)
 	vv := record
+	if vv.ContentType == "file" {
+		vv.FileListJSON, _ = mark_stale_files(vv.FileListJSON)
+	}
 	// vv := &PasteListItemResp{
 	// 	Id:           record.Id,
 	// 	ContentType:  record.ContentType,
@@ -156,6 +335,21 @@ func (s *PasteController) DeletePasteEvent(body PasteEventBody) (*models.PasteEv
 
 type PasteWriteBody struct {
 	EventId string `json:"paste_event_id"`
+	// Mode 为 markdown 时把 html 内容转换为 Markdown 文本写入
+	Mode string `json:"mode"`
+}
+
+// paste_event_markdown 旧记录没有保存 Markdown 时重新转换
+func paste_event_markdown(record *models.PasteEvent) string {
+	if record.Markdown != "" {
+		return record.Markdown
+	}
+	if record.Html != "" {
+		if markdown, err := _html.ToMarkdown(record.Html); err == nil && markdown != "" {
+			return markdown
+		}
+	}
+	return record.Text
 }
 
 func (s *PasteController) WritePasteContent(body PasteWriteBody) (int, error) {
@@ -166,6 +360,12 @@ func (s *PasteController) WritePasteContent(body PasteWriteBody) (int, error) {
 	if err := s.db.Where("id = ?", body.EventId).First(&record).Error; err != nil {
 		return 0, err
 	}
+	if body.Mode == "markdown" {
+		if err := clipboard.WriteText(paste_event_markdown(&record)); err != nil {
+			return 0, err
+		}
+		return 1, nil
+	}
 	is_text := record.ContentType == "text"
 	is_html := record.ContentType == "html"
 	is_image := record.ContentType == "image"
@@ -205,22 +405,31 @@ func (s *PasteController) WritePasteContent(body PasteWriteBody) (int, error) {
 		if err := json.Unmarshal([]byte(record.FileListJSON), &files); err != nil {
 			return 0, err
 		}
-		var errors []string
+		var failed []string
 		var file_paths []string
 		for _, f := range files {
-			_, err := os.Stat(f.AbsolutePath)
+			if _, err := os.Stat(f.AbsolutePath); err == nil {
+				file_paths = append(file_paths, f.AbsolutePath)
+				continue
+			}
+			// 原文件已被移动或删除，从快照恢复
+			restored, err := s.restore_file(&f)
 			if err != nil {
-				errors = append(errors, err.Error())
+				failed = append(failed, err.Error())
 				continue
 			}
-			file_paths = append(file_paths, f.AbsolutePath)
+			file_paths = append(file_paths, restored)
 		}
 		if len(file_paths) == 0 {
-			return 0, fmt.Errorf("There's no valid file can copy.")
+			return 0, fmt.Errorf("There's no valid file can copy. %s", strings.Join(failed, "; "))
 		}
 		if err := clipboard.WriteFiles(file_paths); err != nil {
 			return 0, err
 		}
+		if len(failed) != 0 {
+			// 其他文件已经写入剪贴板，返回错误提示哪些文件没有复制
+			return 1, fmt.Errorf("%d of %d files are not copied. %s", len(failed), len(files), strings.Join(failed, "; "))
+		}
 		return 1, nil
 	}
 	if is_text {
@@ -0,0 +1,345 @@
+package controller
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"gorm.io/gorm"
+
+	"devboard/internal/sensitive"
+	"devboard/internal/transformer"
+	"devboard/models"
+)
+
+// 批量操作支持的动作
+const (
+	PasteBulkDelete           = "delete"
+	PasteBulkRestore          = "restore"
+	PasteBulkAddCategories    = "add_categories"
+	PasteBulkRemoveCategories = "remove_categories"
+	PasteBulkPin              = "pin"
+	PasteBulkUnpin            = "unpin"
+	PasteBulkRedetect         = "redetect"
+	PasteBulkExport           = "export"
+)
+
+// 每个事务处理的记录数量
+const paste_bulk_batch_size = 100
+
+// PasteBulkBody Ids 不为空时只处理这些记录，否则按 Filter 筛选，两者都为空时报错，避免误操作所有记录
+type PasteBulkBody struct {
+	Action string         `json:"action"`
+	Ids    []string       `json:"ids"`
+	Filter *PasteListBody `json:"filter"`
+	// All 处理所有记录，Filter 没有任何条件时必须传 true
+	All bool `json:"all"`
+	// CategoryIds 添加、移除分类时使用
+	CategoryIds []string `json:"category_ids"`
+	// TaskId 前端用来区分进度事件，为空时自动生成
+	TaskId string `json:"task_id"`
+}
+
+type PasteBulkItemResult struct {
+	Id    string `json:"id"`
+	Ok    bool   `json:"ok"`
+	Error string `json:"error,omitempty"`
+}
+
+type PasteBulkResult struct {
+	TaskId    string                `json:"task_id"`
+	Action    string                `json:"action"`
+	Total     int                   `json:"total"`
+	Succeeded int                   `json:"succeeded"`
+	Failed    int                   `json:"failed"`
+	Items     []PasteBulkItemResult `json:"items"`
+}
+
+func (r *PasteBulkResult) add(id string, err error) {
+	item := PasteBulkItemResult{Id: id, Ok: err == nil}
+	if err != nil {
+		item.Error = err.Error()
+		r.Failed += 1
+	} else {
+		r.Succeeded += 1
+	}
+	r.Items = append(r.Items, item)
+}
+
+// sync_columns 使用 UpdateColumns 时不会触发 BeforeUpdate，需要手动更新同步相关的字段
+func sync_columns(columns map[string]interface{}, operation_type int) map[string]interface{} {
+	now := strconv.FormatInt(time.Now().UnixMilli(), 10)
+	columns["last_operation_time"] = now
+	columns["last_operation_type"] = operation_type
+	columns["sync_status"] = 1
+	columns["updated_at"] = now
+	return columns
+}
+
+// ResolvePasteBulkIds 返回需要处理的记录 id，恢复时只查找已删除的记录
+func (s *PasteController) ResolvePasteBulkIds(body PasteBulkBody) ([]string, error) {
+	deleted := body.Action == PasteBulkRestore
+	if len(body.Ids) == 0 && !body.All {
+		if body.Filter == nil {
+			return nil, fmt.Errorf("Missing the ids or filter")
+		}
+		if body.Filter.empty() {
+			return nil, fmt.Errorf("The filter has no conditions, pass all to process every record")
+		}
+	}
+	query := s.db.Unscoped().Model(&models.PasteEvent{})
+	if deleted {
+		query = query.Where("paste_event.deleted_at IS NOT NULL")
+	} else {
+		query = query.Where("paste_event.deleted_at IS NULL")
+	}
+	if len(body.Ids) != 0 {
+		query = query.Where("paste_event.id IN ?", body.Ids)
+	} else if body.Filter != nil {
+		query = apply_paste_list_filter(query, *body.Filter)
+	}
+	var ids []string
+	if err := query.Order("paste_event.updated_at DESC").Pluck("paste_event.id", &ids).Error; err != nil {
+		return nil, err
+	}
+	return ids, nil
+}
+
+// add_missing_ids 传入的 id 找不到对应记录时标记为失败
+func (r *PasteBulkResult) add_missing_ids(body PasteBulkBody, ids []string) {
+	found := make(map[string]bool)
+	for _, id := range ids {
+		found[id] = true
+	}
+	for _, id := range body.Ids {
+		if !found[id] {
+			found[id] = true
+			r.Total += 1
+			r.add(id, gorm.ErrRecordNotFound)
+		}
+	}
+}
+
+func (s *PasteController) paste_bulk_operation(body PasteBulkBody) (func(tx *gorm.DB, id string) error, error) {
+	switch body.Action {
+	case PasteBulkDelete:
+		return func(tx *gorm.DB, id string) error {
+			return tx.Model(&models.PasteEvent{}).Where("id = ?", id).UpdateColumns(sync_columns(map[string]interface{}{
+				"deleted_at": time.Now(),
+			}, 3)).Error
+		}, nil
+	case PasteBulkRestore:
+		return func(tx *gorm.DB, id string) error {
+			return tx.Unscoped().Model(&models.PasteEvent{}).Where("id = ?", id).UpdateColumns(sync_columns(map[string]interface{}{
+				"deleted_at": nil,
+			}, 2)).Error
+		}, nil
+	case PasteBulkPin, PasteBulkUnpin:
+		pinned := body.Action == PasteBulkPin
+		return func(tx *gorm.DB, id string) error {
+			return tx.Model(&models.PasteEvent{}).Where("id = ?", id).UpdateColumns(sync_columns(map[string]interface{}{
+				"pinned": pinned,
+			}, 2)).Error
+		}, nil
+	case PasteBulkAddCategories:
+		if len(body.CategoryIds) == 0 {
+			return nil, fmt.Errorf("Missing the category_ids")
+		}
+		if err := validate_category_ids(s.db, body.CategoryIds); err != nil {
+			return nil, err
+		}
+		return func(tx *gorm.DB, id string) error {
+			for _, c := range body.CategoryIds {
+				if _, err := ensure_category_mapping(tx, id, c); err != nil {
+					return err
+				}
+			}
+			return nil
+		}, nil
+	case PasteBulkRemoveCategories:
+		if len(body.CategoryIds) == 0 {
+			return nil, fmt.Errorf("Missing the category_ids")
+		}
+		return func(tx *gorm.DB, id string) error {
+			for _, c := range body.CategoryIds {
+				if err := remove_category_mapping(tx, id, c); err != nil {
+					return err
+				}
+			}
+			return nil
+		}, nil
+	case PasteBulkRedetect:
+		return redetect_paste_event, nil
+	}
+	return nil, fmt.Errorf("Unknown action %s", body.Action)
+}
+
+// BulkUpdatePasteEvents 分批在事务中执行，单条记录失败时只回滚这一条，每处理完一批调用一次 progress
+func (s *PasteController) BulkUpdatePasteEvents(body PasteBulkBody, progress func(done int, total int)) (*PasteBulkResult, error) {
+	op, err := s.paste_bulk_operation(body)
+	if err != nil {
+		return nil, err
+	}
+	ids, err := s.ResolvePasteBulkIds(body)
+	if err != nil {
+		return nil, err
+	}
+	result := &PasteBulkResult{
+		TaskId: body.TaskId,
+		Action: body.Action,
+		Total:  len(ids),
+		Items:  make([]PasteBulkItemResult, 0, len(ids)),
+	}
+	result.add_missing_ids(body, ids)
+	for start := 0; start < len(ids); start += paste_bulk_batch_size {
+		batch := ids[start:min(start+paste_bulk_batch_size, len(ids))]
+		errs := make([]error, len(batch))
+		err := s.db.Transaction(func(tx *gorm.DB) error {
+			for i, id := range batch {
+				name := "item" + strconv.Itoa(i)
+				if err := tx.SavePoint(name).Error; err != nil {
+					return err
+				}
+				errs[i] = op(tx, id)
+				if errs[i] != nil {
+					if err := tx.RollbackTo(name).Error; err != nil {
+						return err
+					}
+				}
+			}
+			return nil
+		})
+		for i, id := range batch {
+			if err != nil {
+				// 事务提交失败时，这一批的记录都没有生效
+				errs[i] = err
+			}
+			result.add(id, errs[i])
+		}
+		if progress != nil {
+			progress(start+len(batch), len(ids))
+		}
+	}
+	return result, nil
+}
+
+// redetect_paste_event 重新检测文本的语言、时间、颜色和敏感信息，补充分类并重新提取实体，不会移除已有的分类
+func redetect_paste_event(tx *gorm.DB, id string) error {
+	var record models.PasteEvent
+	if err := tx.Select("id", "content_type", "text", "details").Where("id = ?", id).First(&record).Error; err != nil {
+		return err
+	}
+	if record.ContentType != "text" && record.ContentType != "html" {
+		return nil
+	}
+	details := map[string]interface{}{}
+	if record.Details != "" {
+		json.Unmarshal([]byte(record.Details), &details)
+	}
+	for _, key := range []string{"language", "language_confidence", "time", "color", "is_secret"} {
+		delete(details, key)
+	}
+	var categories []string
+	if record.Text != "" {
+		content := transformer.DetectTextContent(record.Text)
+		set_text_content_details(details, content)
+		categories = content.Categories
+	}
+	if sensitive.ContainsSecret(record.Text) {
+		details["is_secret"] = true
+	} else if record.ContentType == "html" {
+		details["is_secret"] = false
+	}
+	d, _ := json.Marshal(&details)
+	if err := tx.Model(&models.PasteEvent{}).Where("id = ?", id).UpdateColumns(sync_columns(map[string]interface{}{
+		"details": string(d),
+	}, 2)).Error; err != nil {
+		return err
+	}
+	for _, c := range categories {
+		if _, err := ensure_category_mapping(tx, id, c); err != nil {
+			return err
+		}
+	}
+	return save_paste_event_entities(tx, id, record.Text)
+}
+
+// PasteEventExportItem 导出的记录包含完整内容
+type PasteEventExportItem struct {
+	Id           string   `json:"id"`
+	ContentType  string   `json:"content_type"`
+	Title        string   `json:"title,omitempty"`
+	Text         string   `json:"text,omitempty"`
+	Html         string   `json:"html,omitempty"`
+	Markdown     string   `json:"markdown,omitempty"`
+	FileListJSON string   `json:"file_list_json,omitempty"`
+	ImageBase64  string   `json:"image_base64,omitempty"`
+	Details      string   `json:"details,omitempty"`
+	Pinned       bool     `json:"pinned,omitempty"`
+	Categories   []string `json:"categories"`
+	CreatedAt    string   `json:"created_at"`
+	UpdatedAt    string   `json:"updated_at"`
+}
+
+// ExportPasteEvents 分批读取记录，找不到的记录在结果中标记为失败
+func (s *PasteController) ExportPasteEvents(body PasteBulkBody, progress func(done int, total int)) ([]PasteEventExportItem, *PasteBulkResult, error) {
+	ids, err := s.ResolvePasteBulkIds(body)
+	if err != nil {
+		return nil, nil, err
+	}
+	result := &PasteBulkResult{
+		TaskId: body.TaskId,
+		Action: PasteBulkExport,
+		Total:  len(ids),
+		Items:  make([]PasteBulkItemResult, 0, len(ids)),
+	}
+	result.add_missing_ids(body, ids)
+	items := make([]PasteEventExportItem, 0, len(ids))
+	for start := 0; start < len(ids); start += paste_bulk_batch_size {
+		batch := ids[start:min(start+paste_bulk_batch_size, len(ids))]
+		var records []models.PasteEvent
+		if err := s.db.Where("id IN ?", batch).Find(&records).Error; err != nil {
+			return nil, nil, err
+		}
+		categories_map, err := s.fetch_paste_categories_map(batch)
+		if err != nil {
+			return nil, nil, err
+		}
+		record_map := make(map[string]*models.PasteEvent)
+		for i := range records {
+			record_map[records[i].Id] = &records[i]
+		}
+		for _, id := range batch {
+			v := record_map[id]
+			if v == nil {
+				result.add(id, gorm.ErrRecordNotFound)
+				continue
+			}
+			item := PasteEventExportItem{
+				Id:           v.Id,
+				ContentType:  v.ContentType,
+				Title:        v.Title,
+				Text:         v.Text,
+				Html:         v.Html,
+				Markdown:     v.Markdown,
+				FileListJSON: v.FileListJSON,
+				ImageBase64:  v.ImageBase64,
+				Details:      v.Details,
+				Pinned:       v.Pinned,
+				Categories:   []string{},
+				CreatedAt:    v.CreatedAt,
+				UpdatedAt:    v.UpdatedAt,
+			}
+			for _, c := range categories_map[id] {
+				item.Categories = append(item.Categories, c.Id)
+			}
+			items = append(items, item)
+			result.add(id, nil)
+		}
+		if progress != nil {
+			progress(start+len(batch), len(ids))
+		}
+	}
+	return items, result, nil
+}
@@ -0,0 +1,114 @@
+package controller
+
+import (
+	"fmt"
+
+	"gorm.io/gorm"
+
+	"devboard/internal/transformer"
+	"devboard/models"
+)
+
+type PasteCategoryAssignBody struct {
+	PasteEventId string   `json:"paste_event_id"`
+	CategoryIds  []string `json:"category_ids"`
+}
+
+type PasteCategoryUnassignBody struct {
+	PasteEventId string `json:"paste_event_id"`
+	CategoryId   string `json:"category_id"`
+}
+
+// AssignCategories 手动为记录添加分类，分类必须存在
+func (s *PasteController) AssignCategories(body PasteCategoryAssignBody) ([]PasteCategoryResp, error) {
+	if body.PasteEventId == "" {
+		return nil, fmt.Errorf("Missing the paste_event_id")
+	}
+	if len(body.CategoryIds) == 0 {
+		return nil, fmt.Errorf("Missing the category_ids")
+	}
+	if err := validate_category_ids(s.db, body.CategoryIds); err != nil {
+		return nil, err
+	}
+	var record models.PasteEvent
+	if err := s.db.Select("id").Where("id = ?", body.PasteEventId).First(&record).Error; err != nil {
+		return nil, err
+	}
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		for _, id := range body.CategoryIds {
+			if _, err := ensure_category_mapping(tx, record.Id, id); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.fetch_paste_categories(record.Id)
+}
+
+// UnassignCategory 移除记录的分类，关联记录是软删除，其他设备同步后也会移除
+func (s *PasteController) UnassignCategory(body PasteCategoryUnassignBody) ([]PasteCategoryResp, error) {
+	if body.PasteEventId == "" {
+		return nil, fmt.Errorf("Missing the paste_event_id")
+	}
+	if body.CategoryId == "" {
+		return nil, fmt.Errorf("Missing the category_id")
+	}
+	var count int64
+	if err := s.db.Model(&models.PasteEventCategoryMapping{}).
+		Where("paste_event_id = ? AND category_id = ?", body.PasteEventId, body.CategoryId).
+		Count(&count).Error; err != nil {
+		return nil, err
+	}
+	if count == 0 {
+		return nil, fmt.Errorf("The category isn't assigned to the paste event")
+	}
+	if err := remove_category_mapping(s.db, body.PasteEventId, body.CategoryId); err != nil {
+		return nil, err
+	}
+	return s.fetch_paste_categories(body.PasteEventId)
+}
+
+func (s *PasteController) fetch_paste_categories(id string) ([]PasteCategoryResp, error) {
+	categories, err := s.fetch_paste_categories_map([]string{id})
+	if err != nil {
+		return nil, err
+	}
+	if categories[id] == nil {
+		return []PasteCategoryResp{}, nil
+	}
+	return categories[id], nil
+}
+
+// SuggestPasteEventCategories 重新检测记录的文本内容，把存在但还没有关联的分类保存为待确认的推荐
+func (s *PasteController) SuggestPasteEventCategories(body PasteEventBody) ([]models.PasteEventCategorySuggestion, error) {
+	if body.PasteEventId == "" {
+		return nil, fmt.Errorf("Missing the paste_event_id")
+	}
+	var record models.PasteEvent
+	if err := s.db.Select("id", "content_type", "text").Where("id = ?", body.PasteEventId).First(&record).Error; err != nil {
+		return nil, err
+	}
+	var detected []string
+	if record.ContentType == "text" || record.ContentType == "html" {
+		detected = transformer.TextContentDetector(record.Text)
+	}
+	if len(detected) != 0 {
+		var existing []string
+		if err := s.db.Model(&models.CategoryNode{}).Where("id IN ?", detected).Pluck("id", &existing).Error; err != nil {
+			return nil, err
+		}
+		if len(existing) != 0 {
+			if _, err := s.SavePasteEventSuggestion(PasteEventSuggestionBody{
+				PasteEventId: record.Id,
+				CategoryIds:  existing,
+				Source:       "detector",
+			}); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return s.FetchCategorySuggestionList(body)
+}
@@ -0,0 +1,52 @@
+package controller
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"devboard/models"
+)
+
+// 通过 /paste_content 读取的内容类型
+const (
+	PasteContentText     = "text"
+	PasteContentHTML     = "html"
+	PasteContentMarkdown = "markdown"
+	PasteContentImage    = "image"
+)
+
+// PasteContentURL 前端通过该地址按需读取记录的完整内容，见 service.PasteContentService
+func PasteContentURL(id string, kind string) string {
+	return "/paste_content?id=" + url.QueryEscape(id) + "&type=" + kind
+}
+
+// FetchPasteEventContent 返回记录的完整内容和 mime 类型
+func (s *PasteController) FetchPasteEventContent(id string, kind string) ([]byte, string, error) {
+	if id == "" {
+		return nil, "", fmt.Errorf("缺少 id 参数")
+	}
+	var record models.PasteEvent
+	if err := s.db.Where("id = ?", id).First(&record).Error; err != nil {
+		return nil, "", err
+	}
+	switch kind {
+	case PasteContentImage:
+		if record.ImageBase64 == "" {
+			return nil, "", fmt.Errorf("The record has no image")
+		}
+		data, err := base64.StdEncoding.DecodeString(record.ImageBase64)
+		if err != nil {
+			return nil, "", err
+		}
+		return data, http.DetectContentType(data), nil
+	case PasteContentHTML:
+		return []byte(record.Html), "text/html; charset=utf-8", nil
+	case PasteContentMarkdown:
+		return []byte(paste_event_markdown(&record)), "text/markdown; charset=utf-8", nil
+	case PasteContentText, "":
+		return []byte(record.Text), "text/plain; charset=utf-8", nil
+	}
+	return nil, "", fmt.Errorf("Unknown content type %s", kind)
+}
@@ -0,0 +1,94 @@
+package controller
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"devboard/internal/sensitive"
+	"devboard/internal/transformer"
+	"devboard/models"
+)
+
+// DerivedPasteEventBody 由已有记录加工得到的新记录
+type DerivedPasteEventBody struct {
+	OriginId string
+	Text     string
+	// Details 额外写入 Details 的字段，derived_from 会自动加上
+	Details map[string]interface{}
+	// Categories 在检测出的分类之外额外添加的分类，例如代码块标注的语言
+	Categories []string
+}
+
+// CreateDerivedPasteEvent 创建一条文本记录，来源应用和设备沿用原记录。内容与已有文本记录相同时直接返回已有记录
+func (s *PasteController) CreateDerivedPasteEvent(body DerivedPasteEventBody) (*models.PasteEvent, bool, error) {
+	if body.Text == "" {
+		return nil, false, fmt.Errorf("The content is empty")
+	}
+	var origin models.PasteEvent
+	if err := s.db.Where("id = ?", body.OriginId).First(&origin).Error; err != nil {
+		return nil, false, err
+	}
+	var existing []models.PasteEvent
+	if err := s.db.Where("content_type = ? AND text = ?", "text", body.Text).Limit(1).Find(&existing).Error; err != nil {
+		return nil, false, err
+	}
+	if len(existing) != 0 {
+		return &existing[0], false, nil
+	}
+	details := map[string]interface{}{}
+	for k, v := range body.Details {
+		details[k] = v
+	}
+	details["derived_from"] = origin.Id
+	if sensitive.ContainsSecret(body.Text) || IsSecretPasteEvent(&origin) {
+		details["is_secret"] = true
+	}
+	d, _ := json.Marshal(&details)
+	created := models.PasteEvent{
+		ContentType: "text",
+		Text:        body.Text,
+		Details:     string(d),
+		AppId:       origin.AppId,
+		DeviceId:    origin.DeviceId,
+	}
+	tx := s.db.Begin()
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+			return
+		}
+	}()
+	if err := tx.Create(&created).Error; err != nil {
+		tx.Rollback()
+		return nil, false, err
+	}
+	categories := transformer.TextContentDetector(body.Text)
+	categories = append(categories, body.Categories...)
+	categories = append(categories, "text")
+	seen := make(map[string]bool)
+	for _, c := range categories {
+		if seen[c] {
+			continue
+		}
+		seen[c] = true
+		created.Categories = append(created.Categories, models.CategoryNode{
+			BaseModel: models.BaseModel{
+				Id: c,
+			},
+			Label: c,
+		})
+		if _, err := ensure_category_mapping(tx, created.Id, c); err != nil {
+			tx.Rollback()
+			return nil, false, err
+		}
+	}
+	if err := save_paste_event_entities(tx, created.Id, created.Text); err != nil {
+		tx.Rollback()
+		return nil, false, err
+	}
+	if err := tx.Commit().Error; err != nil {
+		tx.Rollback()
+		return nil, false, err
+	}
+	return &created, true, nil
+}
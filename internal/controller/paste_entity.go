@@ -0,0 +1,85 @@
+package controller
+
+import (
+	"fmt"
+
+	"gorm.io/gorm"
+
+	"devboard/internal/transformer"
+	"devboard/models"
+)
+
+const entity_rebuild_batch_size = 200
+
+// save_paste_event_entities 重新提取记录文本中的实体，旧的实体直接删除
+func save_paste_event_entities(tx *gorm.DB, paste_event_id string, text string) error {
+	if err := tx.Unscoped().Where("paste_event_id = ?", paste_event_id).Delete(&models.PasteEventEntity{}).Error; err != nil {
+		return err
+	}
+	if text == "" {
+		return nil
+	}
+	entities := transformer.ExtractEntities(text)
+	if len(entities) == 0 {
+		return nil
+	}
+	records := make([]models.PasteEventEntity, 0, len(entities))
+	for _, e := range entities {
+		records = append(records, models.PasteEventEntity{
+			PasteEventId: paste_event_id,
+			Type:         e.Type,
+			Value:        e.Value,
+			StartOffset:  e.Start,
+			EndOffset:    e.End,
+		})
+	}
+	return tx.CreateInBatches(&records, 100).Error
+}
+
+func (s *PasteController) FetchPasteEventEntityList(body PasteProfileBody) ([]models.PasteEventEntity, error) {
+	if body.EventId == "" {
+		return nil, fmt.Errorf("Missing the paste_event_id")
+	}
+	list := []models.PasteEventEntity{}
+	if err := s.db.Where("paste_event_id = ?", body.EventId).Order("start_offset ASC").Find(&list).Error; err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+func (s *PasteController) RefreshPasteEventEntities(body PasteProfileBody) ([]models.PasteEventEntity, error) {
+	if body.EventId == "" {
+		return nil, fmt.Errorf("Missing the paste_event_id")
+	}
+	var record models.PasteEvent
+	if err := s.db.Where("id = ?", body.EventId).First(&record).Error; err != nil {
+		return nil, err
+	}
+	if err := s.db.Transaction(func(tx *gorm.DB) error {
+		return save_paste_event_entities(tx, record.Id, record.Text)
+	}); err != nil {
+		return nil, err
+	}
+	return s.FetchPasteEventEntityList(body)
+}
+
+// RebuildPasteEventEntities 为所有文本记录重新提取实体，用于升级前已存在的记录
+func (s *PasteController) RebuildPasteEventEntities() (int, error) {
+	total := 0
+	var records []models.PasteEvent
+	r := s.db.Select("id", "text").Where("text IS NOT NULL AND text != ''").FindInBatches(&records, entity_rebuild_batch_size, func(_ *gorm.DB, batch int) error {
+		return s.db.Transaction(func(tx *gorm.DB) error {
+			for _, record := range records {
+				if err := save_paste_event_entities(tx, record.Id, record.Text); err != nil {
+					return err
+				}
+				total += 1
+			}
+			return nil
+		})
+	})
+	if r.Error != nil {
+		return total, r.Error
+	}
+	return total, nil
+}
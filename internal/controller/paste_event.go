@@ -5,14 +5,16 @@ import (
 	"encoding/base64"
 	"encoding/json"
 	"fmt"
-	"image/png"
+	"image"
 	"mime"
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
 
 	"gorm.io/gorm"
 
+	"devboard/internal/sensitive"
 	"devboard/internal/transformer"
 	"devboard/models"
 	_html "devboard/pkg/html"
@@ -25,6 +27,12 @@ type PasteExtraInfo struct {
 	WindowTitle string
 	PlainText   string
 	MachineId   string
+	// OriginalText 文本被清理过（例如删除了链接的跟踪参数）时的原始内容
+	OriginalText string
+	// HTMLProfile 清理 html 时使用的规则，见 _html.FindSanitizeProfile
+	HTMLProfile string
+	// FileSnapshot 不为空时把符合条件的文件内容保存到 blob 目录
+	FileSnapshot *FileSnapshotPolicy
 }
 
 var unknown_app_id = ""
@@ -93,6 +101,36 @@ func get_device_id(db *gorm.DB, machine_id string) string {
 	return device_id
 }
 
+// set_text_content_details 把检测出的代码语言、时间等信息写入 Details
+func set_text_content_details(details map[string]interface{}, content transformer.TextContent) {
+	if content.Language != "" {
+		details["language"] = content.Language
+		details["language_confidence"] = content.Confidence
+	}
+	if content.Time != nil {
+		details["time"] = map[string]interface{}{
+			"instant":  content.Time.Instant.UTC().Format(time.RFC3339Nano),
+			"unix_ms":  content.Time.Instant.UnixMilli(),
+			"format":   content.Time.Format,
+			"has_zone": content.Time.HasZone,
+		}
+	}
+	if c := content.Color; c != nil {
+		white := &transformer.Color{R: 1, G: 1, B: 1, A: 1}
+		black := &transformer.Color{A: 1}
+		details["color"] = map[string]interface{}{
+			"hex":            c.Hex(),
+			"rgb":            c.RGB(),
+			"hsl":            c.HSL(),
+			"oklch":          c.OKLCH(),
+			"alpha":          c.A,
+			"name":           c.Name,
+			"contrast_white": c.ContrastRatio(white),
+			"contrast_black": c.ContrastRatio(black),
+		}
+	}
+}
+
 func (s *PasteController) HandlePasteText(text string, extra *PasteExtraInfo) (*models.PasteEvent, error) {
     var created_paste_event models.PasteEvent
     var existing []models.PasteEvent
@@ -122,6 +160,29 @@ func (s *PasteController) HandlePasteText(text string, extra *PasteExtraInfo) (*
 		AppId:       get_app_id(s.db, extra.AppName),
 		DeviceId:    get_device_id(s.db, extra.MachineId),
 	}
+	content := transformer.DetectTextContent(text)
+	details := map[string]interface{}{}
+	if sensitive.ContainsSecret(text) {
+		details["is_secret"] = true
+	}
+	set_text_content_details(details, content)
+	if extra.OriginalText != "" && extra.OriginalText != text {
+		details["original_text"] = extra.OriginalText
+	}
+	if extra.WindowTitle != "" {
+		details["window_title"] = extra.WindowTitle
+	}
+	if len(details) != 0 {
+		d, _ := json.Marshal(&details)
+		created_paste_event.Details = string(d)
+	}
+	rule_categories := s.rule_categories(transformer.CategoryRuleInput{
+		Text:        text,
+		AppName:     extra.AppName,
+		WindowTitle: extra.WindowTitle,
+		ContentType: "text",
+		Language:    content.Language,
+	})
 	tx := s.db.Begin()
 	defer func() {
 		if r := recover(); r != nil {
@@ -134,8 +195,9 @@ func (s *PasteController) HandlePasteText(text string, extra *PasteExtraInfo) (*
 		return nil, err
 	}
 	var errors []error
-	categories := transformer.TextContentDetector(text)
-	categories = append(categories, "text")
+	categories := append_categories(nil, content.Categories...)
+	categories = append_categories(categories, "text")
+	categories = append_categories(categories, rule_categories...)
 	for _, c := range categories {
 		created_paste_event.Categories = append(created_paste_event.Categories, models.CategoryNode{
 			BaseModel: models.BaseModel{
@@ -155,6 +217,10 @@ func (s *PasteController) HandlePasteText(text string, extra *PasteExtraInfo) (*
 	if len(errors) != 0 {
 		return nil, errors[0]
 	}
+	if err := save_paste_event_entities(tx, created_paste_event.Id, text); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
 	if err := tx.Commit().Error; err != nil {
 		tx.Rollback()
 		return nil, err
@@ -167,7 +233,7 @@ func (s *PasteController) HandlePasteHTML(html_content string, extra *PasteExtra
     r := _html.ParseHTMLContent(html_content)
     text := extra.PlainText
     html := r.HTMLContent
-    html = _html.CleanRichTextStrict(html)
+    html = _html.Sanitize(html, _html.FindSanitizeProfile(extra.HTMLProfile))
     var existing []models.PasteEvent
     if err := s.db.Where("content_type = ? AND html = ?", "html", html).Limit(1).Find(&existing).Error; err == nil && len(existing) > 0 {
         tx := s.db.Begin()
@@ -187,18 +253,44 @@ func (s *PasteController) HandlePasteHTML(html_content string, extra *PasteExtra
         }
         return nil, nil
     }
-	details, _ := json.Marshal(&map[string]interface{}{
+	content := transformer.DetectTextContent(text)
+	details_map := map[string]interface{}{
 		"source_url":   r.SourceURL,
 		"window_title": extra.WindowTitle,
-	})
+		"is_secret":    sensitive.ContainsSecret(text),
+	}
+	if text != "" {
+		set_text_content_details(details_map, content)
+	}
+	if extracted, err := _html.Extract(html, r.SourceURL); err == nil {
+		if n := len(extracted.CodeBlocks) + len(extracted.Tables) + len(extracted.Links); n != 0 {
+			details_map["extracted"] = map[string]interface{}{
+				"code_blocks": len(extracted.CodeBlocks),
+				"tables":      len(extracted.Tables),
+				"links":       len(extracted.Links),
+			}
+		}
+	}
+	details, _ := json.Marshal(&details_map)
+	// 清理前的 html 保留了代码块的语言等信息
+	markdown, _ := _html.ToMarkdown(r.HTMLContent)
 	created_paste_event = models.PasteEvent{
 		ContentType: "html",
 		Text:        text,
 		Html:        html,
+		Markdown:    markdown,
 		Details:     string(details),
 		AppId:       get_app_id(s.db, extra.AppName),
 		DeviceId:    get_device_id(s.db, extra.MachineId),
 	}
+	rule_categories := s.rule_categories(transformer.CategoryRuleInput{
+		Text:        text,
+		AppName:     extra.AppName,
+		WindowTitle: extra.WindowTitle,
+		ContentType: "html",
+		Language:    content.Language,
+		URLs:        html_rule_urls(text, r.SourceURL),
+	})
 	tx := s.db.Begin()
 	defer func() {
 		if r := recover(); r != nil {
@@ -213,9 +305,9 @@ func (s *PasteController) HandlePasteHTML(html_content string, extra *PasteExtra
 	var errors []error
 	categories := []string{"html"}
 	if text != "" {
-		extra_categories := transformer.TextContentDetector(text)
-		categories = append(categories, extra_categories...)
+		categories = append_categories(categories, content.Categories...)
 	}
+	categories = append_categories(categories, rule_categories...)
 	for _, c := range categories {
 		created_paste_event.Categories = append(created_paste_event.Categories, models.CategoryNode{
 			BaseModel: models.BaseModel{
@@ -235,6 +327,10 @@ func (s *PasteController) HandlePasteHTML(html_content string, extra *PasteExtra
 	if len(errors) != 0 {
 		return nil, errors[0]
 	}
+	if err := save_paste_event_entities(tx, created_paste_event.Id, text); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
 	if err := tx.Commit().Error; err != nil {
 		tx.Rollback()
 		return nil, err
@@ -274,9 +370,11 @@ func (s *PasteController) HandlePastePNG(image_bytes []byte, extra *PasteExtraIn
     }
 	details := "{}"
 	reader := bytes.NewReader(image_bytes)
-	info, err := png.DecodeConfig(reader)
+	// 缩略图、EXIF 等信息由 ProcessPasteImage 在后台生成
+	info, format, err := image.DecodeConfig(reader)
 	if err == nil {
 		t, err := json.Marshal(&map[string]interface{}{
+			"format":          format,
 			"width":           info.Width,
 			"height":          info.Height,
 			"size":            len(image_bytes),
@@ -294,6 +392,11 @@ func (s *PasteController) HandlePastePNG(image_bytes []byte, extra *PasteExtraIn
 		AppId:       get_app_id(s.db, extra.AppName),
 		DeviceId:    get_device_id(s.db, extra.MachineId),
 	}
+	rule_categories := s.rule_categories(transformer.CategoryRuleInput{
+		AppName:     extra.AppName,
+		WindowTitle: extra.WindowTitle,
+		ContentType: "image",
+	})
 	tx := s.db.Begin()
 	defer func() {
 		if r := recover(); r != nil {
@@ -306,7 +409,7 @@ func (s *PasteController) HandlePastePNG(image_bytes []byte, extra *PasteExtraIn
 		return nil, err
 	}
 	var errors []error
-	categories := []string{"image"}
+	categories := append_categories([]string{"image"}, rule_categories...)
 	for _, c := range categories {
 		created_paste_event.Categories = append(created_paste_event.Categories, models.CategoryNode{
 			BaseModel: models.BaseModel{
@@ -340,6 +443,15 @@ type FileInPasteEvent struct {
 	Name         string `json:"name"`
 	AbsolutePath string `json:"absolute_path"`
 	MimeType     string `json:"mime_type"`
+	// Size、ModTime 复制时文件的大小和修改时间（毫秒）
+	Size    int64 `json:"size,omitempty"`
+	ModTime int64 `json:"mtime,omitempty"`
+	// Hash 文件内容的 sha256，文件夹和超过 file_hash_max_size 的文件没有
+	Hash string `json:"hash,omitempty"`
+	// BlobHash 文件内容保存到 blob 目录后的哈希，原文件不存在时从这里恢复
+	BlobHash string `json:"blob_hash,omitempty"`
+	// Stale 返回记录时检查，见 FileStaleMissing、FileStaleModified，不保存到数据库
+	Stale string `json:"stale,omitempty"`
 }
 
 func (s *PasteController) HandlePasteFile(files []string, extra *PasteExtraInfo) (*models.PasteEvent, error) {
@@ -358,6 +470,7 @@ func (s *PasteController) HandlePasteFile(files []string, extra *PasteExtraInfo)
 				Name:         name,
 				AbsolutePath: f,
 				MimeType:     "folder",
+				ModTime:      info.ModTime().UnixMilli(),
 			})
 			continue
 		}
@@ -373,17 +486,14 @@ func (s *PasteController) HandlePasteFile(files []string, extra *PasteExtraInfo)
 			Name:         name,
 			AbsolutePath: f,
 			MimeType:     mime_type,
+			Size:         info.Size(),
+			ModTime:      info.ModTime().UnixMilli(),
 		})
 	}
 	if len(results) == 0 {
 		return nil, fmt.Errorf("No valid file")
 	}
-    content, err := json.Marshal(&results)
-    if err != nil {
-        return nil, err
-    }
-    var existing []models.PasteEvent
-    if err := s.db.Where("content_type = ? AND file_list_json = ?", "file", string(content)).Limit(1).Find(&existing).Error; err == nil && len(existing) > 0 {
+    if existing, err := s.find_same_file_list(results); err == nil && existing != nil {
         tx := s.db.Begin()
         defer func() {
             if r := recover(); r != nil {
@@ -391,7 +501,7 @@ func (s *PasteController) HandlePasteFile(files []string, extra *PasteExtraInfo)
                 return
             }
         }()
-        if err := tx.Save(&existing[0]).Error; err != nil {
+        if err := tx.Save(existing).Error; err != nil {
             tx.Rollback()
             return nil, err
         }
@@ -401,6 +511,14 @@ func (s *PasteController) HandlePasteFile(files []string, extra *PasteExtraInfo)
         }
         return nil, nil
     }
+	// 不是重复复制时才计算哈希和保存文件快照
+	for i := range results {
+		s.snapshot_file(&results[i], extra.FileSnapshot)
+	}
+	content, err := json.Marshal(&results)
+	if err != nil {
+		return nil, err
+	}
 	details, _ := json.Marshal(&map[string]interface{}{
 		"window_title": extra.WindowTitle,
 	})
@@ -411,6 +529,12 @@ func (s *PasteController) HandlePasteFile(files []string, extra *PasteExtraInfo)
 		AppId:        get_app_id(s.db, extra.AppName),
 		DeviceId:     get_device_id(s.db, extra.MachineId),
 	}
+	rule_categories := s.rule_categories(transformer.CategoryRuleInput{
+		Text:        file_list_rule_text(string(content)),
+		AppName:     extra.AppName,
+		WindowTitle: extra.WindowTitle,
+		ContentType: "file",
+	})
 	tx := s.db.Begin()
 	defer func() {
 		if r := recover(); r != nil {
@@ -423,7 +547,7 @@ func (s *PasteController) HandlePasteFile(files []string, extra *PasteExtraInfo)
 		return nil, err
 	}
 	var errors []error
-	categories := []string{"file"}
+	categories := append_categories([]string{"file"}, rule_categories...)
 	for _, c := range categories {
 		created_paste_event.Categories = append(created_paste_event.Categories, models.CategoryNode{
 			BaseModel: models.BaseModel{
@@ -0,0 +1,182 @@
+package controller
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"devboard/models"
+	"devboard/pkg/blob"
+)
+
+const (
+	// FileStaleMissing 文件已被移动或删除
+	FileStaleMissing = "missing"
+	// FileStaleModified 文件在复制后被修改过
+	FileStaleModified = "modified"
+)
+
+// FileSnapshotPolicy 复制文件时哪些文件的内容需要保存到 blob 目录
+type FileSnapshotPolicy struct {
+	// MaxSize 超过该大小的文件不保存，单位字节
+	MaxSize int64
+	// MimeTypes 支持 image/* 这种写法，为空时不限制类型
+	MimeTypes []string
+}
+
+func (p *FileSnapshotPolicy) Match(mime_type string, size int64) bool {
+	if p.MaxSize > 0 && size > p.MaxSize {
+		return false
+	}
+	if len(p.MimeTypes) == 0 {
+		return true
+	}
+	for _, t := range p.MimeTypes {
+		t = strings.ToLower(strings.TrimSpace(t))
+		if prefix, ok := strings.CutSuffix(t, "*"); ok {
+			if strings.HasPrefix(mime_type, prefix) {
+				return true
+			}
+			continue
+		}
+		if mime_type == t {
+			return true
+		}
+	}
+	return false
+}
+
+// SetBlobStore 设置保存文件快照的目录
+func (s *PasteController) SetBlobStore(store *blob.Store) *PasteController {
+	s.blob = store
+	return s
+}
+
+// 超过该大小的文件不计算哈希，避免复制大文件时长时间读取磁盘
+const file_hash_max_size = 256 * 1024 * 1024
+
+// snapshot_file 记录文件内容的哈希，符合 policy 时同时把文件内容复制到 blob 目录
+func (s *PasteController) snapshot_file(f *FileInPasteEvent, policy *FileSnapshotPolicy) {
+	if f.MimeType == "folder" {
+		return
+	}
+	if policy != nil && s.blob != nil && policy.Match(f.MimeType, f.Size) {
+		hash, _, err := s.blob.PutFile(f.AbsolutePath)
+		if err == nil {
+			_, err = save_blob(s.db, hash, "file", f.MimeType, f.Size)
+		}
+		if err == nil {
+			f.Hash = hash
+			f.BlobHash = hash
+			return
+		}
+		fmt.Println("[ERROR]save file snapshot failed", f.AbsolutePath, err.Error())
+	}
+	if f.Size > file_hash_max_size {
+		return
+	}
+	hash, err := blob.HashFile(f.AbsolutePath)
+	if err != nil {
+		fmt.Println("[ERROR]hash file failed", f.AbsolutePath, err.Error())
+		return
+	}
+	f.Hash = hash
+}
+
+// same_file_list 文件的路径、大小和修改时间都相同时认为是重复复制，不比较哈希
+func same_file_list(a []FileInPasteEvent, b []FileInPasteEvent) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i].AbsolutePath != b[i].AbsolutePath || a[i].MimeType != b[i].MimeType || a[i].Size != b[i].Size || a[i].ModTime != b[i].ModTime {
+			return false
+		}
+	}
+	return true
+}
+
+// find_same_file_list 查找复制过相同文件的记录，先用第一个文件的路径缩小范围
+func (s *PasteController) find_same_file_list(files []FileInPasteEvent) (*models.PasteEvent, error) {
+	path, err := json.Marshal(files[0].AbsolutePath)
+	if err != nil {
+		return nil, err
+	}
+	var candidates []models.PasteEvent
+	if err := s.db.Where("content_type = ? AND file_list_json LIKE ?", "file", "%"+string(path)+"%").
+		Order("updated_at DESC").
+		Limit(20).
+		Find(&candidates).Error; err != nil {
+		return nil, err
+	}
+	for i := range candidates {
+		var existing []FileInPasteEvent
+		if err := json.Unmarshal([]byte(candidates[i].FileListJSON), &existing); err != nil {
+			continue
+		}
+		if same_file_list(files, existing) {
+			return &candidates[i], nil
+		}
+	}
+	return nil, nil
+}
+
+// file_stale 检查复制后文件是否被移动、删除或修改，旧记录没有修改时间时只检查是否存在
+func file_stale(f *FileInPasteEvent) string {
+	info, err := os.Stat(f.AbsolutePath)
+	if err != nil {
+		return FileStaleMissing
+	}
+	if f.MimeType == "folder" || f.ModTime == 0 {
+		return ""
+	}
+	if info.Size() != f.Size || info.ModTime().UnixMilli() != f.ModTime {
+		return FileStaleModified
+	}
+	return ""
+}
+
+// mark_stale_files 给 file_list_json 中的每个文件加上 stale 标记，返回是否有文件失效
+func mark_stale_files(file_list_json string) (string, bool) {
+	var files []FileInPasteEvent
+	if err := json.Unmarshal([]byte(file_list_json), &files); err != nil {
+		return file_list_json, false
+	}
+	stale := false
+	for i := range files {
+		files[i].Stale = file_stale(&files[i])
+		if files[i].Stale != "" {
+			stale = true
+		}
+	}
+	if !stale {
+		return file_list_json, false
+	}
+	content, err := json.Marshal(&files)
+	if err != nil {
+		return file_list_json, stale
+	}
+	return string(content), stale
+}
+
+// restore_file 把快照复制到临时目录并返回路径，文件名与原文件相同
+func (s *PasteController) restore_file(f *FileInPasteEvent) (string, error) {
+	if f.BlobHash == "" || s.blob == nil || !s.blob.Has(f.BlobHash) {
+		return "", fmt.Errorf("There's no snapshot of %s", f.Name)
+	}
+	dst := filepath.Join(os.TempDir(), "devboard", "restored", f.BlobHash[:16], filepath.Base(f.Name))
+	if info, err := os.Stat(dst); err == nil && info.Size() == f.Size {
+		return dst, nil
+	}
+	if err := s.blob.CopyTo(f.BlobHash, dst); err != nil {
+		return "", err
+	}
+	if f.ModTime != 0 {
+		mtime := time.UnixMilli(f.ModTime)
+		os.Chtimes(dst, mtime, mtime)
+	}
+	return dst, nil
+}
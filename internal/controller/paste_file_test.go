@@ -0,0 +1,43 @@
+package controller_test
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"devboard/internal/controller"
+	"devboard/pkg/blob"
+)
+
+func TestHandlePasteFileHash(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "a.txt")
+	if err := os.WriteFile(src, []byte("file content"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	s := controller.NewPasteController(open_test_db(t), "local")
+	// 没有设置快照规则时也要记录哈希
+	created, err := s.HandlePasteFile([]string{src, dir}, &controller.PasteExtraInfo{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var files []controller.FileInPasteEvent
+	if err := json.Unmarshal([]byte(created.FileListJSON), &files); err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 2 {
+		t.Fatalf("got %d files, want 2", len(files))
+	}
+	want, _ := blob.HashFile(src)
+	if files[0].Hash != want || files[0].BlobHash != "" || files[0].Size != 12 || files[0].ModTime == 0 {
+		t.Errorf("unexpected file %+v", files[0])
+	}
+	if files[1].MimeType != "folder" || files[1].Hash != "" {
+		t.Errorf("unexpected folder %+v", files[1])
+	}
+	// 文件没有变化时不会重复创建记录
+	if again, err := s.HandlePasteFile([]string{src, dir}, &controller.PasteExtraInfo{}); err != nil || again != nil {
+		t.Errorf("expected the same file list to be ignored, got %v, %v", again, err)
+	}
+}
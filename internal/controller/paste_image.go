@@ -0,0 +1,263 @@
+package controller
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"gorm.io/gorm"
+
+	"devboard/models"
+	"devboard/pkg/imaging"
+)
+
+// 缩略图最长边的大小，列表使用 small，预览使用 medium、large
+const (
+	thumbnail_small_size  = 160
+	thumbnail_medium_size = 480
+	thumbnail_large_size  = 960
+)
+
+// 两张图片的 dHash 和 pHash 差异都不超过该值时认为是相似图片，例如同一个窗口的多次截图
+const similar_image_max_distance = 10
+
+type PasteImageThumbnails struct {
+	Small  string `json:"small"`
+	Medium string `json:"medium"`
+	Large  string `json:"large"`
+}
+
+type PasteImageResp struct {
+	Format     string               `json:"format"`
+	Width      int                  `json:"width"`
+	Height     int                  `json:"height"`
+	EXIF       *imaging.EXIF        `json:"exif,omitempty"`
+	Thumbnails PasteImageThumbnails `json:"thumbnails"`
+	GroupId    string               `json:"group_id,omitempty"`
+	// SimilarCount 同一组中其他图片的数量
+	SimilarCount int `json:"similar_count,omitempty"`
+}
+
+func to_paste_image_resp(record *models.PasteEventImage) *PasteImageResp {
+	resp := &PasteImageResp{
+		Format:  record.Format,
+		Width:   record.Width,
+		Height:  record.Height,
+		GroupId: record.GroupId,
+		Thumbnails: PasteImageThumbnails{
+			Small:  BlobURL(record.ThumbSmall),
+			Medium: BlobURL(record.ThumbMedium),
+			Large:  BlobURL(record.ThumbLarge),
+		},
+	}
+	if record.EXIF != "" {
+		var exif imaging.EXIF
+		if err := json.Unmarshal([]byte(record.EXIF), &exif); err == nil {
+			resp.EXIF = &exif
+		}
+	}
+	return resp
+}
+
+// fetch_paste_image_map 返回已生成缩略图的图片信息，key 为记录 id
+func (s *PasteController) fetch_paste_image_map(ids []string) (map[string]*PasteImageResp, error) {
+	result := make(map[string]*PasteImageResp)
+	if len(ids) == 0 {
+		return result, nil
+	}
+	var list []models.PasteEventImage
+	if err := s.db.Where("paste_event_id IN ? AND thumb_small <> ''", ids).Find(&list).Error; err != nil {
+		return nil, err
+	}
+	var group_ids []string
+	for i := range list {
+		result[list[i].PasteEventId] = to_paste_image_resp(&list[i])
+		group_ids = append(group_ids, list[i].GroupId)
+	}
+	if len(group_ids) == 0 {
+		return result, nil
+	}
+	var counts []struct {
+		GroupId string
+		Count   int
+	}
+	if err := s.db.Table("paste_event_image").
+		Select("paste_event_image.group_id, COUNT(*) AS count").
+		Joins("JOIN paste_event ON paste_event.id = paste_event_image.paste_event_id AND paste_event.deleted_at IS NULL").
+		Where("paste_event_image.group_id IN ?", group_ids).
+		Group("paste_event_image.group_id").
+		Scan(&counts).Error; err != nil {
+		return nil, err
+	}
+	count_map := make(map[string]int)
+	for _, c := range counts {
+		count_map[c.GroupId] = c.Count
+	}
+	for _, v := range result {
+		if n := count_map[v.GroupId]; n > 1 {
+			v.SimilarCount = n - 1
+		}
+	}
+	return result, nil
+}
+
+// find_similar_image_group 在已有图片中查找最相似的一张，返回它所在的分组
+func (s *PasteController) find_similar_image_group(event_id string, dhash uint64, phash uint64) (string, error) {
+	var list []models.PasteEventImage
+	if err := s.db.
+		Select("paste_event_image.paste_event_id, paste_event_image.dhash, paste_event_image.phash, paste_event_image.group_id").
+		Joins("JOIN paste_event ON paste_event.id = paste_event_image.paste_event_id AND paste_event.deleted_at IS NULL").
+		Where("paste_event_image.paste_event_id <> ? AND paste_event_image.dhash <> ''", event_id).
+		Find(&list).Error; err != nil {
+		return "", err
+	}
+	group_id := ""
+	best := -1
+	for _, v := range list {
+		d, ok1 := imaging.ParseHash(v.DHash)
+		p, ok2 := imaging.ParseHash(v.PHash)
+		if !ok1 || !ok2 {
+			continue
+		}
+		dd, pd := imaging.Distance(d, dhash), imaging.Distance(p, phash)
+		if dd > similar_image_max_distance || pd > similar_image_max_distance {
+			continue
+		}
+		if best == -1 || dd+pd < best {
+			best = dd + pd
+			group_id = v.GroupId
+			if group_id == "" {
+				group_id = v.PasteEventId
+			}
+		}
+	}
+	return group_id, nil
+}
+
+// ProcessPasteImage 读取图片的格式信息，生成缩略图和感知哈希，并找出相似的图片
+func (s *PasteController) ProcessPasteImage(event_id string) (*models.PasteEventImage, error) {
+	if s.blob == nil {
+		return nil, fmt.Errorf("The blob store is not initialized")
+	}
+	var event models.PasteEvent
+	if err := s.db.Where("id = ? AND content_type = ?", event_id, "image").First(&event).Error; err != nil {
+		return nil, err
+	}
+	var record models.PasteEventImage
+	if err := s.db.Where("paste_event_id = ?", event_id).First(&record).Error; err != nil {
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, err
+		}
+		record = models.PasteEventImage{PasteEventId: event_id}
+	}
+	fail := func(err error) (*models.PasteEventImage, error) {
+		record.LastError = err.Error()
+		s.db.Save(&record)
+		return &record, err
+	}
+	data, err := base64.StdEncoding.DecodeString(event.ImageBase64)
+	if err != nil {
+		return fail(err)
+	}
+	img, info, err := imaging.Decode(data)
+	if err != nil {
+		return fail(err)
+	}
+	record.Format = info.Format
+	record.Width = info.Width
+	record.Height = info.Height
+	record.ColorModel = info.ColorModel
+	record.EXIF = ""
+	if info.EXIF != nil {
+		exif, _ := json.Marshal(info.EXIF)
+		record.EXIF = string(exif)
+	}
+	thumbnails := []struct {
+		size   int
+		target *string
+	}{
+		{thumbnail_small_size, &record.ThumbSmall},
+		{thumbnail_medium_size, &record.ThumbMedium},
+		{thumbnail_large_size, &record.ThumbLarge},
+	}
+	for _, t := range thumbnails {
+		buf, mime_type, err := imaging.Encode(imaging.Thumbnail(img, t.size))
+		if err != nil {
+			return fail(err)
+		}
+		hash, err := s.blob.Put(buf)
+		if err != nil {
+			return fail(err)
+		}
+		if _, err := save_blob(s.db, hash, "thumbnail", mime_type, int64(len(buf))); err != nil {
+			return fail(err)
+		}
+		*t.target = hash
+	}
+	dhash, phash := imaging.DHash(img), imaging.PHash(img)
+	record.DHash = imaging.FormatHash(dhash)
+	record.PHash = imaging.FormatHash(phash)
+	group_id, err := s.find_similar_image_group(event_id, dhash, phash)
+	if err != nil {
+		return fail(err)
+	}
+	if group_id == "" {
+		group_id = event_id
+	}
+	record.GroupId = group_id
+	record.LastError = ""
+	if err := s.db.Save(&record).Error; err != nil {
+		return nil, err
+	}
+	return &record, nil
+}
+
+// FetchUnprocessedImageIdList 还没有生成缩略图的图片记录，不包括处理失败的，exclude 中的记录也跳过
+func (s *PasteController) FetchUnprocessedImageIdList(limit int, exclude []string) ([]string, error) {
+	var ids []string
+	query := s.db.Model(&models.PasteEvent{}).
+		Where("content_type = ?", "image").
+		Where("NOT EXISTS (SELECT 1 FROM paste_event_image WHERE paste_event_image.paste_event_id = paste_event.id)")
+	if len(exclude) != 0 {
+		query = query.Where("id NOT IN ?", exclude)
+	}
+	err := query.Order("created_at DESC").
+		Limit(limit).
+		Pluck("id", &ids).Error
+	if err != nil {
+		return nil, err
+	}
+	return ids, nil
+}
+
+// FetchSimilarPasteEventList 与指定图片在同一组的其他图片
+func (s *PasteController) FetchSimilarPasteEventList(body PasteEventBody) ([]PasteListItemResp, error) {
+	if body.PasteEventId == "" {
+		return nil, fmt.Errorf("缺少 id 参数")
+	}
+	var record models.PasteEventImage
+	if err := s.db.Where("paste_event_id = ?", body.PasteEventId).First(&record).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return []PasteListItemResp{}, nil
+		}
+		return nil, err
+	}
+	var list []paste_event_summary
+	if err := s.paste_summary_query().
+		Where("paste_event.id IN (SELECT paste_event_id FROM paste_event_image WHERE group_id = ?) AND paste_event.id <> ?", record.GroupId, body.PasteEventId).
+		Order("paste_event.created_at DESC").
+		Find(&list).Error; err != nil {
+		return nil, err
+	}
+	return s.to_paste_list_items(list)
+}
+
+// FetchPasteImage 还没有生成缩略图时返回 nil
+func (s *PasteController) FetchPasteImage(event_id string) (*PasteImageResp, error) {
+	images, err := s.fetch_paste_image_map([]string{event_id})
+	if err != nil {
+		return nil, err
+	}
+	return images[event_id], nil
+}
@@ -0,0 +1,50 @@
+package controller
+
+import (
+	"gorm.io/gorm"
+
+	"devboard/models"
+)
+
+type PasteSearchBody struct {
+	Keywords []string
+	StartAt  int64 // 毫秒时间戳，0 表示不限制
+	EndAt    int64
+	Limit    int
+}
+
+// SearchPasteEvents 按关键词和时间范围查找文本类记录，任意关键词命中文本、标题或备注即返回，标记为机密的记录不会返回
+func (s *PasteController) SearchPasteEvents(body PasteSearchBody) ([]models.PasteEvent, error) {
+	query := s.db.Model(&models.PasteEvent{}).
+		Where("paste_event.content_type IN ?", []string{"text", "html"}).
+		Where("COALESCE(paste_event.details, '') NOT LIKE ?", `%"is_secret":true%`)
+	if len(body.Keywords) != 0 {
+		var cond *gorm.DB
+		for _, k := range body.Keywords {
+			like := "%" + k + "%"
+			if cond == nil {
+				cond = s.db.Where("paste_event.text LIKE ? OR paste_event.title LIKE ? OR "+remark_keyword_condition, like, like, like)
+				continue
+			}
+			cond = cond.Or("paste_event.text LIKE ? OR paste_event.title LIKE ? OR "+remark_keyword_condition, like, like, like)
+		}
+		query = query.Where(cond)
+	}
+	if body.StartAt > 0 {
+		query = query.Where("CAST(paste_event.created_at AS INTEGER) >= ?", body.StartAt)
+	}
+	if body.EndAt > 0 {
+		query = query.Where("CAST(paste_event.created_at AS INTEGER) < ?", body.EndAt)
+	}
+	limit := body.Limit
+	if limit <= 0 {
+		limit = 200
+	}
+	var list []models.PasteEvent
+	if err := query.Preload("Remarks", func(db *gorm.DB) *gorm.DB {
+		return db.Order("remark.created_at ASC")
+	}).Order("paste_event.updated_at DESC").Limit(limit).Find(&list).Error; err != nil {
+		return nil, err
+	}
+	return list, nil
+}
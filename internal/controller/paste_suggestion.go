@@ -0,0 +1,182 @@
+package controller
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"gorm.io/gorm"
+
+	"devboard/models"
+)
+
+// IsSecretPasteEvent 粘贴时检测到包含密钥等信息的记录会在 details 中标记 is_secret
+func IsSecretPasteEvent(record *models.PasteEvent) bool {
+	if record.Details == "" {
+		return false
+	}
+	var details map[string]interface{}
+	if err := json.Unmarshal([]byte(record.Details), &details); err != nil {
+		return false
+	}
+	v, ok := details["is_secret"].(bool)
+	return ok && v
+}
+
+type CategoryLabel struct {
+	Id    string `json:"id"`
+	Label string `json:"label"`
+}
+
+// FetchCategoryLabelList 所有可用分类的 id 和名称
+func (s *PasteController) FetchCategoryLabelList() ([]CategoryLabel, error) {
+	var list []CategoryLabel
+	if err := s.db.Model(&models.CategoryNode{}).Where("is_active = ?", true).Select("id", "label").Order("sort_order ASC").Find(&list).Error; err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+type PasteEventSuggestionBody struct {
+	PasteEventId string   `json:"paste_event_id"`
+	Title        string   `json:"title"`
+	CategoryIds  []string `json:"category_ids"`
+	Source       string   `json:"source"`
+}
+
+// SavePasteEventSuggestion 保存自动生成的标题和推荐分类，已经关联或之前被拒绝的分类不会重复推荐
+func (s *PasteController) SavePasteEventSuggestion(body PasteEventSuggestionBody) ([]models.PasteEventCategorySuggestion, error) {
+	if body.PasteEventId == "" {
+		return nil, fmt.Errorf("Missing the paste_event_id")
+	}
+	if body.Source == "" {
+		body.Source = "llm"
+	}
+	var record models.PasteEvent
+	if err := s.db.Where("id = ?", body.PasteEventId).First(&record).Error; err != nil {
+		return nil, err
+	}
+	categories, err := s.fetch_paste_categories_map([]string{record.Id})
+	if err != nil {
+		return nil, err
+	}
+	skipped := make(map[string]bool)
+	for _, c := range categories[record.Id] {
+		skipped[c.Id] = true
+	}
+	var existing []models.PasteEventCategorySuggestion
+	if err := s.db.Unscoped().Where("paste_event_id = ?", body.PasteEventId).Find(&existing).Error; err != nil {
+		return nil, err
+	}
+	for _, v := range existing {
+		skipped[v.CategoryId] = true
+	}
+	tx := s.db.Begin()
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+		}
+	}()
+	// 用户已经填写过标题时不覆盖
+	if body.Title != "" && record.Title == "" {
+		if err := tx.Model(&record).UpdateColumns(sync_columns(map[string]interface{}{
+			"title": body.Title,
+		}, 2)).Error; err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+	}
+	var created_list []models.PasteEventCategorySuggestion
+	for _, id := range body.CategoryIds {
+		if skipped[id] {
+			continue
+		}
+		skipped[id] = true
+		created := models.PasteEventCategorySuggestion{
+			PasteEventId: body.PasteEventId,
+			CategoryId:   id,
+			Source:       body.Source,
+			Status:       models.SuggestionStatusPending,
+		}
+		if err := tx.Create(&created).Error; err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+		created_list = append(created_list, created)
+	}
+	if err := tx.Commit().Error; err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	return created_list, nil
+}
+
+func (s *PasteController) FetchCategorySuggestionList(body PasteEventBody) ([]models.PasteEventCategorySuggestion, error) {
+	if body.PasteEventId == "" {
+		return nil, fmt.Errorf("Missing the paste_event_id")
+	}
+	list := make([]models.PasteEventCategorySuggestion, 0)
+	if err := s.db.Where("paste_event_id = ? AND status = ?", body.PasteEventId, models.SuggestionStatusPending).Preload("Category").Order("created_at ASC").Find(&list).Error; err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+type CategorySuggestionBody struct {
+	Id string `json:"id"`
+}
+
+func (s *PasteController) find_pending_suggestion(tx *gorm.DB, id string) (*models.PasteEventCategorySuggestion, error) {
+	if id == "" {
+		return nil, fmt.Errorf("Missing the id")
+	}
+	var existing models.PasteEventCategorySuggestion
+	if err := tx.Where("id = ?", id).First(&existing).Error; err != nil {
+		return nil, err
+	}
+	if existing.Status != models.SuggestionStatusPending {
+		return nil, fmt.Errorf("The suggestion has been handled")
+	}
+	return &existing, nil
+}
+
+// AcceptCategorySuggestion 接受推荐，为记录添加对应分类
+func (s *PasteController) AcceptCategorySuggestion(body CategorySuggestionBody) (*models.PasteEventCategorySuggestion, error) {
+	tx := s.db.Begin()
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+		}
+	}()
+	existing, err := s.find_pending_suggestion(tx, body.Id)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	if _, err := ensure_category_mapping(tx, existing.PasteEventId, existing.CategoryId); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	existing.Status = models.SuggestionStatusAccepted
+	if err := tx.Save(existing).Error; err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	if err := tx.Commit().Error; err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	return existing, nil
+}
+
+// RejectCategorySuggestion 拒绝推荐，之后不会再推荐该分类
+func (s *PasteController) RejectCategorySuggestion(body CategorySuggestionBody) (*models.PasteEventCategorySuggestion, error) {
+	existing, err := s.find_pending_suggestion(s.db, body.Id)
+	if err != nil {
+		return nil, err
+	}
+	existing.Status = models.SuggestionStatusRejected
+	if err := s.db.Save(existing).Error; err != nil {
+		return nil, err
+	}
+	return existing, nil
+}
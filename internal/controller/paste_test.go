@@ -0,0 +1,25 @@
+package controller_test
+
+import (
+	"testing"
+
+	"devboard/internal/controller"
+	"devboard/models"
+)
+
+func TestFetchPasteEventListHTMLPreview(t *testing.T) {
+	s := controller.NewPasteController(open_test_db(t), "local")
+	if _, err := s.HandlePasteHTML(`<p>a &amp; b &lt;c&gt;</p>`, &controller.PasteExtraInfo{}); err != nil {
+		t.Fatal(err)
+	}
+	r, err := s.FetchPasteEventList(controller.PasteListBody{Pagination: models.Pagination{PageSize: 10}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(r.List) != 1 {
+		t.Fatalf("got %d records, want 1", len(r.List))
+	}
+	if got := r.List[0]; got.Text != "a & b <c>" || got.TextLength != 9 {
+		t.Errorf("unexpected preview %q, length %d", got.Text, got.TextLength)
+	}
+}
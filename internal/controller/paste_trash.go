@@ -0,0 +1,210 @@
+package controller
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"devboard/models"
+)
+
+// FetchDeletedPasteEventList 回收站中的记录，updated_at 为删除的时间
+func (s *PasteController) FetchDeletedPasteEventList(body PasteListBody) (*ListResp[PasteListItemResp], error) {
+	query := apply_paste_list_filter(s.paste_summary_query().Unscoped().Where("paste_event.deleted_at IS NOT NULL"), body)
+	pb := models.NewPaginationBuilder[paste_event_summary](query).
+		SetLimit(body.PageSize).
+		SetPage(body.Page).
+		SetNextMarker(body.NextMarker).
+		SetOrderBy("paste_event.updated_at DESC")
+	var list1 []paste_event_summary
+	if err := pb.Build().Find(&list1).Error; err != nil {
+		return nil, err
+	}
+	list2, has_more, next_marker := pb.ProcessResults(list1)
+	list, err := s.to_paste_list_items(list2)
+	if err != nil {
+		return nil, err
+	}
+	return &ListResp[PasteListItemResp]{
+		List:       list,
+		Page:       body.Page,
+		PageSize:   pb.GetLimit(),
+		HasMore:    has_more,
+		NextMarker: next_marker,
+	}, nil
+}
+
+// RestorePasteEvent 清空 DeletedAt 后保存，BeforeUpdate 会把 last_operation_type 改为编辑，其他设备同步后也会恢复
+func (s *PasteController) RestorePasteEvent(body PasteEventBody) (*models.PasteEvent, error) {
+	if body.PasteEventId == "" {
+		return nil, fmt.Errorf("缺少 id 参数")
+	}
+	var existing models.PasteEvent
+	if err := s.db.Unscoped().Where("id = ? AND deleted_at IS NOT NULL", body.PasteEventId).First(&existing).Error; err != nil {
+		return nil, err
+	}
+	existing.DeletedAt = gorm.DeletedAt{}
+	if err := s.db.Unscoped().Save(&existing).Error; err != nil {
+		return nil, err
+	}
+	return &existing, nil
+}
+
+type PastePurgeBody struct {
+	Ids []string `json:"ids"`
+	// SyncedOnly 开启同步时只删除软删除已经推送到远端的记录，否则其他设备不知道记录被删除了
+	SyncedOnly bool `json:"-"`
+}
+
+// purgeable_query 回收站中可以永久删除的数据
+func purgeable_query(db *gorm.DB, synced_only bool) *gorm.DB {
+	query := db.Unscoped().Where("deleted_at IS NOT NULL")
+	if synced_only {
+		query = query.Where("sync_status = ?", 2)
+	}
+	return query
+}
+
+// PurgeResult Total 为永久删除的数量
+type PurgeResult struct {
+	Total int `json:"total"`
+	// PendingSync 开启同步时删除还没有推送到远端的数量，这些数据这次没有永久删除，同步后再删除
+	PendingSync int `json:"pending_sync"`
+}
+
+// count_pending_sync synced_only 时统计因为删除还没有同步而跳过的数据
+func count_pending_sync(db *gorm.DB, model interface{}, ids []string, synced_only bool) (int, error) {
+	if !synced_only {
+		return 0, nil
+	}
+	var n int64
+	if err := db.Unscoped().Model(model).
+		Where("id IN ? AND deleted_at IS NOT NULL AND COALESCE(sync_status, 0) != ?", ids, 2).
+		Count(&n).Error; err != nil {
+		return 0, err
+	}
+	return int(n), nil
+}
+
+// add_purged_records 记录永久删除的同步数据，从远端拉取时跳过，见 ApplyRemoteRecordTasks
+func add_purged_records(tx *gorm.DB, table string, ids []string) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	now := strconv.FormatInt(time.Now().UnixMilli(), 10)
+	records := make([]models.PurgedRecord, 0, len(ids))
+	for _, id := range ids {
+		records = append(records, models.PurgedRecord{Id: id, Table: table, PurgedAt: now})
+	}
+	return tx.Clauses(clause.OnConflict{DoNothing: true}).CreateInBatches(&records, 100).Error
+}
+
+// PurgePasteEvents 永久删除回收站中的记录，以及关联的分类、实体、图片信息和备注，不再被引用的 blob 文件也会删除。
+// 只处理已经软删除的记录
+func (s *PasteController) PurgePasteEvents(body PastePurgeBody) (*PurgeResult, error) {
+	result := &PurgeResult{}
+	if len(body.Ids) == 0 {
+		return result, nil
+	}
+	pending, err := count_pending_sync(s.db, &models.PasteEvent{}, body.Ids, body.SyncedOnly)
+	if err != nil {
+		return nil, err
+	}
+	result.PendingSync = pending
+	var records []models.PasteEvent
+	if err := purgeable_query(s.db, body.SyncedOnly).Select("id", "content_type", "file_list_json").
+		Where("id IN ?", body.Ids).
+		Find(&records).Error; err != nil {
+		return nil, err
+	}
+	if len(records) == 0 {
+		return result, nil
+	}
+	var ids []string
+	var hashes []string
+	for _, v := range records {
+		ids = append(ids, v.Id)
+		if v.ContentType == "file" {
+			var files []FileInPasteEvent
+			json.Unmarshal([]byte(v.FileListJSON), &files)
+			for _, f := range files {
+				if f.BlobHash != "" {
+					hashes = append(hashes, f.BlobHash)
+				}
+			}
+		}
+	}
+	var images []models.PasteEventImage
+	if err := s.db.Where("paste_event_id IN ?", ids).Find(&images).Error; err != nil {
+		return nil, err
+	}
+	for _, v := range images {
+		for _, h := range []string{v.ThumbSmall, v.ThumbMedium, v.ThumbLarge} {
+			if h != "" {
+				hashes = append(hashes, h)
+			}
+		}
+	}
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		var remark_ids []string
+		if err := tx.Unscoped().Model(&models.Remark{}).Where("paste_event_id IN ?", ids).Pluck("id", &remark_ids).Error; err != nil {
+			return err
+		}
+		remark_hashes, err := purge_remark_related(tx, remark_ids)
+		if err != nil {
+			return err
+		}
+		hashes = append(hashes, remark_hashes...)
+		var mapping_ids []string
+		if err := tx.Unscoped().Model(&models.PasteEventCategoryMapping{}).Where("paste_event_id IN ?", ids).Pluck("id", &mapping_ids).Error; err != nil {
+			return err
+		}
+		purged := map[string][]string{
+			"paste_event":                  ids,
+			"paste_event_category_mapping": mapping_ids,
+			"remark":                       remark_ids,
+		}
+		for table, v := range purged {
+			if err := add_purged_records(tx, table, v); err != nil {
+				return err
+			}
+		}
+		related := []interface{}{
+			&models.PasteEventCategoryMapping{},
+			&models.PasteEventCategorySuggestion{},
+			&models.PasteEventEntity{},
+			&models.PasteEventImage{},
+			&models.Remark{},
+		}
+		for _, m := range related {
+			if err := tx.Unscoped().Where("paste_event_id IN ?", ids).Delete(m).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Unscoped().Where("id IN ?", ids).Delete(&models.PasteEvent{}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := delete_unused_blobs(s.db, s.blob, hashes); err != nil {
+		fmt.Println("[ERROR]delete unused blobs failed", err.Error())
+	}
+	result.Total = len(ids)
+	return result, nil
+}
+
+// FetchExpiredPasteEventIdList 删除时间早于 before 的记录 id，synced_only 见 PastePurgeBody.SyncedOnly
+func (s *PasteController) FetchExpiredPasteEventIdList(before time.Time, limit int, synced_only bool) ([]string, error) {
+	var ids []string
+	if err := purgeable_query(s.db, synced_only).Model(&models.PasteEvent{}).
+		Where("deleted_at < ?", before).
+		Limit(limit).
+		Pluck("id", &ids).Error; err != nil {
+		return nil, err
+	}
+	return ids, nil
+}
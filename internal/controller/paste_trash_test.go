@@ -0,0 +1,132 @@
+package controller_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"gorm.io/gorm"
+
+	"devboard/internal/controller"
+	"devboard/models"
+	"devboard/pkg/blob"
+)
+
+func delete_test_paste_event(t *testing.T, s *controller.PasteController, id string) {
+	t.Helper()
+	if _, err := s.DeletePasteEvent(controller.PasteEventBody{PasteEventId: id}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func count_rows(t *testing.T, db *gorm.DB, model interface{}, query string, args ...interface{}) int64 {
+	t.Helper()
+	var n int64
+	if err := db.Unscoped().Model(model).Where(query, args...).Count(&n).Error; err != nil {
+		t.Fatal(err)
+	}
+	return n
+}
+
+func TestRestorePasteEvent(t *testing.T) {
+	db := open_test_db(t)
+	s := controller.NewPasteController(db, "local")
+	ids := paste_texts(t, s, "restore me")
+	delete_test_paste_event(t, s, ids[0])
+	if _, err := s.RestorePasteEvent(controller.PasteEventBody{PasteEventId: ids[0]}); err != nil {
+		t.Fatal(err)
+	}
+	var record models.PasteEvent
+	if err := db.Where("id = ?", ids[0]).First(&record).Error; err != nil {
+		t.Fatal(err)
+	}
+	if record.LastOperationType != 2 || record.SyncStatus != 1 {
+		t.Errorf("got last_operation_type %d, sync_status %d", record.LastOperationType, record.SyncStatus)
+	}
+	// 不在回收站中的记录不能恢复
+	if _, err := s.RestorePasteEvent(controller.PasteEventBody{PasteEventId: ids[0]}); err == nil {
+		t.Errorf("restoring a record that isn't deleted should fail")
+	}
+}
+
+func TestPurgePasteEvents(t *testing.T) {
+	db := open_test_db(t)
+	store, err := blob.NewStore(filepath.Join(t.TempDir(), "blobs"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := controller.NewPasteController(db, "local").SetBlobStore(store)
+	src := filepath.Join(t.TempDir(), "a.txt")
+	if err := os.WriteFile(src, []byte("snapshot content"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	created, err := s.HandlePasteFile([]string{src}, &controller.PasteExtraInfo{FileSnapshot: &controller.FileSnapshotPolicy{}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	hash, _ := blob.HashFile(src)
+	if !store.Has(hash) {
+		t.Fatal("the file should be snapshotted")
+	}
+	category := create_test_category(t, controller.NewCategoryController(db), "work", "")
+	if err := db.Create(&models.PasteEventCategoryMapping{PasteEventId: created.Id, CategoryId: category}).Error; err != nil {
+		t.Fatal(err)
+	}
+	if _, err := controller.NewRemarkController(db).CreateRemark(controller.RemarkCreateBody{PasteEventId: created.Id, Content: "note"}); err != nil {
+		t.Fatal(err)
+	}
+	mappings := count_rows(t, db, &models.PasteEventCategoryMapping{}, "paste_event_id = ?", created.Id)
+	// 不在回收站中的记录不会被删除
+	if r, err := s.PurgePasteEvents(controller.PastePurgeBody{Ids: []string{created.Id}}); err != nil || r.Total != 0 {
+		t.Fatalf("got %+v, %v", r, err)
+	}
+	delete_test_paste_event(t, s, created.Id)
+	r, err := s.PurgePasteEvents(controller.PastePurgeBody{Ids: []string{created.Id}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Total != 1 || r.PendingSync != 0 {
+		t.Errorf("got %+v, want 1 purged", r)
+	}
+	if n := count_rows(t, db, &models.PasteEvent{}, "id = ?", created.Id); n != 0 {
+		t.Errorf("the record should be purged")
+	}
+	for _, m := range []interface{}{&models.PasteEventCategoryMapping{}, &models.Remark{}} {
+		if n := count_rows(t, db, m, "paste_event_id = ?", created.Id); n != 0 {
+			t.Errorf("got %d rows of %T, want 0", n, m)
+		}
+	}
+	if store.Has(hash) {
+		t.Errorf("the unreferenced snapshot should be deleted")
+	}
+	for table, want := range map[string]int64{"paste_event": 1, "paste_event_category_mapping": mappings, "remark": 1} {
+		if n := count_rows(t, db, &models.PurgedRecord{}, "table_name = ?", table); n != want {
+			t.Errorf("got %d purged records of %s, want %d", n, table, want)
+		}
+	}
+}
+
+func TestPurgePasteEventsSyncedOnly(t *testing.T) {
+	db := open_test_db(t)
+	s := controller.NewPasteController(db, "local")
+	ids := paste_texts(t, s, "not synced")
+	delete_test_paste_event(t, s, ids[0])
+	// 删除还没有推送到远端时跳过，并告诉调用方原因
+	r, err := s.PurgePasteEvents(controller.PastePurgeBody{Ids: ids, SyncedOnly: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Total != 0 || r.PendingSync != 1 {
+		t.Errorf("got %+v, want 1 pending", r)
+	}
+	if err := db.Unscoped().Model(&models.PasteEvent{}).Where("id = ?", ids[0]).UpdateColumn("sync_status", 2).Error; err != nil {
+		t.Fatal(err)
+	}
+	r, err = s.PurgePasteEvents(controller.PastePurgeBody{Ids: ids, SyncedOnly: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Total != 1 || r.PendingSync != 0 {
+		t.Errorf("got %+v, want 1 purged", r)
+	}
+}
@@ -0,0 +1,136 @@
+package controller
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+
+	"devboard/internal/transformer"
+	"devboard/models"
+)
+
+type RecipeController struct {
+	db *gorm.DB
+}
+
+func NewRecipeController(db *gorm.DB) *RecipeController {
+	return &RecipeController{
+		db: db,
+	}
+}
+
+type RecipeResp struct {
+	Id          string                   `json:"id"`
+	Name        string                   `json:"name"`
+	Description string                   `json:"description"`
+	Steps       []transformer.RecipeStep `json:"steps"`
+	CreatedAt   string                   `json:"created_at"`
+}
+
+func to_recipe_resp(record models.TransformRecipe) RecipeResp {
+	steps := []transformer.RecipeStep{}
+	json.Unmarshal([]byte(record.StepsJSON), &steps)
+	return RecipeResp{
+		Id:          record.Id,
+		Name:        record.Name,
+		Description: record.Description,
+		Steps:       steps,
+		CreatedAt:   record.CreatedAt,
+	}
+}
+
+type RecipeSaveBody struct {
+	Id          string                   `json:"id"` // 为空表示新增
+	Name        string                   `json:"name"`
+	Description string                   `json:"description"`
+	Steps       []transformer.RecipeStep `json:"steps"`
+}
+
+func (s *RecipeController) SaveRecipe(body RecipeSaveBody) (*RecipeResp, error) {
+	if body.Name == "" {
+		return nil, fmt.Errorf("Missing the name")
+	}
+	if err := transformer.ValidateRecipe(body.Steps); err != nil {
+		return nil, err
+	}
+	steps, err := json.Marshal(&body.Steps)
+	if err != nil {
+		return nil, err
+	}
+	var existing []models.TransformRecipe
+	if err := s.db.Where("name = ? AND id != ?", body.Name, body.Id).Limit(1).Find(&existing).Error; err != nil {
+		return nil, err
+	}
+	if len(existing) != 0 {
+		return nil, fmt.Errorf("The recipe %s is existing", body.Name)
+	}
+	if body.Id == "" {
+		created := models.TransformRecipe{
+			Name:        body.Name,
+			Description: body.Description,
+			StepsJSON:   string(steps),
+		}
+		if err := s.db.Create(&created).Error; err != nil {
+			return nil, err
+		}
+		r := to_recipe_resp(created)
+		return &r, nil
+	}
+	var record models.TransformRecipe
+	if err := s.db.Where("id = ?", body.Id).First(&record).Error; err != nil {
+		return nil, err
+	}
+	record.Name = body.Name
+	record.Description = body.Description
+	record.StepsJSON = string(steps)
+	if err := s.db.Save(&record).Error; err != nil {
+		return nil, err
+	}
+	r := to_recipe_resp(record)
+	return &r, nil
+}
+
+type RecipeBody struct {
+	Id string `json:"id"`
+}
+
+func (s *RecipeController) FetchRecipe(body RecipeBody) (*RecipeResp, error) {
+	if body.Id == "" {
+		return nil, fmt.Errorf("Missing the id")
+	}
+	var record models.TransformRecipe
+	if err := s.db.Where("id = ?", body.Id).First(&record).Error; err != nil {
+		return nil, err
+	}
+	r := to_recipe_resp(record)
+	return &r, nil
+}
+
+func (s *RecipeController) FetchRecipeList() ([]RecipeResp, error) {
+	var records []models.TransformRecipe
+	if err := s.db.Order("created_at DESC").Find(&records).Error; err != nil {
+		return nil, err
+	}
+	list := make([]RecipeResp, 0, len(records))
+	for _, r := range records {
+		list = append(list, to_recipe_resp(r))
+	}
+	return list, nil
+}
+
+func (s *RecipeController) DeleteRecipe(body RecipeBody) error {
+	if body.Id == "" {
+		return fmt.Errorf("Missing the id")
+	}
+	var existing models.TransformRecipe
+	if err := s.db.Where("id = ?", body.Id).First(&existing).Error; err != nil {
+		return err
+	}
+	existing.DeletedAt = gorm.DeletedAt{Time: time.Now(), Valid: true}
+	if err := s.db.Save(&existing).Error; err != nil {
+		return err
+	}
+	return nil
+}
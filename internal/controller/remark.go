@@ -2,15 +2,29 @@ package controller
 
 import (
 	"devboard/models"
+	"devboard/pkg/blob"
+	_html "devboard/pkg/html"
+	"encoding/base64"
 	"fmt"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
 	"time"
 
 	"gorm.io/gorm"
 )
 
+// 单个附件的最大大小
+const remark_attachment_max_size = 50 * 1024 * 1024
+
 type PasteEventRemarkController struct {
 	db         *gorm.DB
 	machine_id string
+	// blob 保存附件，为空时无法添加附件
+	blob *blob.Store
 }
 
 func NewRemarkController(db *gorm.DB) *PasteEventRemarkController {
@@ -19,12 +33,37 @@ func NewRemarkController(db *gorm.DB) *PasteEventRemarkController {
 	}
 }
 
+// SetBlobStore 设置保存附件的目录
+func (s *PasteEventRemarkController) SetBlobStore(store *blob.Store) *PasteEventRemarkController {
+	s.blob = store
+	return s
+}
+
+// render_remarks 生成 Markdown 对应的 html 和附件的地址
+func render_remarks(remarks []models.Remark) {
+	for i := range remarks {
+		remarks[i].HTML = _html.RenderMarkdown(remarks[i].Content)
+		for j := range remarks[i].Attachments {
+			remarks[i].Attachments[j].URL = BlobURL(remarks[i].Attachments[j].BlobHash)
+		}
+	}
+}
+
+func preload_remark_attachments(db *gorm.DB) *gorm.DB {
+	return db.Preload("Attachments", func(db *gorm.DB) *gorm.DB {
+		return db.Order("remark_attachment.created_at ASC")
+	})
+}
+
 type RemarkCreateBody struct {
 	Content      string `json:"content"`
 	PasteEventId string `json:"paste_event_id"`
 }
 
 func (s *PasteEventRemarkController) CreateRemark(body RemarkCreateBody) (*models.Remark, error) {
+	if body.PasteEventId == "" {
+		return nil, fmt.Errorf("Missing the paste_event_id")
+	}
 	tx := s.db.Begin()
 	defer func() {
 		if r := recover(); r != nil {
@@ -38,6 +77,7 @@ func (s *PasteEventRemarkController) CreateRemark(body RemarkCreateBody) (*model
 		PasteEventId: body.PasteEventId,
 	}
 	if err := tx.Create(&created).Error; err != nil {
+		tx.Rollback()
 		return nil, err
 	}
 	// if err := tx.Model(&models.PasteEvent{}).Where("id = ?", body.PasteEventId).Update("sync_status", 1).Error; err != nil {
@@ -47,9 +87,182 @@ func (s *PasteEventRemarkController) CreateRemark(body RemarkCreateBody) (*model
 		tx.Rollback()
 		return nil, err
 	}
+	created.Attachments = []models.RemarkAttachment{}
+	created.HTML = _html.RenderMarkdown(created.Content)
+	return &created, nil
+}
+
+type RemarkUpdateBody struct {
+	Id      string `json:"id"`
+	Content string `json:"content"`
+}
+
+// UpdateRemark 修改内容前把原来的内容保存为一个版本
+func (s *PasteEventRemarkController) UpdateRemark(body RemarkUpdateBody) (*models.Remark, error) {
+	if body.Id == "" {
+		return nil, fmt.Errorf("Missing the id")
+	}
+	var existing models.Remark
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := preload_remark_attachments(tx).Where("id = ?", body.Id).First(&existing).Error; err != nil {
+			return err
+		}
+		if existing.Content == body.Content {
+			return nil
+		}
+		revision := models.RemarkRevision{
+			RemarkId: existing.Id,
+			Content:  existing.Content,
+			Revision: existing.Revision,
+		}
+		if err := tx.Create(&revision).Error; err != nil {
+			return err
+		}
+		existing.Content = body.Content
+		existing.Revision += 1
+		existing.EditedAt = strconv.FormatInt(time.Now().UnixMilli(), 10)
+		return tx.Omit("Attachments").Save(&existing).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	list := []models.Remark{existing}
+	render_remarks(list)
+	return &list[0], nil
+}
+
+type RemarkRevisionListBody struct {
+	RemarkId string `json:"remark_id"`
+}
+
+// FetchRemarkRevisionList 备注的历史版本，最新的在前面
+func (s *PasteEventRemarkController) FetchRemarkRevisionList(body RemarkRevisionListBody) ([]models.RemarkRevision, error) {
+	if body.RemarkId == "" {
+		return nil, fmt.Errorf("Missing the remark_id")
+	}
+	list := []models.RemarkRevision{}
+	if err := s.db.Where("remark_id = ?", body.RemarkId).Order("revision DESC").Find(&list).Error; err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+type RemarkAttachBody struct {
+	RemarkId string `json:"remark_id"`
+	// Paths 本地文件的路径
+	Paths []string `json:"paths"`
+	// ImageBase64 粘贴的图片，Name 为空时使用 image.png
+	ImageBase64 string `json:"image_base64"`
+	Name        string `json:"name"`
+}
+
+func (s *PasteEventRemarkController) put_attachment(remark_id string, name string, mime_type string, hash string, size int64) (*models.RemarkAttachment, error) {
+	if _, err := save_blob(s.db, hash, "remark_attachment", mime_type, size); err != nil {
+		return nil, err
+	}
+	created := models.RemarkAttachment{
+		RemarkId: remark_id,
+		Name:     name,
+		MimeType: mime_type,
+		Size:     size,
+		BlobHash: hash,
+	}
+	if err := s.db.Create(&created).Error; err != nil {
+		return nil, err
+	}
+	created.URL = BlobURL(hash)
 	return &created, nil
 }
 
+// AddRemarkAttachments 把文件或图片保存到 blob 目录并关联到备注
+func (s *PasteEventRemarkController) AddRemarkAttachments(body RemarkAttachBody) ([]models.RemarkAttachment, error) {
+	if body.RemarkId == "" {
+		return nil, fmt.Errorf("Missing the remark_id")
+	}
+	if s.blob == nil {
+		return nil, fmt.Errorf("The blob store is not initialized")
+	}
+	var existing models.Remark
+	if err := s.db.Where("id = ?", body.RemarkId).First(&existing).Error; err != nil {
+		return nil, err
+	}
+	var created []models.RemarkAttachment
+	for _, path := range body.Paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			return created, err
+		}
+		if info.IsDir() {
+			return created, fmt.Errorf("%s is a folder", info.Name())
+		}
+		if info.Size() > remark_attachment_max_size {
+			return created, fmt.Errorf("%s is larger than %dMB", info.Name(), remark_attachment_max_size/1024/1024)
+		}
+		mime_type := strings.Split(mime.TypeByExtension(filepath.Ext(path)), ";")[0]
+		if mime_type == "" {
+			mime_type = "application/octet-stream"
+		}
+		hash, size, err := s.blob.PutFile(path)
+		if err != nil {
+			return created, err
+		}
+		a, err := s.put_attachment(body.RemarkId, info.Name(), mime_type, hash, size)
+		if err != nil {
+			return created, err
+		}
+		created = append(created, *a)
+	}
+	if body.ImageBase64 != "" {
+		data, err := base64.StdEncoding.DecodeString(body.ImageBase64)
+		if err != nil {
+			return created, err
+		}
+		if len(data) > remark_attachment_max_size {
+			return created, fmt.Errorf("The image is larger than %dMB", remark_attachment_max_size/1024/1024)
+		}
+		mime_type := http.DetectContentType(data)
+		if !strings.HasPrefix(mime_type, "image/") {
+			return created, fmt.Errorf("The content is not an image")
+		}
+		name := body.Name
+		if name == "" {
+			name = "image.png"
+		}
+		hash, err := s.blob.Put(data)
+		if err != nil {
+			return created, err
+		}
+		a, err := s.put_attachment(body.RemarkId, name, mime_type, hash, int64(len(data)))
+		if err != nil {
+			return created, err
+		}
+		created = append(created, *a)
+	}
+	return created, nil
+}
+
+type RemarkAttachmentBody struct {
+	Id string `json:"id"`
+}
+
+// DeleteRemarkAttachment 附件不进入回收站，没有其他引用时同时删除文件
+func (s *PasteEventRemarkController) DeleteRemarkAttachment(body RemarkAttachmentBody) error {
+	if body.Id == "" {
+		return fmt.Errorf("Missing the id")
+	}
+	var existing models.RemarkAttachment
+	if err := s.db.Where("id = ?", body.Id).First(&existing).Error; err != nil {
+		return err
+	}
+	if err := s.db.Unscoped().Delete(&existing).Error; err != nil {
+		return err
+	}
+	if err := delete_unused_blobs(s.db, s.blob, []string{existing.BlobHash}); err != nil {
+		fmt.Println("[ERROR]delete unused blobs failed", err.Error())
+	}
+	return nil
+}
+
 type RemarkListBody struct {
 	models.Pagination
 
@@ -68,12 +281,14 @@ func (s *PasteEventRemarkController) FetchRemarkList(body RemarkListBody) (*List
 	pb := models.NewPaginationBuilder[models.Remark](query).
 		SetLimit(body.PageSize).
 		SetPage(body.Page).
+		SetNextMarker(body.NextMarker).
 		SetOrderBy("remark.created_at DESC")
 	var list1 []models.Remark
-	if err := pb.Build().Find(&list1).Error; err != nil {
+	if err := preload_remark_attachments(pb.Build()).Find(&list1).Error; err != nil {
 		return nil, err
 	}
 	list2, has_more, next_marker := pb.ProcessResults(list1)
+	render_remarks(list2)
 	return &ListResp[models.Remark]{
 		List:       list2,
 		Page:       body.Page,
@@ -101,3 +316,128 @@ func (s *PasteEventRemarkController) DeleteRemark(body RemarkDeleteBody) (*model
 	}
 	return &existing, nil
 }
+
+// FetchDeletedRemarkList 回收站中的备注，按删除时间倒序
+func (s *PasteEventRemarkController) FetchDeletedRemarkList(body RemarkListBody) (*ListResp[models.Remark], error) {
+	query := s.db.Unscoped().Model(&models.Remark{}).Where("remark.deleted_at IS NOT NULL")
+	if body.Keyword != "" {
+		query = query.Where("remark.content LIKE ?", "%"+body.Keyword+"%")
+	}
+	if body.PasteEventId != "" {
+		query = query.Where("remark.paste_event_id = ?", body.PasteEventId)
+	}
+	pb := models.NewPaginationBuilder[models.Remark](query).
+		SetLimit(body.PageSize).
+		SetPage(body.Page).
+		SetNextMarker(body.NextMarker).
+		SetOrderBy("remark.updated_at DESC")
+	var list1 []models.Remark
+	if err := preload_remark_attachments(pb.Build()).Find(&list1).Error; err != nil {
+		return nil, err
+	}
+	list2, has_more, next_marker := pb.ProcessResults(list1)
+	render_remarks(list2)
+	return &ListResp[models.Remark]{
+		List:       list2,
+		Page:       body.Page,
+		PageSize:   pb.GetLimit(),
+		HasMore:    has_more,
+		NextMarker: next_marker,
+	}, nil
+}
+
+// RestoreRemark 恢复后 last_operation_type 改为编辑，便于同步到其他设备
+func (s *PasteEventRemarkController) RestoreRemark(body RemarkDeleteBody) (*models.Remark, error) {
+	if body.Id == "" {
+		return nil, fmt.Errorf("Missing the id")
+	}
+	var existing models.Remark
+	if err := s.db.Unscoped().Where("id = ? AND deleted_at IS NOT NULL", body.Id).First(&existing).Error; err != nil {
+		return nil, err
+	}
+	existing.DeletedAt = gorm.DeletedAt{}
+	if err := s.db.Unscoped().Save(&existing).Error; err != nil {
+		return nil, err
+	}
+	return &existing, nil
+}
+
+type RemarkPurgeBody struct {
+	Ids []string `json:"ids"`
+	// SyncedOnly 见 PastePurgeBody.SyncedOnly
+	SyncedOnly bool `json:"-"`
+}
+
+// PurgeRemarks 永久删除回收站中的备注
+func (s *PasteEventRemarkController) PurgeRemarks(body RemarkPurgeBody) (*PurgeResult, error) {
+	result := &PurgeResult{}
+	if len(body.Ids) == 0 {
+		return result, nil
+	}
+	pending, err := count_pending_sync(s.db, &models.Remark{}, body.Ids, body.SyncedOnly)
+	if err != nil {
+		return nil, err
+	}
+	result.PendingSync = pending
+	var ids []string
+	if err := purgeable_query(s.db, body.SyncedOnly).Model(&models.Remark{}).
+		Where("id IN ?", body.Ids).
+		Pluck("id", &ids).Error; err != nil {
+		return nil, err
+	}
+	if len(ids) == 0 {
+		return result, nil
+	}
+	var hashes []string
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		hashes, err = purge_remark_related(tx, ids)
+		if err != nil {
+			return err
+		}
+		if err := add_purged_records(tx, "remark", ids); err != nil {
+			return err
+		}
+		return tx.Unscoped().Where("id IN ?", ids).Delete(&models.Remark{}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := delete_unused_blobs(s.db, s.blob, hashes); err != nil {
+		fmt.Println("[ERROR]delete unused blobs failed", err.Error())
+	}
+	result.Total = len(ids)
+	return result, nil
+}
+
+// purge_remark_related 永久删除备注的历史版本和附件，返回附件引用的 blob
+func purge_remark_related(tx *gorm.DB, remark_ids []string) ([]string, error) {
+	if len(remark_ids) == 0 {
+		return nil, nil
+	}
+	var hashes []string
+	if err := tx.Unscoped().Model(&models.RemarkAttachment{}).
+		Where("remark_id IN ?", remark_ids).
+		Pluck("blob_hash", &hashes).Error; err != nil {
+		return nil, err
+	}
+	if err := tx.Unscoped().Where("remark_id IN ?", remark_ids).Delete(&models.RemarkAttachment{}).Error; err != nil {
+		return nil, err
+	}
+	if err := tx.Unscoped().Where("remark_id IN ?", remark_ids).Delete(&models.RemarkRevision{}).Error; err != nil {
+		return nil, err
+	}
+	return hashes, nil
+}
+
+// FetchExpiredRemarkIdList 删除时间早于 before 的备注 id，synced_only 见 PastePurgeBody.SyncedOnly
+func (s *PasteEventRemarkController) FetchExpiredRemarkIdList(before time.Time, limit int, synced_only bool) ([]string, error) {
+	var ids []string
+	if err := purgeable_query(s.db, synced_only).Model(&models.Remark{}).
+		Where("deleted_at < ?", before).
+		Limit(limit).
+		Pluck("id", &ids).Error; err != nil {
+		return nil, err
+	}
+	return ids, nil
+}
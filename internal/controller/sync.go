@@ -0,0 +1,122 @@
+package controller
+
+import (
+	"regexp"
+	"strconv"
+	"time"
+
+	"gorm.io/gorm"
+
+	"devboard/models"
+	_html "devboard/pkg/html"
+	"devboard/pkg/synchronizer"
+)
+
+// sanitize_synced_html 其他设备同步过来的 html 没有经过本机的清理，保存前按照本机的规则清理
+func sanitize_synced_html(table_name string, data map[string]interface{}, profile string) {
+	if table_name != "paste_event" {
+		return
+	}
+	if v, ok := data["html"].(string); ok && v != "" {
+		data["html"] = _html.Sanitize(v, _html.FindSanitizeProfile(profile))
+	}
+}
+
+// ApplyRemoteRecordTasks 把从远端拉取的记录写入本地数据库，执行的日志和错误追加到 result 中
+func ApplyRemoteRecordTasks(db *gorm.DB, t synchronizer.TableSynchronizeSetting, result *synchronizer.SynchronizeResult, html_profile string) {
+	table_name := t.Name
+	add_message := func(msg *synchronizer.SynchronizeMessage) {
+		result.Messages = append(result.Messages, msg)
+	}
+	log := func(content string) {
+		result.Logs = append(result.Logs, content)
+	}
+	time_pattern := `^\d{4}-\d{2}-\d{2}T\d{2}:\d{2}:\d{2}(\.\d+)?(Z|([+-]\d{2}:\d{2}))$`
+	// 本地永久删除过的记录不再从远端创建
+	purged := make(map[string]bool)
+	var purged_ids []string
+	if err := db.Model(&models.PurgedRecord{}).Where("table_name = ?", table_name).Pluck("id", &purged_ids).Error; err != nil {
+		log("[ERROR]fetch purged records failed, because " + err.Error())
+	}
+	for _, id := range purged_ids {
+		purged[id] = true
+	}
+	// var timestamp_regex = regexp.MustCompile(`^[0-9]{8}$`)
+	for _, r := range result.RecordTasks {
+		// var d map[string]interface{}
+		// if err := json.Unmarshal([]byte(r.Content), &d); err != nil {
+		// 	continue
+		// }
+		log("[LOG]apply record task, the type is " + r.Type)
+		if r.Type == "create" {
+			if id, ok := r.Data[t.IdFieldName].(string); ok && purged[id] {
+				log("[LOG]skip the purged record " + id)
+				continue
+			}
+			r.Data["sync_status"] = 2
+			created_at_str, ok := r.Data["created_at"].(string)
+			if !ok {
+				continue
+			}
+			match, _ := regexp.MatchString(time_pattern, created_at_str)
+			if match {
+				t, err := time.Parse(time.RFC3339Nano, created_at_str)
+				if err != nil {
+					continue
+				}
+				r.Data["created_at"] = strconv.Itoa(int(t.UnixMilli()))
+			}
+			sanitize_synced_html(table_name, r.Data, html_profile)
+			if err := db.Table(table_name).Create(r.Data).Error; err != nil {
+				log("[LOG]create record task failed, because " + err.Error())
+				continue
+			}
+		}
+		if r.Type == "update" {
+			r.Data["sync_status"] = 2
+			sanitize_synced_html(table_name, r.Data, html_profile)
+			result := db.Table(table_name).Where("id = ?", r.Id).Updates(r.Data)
+			if result.Error != nil {
+				log("[ERROR]update record failed, because " + result.Error.Error())
+				add_message(&synchronizer.SynchronizeMessage{
+					Type:  synchronizer.SynchronizeMessageError,
+					Scope: "database",
+					Text:  result.Error.Error(),
+				})
+				continue
+			}
+			if result.RowsAffected == 0 {
+				log("[ERROR]update record failed, no matched record.")
+				// errors = append(errors, fmt.Errorf("未找到要更新的记录ID: %s", r.Id))
+				add_message(&synchronizer.SynchronizeMessage{
+					Type:  synchronizer.SynchronizeMessageError,
+					Scope: "database",
+					Text:  "",
+				})
+				continue
+			}
+			log("[ERROR]update record success, affected rows " + strconv.Itoa(int(result.RowsAffected)))
+		}
+		if r.Type == "to_draft" {
+			data := map[string]interface{}{"sync_status": 1}
+			if err := db.Table(table_name).Where("id = ?", r.Id).Updates(data).Error; err != nil {
+				log("[ERROR]update record sync status failed, " + err.Error())
+			}
+		}
+		if r.Type == "to_published" {
+			data := map[string]interface{}{"sync_status": 2}
+			if err := db.Table(table_name).Where("id = ?", r.Id).Updates(data).Error; err != nil {
+				log("[ERROR]update record sync status failed, " + err.Error())
+			}
+		}
+		// if r.Action == 3 {
+		// 	result := s.Biz.DB.Table(table_name).Where("id = ?", r.Id).Delete(nil)
+		// 	if result.Error != nil {
+		// 		return fmt.Errorf("删除记录失败: %v", result.Error)
+		// 	}
+		// 	if result.RowsAffected == 0 {
+		// 		return fmt.Errorf("未找到要删除的记录ID: %s", action.Id)
+		// 	}
+		// }
+	}
+}
@@ -0,0 +1,50 @@
+package controller_test
+
+import (
+	"strings"
+	"testing"
+
+	"devboard/internal/controller"
+	"devboard/models"
+	"devboard/pkg/synchronizer"
+)
+
+func TestApplyRemoteRecordTasks(t *testing.T) {
+	db := open_test_db(t)
+	if err := db.Create(&models.PurgedRecord{Id: "purged", Table: "paste_event", PurgedAt: "1"}).Error; err != nil {
+		t.Fatal(err)
+	}
+	remote := func(id string, html string) *synchronizer.RecordTask {
+		return &synchronizer.RecordTask{
+			Type: "create",
+			Id:   id,
+			Data: map[string]interface{}{
+				"id":           id,
+				"content_type": "html",
+				"text":         "remote",
+				"html":         html,
+				"created_at":   "1700000000000",
+			},
+		}
+	}
+	result := &synchronizer.SynchronizeResult{
+		RecordTasks: []*synchronizer.RecordTask{
+			remote("purged", "<p>purged</p>"),
+			remote("created", `<p onclick="alert(1)">hi</p><script>alert(2)</script>`),
+		},
+	}
+	controller.ApplyRemoteRecordTasks(db, synchronizer.TableSynchronizeSetting{Name: "paste_event", IdFieldName: "id"}, result, "")
+	if n := count_rows(t, db, &models.PasteEvent{}, "id = ?", "purged"); n != 0 {
+		t.Errorf("the purged record should not be created again")
+	}
+	var record models.PasteEvent
+	if err := db.Where("id = ?", "created").First(&record).Error; err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(record.Html, "alert") || !strings.Contains(record.Html, "hi") {
+		t.Errorf("the synced html is not sanitized: %q", record.Html)
+	}
+	if record.SyncStatus != 2 {
+		t.Errorf("got sync_status %d, want 2", record.SyncStatus)
+	}
+}
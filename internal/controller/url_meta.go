@@ -0,0 +1,81 @@
+package controller
+
+import (
+	"errors"
+	"strconv"
+	"time"
+
+	"gorm.io/gorm"
+
+	"devboard/models"
+)
+
+const (
+	URLMetaStatusPending = 1
+	URLMetaStatusOk      = 2
+	URLMetaStatusFailed  = 3
+	URLMetaStatusSkipped = 4
+)
+
+type URLMetaController struct {
+	db *gorm.DB
+}
+
+func NewURLMetaController(db *gorm.DB) *URLMetaController {
+	return &URLMetaController{
+		db: db,
+	}
+}
+
+// FetchURLMeta 查询缓存，不存在时返回 nil
+func (s *URLMetaController) FetchURLMeta(url string) (*models.URLMeta, error) {
+	var record models.URLMeta
+	if err := s.db.Where("url = ?", url).First(&record).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &record, nil
+}
+
+// EnsureURLMeta 不存在时创建一条待获取的记录
+func (s *URLMetaController) EnsureURLMeta(url string, domain string) (*models.URLMeta, error) {
+	existing, err := s.FetchURLMeta(url)
+	if err != nil {
+		return nil, err
+	}
+	if existing != nil {
+		return existing, nil
+	}
+	created := models.URLMeta{
+		URL:    url,
+		Domain: domain,
+		Status: URLMetaStatusPending,
+	}
+	if err := s.db.Create(&created).Error; err != nil {
+		return nil, err
+	}
+	return &created, nil
+}
+
+func (s *URLMetaController) SaveURLMeta(record *models.URLMeta) error {
+	return s.db.Save(record).Error
+}
+
+// FetchDueURLMetaList 待获取以及到了重试时间的记录
+func (s *URLMetaController) FetchDueURLMetaList(max_attempts int, limit int) ([]models.URLMeta, error) {
+	now := strconv.FormatInt(time.Now().UnixMilli(), 10)
+	var list []models.URLMeta
+	err := s.db.
+		Where("status IN ?", []int{URLMetaStatusPending, URLMetaStatusFailed}).
+		Where("attempts < ?", max_attempts).
+		Where("next_retry_at = '' OR CAST(next_retry_at AS INTEGER) <= CAST(? AS INTEGER)", now).
+		Order("created_at").
+		Limit(limit).
+		Find(&list).Error
+	if err != nil {
+		return nil, err
+	}
+	return list, nil
+}
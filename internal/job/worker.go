@@ -0,0 +1,112 @@
+package job
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Task 一个后台任务
+type Task struct {
+	Name string
+	Run  func(ctx context.Context) error
+}
+
+// Worker 单协程按顺序执行任务的后台队列，带有速率限制，队列满时直接丢弃新任务
+type Worker struct {
+	name     string
+	tasks    chan Task
+	interval func() time.Duration
+
+	mu      sync.Mutex
+	started bool
+	last    time.Time
+}
+
+// NewWorker size 为队列长度，interval 返回两个任务之间的最小间隔，每次执行前读取，方便跟随用户配置变化
+func NewWorker(name string, size int, interval func() time.Duration) *Worker {
+	if size <= 0 {
+		size = 16
+	}
+	return &Worker{
+		name:     name,
+		tasks:    make(chan Task, size),
+		interval: interval,
+	}
+}
+
+// Start 启动消费协程，重复调用无副作用
+func (w *Worker) Start(ctx context.Context) *Worker {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.started {
+		return w
+	}
+	w.started = true
+	go w.loop(ctx)
+	return w
+}
+
+// Push 加入队列，队列已满时返回 false
+func (w *Worker) Push(task Task) bool {
+	select {
+	case w.tasks <- task:
+		return true
+	default:
+		fmt.Printf("[JOB]%s queue is full, drop task %s\n", w.name, task.Name)
+		return false
+	}
+}
+
+func (w *Worker) wait(ctx context.Context) error {
+	if w.interval == nil {
+		return nil
+	}
+	d := w.interval() - time.Since(w.last)
+	if d <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+func (w *Worker) loop(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case task := <-w.tasks:
+			if err := w.wait(ctx); err != nil {
+				return
+			}
+			w.last = time.Now()
+			w.run(ctx, task)
+		}
+	}
+}
+
+func (w *Worker) run(ctx context.Context, task Task) {
+	defer func() {
+		if r := recover(); r != nil {
+			fmt.Printf("[JOB]%s task %s panic, %v\n", w.name, task.Name, r)
+		}
+	}()
+	if err := task.Run(ctx); err != nil {
+		fmt.Printf("[JOB]%s task %s failed, %v\n", w.name, task.Name, err.Error())
+	}
+}
+
+// PerMinute 把每分钟次数转换为任务间隔，n <= 0 时不限制
+func PerMinute(n int) time.Duration {
+	if n <= 0 {
+		return 0
+	}
+	return time.Minute / time.Duration(n)
+}
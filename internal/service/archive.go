@@ -0,0 +1,88 @@
+package service
+
+import (
+	"time"
+
+	"github.com/wailsapp/wails/v3/pkg/application"
+
+	"devboard/internal/biz"
+	"devboard/internal/controller"
+)
+
+// ArchiveService 导出和导入完整的历史记录，用于备份和迁移
+type ArchiveService struct {
+	App *application.App
+	Biz *biz.BizApp
+}
+
+func NewArchiveService(app *application.App, biz *biz.BizApp) *ArchiveService {
+	return &ArchiveService{
+		App: app,
+		Biz: biz,
+	}
+}
+
+func (s *ArchiveService) ExportArchive(body controller.ArchiveExportBody) *Result {
+	if err := s.Biz.Ensure(); err != nil {
+		return Error(err)
+	}
+	dialog := application.SaveFileDialog()
+	dialog.CanCreateDirectories(true)
+	dialog.SetFilename("devboard_archive_" + time.Now().Format("20060102150405") + ".zip")
+	path, err := dialog.PromptForSingleSelection()
+	if err != nil {
+		return Error(err)
+	}
+	if path == "" {
+		return Ok(map[string]interface{}{
+			"cancel": true,
+		})
+	}
+	manifest, err := s.Biz.ExportArchive(body, path)
+	if err != nil {
+		return Error(err)
+	}
+	return Ok(map[string]interface{}{
+		"path":     path,
+		"manifest": manifest,
+	})
+}
+
+// ChooseArchive 选择需要导入的归档，返回路径，再通过 ImportArchive 预览或导入
+func (s *ArchiveService) ChooseArchive() *Result {
+	dialog := application.OpenFileDialog()
+	dialog.SetTitle("Select Archive")
+	dialog.SetOptions(&application.OpenFileDialogOptions{
+		CanChooseFiles: true,
+		Filters: []application.FileFilter{
+			{
+				DisplayName: "Archive (*.zip)",
+				Pattern:     "*.zip",
+			},
+		},
+	})
+	path, err := dialog.PromptForSingleSelection()
+	if err != nil {
+		return Error(err)
+	}
+	if path == "" {
+		return Ok(map[string]interface{}{
+			"cancel": true,
+		})
+	}
+	return Ok(map[string]interface{}{
+		"path": path,
+	})
+}
+
+// ImportArchive dry_run 为 true 时只返回统计结果
+func (s *ArchiveService) ImportArchive(body controller.ArchiveImportBody) *Result {
+	if err := s.Biz.Ensure(); err != nil {
+		return Error(err)
+	}
+	r, err := s.Biz.ImportArchive(body)
+	if err != nil {
+		return Error(err)
+	}
+	return Ok(r)
+}
@@ -0,0 +1,52 @@
+package service
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/wailsapp/wails/v3/pkg/application"
+
+	"devboard/internal/biz"
+)
+
+// BlobService 通过 /blob?hash=xxx 读取 blob 目录中的文件
+type BlobService struct {
+	App *application.App
+	Biz *biz.BizApp
+}
+
+func NewBlobService(app *application.App, biz *biz.BizApp) *BlobService {
+	return &BlobService{App: app, Biz: biz}
+}
+
+func (s *BlobService) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	hash := r.URL.Query().Get("hash")
+	if hash == "" {
+		http.Error(w, "Missing 'hash' parameter", http.StatusBadRequest)
+		return
+	}
+	if s.Biz.Blob == nil || s.Biz.ControllerMap == nil {
+		http.Error(w, "Blob store is not ready", http.StatusServiceUnavailable)
+		return
+	}
+	p, err := s.Biz.Blob.Path(hash)
+	if err != nil {
+		http.Error(w, "Invalid hash", http.StatusBadRequest)
+		return
+	}
+	if !s.Biz.Blob.Has(hash) {
+		http.Error(w, "Blob not found", http.StatusNotFound)
+		return
+	}
+	if record, err := s.Biz.ControllerMap.Blob.FetchBlob(hash); err == nil && record.MimeType != "" {
+		w.Header().Set("Content-Type", record.MimeType)
+		// 备注附件可能是 html、svg 等文件，除了图片都作为下载处理，避免在应用中执行
+		if !strings.HasPrefix(record.MimeType, "image/") || record.MimeType == "image/svg+xml" {
+			w.Header().Set("Content-Disposition", "attachment")
+		}
+	}
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	// 文件名就是内容的哈希，内容不会变化
+	w.Header().Set("Cache-Control", "public, max-age=31536000, immutable")
+	http.ServeFile(w, r, p)
+}
@@ -35,21 +35,154 @@ func (s *CategoryService) FetchCategoryTree() *Result {
 	return Ok(r)
 }
 
+func (s *CategoryService) FetchCategoryList(body controller.CategoryListBody) *Result {
+	if err := s.Biz.Ensure(); err != nil {
+		return Error(err)
+	}
+	r, err := s.Biz.ControllerMap.Category.FetchCategoryList(body)
+	if err != nil {
+		return Error(err)
+	}
+	return Ok(r)
+}
+
+func (s *CategoryService) UpdateCategory(body controller.CategoryUpdateBody) *Result {
+	if err := s.Biz.Ensure(); err != nil {
+		return Error(err)
+	}
+	r, err := s.Biz.ControllerMap.Category.UpdateCategory(body)
+	if err != nil {
+		return Error(err)
+	}
+	return Ok(r)
+}
+
+func (s *CategoryService) MoveCategory(body controller.CategoryMoveBody) *Result {
+	if err := s.Biz.Ensure(); err != nil {
+		return Error(err)
+	}
+	r, err := s.Biz.ControllerMap.Category.MoveCategory(body)
+	if err != nil {
+		return Error(err)
+	}
+	return Ok(r)
+}
+
+func (s *CategoryService) ReorderCategories(body controller.CategoryReorderBody) *Result {
+	if err := s.Biz.Ensure(); err != nil {
+		return Error(err)
+	}
+	list, err := s.Biz.ControllerMap.Category.ReorderCategories(body)
+	if err != nil {
+		return Error(err)
+	}
+	return Ok(list)
+}
+
+func (s *CategoryService) SetCategoryActive(body controller.CategoryActiveBody) *Result {
+	if err := s.Biz.Ensure(); err != nil {
+		return Error(err)
+	}
+	r, err := s.Biz.ControllerMap.Category.SetCategoryActive(body)
+	if err != nil {
+		return Error(err)
+	}
+	return Ok(r)
+}
+
+func (s *CategoryService) DeleteCategory(body controller.CategoryDeleteBody) *Result {
+	if err := s.Biz.Ensure(); err != nil {
+		return Error(err)
+	}
+	ids, err := s.Biz.ControllerMap.Category.DeleteCategory(body)
+	if err != nil {
+		return Error(err)
+	}
+	return Ok(ids)
+}
+
+func (s *CategoryService) SaveCategoryRule(body controller.CategoryRuleSaveBody) *Result {
+	if err := s.Biz.Ensure(); err != nil {
+		return Error(err)
+	}
+	r, err := s.Biz.ControllerMap.Category.SaveCategoryRule(body)
+	if err != nil {
+		return Error(err)
+	}
+	return Ok(r)
+}
+
+func (s *CategoryService) FetchCategoryRuleList() *Result {
+	if err := s.Biz.Ensure(); err != nil {
+		return Error(err)
+	}
+	list, err := s.Biz.ControllerMap.Category.FetchCategoryRuleList()
+	if err != nil {
+		return Error(err)
+	}
+	return Ok(list)
+}
+
+func (s *CategoryService) DeleteCategoryRule(body controller.CategoryRuleBody) *Result {
+	if err := s.Biz.Ensure(); err != nil {
+		return Error(err)
+	}
+	r, err := s.Biz.ControllerMap.Category.DeleteCategoryRule(body)
+	if err != nil {
+		return Error(err)
+	}
+	return Ok(r)
+}
+
+// PreviewCategoryRules 重新应用规则前预览会影响的记录数量
+func (s *CategoryService) PreviewCategoryRules(body controller.CategoryRuleApplyBody) *Result {
+	if err := s.Biz.Ensure(); err != nil {
+		return Error(err)
+	}
+	r, err := s.Biz.PreviewCategoryRules(body)
+	if err != nil {
+		return Error(err)
+	}
+	return Ok(r)
+}
+
+// ApplyCategoryRules 在后台对历史记录重新应用规则，进度通过事件通知
+func (s *CategoryService) ApplyCategoryRules(body controller.CategoryRuleApplyBody) *Result {
+	if err := s.Biz.Ensure(); err != nil {
+		return Error(err)
+	}
+	task_id, err := s.Biz.ApplyCategoryRules(body)
+	if err != nil {
+		return Error(err)
+	}
+	return Ok(map[string]interface{}{
+		"task_id": task_id,
+	})
+}
+
 type CategoryTreeResp struct {
-	Id        string             `json:"id"`
-	Label     string             `json:"label"`
-	CreatedAt string             `json:"created_at"`
-	Parents   []CategoryTreeResp `json:"parents"`
+	Id          string             `json:"id"`
+	Label       string             `json:"label"`
+	Color       string             `json:"color,omitempty"`
+	Icon        string             `json:"icon,omitempty"`
+	DirectCount int                `json:"direct_count"`
+	TotalCount  int                `json:"total_count"`
+	CreatedAt   string             `json:"created_at"`
+	Parents     []CategoryTreeResp `json:"parents"`
 }
 
 func category_nodes_process(r []controller.CategoryTree) []CategoryTreeResp {
 	var nodes []CategoryTreeResp
 	for _, n := range r {
 		nodes = append(nodes, CategoryTreeResp{
-			Id:        n.Id,
-			Label:     n.Label,
-			CreatedAt: n.CreatedAt,
-			Parents:   category_nodes_process(n.Parents),
+			Id:          n.Id,
+			Label:       n.Label,
+			Color:       n.Color,
+			Icon:        n.Icon,
+			DirectCount: n.DirectCount,
+			TotalCount:  n.TotalCount,
+			CreatedAt:   n.CreatedAt,
+			Parents:     category_nodes_process(n.Parents),
 		})
 	}
 	return nodes
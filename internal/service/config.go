@@ -12,14 +12,14 @@ type ConfigService struct {
 }
 
 func (s *ConfigService) Read() *Result {
-	return Ok(s.Biz.Perferences.Value)
+	return Ok(s.Biz.Perferences.Value.Public())
 }
 
 func (s *ConfigService) WriteConfig(body map[string]interface{}) *Result {
-	if err := s.Biz.Perferences.WriteConfig(body); err != nil {
+	if err := s.Biz.Perferences.WriteValues(body); err != nil {
 		return Error(err)
 	}
-	return Ok(s.Biz.Perferences.Value)
+	return Ok(s.Biz.Perferences.Value.Public())
 }
 
 type SettingsUpdateBody struct {
@@ -0,0 +1,235 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+
+	"github.com/wailsapp/wails/v3/pkg/application"
+
+	"devboard/internal/biz"
+	"devboard/pkg/llm"
+)
+
+type LLMService struct {
+	App *application.App
+	Biz *biz.BizApp
+}
+
+func NewLLMService(app *application.App, biz *biz.BizApp) *LLMService {
+	return &LLMService{
+		App: app,
+		Biz: biz,
+	}
+}
+
+func (s *LLMService) FetchProviderList() *Result {
+	providers, default_name := s.Biz.LLM.List()
+	list := make([]llm.ProviderResp, 0)
+	for _, p := range providers {
+		list = append(list, p.Public(p.Name == default_name))
+	}
+	return Ok(map[string]interface{}{
+		"list":             list,
+		"default_provider": default_name,
+	})
+}
+
+type LLMProviderSaveBody struct {
+	// PrevName 修改名称时传入原名称，新增时为空
+	PrevName    string  `json:"prev_name"`
+	Name        string  `json:"name"`
+	BaseURL     string  `json:"base_url"`
+	APIKey      string  `json:"api_key"` // 为空表示沿用原来的 key
+	ClearAPIKey bool    `json:"clear_api_key"`
+	Model       string  `json:"model"`
+	Temperature float64 `json:"temperature"`
+	Timeout     int     `json:"timeout"`
+
+	EmbeddingModel string `json:"embedding_model"`
+}
+
+func (body *LLMProviderSaveBody) validate() error {
+	if body.Name == "" {
+		return fmt.Errorf("Missing the name")
+	}
+	if body.BaseURL == "" {
+		return fmt.Errorf("Missing the base_url")
+	}
+	u, err := url.Parse(body.BaseURL)
+	if err != nil || u.Host == "" || (u.Scheme != "http" && u.Scheme != "https") {
+		return fmt.Errorf("Invalid base_url")
+	}
+	if body.Temperature < 0 || body.Temperature > 2 {
+		return fmt.Errorf("The temperature should between 0 and 2")
+	}
+	if body.Timeout < 0 {
+		return fmt.Errorf("Invalid timeout")
+	}
+	return nil
+}
+
+func (s *LLMService) SaveProvider(body LLMProviderSaveBody) *Result {
+	if err := body.validate(); err != nil {
+		return Error(err)
+	}
+	v := *s.Biz.Perferences.Value
+	providers := make([]llm.Provider, 0, len(v.LLM.Providers)+1)
+	prev_name := body.PrevName
+	if prev_name == "" {
+		prev_name = body.Name
+	}
+	created := llm.Provider{
+		Name:        body.Name,
+		BaseURL:     body.BaseURL,
+		APIKey:      body.APIKey,
+		Model:       body.Model,
+		Temperature: body.Temperature,
+		Timeout:     body.Timeout,
+
+		EmbeddingModel: body.EmbeddingModel,
+	}
+	replaced := false
+	for _, p := range v.LLM.Providers {
+		if p.Name == body.Name && body.Name != prev_name {
+			return Error(fmt.Errorf("The provider %s is existing", body.Name))
+		}
+		if p.Name != prev_name {
+			providers = append(providers, p)
+			continue
+		}
+		if created.APIKey == "" && !body.ClearAPIKey {
+			created.APIKey = p.APIKey
+		}
+		providers = append(providers, created)
+		replaced = true
+	}
+	if !replaced {
+		providers = append(providers, created)
+	}
+	v.LLM.Providers = providers
+	if v.LLM.DefaultProvider == "" || v.LLM.DefaultProvider == prev_name {
+		v.LLM.DefaultProvider = body.Name
+	}
+	if err := s.Biz.Perferences.Set(&v); err != nil {
+		return Error(err)
+	}
+	return Ok(created.Public(v.LLM.DefaultProvider == created.Name))
+}
+
+type LLMProviderBody struct {
+	Name string `json:"name"`
+}
+
+func (s *LLMService) DeleteProvider(body LLMProviderBody) *Result {
+	if body.Name == "" {
+		return Error(fmt.Errorf("Missing the name"))
+	}
+	v := *s.Biz.Perferences.Value
+	providers := make([]llm.Provider, 0, len(v.LLM.Providers))
+	for _, p := range v.LLM.Providers {
+		if p.Name != body.Name {
+			providers = append(providers, p)
+		}
+	}
+	v.LLM.Providers = providers
+	if v.LLM.DefaultProvider == body.Name {
+		v.LLM.DefaultProvider = ""
+		if len(providers) != 0 {
+			v.LLM.DefaultProvider = providers[0].Name
+		}
+	}
+	if err := s.Biz.Perferences.Set(&v); err != nil {
+		return Error(err)
+	}
+	return Ok(nil)
+}
+
+func (s *LLMService) SetDefaultProvider(body LLMProviderBody) *Result {
+	if _, err := s.Biz.LLM.Resolve(body.Name); err != nil {
+		return Error(err)
+	}
+	v := *s.Biz.Perferences.Value
+	v.LLM.DefaultProvider = body.Name
+	if err := s.Biz.Perferences.Set(&v); err != nil {
+		return Error(err)
+	}
+	return Ok(nil)
+}
+
+type LLMProviderTestBody struct {
+	LLMProviderSaveBody
+	// Unsaved 为 true 时测试表单中尚未保存的配置，否则测试已保存的 Name 对应的厂商
+	Unsaved bool `json:"unsaved"`
+}
+
+func (s *LLMService) TestProvider(body LLMProviderTestBody) *Result {
+	var provider *llm.Provider
+	if body.Unsaved {
+		if err := body.validate(); err != nil {
+			return Error(err)
+		}
+		provider = &llm.Provider{
+			Name:    body.Name,
+			BaseURL: body.BaseURL,
+			APIKey:  body.APIKey,
+			Model:   body.Model,
+			Timeout: body.Timeout,
+		}
+		if provider.APIKey == "" && !body.ClearAPIKey {
+			if existing, err := s.Biz.LLM.Resolve(body.PrevName); err == nil && body.PrevName != "" {
+				provider.APIKey = existing.APIKey
+			}
+		}
+	} else {
+		p, err := s.Biz.LLM.Resolve(body.Name)
+		if err != nil {
+			return Error(err)
+		}
+		provider = p
+	}
+	r, err := provider.Ping(context.Background())
+	if err != nil {
+		return Error(err)
+	}
+	return Ok(r)
+}
+
+type LLMChatBody struct {
+	Provider    string               `json:"provider"`
+	Model       string               `json:"model"`
+	Temperature *float64             `json:"temperature"`
+	Messages    []llm.LLMChatMessage `json:"messages"`
+}
+
+func (s *LLMService) Chat(body LLMChatBody) *Result {
+	if len(body.Messages) == 0 {
+		return Error(fmt.Errorf("Missing the messages"))
+	}
+	provider, err := s.Biz.LLM.Resolve(body.Provider)
+	if err != nil {
+		return Error(err)
+	}
+	content, err := provider.Chat(context.Background(), body.Messages, llm.ChatOptions{
+		Model:       body.Model,
+		Temperature: body.Temperature,
+	})
+	if err != nil {
+		return Error(err)
+	}
+	return Ok(map[string]interface{}{
+		"provider": provider.Name,
+		"content":  content,
+	})
+}
+
+func (s *LLMService) AskClipboard(body biz.AskClipboardBody) *Result {
+	if err := s.Biz.Ensure(); err != nil {
+		return Error(err)
+	}
+	r, err := s.Biz.AskClipboard(context.Background(), body)
+	if err != nil {
+		return Error(err)
+	}
+	return Ok(r)
+}
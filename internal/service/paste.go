@@ -2,6 +2,7 @@ package service
 
 import (
 	"encoding/base64"
+	"encoding/json"
 	"fmt"
 	"net/url"
 	"os"
@@ -13,6 +14,7 @@ import (
 
 	"devboard/internal/biz"
 	"devboard/internal/controller"
+	"devboard/internal/transformer"
 	"devboard/models"
 )
 
@@ -61,6 +63,111 @@ func (s *PasteService) DeletePasteEvent(body controller.PasteEventBody) *Result
 	return Ok(nil)
 }
 
+func (s *PasteService) FetchCategorySuggestionList(body controller.PasteEventBody) *Result {
+	if err := s.Biz.Ensure(); err != nil {
+		return Error(err)
+	}
+	list, err := s.Biz.ControllerMap.Paste.FetchCategorySuggestionList(body)
+	if err != nil {
+		return Error(err)
+	}
+	return Ok(list)
+}
+
+func (s *PasteService) AcceptCategorySuggestion(body controller.CategorySuggestionBody) *Result {
+	if err := s.Biz.Ensure(); err != nil {
+		return Error(err)
+	}
+	r, err := s.Biz.ControllerMap.Paste.AcceptCategorySuggestion(body)
+	if err != nil {
+		return Error(err)
+	}
+	return Ok(r)
+}
+
+func (s *PasteService) RejectCategorySuggestion(body controller.CategorySuggestionBody) *Result {
+	if err := s.Biz.Ensure(); err != nil {
+		return Error(err)
+	}
+	r, err := s.Biz.ControllerMap.Paste.RejectCategorySuggestion(body)
+	if err != nil {
+		return Error(err)
+	}
+	return Ok(r)
+}
+
+func (s *PasteService) SuggestPasteEventCategories(body controller.PasteEventBody) *Result {
+	if err := s.Biz.Ensure(); err != nil {
+		return Error(err)
+	}
+	list, err := s.Biz.ControllerMap.Paste.SuggestPasteEventCategories(body)
+	if err != nil {
+		return Error(err)
+	}
+	return Ok(list)
+}
+
+func (s *PasteService) AssignCategories(body controller.PasteCategoryAssignBody) *Result {
+	if err := s.Biz.Ensure(); err != nil {
+		return Error(err)
+	}
+	list, err := s.Biz.ControllerMap.Paste.AssignCategories(body)
+	if err != nil {
+		return Error(err)
+	}
+	return Ok(list)
+}
+
+func (s *PasteService) UnassignCategory(body controller.PasteCategoryUnassignBody) *Result {
+	if err := s.Biz.Ensure(); err != nil {
+		return Error(err)
+	}
+	list, err := s.Biz.ControllerMap.Paste.UnassignCategory(body)
+	if err != nil {
+		return Error(err)
+	}
+	return Ok(list)
+}
+
+func (s *PasteService) FetchEntityTypeList() *Result {
+	return Ok(transformer.EntityTypes)
+}
+
+func (s *PasteService) FetchPasteEventEntityList(body controller.PasteProfileBody) *Result {
+	if err := s.Biz.Ensure(); err != nil {
+		return Error(err)
+	}
+	list, err := s.Biz.ControllerMap.Paste.FetchPasteEventEntityList(body)
+	if err != nil {
+		return Error(err)
+	}
+	return Ok(list)
+}
+
+func (s *PasteService) RefreshPasteEventEntities(body controller.PasteProfileBody) *Result {
+	if err := s.Biz.Ensure(); err != nil {
+		return Error(err)
+	}
+	list, err := s.Biz.ControllerMap.Paste.RefreshPasteEventEntities(body)
+	if err != nil {
+		return Error(err)
+	}
+	return Ok(list)
+}
+
+func (s *PasteService) RebuildPasteEventEntities() *Result {
+	if err := s.Biz.Ensure(); err != nil {
+		return Error(err)
+	}
+	total, err := s.Biz.ControllerMap.Paste.RebuildPasteEventEntities()
+	if err != nil {
+		return Error(err)
+	}
+	return Ok(map[string]interface{}{
+		"total": total,
+	})
+}
+
 type PasteEventPreviewBody struct {
 	EventId string `json:"paste_event_id"`
 	Focus   bool   `json:"focus"`
@@ -177,6 +284,52 @@ func (s *PasteService) DownloadContentWithPasteEventId(body controller.PasteProf
 	return Ok(map[string]interface{}{})
 }
 
+func (s *PasteService) BulkUpdatePasteEvents(body controller.PasteBulkBody) *Result {
+	if err := s.Biz.Ensure(); err != nil {
+		return Error(err)
+	}
+	r, err := s.Biz.BulkUpdatePasteEvents(body)
+	if err != nil {
+		return Error(err)
+	}
+	return Ok(r)
+}
+
+// BulkExportPasteEvents 把选中的记录导出为 JSON 文件
+func (s *PasteService) BulkExportPasteEvents(body controller.PasteBulkBody) *Result {
+	if err := s.Biz.Ensure(); err != nil {
+		return Error(err)
+	}
+	dialog := application.SaveFileDialog()
+	dialog.CanCreateDirectories(true)
+	dialog.SetFilename("devboard_export_" + time.Now().Format("20060102150405") + ".json")
+	path, err := dialog.PromptForSingleSelection()
+	if err != nil {
+		return Error(err)
+	}
+	if path == "" {
+		return Ok(map[string]interface{}{
+			"cancel": true,
+		})
+	}
+	items, r, err := s.Biz.BulkExportPasteEvents(body)
+	if err != nil {
+		return Error(err)
+	}
+	content, err := json.MarshalIndent(map[string]interface{}{
+		"version":     1,
+		"exported_at": time.Now().UnixMilli(),
+		"list":        items,
+	}, "", "  ")
+	if err != nil {
+		return Error(err)
+	}
+	if err := os.WriteFile(path, content, 0644); err != nil {
+		return Error(err)
+	}
+	return Ok(r)
+}
+
 func (s *PasteService) GetPasteImageAsTempFile(body controller.PasteProfileBody) *Result {
 	if s.Biz.DB == nil {
 		return Error(fmt.Errorf("请先初始化数据库"))
@@ -232,3 +385,58 @@ func (s *PasteService) MockPasteText(body MockPasteTextBody) *Result {
 	s.App.Event.Emit("clipboard:update", created_paste_event)
 	return Ok(created_paste_event)
 }
+
+func (s *PasteService) FetchPasteEventActionList(body controller.PasteProfileBody) *Result {
+	if err := s.Biz.Ensure(); err != nil {
+		return Error(err)
+	}
+	list, err := s.Biz.FetchPasteEventActionList(body)
+	if err != nil {
+		return Error(err)
+	}
+	return Ok(list)
+}
+
+func (s *PasteService) RunPasteAction(body biz.PasteActionRunBody) *Result {
+	if err := s.Biz.Ensure(); err != nil {
+		return Error(err)
+	}
+	action, err := s.Biz.RunPasteAction(body)
+	if err != nil {
+		return Error(err)
+	}
+	return Ok(action)
+}
+
+func (s *PasteService) FetchSimilarPasteEventList(body controller.PasteEventBody) *Result {
+	if err := s.Biz.Ensure(); err != nil {
+		return Error(err)
+	}
+	list, err := s.Biz.ControllerMap.Paste.FetchSimilarPasteEventList(body)
+	if err != nil {
+		return Error(err)
+	}
+	return Ok(list)
+}
+
+func (s *PasteService) FetchPasteEventExtractList(body controller.PasteProfileBody) *Result {
+	if err := s.Biz.Ensure(); err != nil {
+		return Error(err)
+	}
+	list, err := s.Biz.FetchPasteEventExtractList(body)
+	if err != nil {
+		return Error(err)
+	}
+	return Ok(list)
+}
+
+func (s *PasteService) CreateExtractedPasteEvent(body biz.PasteExtractBody) *Result {
+	if err := s.Biz.Ensure(); err != nil {
+		return Error(err)
+	}
+	created, err := s.Biz.CreateExtractedPasteEvent(body)
+	if err != nil {
+		return Error(err)
+	}
+	return Ok(created)
+}
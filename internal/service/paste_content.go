@@ -0,0 +1,44 @@
+package service
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/wailsapp/wails/v3/pkg/application"
+	"gorm.io/gorm"
+
+	"devboard/internal/biz"
+)
+
+// PasteContentService 通过 /paste_content?id=xxx&type=image 读取记录的完整内容，列表中只返回摘要
+type PasteContentService struct {
+	App *application.App
+	Biz *biz.BizApp
+}
+
+func NewPasteContentService(app *application.App, biz *biz.BizApp) *PasteContentService {
+	return &PasteContentService{App: app, Biz: biz}
+}
+
+func (s *PasteContentService) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if err := s.Biz.Ensure(); err != nil {
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		return
+	}
+	q := r.URL.Query()
+	data, mime_type, err := s.Biz.ControllerMap.Paste.FetchPasteEventContent(q.Get("id"), q.Get("type"))
+	if err != nil {
+		status := http.StatusBadRequest
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			status = http.StatusNotFound
+		}
+		http.Error(w, err.Error(), status)
+		return
+	}
+	w.Header().Set("Content-Type", mime_type)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	// html 已经清理过，这里再禁止执行脚本
+	w.Header().Set("Content-Security-Policy", "default-src 'none'; img-src data: http: https:; style-src 'unsafe-inline'")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Write(data)
+}
@@ -51,3 +51,47 @@ func (s *RemarkService) DeleteRemark(body controller.RemarkDeleteBody) *Result {
 	}
 	return Ok(nil)
 }
+
+func (s *RemarkService) UpdateRemark(body controller.RemarkUpdateBody) *Result {
+	if err := s.Biz.Ensure(); err != nil {
+		return Error(err)
+	}
+	updated, err := s.Biz.ControllerMap.Remark.UpdateRemark(body)
+	if err != nil {
+		return Error(err)
+	}
+	return Ok(updated)
+}
+
+func (s *RemarkService) FetchRemarkRevisionList(body controller.RemarkRevisionListBody) *Result {
+	if err := s.Biz.Ensure(); err != nil {
+		return Error(err)
+	}
+	list, err := s.Biz.ControllerMap.Remark.FetchRemarkRevisionList(body)
+	if err != nil {
+		return Error(err)
+	}
+	return Ok(list)
+}
+
+// AddRemarkAttachments 文件路径可以通过 FileService.OpenFileDialog 选择
+func (s *RemarkService) AddRemarkAttachments(body controller.RemarkAttachBody) *Result {
+	if err := s.Biz.Ensure(); err != nil {
+		return Error(err)
+	}
+	list, err := s.Biz.ControllerMap.Remark.AddRemarkAttachments(body)
+	if err != nil {
+		return Error(err)
+	}
+	return Ok(list)
+}
+
+func (s *RemarkService) DeleteRemarkAttachment(body controller.RemarkAttachmentBody) *Result {
+	if err := s.Biz.Ensure(); err != nil {
+		return Error(err)
+	}
+	if err := s.Biz.ControllerMap.Remark.DeleteRemarkAttachment(body); err != nil {
+		return Error(err)
+	}
+	return Ok(nil)
+}
@@ -2,15 +2,13 @@ package service
 
 import (
 	"path"
-	"regexp"
-	"strconv"
-	"time"
 
 	"github.com/studio-b12/gowebdav"
 	"github.com/wailsapp/wails/v3/pkg/application"
 	"gorm.io/gorm"
 
 	"devboard/internal/biz"
+	"devboard/internal/controller"
 	"devboard/pkg/synchronizer"
 )
 
@@ -80,6 +78,12 @@ var tables = []synchronizer.TableSynchronizeSetting{{
 }, {
 	Name:        "app",
 	IdFieldName: "id",
+}, {
+	Name:        "transform_recipe",
+	IdFieldName: "id",
+}, {
+	Name:        "category_rule",
+	IdFieldName: "id",
 }}
 
 func local_to_remote(t synchronizer.TableSynchronizeSetting, root_dir string, db *gorm.DB, client *gowebdav.Client) *synchronizer.SynchronizeResult {
@@ -173,91 +177,13 @@ func WithTable(table string) func(db *gorm.DB) *gorm.DB {
 	}
 }
 
-func remote_to_local(t synchronizer.TableSynchronizeSetting, root_dir string, db *gorm.DB, client *gowebdav.Client) *synchronizer.SynchronizeResult {
+func remote_to_local(t synchronizer.TableSynchronizeSetting, root_dir string, db *gorm.DB, client *gowebdav.Client, html_profile string) *synchronizer.SynchronizeResult {
 	table_name := t.Name
 	// id_field_name := t.IdFieldName
 	local_client := synchronizer.NewDatabaseLocalClient(db, table_name)
 	remote_client := synchronizer.NewWebdavClient(client)
 	result := synchronizer.BuildRemoteToLocalTasks(t, root_dir, local_client, remote_client)
-	add_message := func(msg *synchronizer.SynchronizeMessage) {
-		result.Messages = append(result.Messages, msg)
-	}
-	log := func(content string) {
-		result.Logs = append(result.Logs, content)
-	}
-	time_pattern := `^\d{4}-\d{2}-\d{2}T\d{2}:\d{2}:\d{2}(\.\d+)?(Z|([+-]\d{2}:\d{2}))$`
-	// var timestamp_regex = regexp.MustCompile(`^[0-9]{8}$`)
-	for _, r := range result.RecordTasks {
-		// var d map[string]interface{}
-		// if err := json.Unmarshal([]byte(r.Content), &d); err != nil {
-		// 	continue
-		// }
-		log("[LOG]apply record task, the type is " + r.Type)
-		if r.Type == "create" {
-			r.Data["sync_status"] = 2
-			created_at_str, ok := r.Data["created_at"].(string)
-			if !ok {
-				continue
-			}
-			match, _ := regexp.MatchString(time_pattern, created_at_str)
-			if match {
-				t, err := time.Parse(time.RFC3339Nano, created_at_str)
-				if err != nil {
-					continue
-				}
-				r.Data["created_at"] = strconv.Itoa(int(t.UnixMilli()))
-			}
-			if err := db.Table(table_name).Create(r.Data).Error; err != nil {
-				log("[LOG]create record task failed, because " + err.Error())
-				continue
-			}
-		}
-		if r.Type == "update" {
-			r.Data["sync_status"] = 2
-			result := db.Table(table_name).Where("id = ?", r.Id).Updates(r.Data)
-			if result.Error != nil {
-				log("[ERROR]update record failed, because " + result.Error.Error())
-				add_message(&synchronizer.SynchronizeMessage{
-					Type:  synchronizer.SynchronizeMessageError,
-					Scope: "database",
-					Text:  result.Error.Error(),
-				})
-				continue
-			}
-			if result.RowsAffected == 0 {
-				log("[ERROR]update record failed, no matched record.")
-				// errors = append(errors, fmt.Errorf("未找到要更新的记录ID: %s", r.Id))
-				add_message(&synchronizer.SynchronizeMessage{
-					Type:  synchronizer.SynchronizeMessageError,
-					Scope: "database",
-					Text:  "",
-				})
-				continue
-			}
-			log("[ERROR]update record success, affected rows " + strconv.Itoa(int(result.RowsAffected)))
-		}
-		if r.Type == "to_draft" {
-			data := map[string]interface{}{"sync_status": 1}
-			if err := db.Table(table_name).Where("id = ?", r.Id).Updates(data).Error; err != nil {
-				log("[ERROR]update record sync status failed, " + err.Error())
-			}
-		}
-		if r.Type == "to_published" {
-			data := map[string]interface{}{"sync_status": 2}
-			if err := db.Table(table_name).Where("id = ?", r.Id).Updates(data).Error; err != nil {
-				log("[ERROR]update record sync status failed, " + err.Error())
-			}
-		}
-		// if r.Action == 3 {
-		// 	result := s.Biz.DB.Table(table_name).Where("id = ?", r.Id).Delete(nil)
-		// 	if result.Error != nil {
-		// 		return fmt.Errorf("删除记录失败: %v", result.Error)
-		// 	}
-		// 	if result.RowsAffected == 0 {
-		// 		return fmt.Errorf("未找到要删除的记录ID: %s", action.Id)
-		// 	}
-		// }
-	}
+	controller.ApplyRemoteRecordTasks(db, t, result, html_profile)
 	return result
 }
 
@@ -275,7 +201,7 @@ func (s *SynchronizeService) RemoteToLocal(body WebDavSyncConfigBody) *Result {
 			r := synchronizer.BuildRemoteToLocalTasks(t, body.RootDir, local_client, remote_client)
 			results[t.Name] = r
 		} else {
-			r := remote_to_local(t, body.RootDir, s.Biz.DB, client)
+			r := remote_to_local(t, body.RootDir, s.Biz.DB, client, s.Biz.HTMLProfile())
 			results[t.Name] = r
 		}
 	}
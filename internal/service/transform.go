@@ -0,0 +1,84 @@
+package service
+
+import (
+	"github.com/wailsapp/wails/v3/pkg/application"
+
+	"devboard/internal/biz"
+	"devboard/internal/controller"
+	"devboard/internal/transformer"
+)
+
+type TransformService struct {
+	App *application.App
+	Biz *biz.BizApp
+}
+
+func NewTransformService(app *application.App, biz *biz.BizApp) *TransformService {
+	return &TransformService{
+		App: app,
+		Biz: biz,
+	}
+}
+
+func (s *TransformService) FetchTransformList() *Result {
+	return Ok(transformer.TransformList())
+}
+
+type TransformPreviewBody struct {
+	Input string                   `json:"input"`
+	Steps []transformer.RecipeStep `json:"steps"`
+}
+
+// PreviewTransform 对任意文本执行转换，用于编辑组合时预览结果
+func (s *TransformService) PreviewTransform(body TransformPreviewBody) *Result {
+	output, err := transformer.RunRecipe(body.Steps, body.Input)
+	if err != nil {
+		return Error(err)
+	}
+	return Ok(map[string]interface{}{
+		"output": output,
+	})
+}
+
+func (s *TransformService) FetchRecipeList() *Result {
+	if err := s.Biz.Ensure(); err != nil {
+		return Error(err)
+	}
+	list, err := s.Biz.ControllerMap.Recipe.FetchRecipeList()
+	if err != nil {
+		return Error(err)
+	}
+	return Ok(list)
+}
+
+func (s *TransformService) SaveRecipe(body controller.RecipeSaveBody) *Result {
+	if err := s.Biz.Ensure(); err != nil {
+		return Error(err)
+	}
+	r, err := s.Biz.ControllerMap.Recipe.SaveRecipe(body)
+	if err != nil {
+		return Error(err)
+	}
+	return Ok(r)
+}
+
+func (s *TransformService) DeleteRecipe(body controller.RecipeBody) *Result {
+	if err := s.Biz.Ensure(); err != nil {
+		return Error(err)
+	}
+	if err := s.Biz.ControllerMap.Recipe.DeleteRecipe(body); err != nil {
+		return Error(err)
+	}
+	return Ok(nil)
+}
+
+func (s *TransformService) RunRecipe(body biz.RunRecipeBody) *Result {
+	if err := s.Biz.Ensure(); err != nil {
+		return Error(err)
+	}
+	r, err := s.Biz.RunRecipe(body)
+	if err != nil {
+		return Error(err)
+	}
+	return Ok(r)
+}
@@ -0,0 +1,103 @@
+package service
+
+import (
+	"time"
+
+	"github.com/wailsapp/wails/v3/pkg/application"
+
+	"devboard/internal/biz"
+	"devboard/internal/controller"
+)
+
+// TrashService 回收站，查看、恢复和永久删除已删除的记录和备注
+type TrashService struct {
+	App *application.App
+	Biz *biz.BizApp
+}
+
+func NewTrashService(app *application.App, biz *biz.BizApp) *TrashService {
+	return &TrashService{
+		App: app,
+		Biz: biz,
+	}
+}
+
+func (s *TrashService) FetchDeletedPasteEventList(body controller.PasteListBody) *Result {
+	if err := s.Biz.Ensure(); err != nil {
+		return Error(err)
+	}
+	list, err := s.Biz.ControllerMap.Paste.FetchDeletedPasteEventList(body)
+	if err != nil {
+		return Error(err)
+	}
+	return Ok(list)
+}
+
+func (s *TrashService) RestorePasteEvent(body controller.PasteEventBody) *Result {
+	if err := s.Biz.Ensure(); err != nil {
+		return Error(err)
+	}
+	restored, err := s.Biz.ControllerMap.Paste.RestorePasteEvent(body)
+	if err != nil {
+		return Error(err)
+	}
+	return Ok(restored)
+}
+
+func (s *TrashService) PurgePasteEvents(body controller.PastePurgeBody) *Result {
+	if err := s.Biz.Ensure(); err != nil {
+		return Error(err)
+	}
+	body.SyncedOnly = s.Biz.SyncEnabled()
+	r, err := s.Biz.ControllerMap.Paste.PurgePasteEvents(body)
+	if err != nil {
+		return Error(err)
+	}
+	return Ok(r)
+}
+
+func (s *TrashService) FetchDeletedRemarkList(body controller.RemarkListBody) *Result {
+	if err := s.Biz.Ensure(); err != nil {
+		return Error(err)
+	}
+	list, err := s.Biz.ControllerMap.Remark.FetchDeletedRemarkList(body)
+	if err != nil {
+		return Error(err)
+	}
+	return Ok(list)
+}
+
+func (s *TrashService) RestoreRemark(body controller.RemarkDeleteBody) *Result {
+	if err := s.Biz.Ensure(); err != nil {
+		return Error(err)
+	}
+	restored, err := s.Biz.ControllerMap.Remark.RestoreRemark(body)
+	if err != nil {
+		return Error(err)
+	}
+	return Ok(restored)
+}
+
+func (s *TrashService) PurgeRemarks(body controller.RemarkPurgeBody) *Result {
+	if err := s.Biz.Ensure(); err != nil {
+		return Error(err)
+	}
+	body.SyncedOnly = s.Biz.SyncEnabled()
+	r, err := s.Biz.ControllerMap.Remark.PurgeRemarks(body)
+	if err != nil {
+		return Error(err)
+	}
+	return Ok(r)
+}
+
+// EmptyTrash 立即清空回收站中的所有记录
+func (s *TrashService) EmptyTrash() *Result {
+	if err := s.Biz.Ensure(); err != nil {
+		return Error(err)
+	}
+	r, err := s.Biz.EmptyTrash(time.Now())
+	if err != nil {
+		return Error(err)
+	}
+	return Ok(r)
+}
@@ -0,0 +1,177 @@
+package transformer
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// 规则条件可以使用的字段
+const (
+	// CategoryRuleFieldText 正则匹配文本内容，文件记录为所有文件路径，每行一个
+	CategoryRuleFieldText = "text"
+	// CategoryRuleFieldApp 正则匹配复制时所在的应用名称
+	CategoryRuleFieldApp = "app"
+	// CategoryRuleFieldWindowTitle 正则匹配复制时的窗口标题
+	CategoryRuleFieldWindowTitle = "window_title"
+	// CategoryRuleFieldContentType 内容类型 text、html、image、file，多个值用逗号分隔
+	CategoryRuleFieldContentType = "content_type"
+	// CategoryRuleFieldLanguage 检测出的代码语言，多个值用逗号分隔，不区分大小写
+	CategoryRuleFieldLanguage = "language"
+	// CategoryRuleFieldURLDomain 文本中任意一个链接的域名，包括子域名，多个值用逗号分隔
+	CategoryRuleFieldURLDomain = "url_domain"
+)
+
+var CategoryRuleFields = []string{
+	CategoryRuleFieldText,
+	CategoryRuleFieldApp,
+	CategoryRuleFieldWindowTitle,
+	CategoryRuleFieldContentType,
+	CategoryRuleFieldLanguage,
+	CategoryRuleFieldURLDomain,
+}
+
+type CategoryRuleCondition struct {
+	Field string `json:"field"`
+	Value string `json:"value"`
+	// Negate 为 true 时条件不满足才算匹配
+	Negate bool `json:"negate"`
+}
+
+// CategoryRuleInput 用于匹配规则的记录信息
+type CategoryRuleInput struct {
+	Text        string
+	AppName     string
+	WindowTitle string
+	ContentType string
+	Language    string
+	// URLs 为空时从 Text 中提取
+	URLs []string
+}
+
+type compiled_condition struct {
+	field  string
+	negate bool
+	re     *regexp.Regexp
+	values []string
+}
+
+// CategoryRuleMatcher 编译后的规则条件，所有条件都满足时才匹配
+type CategoryRuleMatcher struct {
+	conditions []compiled_condition
+}
+
+func split_rule_values(value string) []string {
+	var values []string
+	for _, v := range strings.Split(value, ",") {
+		v = strings.TrimPrefix(strings.ToLower(strings.TrimSpace(v)), ".")
+		if v != "" {
+			values = append(values, v)
+		}
+	}
+	return values
+}
+
+// CompileCategoryRule 检查字段和正则表达式，至少需要一个条件
+func CompileCategoryRule(conditions []CategoryRuleCondition) (*CategoryRuleMatcher, error) {
+	if len(conditions) == 0 {
+		return nil, fmt.Errorf("The rule needs at least one condition")
+	}
+	m := &CategoryRuleMatcher{}
+	for _, c := range conditions {
+		cc := compiled_condition{
+			field:  c.Field,
+			negate: c.Negate,
+		}
+		switch c.Field {
+		case CategoryRuleFieldText, CategoryRuleFieldApp, CategoryRuleFieldWindowTitle:
+			if c.Value == "" {
+				return nil, fmt.Errorf("The value of %s can't be empty", c.Field)
+			}
+			re, err := regexp.Compile(c.Value)
+			if err != nil {
+				return nil, fmt.Errorf("Invalid regexp of %s, %v", c.Field, err)
+			}
+			cc.re = re
+		case CategoryRuleFieldContentType, CategoryRuleFieldLanguage, CategoryRuleFieldURLDomain:
+			cc.values = split_rule_values(c.Value)
+			if len(cc.values) == 0 {
+				return nil, fmt.Errorf("The value of %s can't be empty", c.Field)
+			}
+		default:
+			return nil, fmt.Errorf("Unknown field %s", c.Field)
+		}
+		m.conditions = append(m.conditions, cc)
+	}
+	return m, nil
+}
+
+func rule_url_hosts(input CategoryRuleInput) []string {
+	urls := input.URLs
+	if len(urls) == 0 {
+		for _, e := range ExtractEntities(input.Text) {
+			if e.Type == EntityURL {
+				urls = append(urls, e.Value)
+			}
+		}
+	}
+	var hosts []string
+	for _, u := range urls {
+		if parsed, err := url.Parse(u); err == nil && parsed.Hostname() != "" {
+			hosts = append(hosts, strings.ToLower(parsed.Hostname()))
+		}
+	}
+	return hosts
+}
+
+func (c *compiled_condition) match(input CategoryRuleInput, hosts func() []string) bool {
+	switch c.field {
+	case CategoryRuleFieldText:
+		return c.re.MatchString(input.Text)
+	case CategoryRuleFieldApp:
+		return input.AppName != "" && c.re.MatchString(input.AppName)
+	case CategoryRuleFieldWindowTitle:
+		return input.WindowTitle != "" && c.re.MatchString(input.WindowTitle)
+	case CategoryRuleFieldContentType:
+		for _, v := range c.values {
+			if v == strings.ToLower(input.ContentType) {
+				return true
+			}
+		}
+	case CategoryRuleFieldLanguage:
+		for _, v := range c.values {
+			if v == strings.ToLower(input.Language) {
+				return true
+			}
+		}
+	case CategoryRuleFieldURLDomain:
+		for _, host := range hosts() {
+			for _, d := range c.values {
+				if host == d || strings.HasSuffix(host, "."+d) {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+func (m *CategoryRuleMatcher) Match(input CategoryRuleInput) bool {
+	var hosts []string
+	hosts_loaded := false
+	lazy_hosts := func() []string {
+		if !hosts_loaded {
+			hosts = rule_url_hosts(input)
+			hosts_loaded = true
+		}
+		return hosts
+	}
+	for i := range m.conditions {
+		c := &m.conditions[i]
+		if c.match(input, lazy_hosts) == c.negate {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,60 @@
+package transformer_test
+
+import (
+	"testing"
+
+	"devboard/internal/transformer"
+)
+
+func TestCategoryRuleMatcher(t *testing.T) {
+	input := transformer.CategoryRuleInput{
+		Text:        "see https://docs.github.com/en/rest for the API",
+		AppName:     "Google Chrome",
+		WindowTitle: "REST API - GitHub Docs",
+		ContentType: "html",
+		Language:    "",
+	}
+	cases := []struct {
+		name       string
+		conditions []transformer.CategoryRuleCondition
+		want       bool
+	}{
+		{"domain", []transformer.CategoryRuleCondition{{Field: "url_domain", Value: "github.com"}}, true},
+		{"other domain", []transformer.CategoryRuleCondition{{Field: "url_domain", Value: "gitlab.com, example.com"}}, false},
+		{"all conditions", []transformer.CategoryRuleCondition{
+			{Field: "app", Value: "(?i)chrome"},
+			{Field: "content_type", Value: "text,html"},
+			{Field: "window_title", Value: "GitHub"},
+		}, true},
+		{"one condition fails", []transformer.CategoryRuleCondition{
+			{Field: "app", Value: "(?i)chrome"},
+			{Field: "content_type", Value: "image"},
+		}, false},
+		{"negate", []transformer.CategoryRuleCondition{{Field: "text", Value: "password", Negate: true}}, true},
+		{"language", []transformer.CategoryRuleCondition{{Field: "language", Value: "go"}}, false},
+	}
+	for _, c := range cases {
+		m, err := transformer.CompileCategoryRule(c.conditions)
+		if err != nil {
+			t.Fatalf("%s: %v", c.name, err)
+		}
+		if got := m.Match(input); got != c.want {
+			t.Errorf("%s: expected %v, got %v", c.name, c.want, got)
+		}
+	}
+	m, _ := transformer.CompileCategoryRule([]transformer.CategoryRuleCondition{{Field: "language", Value: "Go"}})
+	if !m.Match(transformer.CategoryRuleInput{Language: "go"}) {
+		t.Error("language should be case insensitive")
+	}
+	invalid := [][]transformer.CategoryRuleCondition{
+		nil,
+		{{Field: "text", Value: "("}},
+		{{Field: "color", Value: "red"}},
+		{{Field: "url_domain", Value: " , "}},
+	}
+	for _, conditions := range invalid {
+		if _, err := transformer.CompileCategoryRule(conditions); err == nil {
+			t.Errorf("%v: expected an error", conditions)
+		}
+	}
+}
@@ -0,0 +1,370 @@
+package transformer
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Color sRGB 颜色，R/G/B/A 都在 0-1 之间
+type Color struct {
+	R float64
+	G float64
+	B float64
+	A float64
+	// Name 为命名颜色时的名称
+	Name string
+}
+
+var hex_color_regexp = regexp.MustCompile(`^#([0-9a-fA-F]{3,4}|[0-9a-fA-F]{6}|[0-9a-fA-F]{8})$`)
+var color_function_regexp = regexp.MustCompile(`^(?i)(rgba?|hsla?|hwb)\(\s*([^()]*?)\s*\)$`)
+
+// 超过该长度的文本不认为是颜色
+const color_max_text_length = 64
+
+func clamp01(v float64) float64 {
+	return math.Max(0, math.Min(1, v))
+}
+
+func parse_hex_color(s string) (*Color, bool) {
+	if !hex_color_regexp.MatchString(s) {
+		return nil, false
+	}
+	h := s[1:]
+	if len(h) <= 4 {
+		var b strings.Builder
+		for _, c := range h {
+			b.WriteRune(c)
+			b.WriteRune(c)
+		}
+		h = b.String()
+	}
+	if len(h) == 6 {
+		h += "ff"
+	}
+	n, err := strconv.ParseUint(h, 16, 32)
+	if err != nil {
+		return nil, false
+	}
+	return &Color{
+		R: float64(n>>24&0xff) / 255,
+		G: float64(n>>16&0xff) / 255,
+		B: float64(n>>8&0xff) / 255,
+		A: float64(n&0xff) / 255,
+	}, true
+}
+
+// split_color_args 支持 rgb(1, 2, 3)、rgb(1 2 3 / 50%) 两种写法，返回分量和透明度
+func split_color_args(s string) ([]string, string, bool) {
+	alpha := ""
+	if i := strings.Index(s, "/"); i != -1 {
+		alpha = strings.TrimSpace(s[i+1:])
+		s = s[:i]
+	}
+	var parts []string
+	if strings.Contains(s, ",") {
+		for _, p := range strings.Split(s, ",") {
+			parts = append(parts, strings.TrimSpace(p))
+		}
+	} else {
+		parts = strings.Fields(s)
+	}
+	if len(parts) == 4 && alpha == "" {
+		alpha = parts[3]
+		parts = parts[:3]
+	}
+	if len(parts) != 3 {
+		return nil, "", false
+	}
+	return parts, alpha, true
+}
+
+// parse_number 解析数字，百分比时返回 v/100 * percent_base
+func parse_number(s string, percent_base float64) (float64, bool) {
+	if strings.HasSuffix(s, "%") {
+		v, err := strconv.ParseFloat(strings.TrimSuffix(s, "%"), 64)
+		if err != nil {
+			return 0, false
+		}
+		return v / 100 * percent_base, true
+	}
+	v, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0, false
+	}
+	return v, true
+}
+
+func parse_hue(s string) (float64, bool) {
+	units := []struct {
+		suffix string
+		ratio  float64
+	}{
+		{"deg", 1},
+		{"grad", 0.9},
+		{"rad", 180 / math.Pi},
+		{"turn", 360},
+	}
+	for _, u := range units {
+		if strings.HasSuffix(s, u.suffix) {
+			v, err := strconv.ParseFloat(strings.TrimSuffix(s, u.suffix), 64)
+			if err != nil {
+				return 0, false
+			}
+			return v * u.ratio, true
+		}
+	}
+	v, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0, false
+	}
+	return v, true
+}
+
+func normalize_hue(h float64) float64 {
+	h = math.Mod(h, 360)
+	if h < 0 {
+		h += 360
+	}
+	return h
+}
+
+func hsl_to_rgb(h float64, s float64, l float64) (float64, float64, float64) {
+	h = normalize_hue(h)
+	f := func(n float64) float64 {
+		k := math.Mod(n+h/30, 12)
+		a := s * math.Min(l, 1-l)
+		return l - a*math.Max(-1, math.Min(math.Min(k-3, 9-k), 1))
+	}
+	return f(0), f(8), f(4)
+}
+
+func hwb_to_rgb(h float64, w float64, b float64) (float64, float64, float64) {
+	if w+b >= 1 {
+		gray := w / (w + b)
+		return gray, gray, gray
+	}
+	r, g, bb := hsl_to_rgb(h, 1, 0.5)
+	scale := func(v float64) float64 {
+		return v*(1-w-b) + w
+	}
+	return scale(r), scale(g), scale(bb)
+}
+
+func parse_color_function(s string) (*Color, bool) {
+	m := color_function_regexp.FindStringSubmatch(s)
+	if m == nil {
+		return nil, false
+	}
+	name := strings.ToLower(m[1])
+	parts, alpha_text, ok := split_color_args(m[2])
+	if !ok {
+		return nil, false
+	}
+	c := &Color{A: 1}
+	if alpha_text != "" {
+		a, ok := parse_number(alpha_text, 1)
+		if !ok {
+			return nil, false
+		}
+		c.A = clamp01(a)
+	}
+	switch name {
+	case "rgb", "rgba":
+		values := make([]float64, 3)
+		for i, p := range parts {
+			v, ok := parse_number(p, 255)
+			if !ok {
+				return nil, false
+			}
+			values[i] = clamp01(v / 255)
+		}
+		c.R, c.G, c.B = values[0], values[1], values[2]
+	case "hsl", "hsla":
+		h, ok1 := parse_hue(parts[0])
+		sat, ok2 := parse_number(parts[1], 1)
+		light, ok3 := parse_number(parts[2], 1)
+		if !ok1 || !ok2 || !ok3 || !strings.HasSuffix(parts[1], "%") || !strings.HasSuffix(parts[2], "%") {
+			return nil, false
+		}
+		c.R, c.G, c.B = hsl_to_rgb(h, clamp01(sat), clamp01(light))
+	case "hwb":
+		h, ok1 := parse_hue(parts[0])
+		w, ok2 := parse_number(parts[1], 1)
+		b, ok3 := parse_number(parts[2], 1)
+		if !ok1 || !ok2 || !ok3 {
+			return nil, false
+		}
+		c.R, c.G, c.B = hwb_to_rgb(h, clamp01(w), clamp01(b))
+	}
+	return c, true
+}
+
+// ParseColor 解析整段文本为颜色，支持 hex、rgb()、rgba()、hsl()、hsla()、hwb() 和 CSS 命名颜色
+func ParseColor(text string) (*Color, bool) {
+	s := strings.TrimSpace(text)
+	if s == "" || len(s) > color_max_text_length {
+		return nil, false
+	}
+	s = strings.TrimSuffix(s, ";")
+	if strings.HasPrefix(s, "#") {
+		return parse_hex_color(s)
+	}
+	if c, ok := parse_color_function(s); ok {
+		return c, true
+	}
+	lower := strings.ToLower(s)
+	if lower == "transparent" {
+		return &Color{A: 0, Name: lower}, true
+	}
+	if hex, ok := named_colors[lower]; ok {
+		c, _ := parse_hex_color("#" + hex)
+		c.Name = lower
+		return c, true
+	}
+	return nil, false
+}
+
+func to_byte(v float64) int {
+	return int(math.Round(clamp01(v) * 255))
+}
+
+func format_float(v float64, precision int) string {
+	return strconv.FormatFloat(v, 'f', precision, 64)
+}
+
+func trim_float(v float64, precision int) string {
+	s := format_float(v, precision)
+	if strings.Contains(s, ".") {
+		s = strings.TrimRight(strings.TrimRight(s, "0"), ".")
+	}
+	return s
+}
+
+func (c *Color) Hex() string {
+	s := fmt.Sprintf("#%02x%02x%02x", to_byte(c.R), to_byte(c.G), to_byte(c.B))
+	if c.A < 1 {
+		s += fmt.Sprintf("%02x", to_byte(c.A))
+	}
+	return s
+}
+
+func (c *Color) RGB() string {
+	if c.A < 1 {
+		return fmt.Sprintf("rgba(%d, %d, %d, %s)", to_byte(c.R), to_byte(c.G), to_byte(c.B), trim_float(c.A, 3))
+	}
+	return fmt.Sprintf("rgb(%d, %d, %d)", to_byte(c.R), to_byte(c.G), to_byte(c.B))
+}
+
+// hue_and_extremes 返回色相以及 RGB 中的最大值、最小值
+func (c *Color) hue_and_extremes() (float64, float64, float64) {
+	max := math.Max(c.R, math.Max(c.G, c.B))
+	min := math.Min(c.R, math.Min(c.G, c.B))
+	d := max - min
+	h := 0.0
+	if d != 0 {
+		switch max {
+		case c.R:
+			h = math.Mod((c.G-c.B)/d, 6)
+		case c.G:
+			h = (c.B-c.R)/d + 2
+		default:
+			h = (c.R-c.G)/d + 4
+		}
+		h = normalize_hue(h * 60)
+	}
+	return h, max, min
+}
+
+func (c *Color) HSL() string {
+	h, max, min := c.hue_and_extremes()
+	l := (max + min) / 2
+	s := 0.0
+	if max != min {
+		s = (max - min) / (1 - math.Abs(2*l-1))
+	}
+	hs, ss, ls := trim_float(h, 1), trim_float(s*100, 1), trim_float(l*100, 1)
+	if c.A < 1 {
+		return fmt.Sprintf("hsla(%s, %s%%, %s%%, %s)", hs, ss, ls, trim_float(c.A, 3))
+	}
+	return fmt.Sprintf("hsl(%s, %s%%, %s%%)", hs, ss, ls)
+}
+
+func (c *Color) HWB() string {
+	h, max, min := c.hue_and_extremes()
+	s := fmt.Sprintf("hwb(%s %s%% %s%%", trim_float(h, 1), trim_float(min*100, 1), trim_float((1-max)*100, 1))
+	if c.A < 1 {
+		s += " / " + trim_float(c.A, 3)
+	}
+	return s + ")"
+}
+
+func srgb_to_linear(v float64) float64 {
+	if v <= 0.04045 {
+		return v / 12.92
+	}
+	return math.Pow((v+0.055)/1.055, 2.4)
+}
+
+// OKLCHValues 返回 OKLCH 的 L(0-1)、C、H(角度)
+func (c *Color) OKLCHValues() (float64, float64, float64) {
+	r, g, b := srgb_to_linear(c.R), srgb_to_linear(c.G), srgb_to_linear(c.B)
+	l := math.Cbrt(0.4122214708*r + 0.5363325363*g + 0.0514459929*b)
+	m := math.Cbrt(0.2119034982*r + 0.6806995451*g + 0.1073969566*b)
+	s := math.Cbrt(0.0883024619*r + 0.2817188376*g + 0.6299787005*b)
+	L := 0.2104542553*l + 0.7936177850*m - 0.0040720468*s
+	A := 1.9779984951*l - 2.4285922050*m + 0.4505937099*s
+	B := 0.0259040371*l + 0.7827717662*m - 0.8086757660*s
+	C := math.Sqrt(A*A + B*B)
+	H := 0.0
+	// 接近灰色时色相没有意义
+	if C > 0.0001 {
+		H = normalize_hue(math.Atan2(B, A) * 180 / math.Pi)
+	}
+	return L, C, H
+}
+
+func (c *Color) OKLCH() string {
+	l, ch, h := c.OKLCHValues()
+	s := fmt.Sprintf("oklch(%s%% %s %s", trim_float(l*100, 2), trim_float(ch, 4), trim_float(h, 2))
+	if c.A < 1 {
+		s += " / " + trim_float(c.A, 3)
+	}
+	return s + ")"
+}
+
+// RelativeLuminance WCAG 定义的相对亮度
+func (c *Color) RelativeLuminance() float64 {
+	return 0.2126*srgb_to_linear(c.R) + 0.7152*srgb_to_linear(c.G) + 0.0722*srgb_to_linear(c.B)
+}
+
+// ContrastRatio 与另一个颜色的对比度，范围 1-21，不考虑透明度
+func (c *Color) ContrastRatio(other *Color) float64 {
+	a, b := c.RelativeLuminance(), other.RelativeLuminance()
+	if a < b {
+		a, b = b, a
+	}
+	return math.Round((a+0.05)/(b+0.05)*100) / 100
+}
+
+// ColorFormats “复制为”支持的格式
+var ColorFormats = []string{"hex", "rgb", "hsl", "hwb", "oklch"}
+
+// Format 按名称输出颜色，名称见 ColorFormats
+func (c *Color) Format(format string) string {
+	switch format {
+	case "hex":
+		return c.Hex()
+	case "rgb":
+		return c.RGB()
+	case "hsl":
+		return c.HSL()
+	case "hwb":
+		return c.HWB()
+	case "oklch":
+		return c.OKLCH()
+	}
+	return ""
+}
@@ -0,0 +1,62 @@
+package transformer_test
+
+import (
+	"testing"
+
+	"devboard/internal/transformer"
+)
+
+func TestParseColor(t *testing.T) {
+	cases := map[string]string{
+		"#f80":                      "#ff8800",
+		"#FF8800":                   "#ff8800",
+		"#ff880080":                 "#ff880080",
+		"rgb(255, 136, 0)":          "#ff8800",
+		"rgb(255 136 0 / 50%)":      "#ff880080",
+		"rgba(100%, 0%, 0%, 0.5)":   "#ff000080",
+		"hsl(32, 100%, 50%)":        "#ff8800",
+		"hsla(120deg 100% 25% / 1)": "#008000",
+		"hwb(0 0% 0%)":              "#ff0000",
+		"hwb(0 50% 50%)":            "#808080",
+		"RebeccaPurple":             "#663399",
+		"transparent":               "#00000000",
+	}
+	for text, want := range cases {
+		c, ok := transformer.ParseColor(text)
+		if !ok {
+			t.Errorf("%q: not parsed", text)
+			continue
+		}
+		if got := c.Hex(); got != want {
+			t.Errorf("%q: expected %s, got %s", text, want, got)
+		}
+	}
+	for _, text := range []string{"#12", "rgb(1, 2)", "hsl(1, 2, 3)", "notacolor", "#ff8800 is orange"} {
+		if _, ok := transformer.ParseColor(text); ok {
+			t.Errorf("%q: should not be parsed as color", text)
+		}
+	}
+}
+
+func TestColorFormats(t *testing.T) {
+	c, _ := transformer.ParseColor("#ff0000")
+	cases := map[string]string{
+		"rgb":   "rgb(255, 0, 0)",
+		"hsl":   "hsl(0, 100%, 50%)",
+		"hwb":   "hwb(0 0% 0%)",
+		"oklch": "oklch(62.8% 0.2577 29.23)",
+	}
+	for format, want := range cases {
+		if got := c.Format(format); got != want {
+			t.Errorf("%s: expected %s, got %s", format, want, got)
+		}
+	}
+	white, _ := transformer.ParseColor("white")
+	black, _ := transformer.ParseColor("black")
+	if r := white.ContrastRatio(black); r != 21 {
+		t.Errorf("expected contrast 21, got %v", r)
+	}
+	if r := c.ContrastRatio(white); r != 4 {
+		t.Errorf("expected contrast 4, got %v", r)
+	}
+}
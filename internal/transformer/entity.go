@@ -0,0 +1,279 @@
+package transformer
+
+import (
+	"net"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	EntityURL      = "url"
+	EntityEmail    = "email"
+	EntityUUID     = "uuid"
+	EntityIP       = "ip"
+	EntityColor    = "color"
+	EntityCron     = "cron"
+	EntityFilePath = "file_path"
+	EntitySemver   = "semver"
+	EntityIssueKey = "issue_key"
+	EntityGitSHA   = "git_sha"
+	EntityPhone    = "phone"
+)
+
+// EntityTypes 按优先级排列，不同类型的实体重叠时保留优先级高的
+var EntityTypes = []string{
+	EntityURL,
+	EntityEmail,
+	EntityUUID,
+	EntityIP,
+	EntityColor,
+	EntityCron,
+	EntityFilePath,
+	EntitySemver,
+	EntityIssueKey,
+	EntityGitSHA,
+	EntityPhone,
+}
+
+// 超过该长度的部分不再提取
+const entity_max_text_length = 100000
+const entity_max_count = 500
+
+// Entity 文本中的一个实体，Start/End 为字符（rune）位置，左闭右开
+type Entity struct {
+	Type  string `json:"type"`
+	Value string `json:"value"`
+	Start int    `json:"start"`
+	End   int    `json:"end"`
+}
+
+type entity_matcher struct {
+	typ string
+	re  *regexp.Regexp
+	// group 取第几个分组作为实体，0 表示整个匹配
+	group int
+	// valid 返回 false 时丢弃该匹配
+	valid func(text string, value string, start int, end int) bool
+	// trim 去掉实体末尾的标点
+	trim string
+}
+
+var entity_matchers = []entity_matcher{
+	{
+		typ:  EntityURL,
+		re:   regexp.MustCompile("https?://[^\\s<>\"'`]+"),
+		trim: ".,;:!?)]}",
+	},
+	{
+		typ:  EntityEmail,
+		re:   regexp.MustCompile(`[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}`),
+		trim: ".",
+	},
+	{
+		typ: EntityUUID,
+		re:  regexp.MustCompile(`\b[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}\b`),
+	},
+	{
+		typ:   EntityIP,
+		re:    regexp.MustCompile(`\b(?:(?:25[0-5]|2[0-4]\d|1\d\d|[1-9]?\d)\.){3}(?:25[0-5]|2[0-4]\d|1\d\d|[1-9]?\d)(?:/(?:3[0-2]|[12]?\d))?\b`),
+		valid: not_inside_dotted_number,
+	},
+	{
+		typ: EntityIP,
+		re:  regexp.MustCompile(`(?:[0-9A-Fa-f]{0,4}:){2,7}[0-9A-Fa-f]{0,4}(?:/\d{1,3})?`),
+		valid: func(text string, value string, start int, end int) bool {
+			addr := value
+			if i := strings.Index(addr, "/"); i != -1 {
+				if _, _, err := net.ParseCIDR(value); err != nil {
+					return false
+				}
+				addr = addr[:i]
+			}
+			return strings.ContainsAny(addr, "0123456789abcdefABCDEF") && net.ParseIP(addr) != nil
+		},
+	},
+	{
+		typ: EntityColor,
+		re:  regexp.MustCompile(`(?i)\b(?:rgba?|hsla?)\(\s*[\d.]+(?:deg|%)?\s*[,\s]\s*[\d.]+%?\s*[,\s]\s*[\d.]+%?\s*(?:[,/]\s*[\d.]+%?\s*)?\)`),
+	},
+	{
+		typ: EntityColor,
+		re:  regexp.MustCompile(`#(?:[0-9a-fA-F]{8}|[0-9a-fA-F]{6}|[0-9a-fA-F]{3,4})\b`),
+		valid: func(text string, value string, start int, end int) bool {
+			if start > 0 && is_word_byte(text[start-1]) {
+				return false
+			}
+			// #123 更可能是 issue 编号
+			return len(value) > 5 || strings.ContainsAny(value, "abcdefABCDEF")
+		},
+	},
+	{
+		typ:   EntityCron,
+		re:    regexp.MustCompile(`(?m)^\s*((?:[\d*/,?LW#-]+|[A-Z]{3}(?:-[A-Z]{3})?)(?:[ \t]+(?:[\d*/,?LW#-]+|[A-Z]{3}(?:-[A-Z]{3})?)){4,5})(?:[ \t]|$)`),
+		group: 1,
+		valid: func(text string, value string, start int, end int) bool {
+			return strings.ContainsAny(value, "*/")
+		},
+	},
+	{
+		typ:   EntityCron,
+		re:    regexp.MustCompile(`(?m)(?:^|\s)(@(?:yearly|annually|monthly|weekly|daily|hourly|reboot))\b`),
+		group: 1,
+	},
+	{
+		typ:   EntityFilePath,
+		re:    regexp.MustCompile(`(?:^|[\s'"(=:])((?:~|\.{1,2})(?:/[\w.@+-]+)+/?|(?:/[\w.@+-]+){2,}/?)`),
+		group: 1,
+		trim:  ".,;:",
+	},
+	{
+		typ:  EntityFilePath,
+		re:   regexp.MustCompile(`\b[A-Za-z]:\\(?:[^\\/:*?"<>|\r\n\t ]+\\)*[^\\/:*?"<>|\r\n\t ]*`),
+		trim: ".,;:",
+	},
+	{
+		typ:   EntitySemver,
+		re:    regexp.MustCompile(`\bv?(?:0|[1-9]\d*)\.(?:0|[1-9]\d*)\.(?:0|[1-9]\d*)(?:-[0-9A-Za-z.-]+)?(?:\+[0-9A-Za-z.-]+)?\b`),
+		valid: not_inside_dotted_number,
+		trim:  ".-",
+	},
+	{
+		typ: EntityIssueKey,
+		re:  regexp.MustCompile(`\b[A-Z][A-Z0-9]{1,9}-[1-9]\d{0,6}\b`),
+		valid: func(text string, value string, start int, end int) bool {
+			prefix := value[:strings.Index(value, "-")]
+			return !issue_key_ignored_prefix[prefix]
+		},
+	},
+	{
+		typ: EntityGitSHA,
+		re:  regexp.MustCompile(`\b[0-9a-f]{7,40}\b`),
+		valid: func(text string, value string, start int, end int) bool {
+			if len(value) > 12 && len(value) != 40 {
+				return false
+			}
+			return strings.ContainsAny(value, "0123456789") && strings.ContainsAny(value, "abcdef")
+		},
+	},
+	{
+		typ: EntityPhone,
+		re:  regexp.MustCompile(`\+\d{1,3}[\s-]?(?:\(\d{1,4}\)[\s-]?)?\d{2,4}(?:[\s-]?\d{2,4}){1,3}|\(\d{2,4}\)\s?\d{3,4}[\s-]\d{4}|\b\d{3}[\s-]\d{3,4}[\s-]\d{4}\b|\b1[3-9]\d{9}\b`),
+		valid: func(text string, value string, start int, end int) bool {
+			n := 0
+			for _, r := range value {
+				if r >= '0' && r <= '9' {
+					n += 1
+				}
+			}
+			return n >= 7 && n <= 15
+		},
+	},
+}
+
+// 这些前缀一般是标准或算法名称，例如 UTF-8、SHA-256
+var issue_key_ignored_prefix = map[string]bool{
+	"UTF": true, "SHA": true, "ISO": true, "RFC": true, "AES": true, "RSA": true, "MD": true,
+	"TLS": true, "SSL": true, "HTTP": true, "ES": true, "IPV": true, "GB": true, "CVE": true,
+}
+
+func is_word_byte(b byte) bool {
+	return b == '_' || (b >= '0' && b <= '9') || (b >= 'a' && b <= 'z') || (b >= 'A' && b <= 'Z')
+}
+
+func is_digit_byte(b byte) bool {
+	return b >= '0' && b <= '9'
+}
+
+// not_inside_dotted_number 过滤 1.2.3.4.5 这类更长的点分数字中的一部分
+func not_inside_dotted_number(text string, value string, start int, end int) bool {
+	if start >= 2 && text[start-1] == '.' && is_digit_byte(text[start-2]) {
+		return false
+	}
+	if end+1 < len(text) && text[end] == '.' && is_digit_byte(text[end+1]) {
+		return false
+	}
+	return true
+}
+
+// ExtractEntities 找出文本中所有的实体，按出现位置排序
+func ExtractEntities(text string) []Entity {
+	if len(text) > entity_max_text_length {
+		// 截断的位置在一个字符中间时退回到这个字符的开头，最多退回 utf8.UTFMax-1 个字节
+		cut := entity_max_text_length
+		for cut > entity_max_text_length-utf8.UTFMax+1 && !utf8.RuneStart(text[cut]) {
+			cut -= 1
+		}
+		text = text[:cut]
+	}
+	type candidate struct {
+		typ        string
+		start, end int // 字节位置
+		priority   int
+	}
+	var candidates []candidate
+	priority := make(map[string]int)
+	for i, t := range EntityTypes {
+		priority[t] = i
+	}
+	for _, m := range entity_matchers {
+		for _, loc := range m.re.FindAllStringSubmatchIndex(text, -1) {
+			start, end := loc[2*m.group], loc[2*m.group+1]
+			if start < 0 {
+				continue
+			}
+			if m.trim != "" {
+				end = start + len(strings.TrimRight(text[start:end], m.trim))
+			}
+			if end <= start {
+				continue
+			}
+			if m.valid != nil && !m.valid(text, text[start:end], start, end) {
+				continue
+			}
+			candidates = append(candidates, candidate{typ: m.typ, start: start, end: end, priority: priority[m.typ]})
+		}
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		if candidates[i].priority != candidates[j].priority {
+			return candidates[i].priority < candidates[j].priority
+		}
+		return candidates[i].start < candidates[j].start
+	})
+	var accepted []candidate
+	for _, c := range candidates {
+		overlapped := false
+		for _, a := range accepted {
+			if c.start < a.end && a.start < c.end {
+				overlapped = true
+				break
+			}
+		}
+		if !overlapped {
+			accepted = append(accepted, c)
+		}
+	}
+	sort.Slice(accepted, func(i, j int) bool {
+		return accepted[i].start < accepted[j].start
+	})
+	if len(accepted) > entity_max_count {
+		accepted = accepted[:entity_max_count]
+	}
+	result := make([]Entity, 0, len(accepted))
+	// 把字节位置转换为字符位置，方便前端定位
+	offset, runes := 0, 0
+	for _, c := range accepted {
+		runes += utf8.RuneCountInString(text[offset:c.start])
+		start := runes
+		runes += utf8.RuneCountInString(text[c.start:c.end])
+		offset = c.end
+		result = append(result, Entity{
+			Type:  c.typ,
+			Value: text[c.start:c.end],
+			Start: start,
+			End:   runes,
+		})
+	}
+	return result
+}
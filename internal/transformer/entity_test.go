@@ -0,0 +1,74 @@
+package transformer_test
+
+import (
+	"strings"
+	"testing"
+
+	"devboard/internal/transformer"
+)
+
+func TestExtractEntities(t *testing.T) {
+	text := `部署 v1.4.2 到 10.0.0.12/24 和 fe80::1，回滚提交 3f2a9c1b
+联系 ops@example.com 或 +86 138-0000-0000，日志在 /var/log/app/error.log
+PROJ-123 修复了 https://example.com/issues?id=1). 颜色 rgb(12, 34, 56) 和 #ff8800
+请求 id 550e8400-e29b-41d4-a716-446655440000
+*/5 * * * * /usr/bin/backup.sh`
+	expected := []transformer.Entity{
+		{Type: "semver", Value: "v1.4.2"},
+		{Type: "ip", Value: "10.0.0.12/24"},
+		{Type: "ip", Value: "fe80::1"},
+		{Type: "git_sha", Value: "3f2a9c1b"},
+		{Type: "email", Value: "ops@example.com"},
+		{Type: "phone", Value: "+86 138-0000-0000"},
+		{Type: "file_path", Value: "/var/log/app/error.log"},
+		{Type: "issue_key", Value: "PROJ-123"},
+		{Type: "url", Value: "https://example.com/issues?id=1"},
+		{Type: "color", Value: "rgb(12, 34, 56)"},
+		{Type: "color", Value: "#ff8800"},
+		{Type: "uuid", Value: "550e8400-e29b-41d4-a716-446655440000"},
+		{Type: "cron", Value: "*/5 * * * *"},
+		{Type: "file_path", Value: "/usr/bin/backup.sh"},
+	}
+	entities := transformer.ExtractEntities(text)
+	if len(entities) != len(expected) {
+		t.Fatalf("expected %d entities, got %d: %v", len(expected), len(entities), entities)
+	}
+	runes := []rune(text)
+	for i, e := range entities {
+		if e.Type != expected[i].Type || e.Value != expected[i].Value {
+			t.Errorf("entity %d: expected %s %q, got %s %q", i, expected[i].Type, expected[i].Value, e.Type, e.Value)
+		}
+		if string(runes[e.Start:e.End]) != e.Value {
+			t.Errorf("entity %d: span [%d, %d) is %q, not %q", i, e.Start, e.End, string(runes[e.Start:e.End]), e.Value)
+		}
+	}
+}
+
+func TestExtractEntitiesIgnore(t *testing.T) {
+	for _, text := range []string{
+		"UTF-8 encoded",
+		"issue #123",
+		"version 1.2.3.4.5",
+		"timestamp 1700000000000",
+		"12:30:45",
+		"deadbeef",
+	} {
+		if entities := transformer.ExtractEntities(text); len(entities) != 0 {
+			t.Errorf("%q: expected no entities, got %v", text, entities)
+		}
+	}
+}
+
+func TestExtractEntitiesLongText(t *testing.T) {
+	// 开头有无效的字节，截断的位置在一个中文字符中间
+	text := "\xff see https://example.com/a " + strings.Repeat("中", 40000)
+	var urls []string
+	for _, e := range transformer.ExtractEntities(text) {
+		if e.Type == transformer.EntityURL {
+			urls = append(urls, e.Value)
+		}
+	}
+	if len(urls) != 1 || urls[0] != "https://example.com/a" {
+		t.Errorf("got urls %v", urls)
+	}
+}
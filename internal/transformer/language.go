@@ -0,0 +1,410 @@
+package transformer
+
+import (
+	"encoding/json"
+	"math"
+	"regexp"
+	"strings"
+)
+
+// language_rule 命中后为对应语言加分，Max 为最多累计的命中次数，默认 1 次
+type language_rule struct {
+	re     *regexp.Regexp
+	weight float64
+	max    int
+}
+
+func rule(pattern string, weight float64) language_rule {
+	return language_rule{re: regexp.MustCompile(pattern), weight: weight, max: 1}
+}
+
+func rule_n(pattern string, weight float64, max int) language_rule {
+	return language_rule{re: regexp.MustCompile(pattern), weight: weight, max: max}
+}
+
+type language_spec struct {
+	name  string
+	rules []language_rule
+	// base 为父语言，自身有得分时会加上父语言的得分，例如 TypeScript 是 JavaScript 的超集
+	base string
+}
+
+// 得分低于该值时认为不是代码
+const language_min_score = 2.5
+
+// 得分达到该值且没有接近的候选时，置信度为 1
+const language_full_score = 6.0
+
+var languages = []language_spec{
+	{
+		name: "Go",
+		rules: []language_rule{
+			rule(`(?m)^package \w+\s*$`, 3),
+			rule(`\w\s*:=\s*`, 1.5),
+			rule(`\bfunc (\(\w+ \*?\w+\) )?\w+\(`, 2),
+			rule(`\bfmt\.\w+\(`, 2),
+			rule(`\btype \w+ (struct|interface) \{`, 3),
+			rule(`\bif err != nil\b`, 3),
+			rule(`(?m)^import \($`, 2),
+			rule(`\bgo func\(|\bchan \w+|\bdefer \w+`, 1.5),
+		},
+	},
+	{
+		name: "Python",
+		rules: []language_rule{
+			rule(`(?m)^\s*def \w+\(.*\)\s*(->\s*[\w\[\], .]+)?:\s*$`, 3),
+			rule(`(?m)^\s*(from [\w.]+ )?import [\w., ]+$`, 1),
+			rule(`(?m)^\s*elif .*:\s*$`, 3),
+			rule(`(?m)^\s*class \w+(\(.*\))?:\s*$`, 3),
+			rule(`\bself\.\w+`, 2),
+			rule(`(?m)^\s*if __name__ == `, 3),
+			rule(`\b(None|True|False)\b`, 1),
+			rule(`'''|"""`, 1),
+			rule(`(?m)^\s*for \w+(, \w+)* in .+:\s*$`, 2),
+			rule(`\bprint\(`, 0.5),
+			rule_n(`(?m)[{;]\s*$`, -1, 3),
+		},
+	},
+	{
+		name: "Rust",
+		rules: []language_rule{
+			rule(`\bfn \w+(<[^>]*>)?\(`, 2.5),
+			rule(`\blet mut\b`, 3),
+			rule(`\b(println|format|vec|panic|macro_rules)!`, 3),
+			rule(`(?m)^\s*use \w+(::[\w{}, *]+)+;`, 2.5),
+			rule(`(?m)^\s*impl\b.*\{`, 2),
+			rule(`&str\b|&mut \w|\bOption<|\bResult<`, 2),
+			rule(`\bpub (fn|struct|enum|mod)\b`, 2),
+			rule(`(?m)^\s*#\[(derive|cfg|test)`, 3),
+			rule(`\)\s*->\s*[\w<>&]+\s*\{`, 1),
+		},
+	},
+	{
+		name: "Java",
+		rules: []language_rule{
+			rule(`\bpublic (final )?class \w+|\bpublic static void main\b`, 3),
+			rule(`\bSystem\.(out|err)\.print`, 3),
+			rule(`(?m)^import (java|javax|org|com)\.[\w.*]+;`, 3),
+			rule(`(?m)^package [\w.]+;`, 3),
+			rule(`(?m)^\s*(public|private|protected)\s+(static\s+)?(final\s+)?[\w<>\[\], ]+\s+\w+\s*\(`, 2),
+			rule(`@Override|@Autowired|@Test\b`, 2),
+			rule(`\bString\[\] \w+|\bnew \w+<.*>\(`, 1.5),
+		},
+	},
+	{
+		name: "Kotlin",
+		rules: []language_rule{
+			rule(`\bfun \w+(<[^>]*>)?\(`, 3),
+			rule(`\bval \w+\s*[:=]`, 1.5),
+			rule(`\bvar \w+\s*:\s*[A-Z]\w*`, 0.5),
+			rule(`\bdata class\b|\bcompanion object\b|\bwhen\s*(\(.*\))?\s*\{|\?\.let\b|(?m)^import kotlin`, 3),
+			rule(`(?m)^package \w+\.[\w.]+\s*$`, 2),
+			rule(`\bprintln\(`, 1.5),
+		},
+	},
+	{
+		name: "Swift",
+		rules: []language_rule{
+			rule(`(?m)^import (UIKit|SwiftUI|Foundation|Cocoa|Combine)\s*$`, 4),
+			rule(`\bfunc \w+\([^)]*\w+:\s*\[?\w`, 2),
+			rule(`\bguard\b.*\belse\b|\bif let\b|@State\b|@IBOutlet\b|@Published\b`, 3),
+			rule(`\bvar body: some View\b|: View\s*\{`, 3),
+			rule(`\)\s*->\s*[\w?\[\]]+\s*\{`, 1),
+			rule(`\blet \w+\s*(:\s*\w+)?\s*=`, 0.5),
+			rule(`\bprint\(`, 0.5),
+		},
+	},
+	{
+		name: "C",
+		rules: []language_rule{
+			rule(`#include\s*<\w+\.h>`, 3),
+			rule(`\b(printf|fprintf|malloc|free|sizeof|memset|strcpy)\(`, 1.5),
+			rule(`\bint main\s*\(`, 2),
+			rule(`(?m)^#(define|ifdef|ifndef|endif)\b`, 2),
+			rule(`\w->\w`, 1),
+			rule(`\btypedef struct\b`, 2),
+		},
+	},
+	{
+		name: "C++",
+		base: "C",
+		rules: []language_rule{
+			rule(`#include\s*<(iostream|vector|string|map|memory|algorithm|unordered_map)>`, 4),
+			rule(`\bstd::`, 3),
+			rule(`\bcout\s*<<|\bcin\s*>>`, 3),
+			rule(`\btemplate\s*<`, 3),
+			rule(`\busing namespace\b|(?m)^namespace \w+`, 3),
+			rule(`\bclass \w+\s*:\s*(public|private|protected)\s+\w+`, 3),
+		},
+	},
+	{
+		name: "SQL",
+		rules: []language_rule{
+			rule(`(?im)^\s*SELECT\s+[\w*,.()\s]+\s+FROM\s+\w+`, 3),
+			rule(`(?i)\bINSERT\s+INTO\s+\w+`, 3),
+			rule(`(?i)\bUPDATE\s+\w+\s+SET\s+\w+\s*=`, 3),
+			rule(`(?i)\bDELETE\s+FROM\s+\w+`, 3),
+			rule(`(?i)\bCREATE\s+(TABLE|INDEX|VIEW|DATABASE|UNIQUE INDEX)\b`, 3),
+			rule(`(?i)\b(ALTER|DROP)\s+TABLE\b`, 3),
+			rule(`\b(WHERE|JOIN|GROUP BY|ORDER BY|LIMIT|VALUES)\b`, 1),
+		},
+	},
+	{
+		name: "XML",
+		rules: []language_rule{
+			rule(`^\s*<\?xml`, 5),
+			rule(`\bxmlns(:\w+)?=`, 3),
+			rule(`<!\[CDATA\[`, 3),
+			rule(`(?s)^\s*<([\w:-]+)[^>]*>.*</([\w:-]+)>\s*$`, 1.5),
+			rule(`<\w+:\w+[\s>]`, 1.5),
+		},
+	},
+	{
+		name: "HTML",
+		rules: []language_rule{
+			rule(`(?i)<!doctype\s+html`, 5),
+			rule(`(?i)<html[\s>]`, 4),
+			rule(`(?i)<(div|span|p|a|body|head|ul|li|table|img|br|section|button|input)[\s>/]`, 2),
+			rule(`(?i)</(div|span|p|a|body|head|ul|li|table|section|button)>`, 1.5),
+			rule(`\sclass="`, 1),
+		},
+	},
+	{
+		name: "JavaScript",
+		rules: []language_rule{
+			rule(`\b(const|let|var)\s+\w+\s*=`, 1.5),
+			rule(`\bfunction\s*\w*\s*\(`, 2.5),
+			rule(`=>`, 1),
+			rule(`\bconsole\.(log|error|warn|info)\(`, 3),
+			rule(`\bdocument\.\w+|\bwindow\.\w+|\brequire\(['"]|\bmodule\.exports\b|\bexport (default|const|function|class)\b`, 2.5),
+			rule(`(?m)^\s*import .* from ['"]`, 1),
+			rule(`===|!==`, 2),
+			rule(`\.then\(|\bawait \w`, 0.5),
+		},
+	},
+	{
+		name: "TypeScript",
+		base: "JavaScript",
+		rules: []language_rule{
+			rule(`\binterface \w+\s*(extends [\w, <>]+)?\s*\{`, 2),
+			rule(`\btype \w+(<[^>]*>)?\s*=\s*`, 2.5),
+			rule(`\w\??:\s*(string|number|boolean|any|void|unknown|never)\b`, 2),
+			rule(`\b(public|private|readonly)\s+\w+\s*[:?]`, 1.5),
+			rule(`\bas (const|string|number|any|unknown)\b`, 2),
+		},
+	},
+	{
+		name: "React",
+		base: "JavaScript",
+		rules: []language_rule{
+			rule(`from ['"]react['"]`, 5),
+			rule(`<\w+[^>]*\bclassName=`, 3),
+			rule(`<\w+[^>]*\bstyle=\{\{`, 3),
+			rule(`\buse(State|Effect|Callback|Memo|Ref|Context)\(`, 3),
+			rule(`return \(\s*<`, 2),
+		},
+	},
+	{
+		name: "Vue",
+		rules: []language_rule{
+			rule(`from ['"]vue['"]`, 5),
+			rule(`<script\s+setup`, 5),
+			rule(`(?m)^<template>`, 3),
+			rule(`\bv-(if|for|model|show|bind|else)\b`, 3),
+			rule(`\s@click=`, 2),
+		},
+	},
+	{
+		name: "Shell",
+		rules: []language_rule{
+			rule(`^#!\s*/(usr/)?bin/(env\s+)?(ba|z)?sh\b`, 5),
+			rule_n(`(?m)^\s*(sudo|apt|apt-get|brew|npm|npx|yarn|pnpm|pip|pip3|cargo|git|docker|kubectl|curl|wget|cd|ls|mkdir|rm|cp|mv|chmod|chown|echo|source|tar|ssh) [^=\n]*$`, 1.5, 2),
+			rule(`(?m)^\s*go (get|install|run|build|mod|test) `, 1.5),
+			rule(`(?m)^\s*export \w+=`, 2),
+			rule(`\$\(`, 1.5),
+			rule(`(?m)^\s*(if \[\[? |fi$|done$|then$|esac$)`, 2),
+			rule(`\s\|\s*(grep|awk|sed|xargs|sort|uniq|head|tail|wc|jq)\b`, 2),
+			rule(`\s--?[a-z][\w-]*`, 0.5),
+		},
+	},
+	{
+		name: "Dockerfile",
+		rules: []language_rule{
+			rule(`(?m)^FROM\s+[\w./:@-]+(\s+AS\s+\w+)?\s*$`, 4),
+			rule_n(`(?m)^(RUN|CMD|ENTRYPOINT|COPY|ADD|WORKDIR|EXPOSE|ENV|ARG|LABEL|USER|VOLUME|HEALTHCHECK)\s`, 1, 3),
+		},
+	},
+	{
+		name: "YAML",
+		rules: []language_rule{
+			rule(`(?m)^---\s*$`, 1.5),
+			rule_n(`(?m)^\s*[\w.-]+:\s+[^\s;{,(][^\n;{]*$`, 0.6, 5),
+			rule_n(`(?m)^\s*[\w.-]+:\s*$`, 0.3, 3),
+			rule_n(`(?m)^\s*- [\w"'$]`, 0.5, 3),
+			rule_n(`(?m)[;{}(]\s*$`, -1, 3),
+			rule(`(?m)^\s*(def|class|if|for|while|else|elif|try|except)\b`, -2),
+		},
+	},
+	{
+		name: "TOML",
+		rules: []language_rule{
+			rule_n(`(?m)^\[[\w.-]+\]\s*$`, 2, 2),
+			rule(`(?m)^\[\[[\w.-]+\]\]\s*$`, 3),
+			rule_n(`(?m)^\s*[\w.-]+\s*=\s*("|'|\[|\{|\d|true\b|false\b)`, 0.8, 4),
+			rule_n(`(?m)[;{}(]\s*$`, -1, 3),
+		},
+	},
+	{
+		name: "CSS",
+		rules: []language_rule{
+			rule(`(?m)^\s*[.#][\w-]+[^{;\n]*\{`, 2),
+			rule_n(`(?m)^\s*[a-z-]+\s*:\s*[^;{\n]+;\s*$`, 0.7, 5),
+			rule(`@media\b|@import\b|@keyframes\b|@font-face\b`, 3),
+			rule(`\b\d+(px|rem|em|vh|vw)\b`, 1.5),
+			rule(`!important\b`, 2),
+			rule(`(?m)^\s*(body|html|div|span|a|p|h[1-6]|ul|li|button|input|:root)\s*[,{:.]`, 1.5),
+		},
+	},
+	{
+		name: "Markdown",
+		rules: []language_rule{
+			rule_n(`(?m)^#{1,6} \S`, 1, 3),
+			rule(`\[[^\]\n]+\]\([^)\n]+\)`, 2),
+			rule("(?m)^```", 3),
+			rule_n(`(?m)^\s*[-*+] \S`, 0.5, 3),
+			rule_n(`(?m)^\s*\d+\. \S`, 0.5, 2),
+			rule(`\*\*\w[^*\n]*\*\*`, 1),
+			rule(`(?m)^> \S`, 1),
+			rule(`(?m)^\|?\s*:?-{3,}:?\s*\|`, 2),
+		},
+	},
+}
+
+// LanguageScore 语言及其得分
+type LanguageScore struct {
+	Language string
+	Score    float64
+}
+
+func is_json_content(code string) bool {
+	s := strings.TrimSpace(code)
+	if !strings.HasPrefix(s, "{") && !strings.HasPrefix(s, "[") {
+		return false
+	}
+	return json.Valid([]byte(s))
+}
+
+var json_prefix_regexp = regexp.MustCompile(`^\{\s*"[a-zA-Z0-9_-]+"\s*:`)
+
+// ScoreCodeLanguages 计算每种语言的得分，按得分从高到低返回，不包含得分不大于 0 的语言
+func ScoreCodeLanguages(code string) []LanguageScore {
+	raw := make(map[string]float64)
+	for _, spec := range languages {
+		score := 0.0
+		for _, r := range spec.rules {
+			max := r.max
+			if max <= 0 {
+				max = 1
+			}
+			n := len(r.re.FindAllStringIndex(code, max))
+			score += r.weight * float64(n)
+		}
+		raw[spec.name] = score
+	}
+	var result []LanguageScore
+	for _, spec := range languages {
+		score := raw[spec.name]
+		if score > 0 && spec.base != "" && raw[spec.base] > 0 {
+			score += raw[spec.base]
+		}
+		if score > 0 {
+			result = append(result, LanguageScore{Language: spec.name, Score: score})
+		}
+	}
+	// 稳定排序，同分时保持 languages 中的顺序
+	for i := 1; i < len(result); i++ {
+		for j := i; j > 0 && result[j].Score > result[j-1].Score; j-- {
+			result[j], result[j-1] = result[j-1], result[j]
+		}
+	}
+	return result
+}
+
+// DetectCodeLanguageWithConfidence 检测代码语言，返回语言和 0-1 之间的置信度，不是代码时返回空字符串
+func DetectCodeLanguageWithConfidence(code string) (string, float64) {
+	if is_json_content(code) {
+		return "JSON", 1
+	}
+	if json_prefix_regexp.MatchString(strings.TrimSpace(code)) {
+		// 以 JSON 对象开头但不完整，例如被截断的内容
+		return "JSON", 0.8
+	}
+	scores := ScoreCodeLanguages(code)
+	if len(scores) == 0 || scores[0].Score < language_min_score {
+		return "", 0
+	}
+	best := scores[0].Score
+	second := 0.0
+	base := ""
+	for _, spec := range languages {
+		if spec.name == scores[0].Language {
+			base = spec.base
+		}
+	}
+	for _, s := range scores[1:] {
+		// 父语言的得分已经包含在最高分中，不作为竞争者
+		if s.Language != base {
+			second = s.Score
+			break
+		}
+	}
+	confidence := best / (best + second) * math.Min(1, best/language_full_score)
+	return scores[0].Language, math.Round(confidence*100) / 100
+}
+
+// DetectCodeLanguage 检测代码语言或框架类型
+func DetectCodeLanguage(code string) string {
+	lang, _ := DetectCodeLanguageWithConfidence(code)
+	return lang
+}
+
+// 代码块 class 中常见的语言名称，例如 language-js
+var language_hint_names = map[string][]string{
+	"Go":         {"go", "golang"},
+	"Python":     {"py", "python", "python3"},
+	"Rust":       {"rs", "rust"},
+	"Java":       {"java"},
+	"Kotlin":     {"kt", "kts", "kotlin"},
+	"Swift":      {"swift"},
+	"C":          {"c", "h"},
+	"C++":        {"cpp", "c++", "cc", "cxx", "hpp"},
+	"SQL":        {"sql", "mysql", "postgresql", "sqlite"},
+	"XML":        {"xml"},
+	"HTML":       {"html", "htm"},
+	"JavaScript": {"js", "javascript", "mjs", "cjs"},
+	"TypeScript": {"ts", "typescript"},
+	"React":      {"jsx", "tsx"},
+	"Vue":        {"vue"},
+	"Shell":      {"sh", "bash", "zsh", "shell", "console", "shell-session"},
+	"Dockerfile": {"dockerfile", "docker"},
+	"YAML":       {"yaml", "yml"},
+	"TOML":       {"toml"},
+	"CSS":        {"css", "scss", "less"},
+	"Markdown":   {"md", "markdown"},
+	"JSON":       {"json", "jsonc"},
+}
+
+var language_aliases = func() map[string]string {
+	m := make(map[string]string)
+	for name, hints := range language_hint_names {
+		for _, h := range hints {
+			m[h] = name
+		}
+	}
+	return m
+}()
+
+// LanguageFromHint 把代码块标注的语言转换为分类使用的语言名称，不认识时返回空字符串
+func LanguageFromHint(hint string) string {
+	return language_aliases[strings.ToLower(strings.TrimSpace(hint))]
+}
@@ -0,0 +1,68 @@
+package transformer_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"devboard/internal/transformer"
+)
+
+// testdata/languages 下每个目录名为期望的语言，none 目录中的内容不应被识别为代码
+func TestDetectCodeLanguageFixtures(t *testing.T) {
+	root := filepath.Join("testdata", "languages")
+	dirs, err := os.ReadDir(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	total := 0
+	failed := 0
+	for _, dir := range dirs {
+		if !dir.IsDir() {
+			continue
+		}
+		expected := dir.Name()
+		if expected == "none" {
+			expected = ""
+		}
+		files, err := os.ReadDir(filepath.Join(root, dir.Name()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, f := range files {
+			content, err := os.ReadFile(filepath.Join(root, dir.Name(), f.Name()))
+			if err != nil {
+				t.Fatal(err)
+			}
+			total += 1
+			lang, confidence := transformer.DetectCodeLanguageWithConfidence(string(content))
+			if lang != expected {
+				failed += 1
+				t.Errorf("%s/%s: expected %q, got %q (confidence %.2f), scores %v", dir.Name(), f.Name(), expected, lang, confidence, transformer.ScoreCodeLanguages(string(content)))
+				continue
+			}
+			if expected != "" && (confidence <= 0 || confidence > 1) {
+				t.Errorf("%s/%s: invalid confidence %.2f", dir.Name(), f.Name(), confidence)
+			}
+		}
+	}
+	if total == 0 {
+		t.Fatal("no fixtures found")
+	}
+	t.Logf("accuracy %d/%d", total-failed, total)
+}
+
+func TestDetectTextContent(t *testing.T) {
+	r := transformer.DetectTextContent("https://example.com")
+	if len(r.Categories) != 1 || r.Categories[0] != "url" || r.Language != "" {
+		t.Errorf("expected url, got %v", r)
+	}
+	r = transformer.DetectTextContent("SELECT id FROM paste_event WHERE id = 1")
+	if r.Language != "SQL" || len(r.Categories) != 2 || r.Categories[1] != "code" {
+		t.Errorf("expected SQL code, got %v", r)
+	}
+	r = transformer.DetectTextContent("items.map(x => x.id)")
+	if r.Language == "JavaScript" {
+		t.Errorf("a single arrow should not be enough to detect JavaScript")
+	}
+}
@@ -0,0 +1,258 @@
+package transformer
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ParsedTime 从文本中解析出的时间
+type ParsedTime struct {
+	Instant time.Time
+	// Format 原文本的格式，例如 unix_ms、RFC3339、relative
+	Format string
+	// HasZone 原文本是否带有时区，没有时按本地时区解析
+	HasZone bool
+}
+
+// 超过该长度的文本不认为是时间
+const time_max_text_length = 64
+
+type time_layout struct {
+	name     string
+	layout   string
+	has_zone bool
+}
+
+var time_layouts = []time_layout{
+	{"RFC3339", time.RFC3339Nano, true},
+	{"ISO8601", "2006-01-02T15:04:05.999999999", false},
+	{"ISO8601", "2006-01-02T15:04:05Z0700", true},
+	{"ISO8601", "2006-01-02T15:04:05.999999999Z0700", true},
+	{"RFC1123", time.RFC1123, true},
+	{"RFC1123Z", time.RFC1123Z, true},
+	{"RFC822", time.RFC822, true},
+	{"RFC822Z", time.RFC822Z, true},
+	{"RFC850", time.RFC850, true},
+	{"ANSIC", time.ANSIC, false},
+	{"UnixDate", time.UnixDate, true},
+	{"RubyDate", time.RubyDate, true},
+	{"GoString", "2006-01-02 15:04:05.999999999 -0700 MST", true},
+	{"DateTime", "2006-01-02 15:04:05.999999999", false},
+	{"DateTime", "2006-01-02 15:04:05.999999999 -0700", true},
+	{"DateTime", "2006-01-02 15:04:05.999999999Z07:00", true},
+	{"DateTime", "2006/01/02 15:04:05.999999999", false},
+	{"DateTime", "2006-01-02 15:04", false},
+	{"DateTime", "2006/01/02 15:04", false},
+	{"DateOnly", time.DateOnly, false},
+	{"DateOnly", "2006/01/02", false},
+	// Apache/Nginx 访问日志
+	{"CommonLog", "02/Jan/2006:15:04:05 -0700", true},
+	// syslog 没有年份
+	{"Syslog", time.StampMicro, false},
+	{"Syslog", time.Stamp, false},
+}
+
+var unix_timestamp_regexp = regexp.MustCompile(`^\d{10}(\d{3})?(\d{3})?(\d{3})?$`)
+var relative_ago_regexp = regexp.MustCompile(`^(\d+|a|an)\s+(second|sec|minute|min|hour|day|week|month|year)s?\s+ago$`)
+var relative_in_regexp = regexp.MustCompile(`^in\s+(\d+|a|an)\s+(second|sec|minute|min|hour|day|week|month|year)s?$`)
+var relative_offset_regexp = regexp.MustCompile(`^(now\s*)?([+-])\s*(\d+)\s*(s|m|h|d|w)$`)
+var relative_word_regexp = regexp.MustCompile(`^(last|next|this)\s+(week|month|year)$`)
+
+// 只接受 2000 到 2100 年之间的时间戳，避免把普通数字识别为时间
+var unix_timestamp_min = time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
+var unix_timestamp_max = time.Date(2100, 1, 1, 0, 0, 0, 0, time.UTC)
+
+func parse_unix_timestamp(s string) (*ParsedTime, bool) {
+	if !unix_timestamp_regexp.MatchString(s) {
+		return nil, false
+	}
+	n, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return nil, false
+	}
+	var t time.Time
+	var format string
+	switch len(s) {
+	case 10:
+		t, format = time.Unix(n, 0), "unix"
+	case 13:
+		t, format = time.UnixMilli(n), "unix_ms"
+	case 16:
+		t, format = time.UnixMicro(n), "unix_us"
+	default:
+		t, format = time.Unix(0, n), "unix_ns"
+	}
+	if t.Before(unix_timestamp_min) || t.After(unix_timestamp_max) {
+		return nil, false
+	}
+	return &ParsedTime{Instant: t, Format: format, HasZone: true}, true
+}
+
+func relative_amount(v string) int {
+	if v == "a" || v == "an" {
+		return 1
+	}
+	n, _ := strconv.Atoi(v)
+	return n
+}
+
+func add_relative(t time.Time, n int, unit string) time.Time {
+	switch unit {
+	case "second", "sec", "s":
+		return t.Add(time.Duration(n) * time.Second)
+	case "minute", "min", "m":
+		return t.Add(time.Duration(n) * time.Minute)
+	case "hour", "h":
+		return t.Add(time.Duration(n) * time.Hour)
+	case "day", "d":
+		return t.AddDate(0, 0, n)
+	case "week", "w":
+		return t.AddDate(0, 0, 7*n)
+	case "month":
+		return t.AddDate(0, n, 0)
+	case "year":
+		return t.AddDate(n, 0, 0)
+	}
+	return t
+}
+
+func parse_relative_time(s string, now time.Time) (*ParsedTime, bool) {
+	s = strings.ToLower(s)
+	relative := func(t time.Time) (*ParsedTime, bool) {
+		return &ParsedTime{Instant: t, Format: "relative", HasZone: false}, true
+	}
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	switch s {
+	case "now":
+		return relative(now)
+	case "today":
+		return relative(today)
+	case "yesterday":
+		return relative(today.AddDate(0, 0, -1))
+	case "tomorrow":
+		return relative(today.AddDate(0, 0, 1))
+	}
+	if m := relative_ago_regexp.FindStringSubmatch(s); m != nil {
+		return relative(add_relative(now, -relative_amount(m[1]), m[2]))
+	}
+	if m := relative_in_regexp.FindStringSubmatch(s); m != nil {
+		return relative(add_relative(now, relative_amount(m[1]), m[2]))
+	}
+	if m := relative_offset_regexp.FindStringSubmatch(s); m != nil {
+		n := relative_amount(m[3])
+		if m[2] == "-" {
+			n = -n
+		}
+		return relative(add_relative(now, n, m[4]))
+	}
+	if m := relative_word_regexp.FindStringSubmatch(s); m != nil {
+		n := 0
+		switch m[1] {
+		case "last":
+			n = -1
+		case "next":
+			n = 1
+		}
+		return relative(add_relative(today, n, m[2]))
+	}
+	return nil, false
+}
+
+// ParseTime 解析整段文本为时间，支持 Unix 时间戳（秒/毫秒/微秒/纳秒）、RFC3339、RFC1123、常见日志格式和相对时间
+func ParseTime(text string, now time.Time) (*ParsedTime, bool) {
+	s := strings.TrimSpace(text)
+	if s == "" || len(s) > time_max_text_length {
+		return nil, false
+	}
+	s = strings.Trim(s, "[]")
+	if t, ok := parse_unix_timestamp(s); ok {
+		return t, true
+	}
+	for _, l := range time_layouts {
+		var t time.Time
+		var err error
+		if l.has_zone {
+			t, err = time.Parse(l.layout, s)
+		} else {
+			t, err = time.ParseInLocation(l.layout, s, now.Location())
+		}
+		if err != nil {
+			continue
+		}
+		if l.name == "Syslog" {
+			t = time.Date(now.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), now.Location())
+			// 没有年份时，比当前时间晚的认为是去年的日志
+			if t.After(now.Add(24 * time.Hour)) {
+				t = t.AddDate(-1, 0, 0)
+			}
+		}
+		return &ParsedTime{Instant: t, Format: l.name, HasZone: l.has_zone}, true
+	}
+	return parse_relative_time(s, now)
+}
+
+// TimeFormats 可以在设置中选择的输出格式，也可以直接填写 Go 的 layout
+var TimeFormats = []string{"RFC3339", "RFC3339Nano", "RFC1123", "DateTime", "DateOnly", "Kitchen", "unix", "unix_ms", "unix_us", "unix_ns", "relative"}
+
+// FormatTime 按格式名称输出时间，未知的名称作为 Go layout 使用
+func FormatTime(t time.Time, format string, now time.Time) string {
+	switch format {
+	case "RFC3339":
+		return t.Format(time.RFC3339)
+	case "RFC3339Nano":
+		return t.Format(time.RFC3339Nano)
+	case "RFC1123":
+		return t.Format(time.RFC1123)
+	case "DateTime":
+		return t.Format(time.DateTime)
+	case "DateOnly":
+		return t.Format(time.DateOnly)
+	case "Kitchen":
+		return t.Format(time.Kitchen)
+	case "unix":
+		return strconv.FormatInt(t.Unix(), 10)
+	case "unix_ms":
+		return strconv.FormatInt(t.UnixMilli(), 10)
+	case "unix_us":
+		return strconv.FormatInt(t.UnixMicro(), 10)
+	case "unix_ns":
+		return strconv.FormatInt(t.UnixNano(), 10)
+	case "relative":
+		return FormatRelativeTime(t, now)
+	}
+	return t.Format(format)
+}
+
+// FormatRelativeTime 输出 3 hours ago、in 2 days 这样的相对时间
+func FormatRelativeTime(t time.Time, now time.Time) string {
+	d := now.Sub(t)
+	future := d < 0
+	d = time.Duration(math.Abs(float64(d)))
+	var n int64
+	var unit string
+	switch {
+	case d < time.Minute:
+		return "just now"
+	case d < time.Hour:
+		n, unit = int64(d/time.Minute), "minute"
+	case d < 24*time.Hour:
+		n, unit = int64(d/time.Hour), "hour"
+	case d < 30*24*time.Hour:
+		n, unit = int64(d/(24*time.Hour)), "day"
+	case d < 365*24*time.Hour:
+		n, unit = int64(d/(30*24*time.Hour)), "month"
+	default:
+		n, unit = int64(d/(365*24*time.Hour)), "year"
+	}
+	if n > 1 {
+		unit += "s"
+	}
+	if future {
+		return fmt.Sprintf("in %d %s", n, unit)
+	}
+	return fmt.Sprintf("%d %s ago", n, unit)
+}
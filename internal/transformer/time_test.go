@@ -0,0 +1,63 @@
+package transformer_test
+
+import (
+	"testing"
+	"time"
+
+	"devboard/internal/transformer"
+)
+
+func TestParseTime(t *testing.T) {
+	now := time.Date(2025, 10, 13, 12, 0, 0, 0, time.UTC)
+	expected := time.Date(2025, 10, 12, 8, 30, 15, 0, time.UTC)
+	cases := []struct {
+		text   string
+		format string
+		want   time.Time
+	}{
+		{"1760257815", "unix", expected},
+		{"1760257815000", "unix_ms", expected},
+		{"1760257815000000", "unix_us", expected},
+		{"1760257815000000000", "unix_ns", expected},
+		{"2025-10-12T08:30:15Z", "RFC3339", expected},
+		{"2025-10-12T16:30:15.000000+08:00", "RFC3339", expected},
+		{"Sun, 12 Oct 2025 08:30:15 UTC", "RFC1123", expected},
+		{"[12/Oct/2025:16:30:15 +0800]", "CommonLog", expected},
+		{"2025-10-12 08:30:15", "DateTime", expected},
+		{"Oct 12 08:30:15", "Syslog", expected},
+		{"3 hours ago", "relative", now.Add(-3 * time.Hour)},
+		{"in 2 days", "relative", now.AddDate(0, 0, 2)},
+		{"yesterday", "relative", time.Date(2025, 10, 12, 0, 0, 0, 0, time.UTC)},
+	}
+	for _, c := range cases {
+		r, ok := transformer.ParseTime(c.text, now)
+		if !ok {
+			t.Errorf("%q: not parsed", c.text)
+			continue
+		}
+		if r.Format != c.format || !r.Instant.Equal(c.want) {
+			t.Errorf("%q: expected %s %v, got %s %v", c.text, c.format, c.want, r.Format, r.Instant)
+		}
+	}
+	for _, text := range []string{"12345", "9999999999999", "hello", "13800000000"} {
+		if _, ok := transformer.ParseTime(text, now); ok {
+			t.Errorf("%q: should not be parsed as time", text)
+		}
+	}
+}
+
+func TestFormatTime(t *testing.T) {
+	now := time.Date(2025, 10, 13, 12, 0, 0, 0, time.UTC)
+	v := time.Date(2025, 10, 12, 8, 30, 15, 0, time.UTC)
+	cases := map[string]string{
+		"RFC3339":    "2025-10-12T08:30:15Z",
+		"unix_ms":    "1760257815000",
+		"relative":   "1 day ago",
+		"2006/01/02": "2025/10/12",
+	}
+	for format, want := range cases {
+		if got := transformer.FormatTime(v, format, now); got != want {
+			t.Errorf("%s: expected %q, got %q", format, want, got)
+		}
+	}
+}
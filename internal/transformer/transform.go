@@ -0,0 +1,118 @@
+package transformer
+
+import (
+	"fmt"
+	"sort"
+)
+
+// TransformOption 转换可接受的参数
+type TransformOption struct {
+	Key          string   `json:"key"`
+	Label        string   `json:"label"`
+	DefaultValue string   `json:"default_value"`
+	Choices      []string `json:"choices,omitempty"`
+}
+
+// Transform 一个纯函数形式的文本转换
+type Transform struct {
+	Id          string            `json:"id"`
+	Name        string            `json:"name"`
+	Group       string            `json:"group"`
+	Description string            `json:"description"`
+	Options     []TransformOption `json:"options,omitempty"`
+
+	Fn func(input string, options map[string]string) (string, error) `json:"-"`
+	// Validate 保存组合转换时检查参数，可以为空
+	Validate func(options map[string]string) error `json:"-"`
+}
+
+// RecipeStep 组合转换中的一步
+type RecipeStep struct {
+	TransformId string            `json:"transform_id"`
+	Options     map[string]string `json:"options,omitempty"`
+}
+
+var transform_map = make(map[string]*Transform)
+
+// RegisterTransform 注册转换，id 重复时覆盖
+func RegisterTransform(t *Transform) {
+	transform_map[t.Id] = t
+}
+
+func FindTransform(id string) (*Transform, bool) {
+	t, ok := transform_map[id]
+	return t, ok
+}
+
+// TransformList 按分组和 id 排序后的所有转换
+func TransformList() []*Transform {
+	list := make([]*Transform, 0, len(transform_map))
+	for _, t := range transform_map {
+		list = append(list, t)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].Group != list[j].Group {
+			return list[i].Group < list[j].Group
+		}
+		return list[i].Id < list[j].Id
+	})
+	return list
+}
+
+func (t *Transform) options_with_default(options map[string]string) map[string]string {
+	result := make(map[string]string)
+	for _, o := range t.Options {
+		result[o.Key] = o.DefaultValue
+	}
+	for k, v := range options {
+		if v != "" {
+			result[k] = v
+		}
+	}
+	return result
+}
+
+// ApplyTransform 执行单个转换，未传的参数使用默认值
+func ApplyTransform(id string, input string, options map[string]string) (string, error) {
+	t, ok := FindTransform(id)
+	if !ok {
+		return "", fmt.Errorf("Transform %s not found", id)
+	}
+	return t.Fn(input, t.options_with_default(options))
+}
+
+// RunRecipe 依次执行每一步，上一步的输出作为下一步的输入
+func RunRecipe(steps []RecipeStep, input string) (string, error) {
+	if len(steps) == 0 {
+		return "", fmt.Errorf("The recipe has no steps")
+	}
+	output := input
+	for i, step := range steps {
+		r, err := ApplyTransform(step.TransformId, output, step.Options)
+		if err != nil {
+			return "", fmt.Errorf("step %d (%s) failed, %v", i+1, step.TransformId, err)
+		}
+		output = r
+	}
+	return output, nil
+}
+
+// ValidateRecipe 检查每一步的转换是否存在以及参数是否有效
+func ValidateRecipe(steps []RecipeStep) error {
+	if len(steps) == 0 {
+		return fmt.Errorf("The recipe has no steps")
+	}
+	for i, step := range steps {
+		t, ok := FindTransform(step.TransformId)
+		if !ok {
+			return fmt.Errorf("step %d, transform %s not found", i+1, step.TransformId)
+		}
+		if t.Validate == nil {
+			continue
+		}
+		if err := t.Validate(t.options_with_default(step.Options)); err != nil {
+			return fmt.Errorf("step %d (%s), %v", i+1, step.TransformId, err)
+		}
+	}
+	return nil
+}
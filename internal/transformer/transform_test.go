@@ -0,0 +1,88 @@
+package transformer_test
+
+import (
+	"testing"
+
+	"devboard/internal/transformer"
+)
+
+func TestValidateRecipe(t *testing.T) {
+	cases := []struct {
+		name  string
+		steps []transformer.RecipeStep
+		ok    bool
+	}{
+		{"empty", nil, false},
+		{"unknown transform", []transformer.RecipeStep{{TransformId: "not_exists"}}, false},
+		{"default indent", []transformer.RecipeStep{{TransformId: "json_pretty"}}, true},
+		{"zero indent", []transformer.RecipeStep{{TransformId: "json_pretty", Options: map[string]string{"indent": "0"}}}, true},
+		{"max indent", []transformer.RecipeStep{{TransformId: "json_pretty", Options: map[string]string{"indent": "8"}}}, true},
+		{"tab indent", []transformer.RecipeStep{{TransformId: "json_pretty", Options: map[string]string{"indent": "tab"}}}, true},
+		{"negative indent", []transformer.RecipeStep{{TransformId: "json_pretty", Options: map[string]string{"indent": "-1"}}}, false},
+		{"huge indent", []transformer.RecipeStep{{TransformId: "json_pretty", Options: map[string]string{"indent": "100000000"}}}, false},
+		{"invalid indent", []transformer.RecipeStep{{TransformId: "json_pretty", Options: map[string]string{"indent": "two"}}}, false},
+		{"invalid second step", []transformer.RecipeStep{
+			{TransformId: "json_minify"},
+			{TransformId: "json_pretty", Options: map[string]string{"indent": "9"}},
+		}, false},
+	}
+	for _, c := range cases {
+		err := transformer.ValidateRecipe(c.steps)
+		if (err == nil) != c.ok {
+			t.Errorf("%s: got error %v, want ok %v", c.name, err, c.ok)
+		}
+	}
+}
+
+func TestApplyTransformJSONPretty(t *testing.T) {
+	cases := []struct {
+		indent string
+		want   string
+		ok     bool
+	}{
+		{"", "{\n  \"a\": 1\n}", true},
+		{"4", "{\n    \"a\": 1\n}", true},
+		{"tab", "{\n\t\"a\": 1\n}", true},
+		{"-2", "", false},
+		{"9", "", false},
+	}
+	for _, c := range cases {
+		got, err := transformer.ApplyTransform("json_pretty", `{"a":1}`, map[string]string{"indent": c.indent})
+		if (err == nil) != c.ok {
+			t.Errorf("indent %q: got error %v, want ok %v", c.indent, err, c.ok)
+			continue
+		}
+		if got != c.want {
+			t.Errorf("indent %q: got %q, want %q", c.indent, got, c.want)
+		}
+	}
+}
+
+func TestRunRecipe(t *testing.T) {
+	got, err := transformer.RunRecipe([]transformer.RecipeStep{
+		{TransformId: "json_minify"},
+		{TransformId: "base64_encode"},
+	}, "{ \"a\": 1 }")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != "eyJhIjoxfQ==" {
+		t.Errorf("got %q", got)
+	}
+	if _, err := transformer.RunRecipe([]transformer.RecipeStep{{TransformId: "json_minify"}}, "not json"); err == nil {
+		t.Errorf("expected an error for invalid json")
+	}
+}
+
+func TestTransformList(t *testing.T) {
+	list := transformer.TransformList()
+	if len(list) == 0 {
+		t.Fatal("no transforms registered")
+	}
+	for i := 1; i < len(list); i++ {
+		a, b := list[i-1], list[i]
+		if a.Group > b.Group || a.Group == b.Group && a.Id >= b.Id {
+			t.Errorf("%s/%s should be after %s/%s", a.Group, a.Id, b.Group, b.Id)
+		}
+	}
+}
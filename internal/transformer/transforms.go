@@ -0,0 +1,431 @@
+package transformer
+
+import (
+	"bytes"
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"html"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+func simple(id string, name string, group string, description string, fn func(input string) (string, error)) *Transform {
+	return &Transform{
+		Id:          id,
+		Name:        name,
+		Group:       group,
+		Description: description,
+		Fn: func(input string, options map[string]string) (string, error) {
+			return fn(input)
+		},
+	}
+}
+
+// json_max_indent 缩进的最大空格数
+const json_max_indent = 8
+
+// json_indent 缩进只能是 0 到 8 个空格或 tab
+func json_indent(value string) (string, error) {
+	if value == "tab" {
+		return "\t", nil
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 0 || n > json_max_indent {
+		return "", fmt.Errorf("indent must be 0-%d or tab, got %q", json_max_indent, value)
+	}
+	return strings.Repeat(" ", n), nil
+}
+
+func validate_json_pretty(options map[string]string) error {
+	_, err := json_indent(options["indent"])
+	return err
+}
+
+func json_pretty(input string, options map[string]string) (string, error) {
+	indent, err := json_indent(options["indent"])
+	if err != nil {
+		return "", err
+	}
+	var buf bytes.Buffer
+	if err := json.Indent(&buf, []byte(strings.TrimSpace(input)), "", indent); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+func json_minify(input string) (string, error) {
+	var buf bytes.Buffer
+	if err := json.Compact(&buf, []byte(strings.TrimSpace(input))); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+func base64_decode(input string) (string, error) {
+	s := strings.TrimSpace(input)
+	encodings := []*base64.Encoding{base64.StdEncoding, base64.URLEncoding, base64.RawStdEncoding, base64.RawURLEncoding}
+	for _, enc := range encodings {
+		if b, err := enc.DecodeString(s); err == nil {
+			return string(b), nil
+		}
+	}
+	return "", fmt.Errorf("invalid base64 content")
+}
+
+var camel_boundary_regexp = regexp.MustCompile(`([a-z0-9])([A-Z])|([A-Z]+)([A-Z][a-z])`)
+
+// split_words 把 fooBar、foo_bar、foo-bar、Foo Bar 等拆分为小写单词
+func split_words(input string) []string {
+	s := camel_boundary_regexp.ReplaceAllString(input, "${1}${3} ${2}${4}")
+	words := strings.FieldsFunc(s, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	for i, w := range words {
+		words[i] = strings.ToLower(w)
+	}
+	return words
+}
+
+// map_lines 对每一行分别执行转换，多行文本时常用于批量修改变量名
+func map_lines(input string, fn func(line string) string) string {
+	lines := strings.Split(input, "\n")
+	for i, line := range lines {
+		lines[i] = fn(line)
+	}
+	return strings.Join(lines, "\n")
+}
+
+func to_camel_case(line string) string {
+	words := split_words(line)
+	for i := 1; i < len(words); i++ {
+		r := []rune(words[i])
+		r[0] = unicode.ToUpper(r[0])
+		words[i] = string(r)
+	}
+	return strings.Join(words, "")
+}
+
+func to_pascal_case(line string) string {
+	words := split_words(line)
+	for i := range words {
+		r := []rune(words[i])
+		r[0] = unicode.ToUpper(r[0])
+		words[i] = string(r)
+	}
+	return strings.Join(words, "")
+}
+
+func hash_hex(sum []byte) string {
+	return hex.EncodeToString(sum)
+}
+
+func timestamp_to_date(input string, options map[string]string) (string, error) {
+	s := strings.TrimSpace(input)
+	n, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return "", fmt.Errorf("invalid timestamp")
+	}
+	var t time.Time
+	switch {
+	case len(s) >= 19:
+		t = time.Unix(0, n)
+	case len(s) >= 16:
+		t = time.UnixMicro(n)
+	case len(s) >= 12:
+		t = time.UnixMilli(n)
+	default:
+		t = time.Unix(n, 0)
+	}
+	if tz := options["timezone"]; tz != "" && tz != "Local" {
+		loc, err := time.LoadLocation(tz)
+		if err != nil {
+			return "", err
+		}
+		t = t.In(loc)
+	}
+	layout := options["layout"]
+	switch layout {
+	case "", "RFC3339":
+		layout = time.RFC3339
+	case "RFC1123":
+		layout = time.RFC1123
+	case "DateTime":
+		layout = time.DateTime
+	}
+	return t.Format(layout), nil
+}
+
+func date_to_timestamp(input string, options map[string]string) (string, error) {
+	s := strings.TrimSpace(input)
+	layouts := []string{time.RFC3339Nano, time.RFC3339, time.RFC1123Z, time.RFC1123, time.DateTime, time.DateOnly}
+	for _, layout := range layouts {
+		t, err := time.ParseInLocation(layout, s, time.Local)
+		if err != nil {
+			continue
+		}
+		if options["unit"] == "ms" {
+			return strconv.FormatInt(t.UnixMilli(), 10), nil
+		}
+		return strconv.FormatInt(t.Unix(), 10), nil
+	}
+	return "", fmt.Errorf("unsupported date format")
+}
+
+func jwt_decode(input string) (string, error) {
+	token := strings.TrimSpace(input)
+	token = strings.TrimPrefix(token, "Bearer ")
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return "", fmt.Errorf("invalid jwt")
+	}
+	decode := func(part string) (interface{}, error) {
+		b, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(part, "="))
+		if err != nil {
+			return nil, err
+		}
+		var v interface{}
+		if err := json.Unmarshal(b, &v); err != nil {
+			return nil, err
+		}
+		return v, nil
+	}
+	header, err := decode(parts[0])
+	if err != nil {
+		return "", fmt.Errorf("invalid jwt header, %v", err)
+	}
+	payload, err := decode(parts[1])
+	if err != nil {
+		return "", fmt.Errorf("invalid jwt payload, %v", err)
+	}
+	b, err := json.MarshalIndent(map[string]interface{}{
+		"header":  header,
+		"payload": payload,
+	}, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+func sort_lines(input string, options map[string]string) (string, error) {
+	lines := strings.Split(input, "\n")
+	if options["case_insensitive"] == "true" {
+		sort.SliceStable(lines, func(i, j int) bool {
+			return strings.ToLower(lines[i]) < strings.ToLower(lines[j])
+		})
+	} else {
+		sort.Strings(lines)
+	}
+	if options["order"] == "desc" {
+		for i, j := 0, len(lines)-1; i < j; i, j = i+1, j-1 {
+			lines[i], lines[j] = lines[j], lines[i]
+		}
+	}
+	return strings.Join(lines, "\n"), nil
+}
+
+func unique_lines(input string) (string, error) {
+	seen := make(map[string]bool)
+	var result []string
+	for _, line := range strings.Split(input, "\n") {
+		if seen[line] {
+			continue
+		}
+		seen[line] = true
+		result = append(result, line)
+	}
+	return strings.Join(result, "\n"), nil
+}
+
+func trim_lines(input string) (string, error) {
+	lines := strings.Split(strings.TrimSpace(input), "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimRightFunc(line, unicode.IsSpace)
+	}
+	return strings.Join(lines, "\n"), nil
+}
+
+func escape_string(input string, options map[string]string) (string, error) {
+	switch options["language"] {
+	case "json", "javascript":
+		b, err := json.Marshal(input)
+		if err != nil {
+			return "", err
+		}
+		return string(b), nil
+	case "go":
+		return strconv.Quote(input), nil
+	case "sql":
+		return "'" + strings.ReplaceAll(input, "'", "''") + "'", nil
+	case "shell":
+		return "'" + strings.ReplaceAll(input, "'", `'\''`) + "'", nil
+	case "regexp":
+		return regexp.QuoteMeta(input), nil
+	case "html":
+		return html.EscapeString(input), nil
+	case "csv":
+		if strings.ContainsAny(input, ",\"\n\r") {
+			return `"` + strings.ReplaceAll(input, `"`, `""`) + `"`, nil
+		}
+		return input, nil
+	}
+	return "", fmt.Errorf("unsupported language %s", options["language"])
+}
+
+func unescape_string(input string, options map[string]string) (string, error) {
+	s := strings.TrimSpace(input)
+	switch options["language"] {
+	case "json", "javascript":
+		if !strings.HasPrefix(s, `"`) {
+			s = `"` + s + `"`
+		}
+		var v string
+		if err := json.Unmarshal([]byte(s), &v); err != nil {
+			return "", err
+		}
+		return v, nil
+	case "go":
+		if !strings.HasPrefix(s, `"`) && !strings.HasPrefix(s, "`") {
+			s = `"` + s + `"`
+		}
+		return strconv.Unquote(s)
+	case "html":
+		return html.UnescapeString(input), nil
+	}
+	return "", fmt.Errorf("unsupported language %s", options["language"])
+}
+
+var escape_languages = []string{"json", "javascript", "go", "sql", "shell", "regexp", "html", "csv"}
+
+func init() {
+	transforms := []*Transform{
+		{
+			Id:          "json_pretty",
+			Name:        "JSON Pretty",
+			Group:       "json",
+			Description: "Format JSON with indentation",
+			Options: []TransformOption{
+				{Key: "indent", Label: "Indent", DefaultValue: "2", Choices: []string{"2", "4", "tab"}},
+			},
+			Fn:       json_pretty,
+			Validate: validate_json_pretty,
+		},
+		simple("json_minify", "JSON Minify", "json", "Remove whitespace from JSON", json_minify),
+		simple("base64_encode", "Base64 Encode", "encoding", "Encode text as base64", func(input string) (string, error) {
+			return base64.StdEncoding.EncodeToString([]byte(input)), nil
+		}),
+		simple("base64_decode", "Base64 Decode", "encoding", "Decode standard or url-safe base64", base64_decode),
+		simple("url_encode", "URL Encode", "encoding", "Escape text for use in a query string", func(input string) (string, error) {
+			return url.QueryEscape(input), nil
+		}),
+		simple("url_decode", "URL Decode", "encoding", "Unescape percent-encoded text", url.QueryUnescape),
+		simple("html_escape", "HTML Entity Encode", "encoding", "Escape <, >, &, ' and \"", func(input string) (string, error) {
+			return html.EscapeString(input), nil
+		}),
+		simple("html_unescape", "HTML Entity Decode", "encoding", "Unescape HTML entities such as &amp;lt;", func(input string) (string, error) {
+			return html.UnescapeString(input), nil
+		}),
+		simple("camel_case", "camelCase", "case", "Convert each line to camelCase", func(input string) (string, error) {
+			return map_lines(input, to_camel_case), nil
+		}),
+		simple("pascal_case", "PascalCase", "case", "Convert each line to PascalCase", func(input string) (string, error) {
+			return map_lines(input, to_pascal_case), nil
+		}),
+		simple("snake_case", "snake_case", "case", "Convert each line to snake_case", func(input string) (string, error) {
+			return map_lines(input, func(line string) string {
+				return strings.Join(split_words(line), "_")
+			}), nil
+		}),
+		simple("kebab_case", "kebab-case", "case", "Convert each line to kebab-case", func(input string) (string, error) {
+			return map_lines(input, func(line string) string {
+				return strings.Join(split_words(line), "-")
+			}), nil
+		}),
+		simple("upper_case", "UPPER CASE", "case", "Convert to upper case", func(input string) (string, error) {
+			return strings.ToUpper(input), nil
+		}),
+		simple("lower_case", "lower case", "case", "Convert to lower case", func(input string) (string, error) {
+			return strings.ToLower(input), nil
+		}),
+		simple("md5", "MD5", "hash", "MD5 hex digest", func(input string) (string, error) {
+			sum := md5.Sum([]byte(input))
+			return hash_hex(sum[:]), nil
+		}),
+		simple("sha1", "SHA-1", "hash", "SHA-1 hex digest", func(input string) (string, error) {
+			sum := sha1.Sum([]byte(input))
+			return hash_hex(sum[:]), nil
+		}),
+		simple("sha256", "SHA-256", "hash", "SHA-256 hex digest", func(input string) (string, error) {
+			sum := sha256.Sum256([]byte(input))
+			return hash_hex(sum[:]), nil
+		}),
+		{
+			Id:          "timestamp_to_date",
+			Name:        "Timestamp to Date",
+			Group:       "time",
+			Description: "Convert a unix timestamp in seconds, milliseconds, microseconds or nanoseconds to a date",
+			Options: []TransformOption{
+				{Key: "timezone", Label: "Time zone", DefaultValue: "Local"},
+				{Key: "layout", Label: "Layout", DefaultValue: "RFC3339", Choices: []string{"RFC3339", "RFC1123", "DateTime"}},
+			},
+			Fn: timestamp_to_date,
+		},
+		{
+			Id:          "date_to_timestamp",
+			Name:        "Date to Timestamp",
+			Group:       "time",
+			Description: "Convert a date to a unix timestamp",
+			Options: []TransformOption{
+				{Key: "unit", Label: "Unit", DefaultValue: "s", Choices: []string{"s", "ms"}},
+			},
+			Fn: date_to_timestamp,
+		},
+		simple("jwt_decode", "JWT Decode", "encoding", "Decode the header and payload of a JWT without verifying it", jwt_decode),
+		{
+			Id:          "sort_lines",
+			Name:        "Sort Lines",
+			Group:       "lines",
+			Description: "Sort lines alphabetically",
+			Options: []TransformOption{
+				{Key: "order", Label: "Order", DefaultValue: "asc", Choices: []string{"asc", "desc"}},
+				{Key: "case_insensitive", Label: "Case insensitive", DefaultValue: "false", Choices: []string{"true", "false"}},
+			},
+			Fn: sort_lines,
+		},
+		simple("unique_lines", "Unique Lines", "lines", "Remove duplicated lines and keep the first one", unique_lines),
+		simple("trim", "Trim", "lines", "Remove leading and trailing whitespace and trailing spaces of each line", trim_lines),
+		{
+			Id:          "escape",
+			Name:        "Escape String",
+			Group:       "escape",
+			Description: "Escape text as a string literal of the chosen language",
+			Options: []TransformOption{
+				{Key: "language", Label: "Language", DefaultValue: "json", Choices: escape_languages},
+			},
+			Fn: escape_string,
+		},
+		{
+			Id:          "unescape",
+			Name:        "Unescape String",
+			Group:       "escape",
+			Description: "Unescape a string literal of the chosen language",
+			Options: []TransformOption{
+				{Key: "language", Label: "Language", DefaultValue: "json", Choices: []string{"json", "javascript", "go", "html"}},
+			},
+			Fn: unescape_string,
+		},
+	}
+	for _, t := range transforms {
+		RegisterTransform(t)
+	}
+}
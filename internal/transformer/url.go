@@ -0,0 +1,142 @@
+package transformer
+
+import (
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// URLCleanRule 删除链接中的跟踪参数
+type URLCleanRule struct {
+	// Domains 规则生效的域名，包括子域名，为空时对所有链接生效
+	Domains []string `json:"domains"`
+	// Params 要删除的参数名，以 * 结尾时按前缀匹配，例如 utm_*
+	Params []string `json:"params"`
+}
+
+// DefaultURLCleanRules 内置的跟踪参数
+var DefaultURLCleanRules = []URLCleanRule{
+	{
+		Params: []string{
+			"utm_*", "fbclid", "gclid", "gclsrc", "dclid", "gbraid", "wbraid", "msclkid", "yclid",
+			"mc_cid", "mc_eid", "igshid", "_hsenc", "_hsmi", "mkt_tok", "vero_id", "spm", "_ga", "_gl",
+		},
+	},
+	{
+		Domains: []string{"taobao.com", "tmall.com", "1688.com", "aliexpress.com", "alibaba.com"},
+		Params:  []string{"scm", "pvid", "algo_expid", "algo_pvid", "ns", "abbucket"},
+	},
+	{
+		Domains: []string{"bilibili.com"},
+		Params:  []string{"spm_id_from", "vd_source", "from_spmid", "share_*", "unique_k"},
+	},
+	{
+		Domains: []string{"youtube.com", "youtu.be"},
+		Params:  []string{"si", "feature"},
+	},
+	{
+		Domains: []string{"twitter.com", "x.com"},
+		Params:  []string{"s", "t", "ref_src"},
+	},
+}
+
+type URLNormalizeOptions struct {
+	Rules []URLCleanRule
+	// SortQuery 按参数名排序，参数顺序不同的链接视为同一个
+	SortQuery bool
+}
+
+func url_rule_match_domain(rule URLCleanRule, host string) bool {
+	if len(rule.Domains) == 0 {
+		return true
+	}
+	for _, d := range rule.Domains {
+		d = strings.TrimPrefix(strings.ToLower(d), ".")
+		if host == d || strings.HasSuffix(host, "."+d) {
+			return true
+		}
+	}
+	return false
+}
+
+func url_param_match(pattern string, name string) bool {
+	pattern = strings.ToLower(pattern)
+	name = strings.ToLower(name)
+	if strings.HasSuffix(pattern, "*") {
+		return strings.HasPrefix(name, strings.TrimSuffix(pattern, "*"))
+	}
+	return pattern == name
+}
+
+// NormalizeURL 删除跟踪参数、小写域名、去掉默认端口，可选对参数排序
+// 不是 http/https 链接时原样返回 false
+func NormalizeURL(raw string, opts URLNormalizeOptions) (string, bool) {
+	text := strings.TrimSpace(raw)
+	if strings.ContainsAny(text, " \t\r\n") {
+		return raw, false
+	}
+	u, err := url.Parse(text)
+	if err != nil || u.Host == "" || u.Opaque != "" {
+		return raw, false
+	}
+	scheme := strings.ToLower(u.Scheme)
+	if scheme != "http" && scheme != "https" {
+		return raw, false
+	}
+	u.Scheme = scheme
+	host := strings.ToLower(u.Hostname())
+	port := u.Port()
+	if (scheme == "http" && port == "80") || (scheme == "https" && port == "443") {
+		port = ""
+	}
+	u.Host = host
+	if strings.Contains(host, ":") {
+		// IPv6 地址需要加上方括号
+		u.Host = "[" + host + "]"
+	}
+	if port != "" {
+		u.Host += ":" + port
+	}
+	var patterns []string
+	for _, rule := range opts.Rules {
+		if url_rule_match_domain(rule, host) {
+			patterns = append(patterns, rule.Params...)
+		}
+	}
+	if u.RawQuery != "" {
+		// 逐个处理原始参数，保留参数原本的编码方式
+		var kept []string
+		for _, pair := range strings.Split(u.RawQuery, "&") {
+			if pair == "" {
+				continue
+			}
+			name := pair
+			if i := strings.Index(pair, "="); i != -1 {
+				name = pair[:i]
+			}
+			if n, err := url.QueryUnescape(name); err == nil {
+				name = n
+			}
+			removed := false
+			for _, p := range patterns {
+				if url_param_match(p, name) {
+					removed = true
+					break
+				}
+			}
+			if !removed {
+				kept = append(kept, pair)
+			}
+		}
+		if opts.SortQuery {
+			sort.SliceStable(kept, func(i, j int) bool {
+				a, _, _ := strings.Cut(kept[i], "=")
+				b, _, _ := strings.Cut(kept[j], "=")
+				return a < b
+			})
+		}
+		u.RawQuery = strings.Join(kept, "&")
+	}
+	u.ForceQuery = false
+	return u.String(), true
+}
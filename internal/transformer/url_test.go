@@ -0,0 +1,37 @@
+package transformer_test
+
+import (
+	"testing"
+
+	"devboard/internal/transformer"
+)
+
+func TestNormalizeURL(t *testing.T) {
+	opts := transformer.URLNormalizeOptions{
+		Rules:     transformer.DefaultURLCleanRules,
+		SortQuery: true,
+	}
+	cases := map[string]string{
+		"https://Example.COM:443/a?utm_source=x&b=2&a=1&fbclid=abc":   "https://example.com/a?a=1&b=2",
+		"http://example.com:80/?gclid=1":                              "http://example.com/",
+		"https://item.taobao.com/item.htm?id=1&spm=a21&scm=1007":      "https://item.taobao.com/item.htm?id=1",
+		"https://www.bilibili.com/video/BV1?spm_id_from=333&p=2":      "https://www.bilibili.com/video/BV1?p=2",
+		"https://example.com/search?q=a%20b&UTM_Medium=email#section": "https://example.com/search?q=a%20b#section",
+		"https://x.com/user/status/1?s=20&t=abc":                      "https://x.com/user/status/1",
+		"https://example.com/path?s=20":                               "https://example.com/path?s=20",
+	}
+	for raw, want := range cases {
+		got, ok := transformer.NormalizeURL(raw, opts)
+		if !ok || got != want {
+			t.Errorf("%s: expected %s, got %s", raw, want, got)
+		}
+	}
+	if got, _ := transformer.NormalizeURL("https://example.com/?b=1&a=2", transformer.URLNormalizeOptions{}); got != "https://example.com/?b=1&a=2" {
+		t.Errorf("query order should be kept, got %s", got)
+	}
+	for _, raw := range []string{"hello world", "mailto:a@b.com", "ftp://example.com/a", "https://a.com b"} {
+		if _, ok := transformer.NormalizeURL(raw, opts); ok {
+			t.Errorf("%q: should not be normalized", raw)
+		}
+	}
+}
@@ -114,12 +114,18 @@ func main() {
 	app.RegisterService(application.NewService(service.NewPasteService(app, biz)))
 	app.RegisterService(application.NewService(service.NewCategoryService(app, biz)))
 	app.RegisterService(application.NewService(service.NewRemarkService(app, biz)))
+	app.RegisterService(application.NewService(service.NewTrashService(app, biz)))
+	app.RegisterService(application.NewService(service.NewArchiveService(app, biz)))
 	app.RegisterService(application.NewService(service.NewSynchronizeService(app, biz)))
 	app.RegisterService(application.NewService(service.NewSystemService(app, biz)))
 	app.RegisterService(application.NewService(service.NewCommonService(app, biz)))
+	app.RegisterService(application.NewService(service.NewLLMService(app, biz)))
+	app.RegisterService(application.NewService(service.NewTransformService(app, biz)))
 	app.RegisterService(application.NewService(&service.DouyinService{App: app, Biz: biz}))
 	app.RegisterService(application.NewService(&service.ConfigService{App: app, Biz: biz}))
 	app.RegisterService(application.NewServiceWithOptions(&service.FileService{App: app}, application.ServiceOptions{Route: "/file"}))
+	app.RegisterService(application.NewServiceWithOptions(service.NewBlobService(app, biz), application.ServiceOptions{Route: "/blob"}))
+	app.RegisterService(application.NewServiceWithOptions(service.NewPasteContentService(app, biz), application.ServiceOptions{Route: "/paste_content"}))
 	fmt.Println("[LOG][Before Ready]service register is completed")
 
 	go func() {
@@ -151,6 +157,9 @@ func main() {
 			return
 		}
 		db.Seed(database, machine_id)
+		if err := db.RunDataMigrations(database); err != nil {
+			fmt.Println("[ERROR]", err.Error())
+		}
 
 		// Create a new window with the necessary options.
 		// 'Title' is the title of the window.
@@ -362,6 +371,7 @@ func main() {
 			SetMachineId(machine_id).
 			InitializeControllerMap().
 			InitializeUserConfig(cfg).
+			StartBackgroundJobs().
 			SetMainWindow(win).
 			SetReady()
 
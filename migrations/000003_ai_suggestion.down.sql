@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS paste_event_category_suggestion;
+ALTER TABLE paste_event DROP COLUMN title;
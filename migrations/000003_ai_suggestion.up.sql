@@ -0,0 +1,19 @@
+ALTER TABLE paste_event ADD COLUMN title TEXT; --标题，由 LLM 自动生成或用户修改
+
+--LLM 推荐的分类，由用户决定接受或拒绝
+CREATE TABLE IF NOT EXISTS paste_event_category_suggestion (
+  id TEXT NOT NULL PRIMARY KEY,
+  paste_event_id TEXT NOT NULL,
+  category_id TEXT NOT NULL,
+  source TEXT NOT NULL DEFAULT 'llm', --推荐来源
+  status INTEGER NOT NULL DEFAULT 1, --1待处理 2已接受 3已拒绝
+  last_operation_time TEXT NOT NULL DEFAULT (strftime('%s','now') * 1000), --最后一次操作的时间
+  last_operation_type INTEGER NOT NULL DEFAULT 1, --最后一次操作的类型 1新增 2编辑 3删除
+  sync_status INTEGER NOT NULL DEFAULT 1, --1未同步 2已同步
+  created_at TEXT NOT NULL DEFAULT (strftime('%s','now') * 1000), -- 创建时间
+  updated_at TEXT,
+  deleted_at TIMESTAMP,
+  FOREIGN KEY (paste_event_id) REFERENCES paste_event(id) ON DELETE CASCADE,
+  FOREIGN KEY (category_id) REFERENCES category_node(id) ON DELETE CASCADE,
+  UNIQUE (paste_event_id, category_id)
+);
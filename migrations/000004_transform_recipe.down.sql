@@ -0,0 +1 @@
+DROP TABLE IF EXISTS transform_recipe;
@@ -0,0 +1,13 @@
+--用户保存的组合转换
+CREATE TABLE IF NOT EXISTS transform_recipe (
+  id TEXT NOT NULL PRIMARY KEY,
+  name TEXT NOT NULL,
+  description TEXT,
+  steps_json TEXT NOT NULL DEFAULT '[]', --转换步骤，JSON 数组
+  last_operation_time TEXT NOT NULL DEFAULT (strftime('%s','now') * 1000), --最后一次操作的时间
+  last_operation_type INTEGER NOT NULL DEFAULT 1, --最后一次操作的类型 1新增 2编辑 3删除
+  sync_status INTEGER NOT NULL DEFAULT 1, --1未同步 2已同步
+  created_at TEXT NOT NULL DEFAULT (strftime('%s','now') * 1000), -- 创建时间
+  updated_at TEXT,
+  deleted_at TIMESTAMP
+);
@@ -0,0 +1,3 @@
+DELETE FROM paste_event_category_mapping WHERE category_id IN ('YAML', 'TOML', 'Shell', 'Dockerfile', 'CSS', 'Markdown', 'C', 'C++', 'Kotlin', 'Swift', 'React', 'Vue');
+DELETE FROM category_hierarchy WHERE child_id IN ('YAML', 'TOML', 'Shell', 'Dockerfile', 'CSS', 'Markdown', 'C', 'C++', 'Kotlin', 'Swift', 'React', 'Vue');
+DELETE FROM category_node WHERE id IN ('YAML', 'TOML', 'Shell', 'Dockerfile', 'CSS', 'Markdown', 'C', 'C++', 'Kotlin', 'Swift', 'React', 'Vue');
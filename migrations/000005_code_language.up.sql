@@ -0,0 +1,39 @@
+--语言检测新增支持的语言
+INSERT OR IGNORE INTO category_node (id, label, last_operation_time, last_operation_type, created_at) VALUES
+('YAML', 'YAML', '1760313600000', 1, '1760313600000'),
+('TOML', 'TOML', '1760313600000', 1, '1760313600000'),
+('Shell', 'Shell', '1760313600000', 1, '1760313600000'),
+('Dockerfile', 'Dockerfile', '1760313600000', 1, '1760313600000'),
+('CSS', 'CSS', '1760313600000', 1, '1760313600000'),
+('Markdown', 'Markdown', '1760313600000', 1, '1760313600000'),
+('C', 'C', '1760313600000', 1, '1760313600000'),
+('C++', 'C++', '1760313600000', 1, '1760313600000'),
+('Kotlin', 'Kotlin', '1760313600000', 1, '1760313600000'),
+('Swift', 'Swift', '1760313600000', 1, '1760313600000'),
+('React', 'React', '1760313600000', 1, '1760313600000'),
+('Vue', 'Vue', '1760313600000', 1, '1760313600000');
+INSERT OR IGNORE INTO category_hierarchy (id, parent_id, child_id, last_operation_time, last_operation_type, created_at) VALUES
+('code_YAML', 'code', 'YAML', '1760313600000', 1, '1760313600000'),
+('code_TOML', 'code', 'TOML', '1760313600000', 1, '1760313600000'),
+('code_Shell', 'code', 'Shell', '1760313600000', 1, '1760313600000'),
+('code_Dockerfile', 'code', 'Dockerfile', '1760313600000', 1, '1760313600000'),
+('code_CSS', 'code', 'CSS', '1760313600000', 1, '1760313600000'),
+('code_Markdown', 'code', 'Markdown', '1760313600000', 1, '1760313600000'),
+('code_C', 'code', 'C', '1760313600000', 1, '1760313600000'),
+('code_C++', 'code', 'C++', '1760313600000', 1, '1760313600000'),
+('code_Kotlin', 'code', 'Kotlin', '1760313600000', 1, '1760313600000'),
+('code_Swift', 'code', 'Swift', '1760313600000', 1, '1760313600000'),
+('code_React', 'code', 'React', '1760313600000', 1, '1760313600000'),
+('code_Vue', 'code', 'Vue', '1760313600000', 1, '1760313600000'),
+('snippet_YAML', 'snippet', 'YAML', '1760313600000', 1, '1760313600000'),
+('snippet_TOML', 'snippet', 'TOML', '1760313600000', 1, '1760313600000'),
+('snippet_Shell', 'snippet', 'Shell', '1760313600000', 1, '1760313600000'),
+('snippet_Dockerfile', 'snippet', 'Dockerfile', '1760313600000', 1, '1760313600000'),
+('snippet_CSS', 'snippet', 'CSS', '1760313600000', 1, '1760313600000'),
+('snippet_Markdown', 'snippet', 'Markdown', '1760313600000', 1, '1760313600000'),
+('snippet_C', 'snippet', 'C', '1760313600000', 1, '1760313600000'),
+('snippet_C++', 'snippet', 'C++', '1760313600000', 1, '1760313600000'),
+('snippet_Kotlin', 'snippet', 'Kotlin', '1760313600000', 1, '1760313600000'),
+('snippet_Swift', 'snippet', 'Swift', '1760313600000', 1, '1760313600000'),
+('snippet_React', 'snippet', 'React', '1760313600000', 1, '1760313600000'),
+('snippet_Vue', 'snippet', 'Vue', '1760313600000', 1, '1760313600000');
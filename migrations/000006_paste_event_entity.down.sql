@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_paste_event_entity_type;
+DROP INDEX IF EXISTS idx_paste_event_entity_paste_event_id;
+DROP TABLE IF EXISTS paste_event_entity;
@@ -0,0 +1,18 @@
+--从文本中提取的实体，可根据文本重新生成，不参与同步
+CREATE TABLE IF NOT EXISTS paste_event_entity (
+  id TEXT NOT NULL PRIMARY KEY,
+  paste_event_id TEXT NOT NULL,
+  type TEXT NOT NULL, --实体类型 url、email、ip 等
+  value TEXT NOT NULL,
+  start_offset INTEGER NOT NULL, --在文本中的字符位置
+  end_offset INTEGER NOT NULL,
+  last_operation_time TEXT NOT NULL DEFAULT (strftime('%s','now') * 1000), --最后一次操作的时间
+  last_operation_type INTEGER NOT NULL DEFAULT 1, --最后一次操作的类型 1新增 2编辑 3删除
+  sync_status INTEGER NOT NULL DEFAULT 1, --1未同步 2已同步
+  created_at TEXT NOT NULL DEFAULT (strftime('%s','now') * 1000), -- 创建时间
+  updated_at TEXT,
+  deleted_at TIMESTAMP,
+  FOREIGN KEY (paste_event_id) REFERENCES paste_event(id) ON DELETE CASCADE
+);
+CREATE INDEX IF NOT EXISTS idx_paste_event_entity_paste_event_id ON paste_event_entity(paste_event_id);
+CREATE INDEX IF NOT EXISTS idx_paste_event_entity_type ON paste_event_entity(type, paste_event_id);
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_url_meta_status;
+DROP TABLE IF EXISTS url_meta;
+DROP TABLE IF EXISTS blob;
@@ -0,0 +1,40 @@
+--保存在 blob 目录中的二进制文件，文件名为内容的 sha256，不参与同步
+CREATE TABLE IF NOT EXISTS blob (
+  id TEXT NOT NULL PRIMARY KEY,
+  hash TEXT NOT NULL UNIQUE, --内容的 sha256
+  kind TEXT NOT NULL, --用途 favicon、og_image 等
+  mime_type TEXT NOT NULL DEFAULT '',
+  size INTEGER NOT NULL DEFAULT 0,
+  last_operation_time TEXT NOT NULL DEFAULT (strftime('%s','now') * 1000), --最后一次操作的时间
+  last_operation_type INTEGER NOT NULL DEFAULT 1, --最后一次操作的类型 1新增 2编辑 3删除
+  sync_status INTEGER NOT NULL DEFAULT 1, --1未同步 2已同步
+  created_at TEXT NOT NULL DEFAULT (strftime('%s','now') * 1000), -- 创建时间
+  updated_at TEXT,
+  deleted_at TIMESTAMP
+);
+--链接的元信息缓存，可重新获取，不参与同步
+CREATE TABLE IF NOT EXISTS url_meta (
+  id TEXT NOT NULL PRIMARY KEY,
+  url TEXT NOT NULL UNIQUE,
+  domain TEXT NOT NULL,
+  canonical_url TEXT NOT NULL DEFAULT '',
+  title TEXT NOT NULL DEFAULT '',
+  description TEXT NOT NULL DEFAULT '',
+  site_name TEXT NOT NULL DEFAULT '',
+  icon_url TEXT NOT NULL DEFAULT '', --图标的原始地址
+  icon_blob TEXT NOT NULL DEFAULT '', --下载后的图标 blob 哈希
+  image_url TEXT NOT NULL DEFAULT '', --og:image 的原始地址
+  image_blob TEXT NOT NULL DEFAULT '',
+  status INTEGER NOT NULL DEFAULT 1, --1待获取 2成功 3失败 4已跳过
+  attempts INTEGER NOT NULL DEFAULT 0, --连续失败次数
+  last_error TEXT NOT NULL DEFAULT '',
+  fetched_at TEXT NOT NULL DEFAULT '', --最后一次成功获取的时间
+  next_retry_at TEXT NOT NULL DEFAULT '', --下次重试的时间，毫秒时间戳
+  last_operation_time TEXT NOT NULL DEFAULT (strftime('%s','now') * 1000), --最后一次操作的时间
+  last_operation_type INTEGER NOT NULL DEFAULT 1, --最后一次操作的类型 1新增 2编辑 3删除
+  sync_status INTEGER NOT NULL DEFAULT 1, --1未同步 2已同步
+  created_at TEXT NOT NULL DEFAULT (strftime('%s','now') * 1000), -- 创建时间
+  updated_at TEXT,
+  deleted_at TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_url_meta_status ON url_meta(status, next_retry_at);
@@ -0,0 +1 @@
+ALTER TABLE paste_event DROP COLUMN markdown;
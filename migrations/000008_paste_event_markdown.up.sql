@@ -0,0 +1 @@
+ALTER TABLE paste_event ADD COLUMN markdown TEXT; --html 内容转换得到的 Markdown
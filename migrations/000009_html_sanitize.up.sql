@@ -0,0 +1,8 @@
+--需要在程序中执行的数据迁移，例如用新的规则重新处理已有的记录
+CREATE TABLE IF NOT EXISTS data_migration (
+  name TEXT NOT NULL PRIMARY KEY,
+  status INTEGER NOT NULL DEFAULT 0, --0待执行 1已完成
+  finished_at TEXT
+);
+--用新的 html 清理规则重新处理已保存的 html
+INSERT OR IGNORE INTO data_migration (name) VALUES ('html_sanitize');
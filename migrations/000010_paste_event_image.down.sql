@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_paste_event_image_group_id;
+DROP TABLE IF EXISTS paste_event_image;
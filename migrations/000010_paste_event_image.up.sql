@@ -0,0 +1,24 @@
+--图片记录的格式信息、缩略图和感知哈希，可以从原图重新生成，不参与同步
+CREATE TABLE IF NOT EXISTS paste_event_image (
+  id TEXT NOT NULL PRIMARY KEY,
+  paste_event_id TEXT NOT NULL UNIQUE,
+  format TEXT NOT NULL DEFAULT '', --png、jpeg、gif
+  width INTEGER NOT NULL DEFAULT 0,
+  height INTEGER NOT NULL DEFAULT 0,
+  color_model TEXT NOT NULL DEFAULT '',
+  exif TEXT NOT NULL DEFAULT '', --EXIF 信息 json
+  thumb_small TEXT NOT NULL DEFAULT '', --缩略图的 blob 哈希，最长边 160
+  thumb_medium TEXT NOT NULL DEFAULT '', --最长边 480
+  thumb_large TEXT NOT NULL DEFAULT '', --最长边 960
+  dhash TEXT NOT NULL DEFAULT '', --差异哈希，16 位十六进制
+  phash TEXT NOT NULL DEFAULT '', --感知哈希，16 位十六进制
+  group_id TEXT NOT NULL DEFAULT '', --相似图片分为一组，值为组内第一张图片的记录 id
+  last_error TEXT NOT NULL DEFAULT '', --处理失败的原因
+  last_operation_time TEXT NOT NULL DEFAULT (strftime('%s','now') * 1000), --最后一次操作的时间
+  last_operation_type INTEGER NOT NULL DEFAULT 1, --最后一次操作的类型 1新增 2编辑 3删除
+  sync_status INTEGER NOT NULL DEFAULT 1, --1未同步 2已同步
+  created_at TEXT NOT NULL DEFAULT (strftime('%s','now') * 1000), -- 创建时间
+  updated_at TEXT,
+  deleted_at TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_paste_event_image_group_id ON paste_event_image(group_id);
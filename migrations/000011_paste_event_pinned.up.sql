@@ -0,0 +1 @@
+ALTER TABLE paste_event ADD COLUMN pinned INTEGER NOT NULL DEFAULT 0; --1已置顶
@@ -0,0 +1,2 @@
+ALTER TABLE category_node DROP COLUMN icon;
+ALTER TABLE category_node DROP COLUMN color;
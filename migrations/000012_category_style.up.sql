@@ -0,0 +1,12 @@
+ALTER TABLE category_node ADD COLUMN color TEXT NOT NULL DEFAULT ''; --#RRGGBB 格式的颜色
+ALTER TABLE category_node ADD COLUMN icon TEXT NOT NULL DEFAULT ''; --图标名称或 emoji
+--按层级关系重新计算 level，根节点为 0，有多个父节点时取最深的一条路径
+WITH RECURSIVE category_depth(id, level) AS (
+  SELECT id, 0 FROM category_node
+  WHERE id NOT IN (SELECT child_id FROM category_hierarchy WHERE deleted_at IS NULL)
+  UNION
+  SELECT category_hierarchy.child_id, category_depth.level + 1 FROM category_hierarchy
+  JOIN category_depth ON category_hierarchy.parent_id = category_depth.id
+  WHERE category_hierarchy.deleted_at IS NULL AND category_depth.level < 100
+)
+UPDATE category_node SET level = COALESCE((SELECT MAX(level) FROM category_depth WHERE category_depth.id = category_node.id), 0);
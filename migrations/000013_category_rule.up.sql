@@ -0,0 +1,15 @@
+--用户定义的自动分类规则，新记录满足所有条件时添加对应的分类
+CREATE TABLE IF NOT EXISTS category_rule (
+  id TEXT NOT NULL PRIMARY KEY,
+  name TEXT NOT NULL,
+  conditions_json TEXT NOT NULL DEFAULT '[]', --条件，JSON 数组，见 transformer.CategoryRuleCondition
+  category_ids_json TEXT NOT NULL DEFAULT '[]', --匹配后添加的分类 id，JSON 数组
+  enabled INTEGER NOT NULL DEFAULT 1,
+  sort_order INTEGER NOT NULL DEFAULT 0,
+  last_operation_time TEXT NOT NULL DEFAULT (strftime('%s','now') * 1000), --最后一次操作的时间
+  last_operation_type INTEGER NOT NULL DEFAULT 1, --最后一次操作的类型 1新增 2编辑 3删除
+  sync_status INTEGER NOT NULL DEFAULT 1, --1未同步 2已同步
+  created_at TEXT NOT NULL DEFAULT (strftime('%s','now') * 1000), -- 创建时间
+  updated_at TEXT,
+  deleted_at TIMESTAMP
+);
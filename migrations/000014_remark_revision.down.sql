@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS remark_attachment;
+DROP TABLE IF EXISTS remark_revision;
+ALTER TABLE remark DROP COLUMN edited_at;
+ALTER TABLE remark DROP COLUMN revision;
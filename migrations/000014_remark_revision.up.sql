@@ -0,0 +1,35 @@
+ALTER TABLE remark ADD COLUMN revision INTEGER NOT NULL DEFAULT 0; --编辑的次数
+ALTER TABLE remark ADD COLUMN edited_at TEXT; --最后一次编辑内容的时间，没有编辑过时为空
+
+--编辑备注时保存修改前的内容
+CREATE TABLE IF NOT EXISTS remark_revision (
+  id TEXT NOT NULL PRIMARY KEY,
+  remark_id TEXT NOT NULL,
+  content TEXT NOT NULL DEFAULT '',
+  revision INTEGER NOT NULL DEFAULT 0, --修改前的版本
+  last_operation_time TEXT NOT NULL DEFAULT (strftime('%s','now') * 1000), --最后一次操作的时间
+  last_operation_type INTEGER NOT NULL DEFAULT 1, --最后一次操作的类型 1新增 2编辑 3删除
+  sync_status INTEGER NOT NULL DEFAULT 1, --1未同步 2已同步
+  created_at TEXT NOT NULL DEFAULT (strftime('%s','now') * 1000), -- 创建时间
+  updated_at TEXT,
+  deleted_at TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_remark_revision_remark_id ON remark_revision (remark_id);
+
+--备注的附件，文件保存在 blob 目录，不参与同步
+CREATE TABLE IF NOT EXISTS remark_attachment (
+  id TEXT NOT NULL PRIMARY KEY,
+  remark_id TEXT NOT NULL,
+  name TEXT NOT NULL DEFAULT '',
+  mime_type TEXT NOT NULL DEFAULT '',
+  size INTEGER NOT NULL DEFAULT 0,
+  blob_hash TEXT NOT NULL,
+  last_operation_time TEXT NOT NULL DEFAULT (strftime('%s','now') * 1000), --最后一次操作的时间
+  last_operation_type INTEGER NOT NULL DEFAULT 1, --最后一次操作的类型 1新增 2编辑 3删除
+  sync_status INTEGER NOT NULL DEFAULT 1, --1未同步 2已同步
+  created_at TEXT NOT NULL DEFAULT (strftime('%s','now') * 1000), -- 创建时间
+  updated_at TEXT,
+  deleted_at TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_remark_attachment_remark_id ON remark_attachment (remark_id);
+CREATE INDEX IF NOT EXISTS idx_remark_attachment_blob_hash ON remark_attachment (blob_hash);
@@ -0,0 +1,7 @@
+--永久删除的同步数据，从远端拉取时跳过这些记录，避免已经清空的记录又被同步回来
+CREATE TABLE IF NOT EXISTS purged_record (
+  id TEXT NOT NULL, --被删除记录的 id
+  table_name TEXT NOT NULL, --被删除记录所在的表
+  purged_at TEXT NOT NULL DEFAULT (strftime('%s','now') * 1000), --永久删除的时间
+  PRIMARY KEY (table_name, id)
+);
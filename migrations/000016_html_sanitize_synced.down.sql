@@ -0,0 +1 @@
+DELETE FROM data_migration WHERE name = 'html_sanitize_synced';
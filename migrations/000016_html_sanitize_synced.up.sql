@@ -0,0 +1,2 @@
+--同步过来的记录之前没有经过清理，重新处理一次已保存的 html
+INSERT OR IGNORE INTO data_migration (name) VALUES ('html_sanitize_synced');
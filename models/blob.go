@@ -0,0 +1,13 @@
+package models
+
+type Blob struct {
+	BaseModel `gorm:"embedded"`
+	Hash      string `json:"hash"`
+	Kind      string `json:"kind"`
+	MimeType  string `json:"mime_type"`
+	Size      int64  `json:"size"`
+}
+
+func (Blob) TableName() string {
+	return "blob"
+}
@@ -7,9 +7,11 @@ type CategoryNode struct {
 	Level       int    `json:"level,omitempty" gorm:"default:0"`
 	SortOrder   int    `json:"sort_order" gorm:"default:0"`
 	IsActive    bool   `json:"is_active" gorm:"default:true"`
+	Color       string `json:"color,omitempty"`
+	Icon        string `json:"icon,omitempty"`
 
 	Parents     []CategoryNode `json:"parents" gorm:"many2many:category_hierarchy;foreignKey:Id;joinForeignKey:ChildId;References:Id;joinReferences:ParentId"`
-	Children    []CategoryNode `json:"children" gorm:"many2many:category_hierarchy;foreignKey:Id;joinForeignKey:ChildId;References:Id;joinReferences:ParentId"`
+	Children    []CategoryNode `json:"children" gorm:"many2many:category_hierarchy;foreignKey:Id;joinForeignKey:ParentId;References:Id;joinReferences:ChildId"`
 	PasteEvents []PasteEvent   `json:"paste_events" gorm:"many2many:paste_event_category_mapping;joinForeignKey:category_id;joinReferences:paste_event_id;"`
 }
 
@@ -36,3 +38,23 @@ type PasteEventCategoryMapping struct {
 func (PasteEventCategoryMapping) TableName() string {
 	return "paste_event_category_mapping"
 }
+
+const (
+	SuggestionStatusPending  = 1
+	SuggestionStatusAccepted = 2
+	SuggestionStatusRejected = 3
+)
+
+type PasteEventCategorySuggestion struct {
+	BaseModel    `gorm:"embedded"`
+	PasteEventId string `json:"paste_event_id" gorm:"column:paste_event_id"`
+	CategoryId   string `json:"category_id" gorm:"column:category_id"`
+	Source       string `json:"source"`
+	Status       int    `json:"status"`
+
+	Category CategoryNode `json:"category" gorm:"foreignKey:CategoryId"`
+}
+
+func (PasteEventCategorySuggestion) TableName() string {
+	return "paste_event_category_suggestion"
+}
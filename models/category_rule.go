@@ -0,0 +1,14 @@
+package models
+
+type CategoryRule struct {
+	BaseModel       `gorm:"embedded"`
+	Name            string `json:"name"`
+	ConditionsJSON  string `json:"conditions_json" gorm:"column:conditions_json"`
+	CategoryIdsJSON string `json:"category_ids_json" gorm:"column:category_ids_json"`
+	Enabled         bool   `json:"enabled"`
+	SortOrder       int    `json:"sort_order"`
+}
+
+func (CategoryRule) TableName() string {
+	return "category_rule"
+}
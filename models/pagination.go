@@ -1,27 +1,52 @@
 package models
 
 import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"reflect"
+	"strings"
+	"sync"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
 )
 
-// PaginationBuilder is a utility for building paginated queries
+var ErrInvalidCursor = errors.New("Invalid cursor")
+
+// 解析后的表结构缓存，用于从记录中读取排序字段的值
+var pagination_schema_cache = &sync.Map{}
+
+// cursor 游标中保存上一页最后一条记录的排序值和 id，Order 用于判断游标是否属于当前的排序方式
+type cursor struct {
+	Order string      `json:"o"`
+	Value interface{} `json:"v"`
+	Id    string      `json:"id"`
+}
+
+// PaginationBuilder 按 (排序字段, id) 进行游标分页，排序字段的值相同时用 id 区分，翻页时不会跳过或重复记录。
+// 传入 next_marker 时使用游标，否则按 page 使用 OFFSET，两者不会同时生效
 type PaginationBuilder[T any] struct {
 	query      *gorm.DB
 	Limit      int
 	page       int
 	nextMarker string
-	orderBy    string
+	// orderColumn 排序字段，例如 paste_event.updated_at，idColumn 为同一张表的 id
+	orderColumn string
+	idColumn    string
+	desc        bool
 }
 
 // NewPaginationBuilder creates a new PaginationBuilder instance
 func NewPaginationBuilder[T any](query *gorm.DB) *PaginationBuilder[T] {
 	return &PaginationBuilder[T]{
-		query:   query,
-		Limit:   20,        // default limit
-		orderBy: "id DESC", // default order
+		query:       query,
+		Limit:       20, // default limit
+		orderColumn: "id",
+		idColumn:    "id",
+		desc:        true,
 	}
 }
 
@@ -46,56 +71,149 @@ func (pb *PaginationBuilder[T]) SetPage(page int) *PaginationBuilder[T] {
 	return pb
 }
 
-// SetNextMarker sets the cursor for cursor-based pagination
+// SetNextMarker 设置上一页返回的游标
 func (pb *PaginationBuilder[T]) SetNextMarker(next_marker string) *PaginationBuilder[T] {
 	pb.nextMarker = next_marker
 	return pb
 }
 
-// SetOrderBy sets the ordering field
+// SetOrderBy 设置排序字段，格式为 "paste_event.updated_at DESC"，不写方向时为 ASC
 func (pb *PaginationBuilder[T]) SetOrderBy(order_by string) *PaginationBuilder[T] {
-	if order_by != "" {
-		pb.orderBy = order_by
+	parts := strings.Fields(order_by)
+	if len(parts) == 0 {
+		return pb
+	}
+	pb.orderColumn = parts[0]
+	pb.desc = len(parts) > 1 && strings.EqualFold(parts[1], "DESC")
+	pb.idColumn = "id"
+	if i := strings.LastIndex(pb.orderColumn, "."); i != -1 {
+		pb.idColumn = pb.orderColumn[:i] + ".id"
 	}
 	return pb
 }
 
-// Build builds the paginated query
-func (pb *PaginationBuilder[T]) Build() *gorm.DB {
-	query := pb.query
+func (pb *PaginationBuilder[T]) order_key() string {
+	direction := "asc"
+	if pb.desc {
+		direction = "desc"
+	}
+	return pb.orderColumn + ":" + direction
+}
 
-	// Apply cursor-based pagination if nextMarker is provided
-	if pb.nextMarker != "" {
-		query = query.Where("id < ?", pb.nextMarker)
+// field_name 排序字段去掉表名后的列名
+func (pb *PaginationBuilder[T]) field_name() string {
+	name := pb.orderColumn
+	if i := strings.LastIndex(name, "."); i != -1 {
+		name = name[i+1:]
 	}
+	return name
+}
 
-	// Apply offset-based pagination if page is provided
-	if pb.page > 0 {
-		query = query.Offset((pb.page - 1) * pb.Limit)
+// order_expr 排序字段为 NULL 时按字段类型的零值排序，和游标中保存的值一致，否则 NULL 的记录无法翻页
+func (pb *PaginationBuilder[T]) order_expr() string {
+	s, err := schema.Parse(new(T), pagination_schema_cache, pb.query.NamingStrategy)
+	if err != nil {
+		return pb.orderColumn
+	}
+	field := s.LookUpField(pb.field_name())
+	if field == nil {
+		return pb.orderColumn
 	}
+	switch field.FieldType.Kind() {
+	case reflect.String:
+		return "COALESCE(" + pb.orderColumn + ", '')"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return "COALESCE(" + pb.orderColumn + ", 0)"
+	}
+	return pb.orderColumn
+}
 
-	// Apply ordering and limit
-	query = query.Order(pb.orderBy).Limit(pb.Limit + 1)
+func (pb *PaginationBuilder[T]) encode_cursor(item T) (string, error) {
+	s, err := schema.Parse(new(T), pagination_schema_cache, pb.query.NamingStrategy)
+	if err != nil {
+		return "", err
+	}
+	order_field := s.LookUpField(pb.field_name())
+	id_field := s.LookUpField("id")
+	if order_field == nil || id_field == nil {
+		return "", fmt.Errorf("Can't find the field %s", pb.field_name())
+	}
+	rv := reflect.ValueOf(&item).Elem()
+	value, _ := order_field.ValueOf(context.Background(), rv)
+	id, _ := id_field.ValueOf(context.Background(), rv)
+	b, err := json.Marshal(&cursor{
+		Order: pb.order_key(),
+		Value: value,
+		Id:    fmt.Sprintf("%v", id),
+	})
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
 
-	return query
+func (pb *PaginationBuilder[T]) decode_cursor(marker string) (*cursor, error) {
+	b, err := base64.RawURLEncoding.DecodeString(marker)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	decoder := json.NewDecoder(strings.NewReader(string(b)))
+	// 数字保持原样，避免大整数精度丢失
+	decoder.UseNumber()
+	var c cursor
+	if err := decoder.Decode(&c); err != nil || c.Id == "" || c.Order != pb.order_key() {
+		return nil, ErrInvalidCursor
+	}
+	if n, ok := c.Value.(json.Number); ok {
+		if v, err := n.Int64(); err == nil {
+			c.Value = v
+		} else if v, err := n.Float64(); err == nil {
+			c.Value = v
+		}
+	}
+	return &c, nil
 }
 
-// ProcessResults processes the query results and returns pagination metadata
-func (pb *PaginationBuilder[T]) ProcessResults(results []T) ([]T, bool, string) {
-	has_more := false
-	next_cursor := ""
-
-	// Check if there are more results
-	if len(results) > pb.Limit {
-		has_more = true
-		results = results[:pb.Limit] // Remove the extra item we fetched
-
-		// Get the last item's ID as next cursor
-		lastItem := reflect.ValueOf(results[pb.Limit-1])
-		if idField := lastItem.FieldByName("Id"); idField.IsValid() {
-			next_cursor = fmt.Sprintf("%v", idField.Interface())
+// Build builds the paginated query
+func (pb *PaginationBuilder[T]) Build() *gorm.DB {
+	query := pb.query
+	order_expr := pb.order_expr()
+	if pb.nextMarker != "" {
+		c, err := pb.decode_cursor(pb.nextMarker)
+		if err != nil {
+			query = query.Session(&gorm.Session{})
+			query.AddError(err)
+			return query
+		}
+		op := ">"
+		if pb.desc {
+			op = "<"
 		}
+		query = query.Where(
+			fmt.Sprintf("(%s %s ? OR (%s = ? AND %s %s ?))", order_expr, op, order_expr, pb.idColumn, op),
+			c.Value, c.Value, c.Id,
+		)
+	} else if pb.page > 1 {
+		query = query.Offset((pb.page - 1) * pb.Limit)
+	}
+	direction := " ASC"
+	if pb.desc {
+		direction = " DESC"
 	}
+	return query.Order(order_expr + direction).Order(pb.idColumn + direction).Limit(pb.Limit + 1)
+}
 
-	return results, has_more, next_cursor
+// ProcessResults 去掉多查询的一条记录，有下一页时返回最后一条记录的游标
+func (pb *PaginationBuilder[T]) ProcessResults(results []T) ([]T, bool, string) {
+	if len(results) <= pb.Limit {
+		return results, false, ""
+	}
+	results = results[:pb.Limit]
+	next_cursor, err := pb.encode_cursor(results[pb.Limit-1])
+	if err != nil {
+		fmt.Println("[ERROR]encode pagination cursor failed", err.Error())
+	}
+	return results, true, next_cursor
 }
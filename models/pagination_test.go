@@ -0,0 +1,168 @@
+package models_test
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"testing"
+
+	"github.com/google/uuid"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+
+	"devboard/models"
+)
+
+type pagination_item struct {
+	Id        string
+	UpdatedAt string
+	Weight    int
+}
+
+func (pagination_item) TableName() string {
+	return "item"
+}
+
+func setup_pagination_db(t *testing.T) (*gorm.DB, []pagination_item) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Exec("CREATE TABLE item (id TEXT PRIMARY KEY, updated_at TEXT, weight INTEGER)").Error; err != nil {
+		t.Fatal(err)
+	}
+	var items []pagination_item
+	for i := 0; i < 23; i++ {
+		// 很多记录的更新时间相同，id 是随机的
+		items = append(items, pagination_item{
+			Id:        uuid.New().String(),
+			UpdatedAt: fmt.Sprintf("17000000000%02d", i/4),
+			Weight:    i % 3,
+		})
+	}
+	if err := db.Create(&items).Error; err != nil {
+		t.Fatal(err)
+	}
+	return db, items
+}
+
+func fetch_all_pages(t *testing.T, db *gorm.DB, order_by string) []pagination_item {
+	var result []pagination_item
+	marker := ""
+	for i := 0; i < 10; i++ {
+		pb := models.NewPaginationBuilder[pagination_item](db.Model(&pagination_item{})).
+			SetLimit(5).
+			SetPage(i + 1).
+			SetNextMarker(marker).
+			SetOrderBy(order_by)
+		var list []pagination_item
+		if err := pb.Build().Find(&list).Error; err != nil {
+			t.Fatal(err)
+		}
+		page, has_more, next_marker := pb.ProcessResults(list)
+		result = append(result, page...)
+		if !has_more {
+			return result
+		}
+		if next_marker == "" {
+			t.Fatal("missing the next marker")
+		}
+		marker = next_marker
+	}
+	t.Fatal("too many pages")
+	return nil
+}
+
+func TestPaginationBuilder(t *testing.T) {
+	db, items := setup_pagination_db(t)
+	cases := []struct {
+		order_by string
+		less     func(a, b pagination_item) bool
+	}{
+		{"item.updated_at DESC", func(a, b pagination_item) bool {
+			if a.UpdatedAt != b.UpdatedAt {
+				return a.UpdatedAt > b.UpdatedAt
+			}
+			return a.Id > b.Id
+		}},
+		{"item.updated_at ASC", func(a, b pagination_item) bool {
+			if a.UpdatedAt != b.UpdatedAt {
+				return a.UpdatedAt < b.UpdatedAt
+			}
+			return a.Id < b.Id
+		}},
+		{"weight DESC", func(a, b pagination_item) bool {
+			if a.Weight != b.Weight {
+				return a.Weight > b.Weight
+			}
+			return a.Id > b.Id
+		}},
+	}
+	for _, c := range cases {
+		expected := append([]pagination_item{}, items...)
+		sort.Slice(expected, func(i, j int) bool {
+			return c.less(expected[i], expected[j])
+		})
+		got := fetch_all_pages(t, db, c.order_by)
+		if len(got) != len(expected) {
+			t.Fatalf("%s: expected %d items, got %d", c.order_by, len(expected), len(got))
+		}
+		for i := range expected {
+			if got[i].Id != expected[i].Id {
+				t.Errorf("%s: item %d expected %s, got %s", c.order_by, i, expected[i].Id, got[i].Id)
+			}
+		}
+	}
+}
+
+func TestPaginationBuilderInvalidCursor(t *testing.T) {
+	db, _ := setup_pagination_db(t)
+	pb := models.NewPaginationBuilder[pagination_item](db.Model(&pagination_item{})).
+		SetLimit(5).
+		SetOrderBy("item.updated_at DESC")
+	var list []pagination_item
+	if err := pb.Build().Find(&list).Error; err != nil {
+		t.Fatal(err)
+	}
+	_, _, marker := pb.ProcessResults(list)
+	for _, m := range []string{"not a cursor", "e30"} {
+		pb := models.NewPaginationBuilder[pagination_item](db.Model(&pagination_item{})).
+			SetNextMarker(m).
+			SetOrderBy("item.updated_at DESC")
+		if err := pb.Build().Find(&list).Error; !errors.Is(err, models.ErrInvalidCursor) {
+			t.Errorf("expected invalid cursor error for %q, got %v", m, err)
+		}
+	}
+	// 游标不能用于其他排序方式
+	other := models.NewPaginationBuilder[pagination_item](db.Model(&pagination_item{})).
+		SetNextMarker(marker).
+		SetOrderBy("item.updated_at ASC")
+	if err := other.Build().Find(&list).Error; !errors.Is(err, models.ErrInvalidCursor) {
+		t.Errorf("expected invalid cursor error, got %v", err)
+	}
+}
+
+func TestPaginationBuilderNullValue(t *testing.T) {
+	db, items := setup_pagination_db(t)
+	// 旧版本的记录没有更新时间
+	for i := 0; i < 3; i++ {
+		id := uuid.New().String()
+		if err := db.Exec("INSERT INTO item (id, updated_at, weight) VALUES (?, NULL, NULL)", id).Error; err != nil {
+			t.Fatal(err)
+		}
+		items = append(items, pagination_item{Id: id})
+	}
+	for _, order_by := range []string{"item.updated_at DESC", "item.updated_at ASC", "weight DESC"} {
+		got := fetch_all_pages(t, db, order_by)
+		if len(got) != len(items) {
+			t.Errorf("%s: expected %d items, got %d", order_by, len(items), len(got))
+		}
+		seen := make(map[string]bool)
+		for _, item := range got {
+			if seen[item.Id] {
+				t.Errorf("%s: duplicated item %s", order_by, item.Id)
+			}
+			seen[item.Id] = true
+		}
+	}
+}
@@ -3,19 +3,24 @@ package models
 type PasteEvent struct {
 	BaseModel    `gorm:"embedded"`
 	ContentType  string `json:"content_type"`
+	Title        string `json:"title,omitempty"`
 	Text         string `json:"text,omitempty"`
 	Html         string `json:"html,omitempty"`
+	Markdown     string `json:"markdown,omitempty"`
 	FileListJSON string `json:"file_list_json,omitempty"`
 	ImageBase64  string `json:"image_base64,omitempty"`
 	Other        string `json:"other,omitempty"`
 	Details      string `json:"details"`
 	AppId        string `json:"app_id,omitempty"`
 	DeviceId     string `json:"device_id,omitempty"`
+	Pinned       bool   `json:"pinned"`
 
 	Device     Device         `json:"device,omitempty" gorm:"ReferenceKey:DeviceId"`
 	App        App            `json:"app,omitempty" gorm:"ReferenceKey:AppId"`
 	Categories []CategoryNode `json:"categories" gorm:"many2many:paste_event_category_mapping;joinForeignKey:paste_event_id;JoinReferences:category_id"`
 	Remarks    []Remark       `json:"remarks" gorm:"ForeignKey:PasteEventId"`
+	// RemarkCount 没有删除的备注数量，读取详情时设置
+	RemarkCount int `json:"remark_count" gorm:"-"`
 }
 
 func (PasteEvent) TableName() string {
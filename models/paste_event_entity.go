@@ -0,0 +1,14 @@
+package models
+
+type PasteEventEntity struct {
+	BaseModel    `gorm:"embedded"`
+	PasteEventId string `json:"paste_event_id"`
+	Type         string `json:"type"`
+	Value        string `json:"value"`
+	StartOffset  int    `json:"start"`
+	EndOffset    int    `json:"end"`
+}
+
+func (PasteEventEntity) TableName() string {
+	return "paste_event_entity"
+}
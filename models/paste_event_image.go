@@ -0,0 +1,22 @@
+package models
+
+type PasteEventImage struct {
+	BaseModel    `gorm:"embedded"`
+	PasteEventId string `json:"paste_event_id"`
+	Format       string `json:"format"`
+	Width        int    `json:"width"`
+	Height       int    `json:"height"`
+	ColorModel   string `json:"color_model"`
+	EXIF         string `json:"exif" gorm:"column:exif"`
+	ThumbSmall   string `json:"thumb_small"`
+	ThumbMedium  string `json:"thumb_medium"`
+	ThumbLarge   string `json:"thumb_large"`
+	DHash        string `json:"dhash" gorm:"column:dhash"`
+	PHash        string `json:"phash" gorm:"column:phash"`
+	GroupId      string `json:"group_id"`
+	LastError    string `json:"last_error"`
+}
+
+func (PasteEventImage) TableName() string {
+	return "paste_event_image"
+}
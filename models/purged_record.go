@@ -0,0 +1,12 @@
+package models
+
+// PurgedRecord 永久删除的同步数据，只保存在本地
+type PurgedRecord struct {
+	Id       string `json:"id" gorm:"primaryKey"`
+	Table    string `json:"table_name" gorm:"column:table_name;primaryKey"`
+	PurgedAt string `json:"purged_at"`
+}
+
+func (PurgedRecord) TableName() string {
+	return "purged_record"
+}
@@ -4,8 +4,42 @@ type Remark struct {
 	BaseModel    `gorm:"embedded"`
 	Content      string `json:"content"`
 	PasteEventId string `json:"paste_event_id"`
+	// Revision 编辑的次数，EditedAt 最后一次编辑的时间
+	Revision int    `json:"revision"`
+	EditedAt string `json:"edited_at,omitempty"`
+
+	// HTML Markdown 内容转换后的 html，读取时生成
+	HTML        string             `json:"html" gorm:"-"`
+	Attachments []RemarkAttachment `json:"attachments" gorm:"ForeignKey:RemarkId"`
 }
 
 func (Remark) TableName() string {
 	return "remark"
 }
+
+type RemarkRevision struct {
+	BaseModel `gorm:"embedded"`
+	RemarkId  string `json:"remark_id"`
+	Content   string `json:"content"`
+	Revision  int    `json:"revision"`
+}
+
+func (RemarkRevision) TableName() string {
+	return "remark_revision"
+}
+
+type RemarkAttachment struct {
+	BaseModel `gorm:"embedded"`
+	RemarkId  string `json:"remark_id"`
+	Name      string `json:"name"`
+	MimeType  string `json:"mime_type"`
+	Size      int64  `json:"size"`
+	BlobHash  string `json:"blob_hash"`
+
+	// URL 读取文件的地址，见 controller.BlobURL
+	URL string `json:"url" gorm:"-"`
+}
+
+func (RemarkAttachment) TableName() string {
+	return "remark_attachment"
+}
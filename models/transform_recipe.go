@@ -0,0 +1,12 @@
+package models
+
+type TransformRecipe struct {
+	BaseModel   `gorm:"embedded"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	StepsJSON   string `json:"steps_json" gorm:"column:steps_json"`
+}
+
+func (TransformRecipe) TableName() string {
+	return "transform_recipe"
+}
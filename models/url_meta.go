@@ -0,0 +1,24 @@
+package models
+
+type URLMeta struct {
+	BaseModel    `gorm:"embedded"`
+	URL          string `json:"url" gorm:"column:url"`
+	Domain       string `json:"domain"`
+	CanonicalURL string `json:"canonical_url" gorm:"column:canonical_url"`
+	Title        string `json:"title"`
+	Description  string `json:"description"`
+	SiteName     string `json:"site_name"`
+	IconURL      string `json:"icon_url" gorm:"column:icon_url"`
+	IconBlob     string `json:"icon_blob"`
+	ImageURL     string `json:"image_url" gorm:"column:image_url"`
+	ImageBlob    string `json:"image_blob"`
+	Status       int    `json:"status"`
+	Attempts     int    `json:"attempts"`
+	LastError    string `json:"last_error"`
+	FetchedAt    string `json:"fetched_at"`
+	NextRetryAt  string `json:"next_retry_at"`
+}
+
+func (URLMeta) TableName() string {
+	return "url_meta"
+}
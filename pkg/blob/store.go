@@ -0,0 +1,193 @@
+package blob
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+)
+
+// Store 按内容的 sha256 保存二进制文件，相同内容只保存一份
+// 文件路径为 dir/哈希前两位/哈希
+type Store struct {
+	dir string
+}
+
+// ErrHashMismatch 写入的内容和期望的哈希不同
+var ErrHashMismatch = errors.New("The blob content does not match the hash")
+
+var hash_regexp = regexp.MustCompile(`^[0-9a-f]{64}$`)
+
+func NewStore(dir string) (*Store, error) {
+	if dir == "" {
+		return nil, fmt.Errorf("Missing the blob dir")
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	return &Store{dir: dir}, nil
+}
+
+func Hash(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// HashFile 计算文件内容的 sha256
+func HashFile(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// Path 返回哈希对应的文件路径，哈希不合法时返回错误，避免拼出目录外的路径
+func (s *Store) Path(hash string) (string, error) {
+	if !hash_regexp.MatchString(hash) {
+		return "", fmt.Errorf("Invalid blob hash %s", hash)
+	}
+	return filepath.Join(s.dir, hash[:2], hash), nil
+}
+
+// Put 保存内容并返回哈希，已存在时不重复写入
+func (s *Store) Put(data []byte) (string, error) {
+	hash := Hash(data)
+	p, err := s.Path(hash)
+	if err != nil {
+		return "", err
+	}
+	if _, err := os.Stat(p); err == nil {
+		return hash, nil
+	}
+	if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+		return "", err
+	}
+	// 先写临时文件再重命名，避免写到一半的文件被读取
+	tmp, err := os.CreateTemp(filepath.Dir(p), hash+".*.tmp")
+	if err != nil {
+		return "", err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return "", err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return "", err
+	}
+	if err := os.Rename(tmp.Name(), p); err != nil {
+		os.Remove(tmp.Name())
+		return "", err
+	}
+	return hash, nil
+}
+
+// PutFile 边复制边计算哈希，用于保存较大的文件
+func (s *Store) PutFile(path string) (string, int64, error) {
+	src, err := os.Open(path)
+	if err != nil {
+		return "", 0, err
+	}
+	defer src.Close()
+	return s.PutReader(src, "")
+}
+
+// PutReader 边读取边写入临时文件并计算哈希，不会把内容全部读到内存中
+// want 不为空时，内容的哈希和 want 不同会返回 ErrHashMismatch，不保存文件
+func (s *Store) PutReader(src io.Reader, want string) (string, int64, error) {
+	tmp, err := os.CreateTemp(s.dir, "upload.*.tmp")
+	if err != nil {
+		return "", 0, err
+	}
+	defer os.Remove(tmp.Name())
+	h := sha256.New()
+	size, err := io.Copy(io.MultiWriter(tmp, h), src)
+	if err != nil {
+		tmp.Close()
+		return "", 0, err
+	}
+	if err := tmp.Close(); err != nil {
+		return "", 0, err
+	}
+	hash := hex.EncodeToString(h.Sum(nil))
+	if want != "" && hash != want {
+		return "", 0, ErrHashMismatch
+	}
+	p, _ := s.Path(hash)
+	if _, err := os.Stat(p); err == nil {
+		return hash, size, nil
+	}
+	if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+		return "", 0, err
+	}
+	if err := os.Rename(tmp.Name(), p); err != nil {
+		return "", 0, err
+	}
+	return hash, size, nil
+}
+
+// CopyTo 把内容复制到 dst，用于恢复文件
+func (s *Store) CopyTo(hash string, dst string) error {
+	p, err := s.Path(hash)
+	if err != nil {
+		return err
+	}
+	src, err := os.Open(p)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return err
+	}
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, src); err != nil {
+		out.Close()
+		os.Remove(dst)
+		return err
+	}
+	return out.Close()
+}
+
+func (s *Store) Has(hash string) bool {
+	p, err := s.Path(hash)
+	if err != nil {
+		return false
+	}
+	_, err = os.Stat(p)
+	return err == nil
+}
+
+func (s *Store) Read(hash string) ([]byte, error) {
+	p, err := s.Path(hash)
+	if err != nil {
+		return nil, err
+	}
+	return os.ReadFile(p)
+}
+
+// Delete 删除文件，文件不存在时不报错
+func (s *Store) Delete(hash string) error {
+	p, err := s.Path(hash)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(p); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,91 @@
+package blob_test
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"devboard/pkg/blob"
+)
+
+func TestStore(t *testing.T) {
+	store, err := blob.NewStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := []byte("hello")
+	hash, err := store.Put(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hash != blob.Hash(data) {
+		t.Errorf("unexpected hash %s", hash)
+	}
+	if again, _ := store.Put(data); again != hash {
+		t.Errorf("the same content should have the same hash")
+	}
+	got, err := store.Read(hash)
+	if err != nil || !bytes.Equal(got, data) {
+		t.Errorf("unexpected content %q %v", got, err)
+	}
+	if _, err := store.Path("../../etc/passwd"); err == nil {
+		t.Error("expected error for invalid hash")
+	}
+	if err := store.Delete(hash); err != nil {
+		t.Fatal(err)
+	}
+	if store.Has(hash) {
+		t.Error("blob should be deleted")
+	}
+}
+
+func TestStorePutFile(t *testing.T) {
+	dir := t.TempDir()
+	store, err := blob.NewStore(filepath.Join(dir, "blobs"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	src := filepath.Join(dir, "a.txt")
+	if err := os.WriteFile(src, []byte("file content"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	hash, size, err := store.PutFile(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected, _ := blob.HashFile(src); hash != expected || size != 12 {
+		t.Errorf("unexpected hash %s or size %d", hash, size)
+	}
+	dst := filepath.Join(dir, "restored", "a.txt")
+	if err := store.CopyTo(hash, dst); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := os.ReadFile(dst); string(got) != "file content" {
+		t.Errorf("unexpected restored content %q", got)
+	}
+}
+
+func TestStorePutReader(t *testing.T) {
+	store, err := blob.NewStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := blob.Hash([]byte("reader content"))
+	hash, size, err := store.PutReader(strings.NewReader("reader content"), want)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hash != want || size != 14 || !store.Has(hash) {
+		t.Errorf("unexpected hash %s or size %d", hash, size)
+	}
+	other := blob.Hash([]byte("other content"))
+	if _, _, err := store.PutReader(strings.NewReader("corrupted"), other); !errors.Is(err, blob.ErrHashMismatch) {
+		t.Errorf("expected ErrHashMismatch, got %v", err)
+	}
+	if store.Has(other) || store.Has(blob.Hash([]byte("corrupted"))) {
+		t.Errorf("mismatched content should not be saved")
+	}
+}
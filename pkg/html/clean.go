@@ -3,222 +3,328 @@ package html
 import (
 	"regexp"
 	"strings"
+
+	xhtml "golang.org/x/net/html"
 )
 
-func CleanStyle(style string, remainingKeys []string) string {
-	if style == "" || len(remainingKeys) == 0 {
-		return ""
+// SanitizeProfile 清理 html 时使用的白名单
+type SanitizeProfile struct {
+	Name string
+	// Tags 保留的标签，不在其中的标签去掉但保留内容
+	Tags map[string]bool
+	// Attrs 所有标签都可以使用的属性
+	Attrs map[string]bool
+	// TagAttrs 特定标签可以使用的属性
+	TagAttrs map[string]map[string]bool
+	// Styles 保留的 css 属性，为空时去掉 style
+	Styles map[string]bool
+	// KeepClass 是否保留 class，为 false 时只保留代码块的语言
+	KeepClass bool
+	// TextOnly 只输出文本
+	TextOnly bool
+}
+
+const (
+	SanitizeProfileStrict     = "strict"
+	SanitizeProfileKeepLayout = "keep_layout"
+	SanitizeProfileTextOnly   = "text_only"
+)
+
+func set_of(values ...string) map[string]bool {
+	m := make(map[string]bool, len(values))
+	for _, v := range values {
+		m[v] = true
 	}
+	return m
+}
 
-	// 创建保留键的映射，便于快速查找
-	allowedKeys := make(map[string]bool)
-	for _, key := range remainingKeys {
-		allowedKeys[strings.ToLower(strings.TrimSpace(key))] = true
+func merge_set(a map[string]bool, values ...string) map[string]bool {
+	m := make(map[string]bool, len(a)+len(values))
+	for k := range a {
+		m[k] = true
+	}
+	for _, v := range values {
+		m[v] = true
 	}
+	return m
+}
+
+// 这些标签连同内容一起去掉
+var sanitize_dropped_tags = set_of(
+	"script", "style", "iframe", "frame", "frameset", "object", "embed", "applet", "noscript",
+	"template", "svg", "math", "head", "title", "meta", "link", "base", "input", "button",
+	"select", "textarea", "option", "audio", "video", "source", "track", "canvas", "param",
+)
 
-	// 分割样式声明
-	declarations := strings.Split(style, "; ")
-	var cleanedDeclarations []string
+var sanitize_void_tags = set_of("br", "hr", "img", "col", "wbr")
 
-	for _, decl := range declarations {
-		decl = strings.TrimSpace(decl)
-		if decl == "" {
-			continue
-		}
+// 包含地址的属性，需要检查协议
+var sanitize_url_attrs = set_of("href", "src", "cite")
+
+var sanitize_url_schemes = set_of("http", "https", "mailto", "tel")
+
+var strict_tags = set_of(
+	"p", "br", "hr", "div", "span", "b", "strong", "i", "em", "u", "s", "del", "ins", "mark", "small",
+	"sub", "sup", "code", "pre", "kbd", "samp", "blockquote", "q", "cite", "abbr",
+	"h1", "h2", "h3", "h4", "h5", "h6", "ul", "ol", "li", "dl", "dt", "dd",
+	"table", "thead", "tbody", "tfoot", "tr", "th", "td", "caption", "colgroup", "col",
+	"a", "img", "figure", "figcaption", "details", "summary",
+)
+
+var strict_tag_attrs = map[string]map[string]bool{
+	"a":        set_of("href"),
+	"img":      set_of("src", "alt", "width", "height"),
+	"ol":       set_of("start", "type"),
+	"li":       set_of("value"),
+	"td":       set_of("colspan", "rowspan", "align"),
+	"th":       set_of("colspan", "rowspan", "align", "scope"),
+	"col":      set_of("span"),
+	"colgroup": set_of("span"),
+	"q":        set_of("cite"),
+	"details":  set_of("open"),
+}
+
+var strict_styles = set_of(
+	"color", "background-color", "font-weight", "font-style", "text-decoration", "text-align", "white-space",
+)
 
-		// 分割键值对
+var ProfileStrict = &SanitizeProfile{
+	Name:     SanitizeProfileStrict,
+	Tags:     strict_tags,
+	Attrs:    set_of("title", "lang", "dir"),
+	TagAttrs: strict_tag_attrs,
+	Styles:   strict_styles,
+}
+
+var ProfileKeepLayout = &SanitizeProfile{
+	Name:     SanitizeProfileKeepLayout,
+	Tags:     merge_set(strict_tags, "section", "article", "header", "footer", "main", "nav", "aside", "center"),
+	Attrs:    set_of("title", "lang", "dir"),
+	TagAttrs: strict_tag_attrs,
+	// 不保留 position 和 z-index，避免内容用 fixed 定位覆盖预览窗口
+	Styles: merge_set(strict_styles,
+		"overflow", "outline",
+		"margin", "margin-left", "margin-right", "margin-top", "margin-bottom",
+		"border", "border-color", "border-width", "border-style", "border-radius",
+		"padding", "padding-inline", "padding-left", "padding-right", "padding-top", "padding-bottom",
+		"width", "min-width", "max-width", "height", "min-height", "max-height",
+		"display", "flex", "flex-flow", "flex-direction", "flex-wrap", "align-items", "justify-content", "gap",
+		"grid-auto-columns", "grid-auto-flow", "grid-auto-rows",
+		"grid-template-areas", "grid-template-columns", "grid-template-rows",
+		"float", "clear", "box-sizing", "box-shadow", "line-height", "direction",
+		"font-size", "font-family", "text-overflow", "text-shadow", "opacity",
+		"background", "list-style", "visibility", "vertical-align",
+	),
+	KeepClass: true,
+}
+
+var ProfileTextOnly = &SanitizeProfile{
+	Name:     SanitizeProfileTextOnly,
+	TextOnly: true,
+}
+
+// FindSanitizeProfile 按名称查找，找不到时返回 ProfileKeepLayout
+func FindSanitizeProfile(name string) *SanitizeProfile {
+	switch name {
+	case SanitizeProfileStrict:
+		return ProfileStrict
+	case SanitizeProfileTextOnly:
+		return ProfileTextOnly
+	}
+	return ProfileKeepLayout
+}
+
+var url_ignored_chars_regexp = regexp.MustCompile(`[\x00-\x20\x7f]+`)
+var url_scheme_regexp = regexp.MustCompile(`^([a-zA-Z][a-zA-Z0-9+.-]*):`)
+var data_image_regexp = regexp.MustCompile(`^data:image/(png|jpeg|jpg|gif|webp);base64,[a-zA-Z0-9+/=\s]*$`)
+
+// safe_url 相对地址和白名单中的协议是安全的，浏览器会忽略协议中的空白和控制字符，判断前先去掉
+func safe_url(tag string, attr string, value string) bool {
+	v := url_ignored_chars_regexp.ReplaceAllString(value, "")
+	m := url_scheme_regexp.FindStringSubmatch(v)
+	if m == nil {
+		return true
+	}
+	scheme := strings.ToLower(m[1])
+	if sanitize_url_schemes[scheme] {
+		return true
+	}
+	if scheme == "data" && tag == "img" && attr == "src" {
+		return data_image_regexp.MatchString(strings.TrimSpace(value))
+	}
+	return false
+}
+
+var css_comment_regexp = regexp.MustCompile(`(?s)/\*.*?\*/`)
+
+// 样式值中出现这些内容时整条声明去掉
+var unsafe_css_value_regexp = regexp.MustCompile(`(?i)url\s*\(|expression\s*\(|javascript:|vbscript:|@import|behavior|-moz-binding|\\|<|>`)
+
+func sanitize_style(style string, allowed map[string]bool) string {
+	if len(allowed) == 0 {
+		return ""
+	}
+	style = css_comment_regexp.ReplaceAllString(style, "")
+	var result []string
+	for _, decl := range strings.Split(style, ";") {
 		parts := strings.SplitN(decl, ":", 2)
 		if len(parts) != 2 {
-			continue // 无效的声明格式，跳过
+			continue
 		}
-
 		key := strings.ToLower(strings.TrimSpace(parts[0]))
 		value := strings.TrimSpace(parts[1])
-		if value == "revert" {
+		if !allowed[key] || value == "" {
 			continue
 		}
-		if value == "initial" {
+		switch strings.ToLower(value) {
+		case "revert", "initial", "inherit", "unset":
 			continue
 		}
-		if value == "inherit" {
+		if unsafe_css_value_regexp.MatchString(value) {
 			continue
 		}
-		// fmt.Println("check need remaining", key, value)
-		// 检查是否在保留列表中
-		if allowedKeys[key] {
-			cleanedDeclarations = append(cleanedDeclarations, key+":"+value)
+		result = append(result, key+":"+value)
+	}
+	return strings.Join(result, "; ")
+}
+
+var language_class_regexp = regexp.MustCompile(`^(?:language|lang|highlight-source)-[\w+#.-]+$`)
+
+// sanitize_class 不保留 class 时只保留 language-xxx 这类标记代码语言的 class
+func sanitize_class(value string, keep bool) string {
+	var result []string
+	for _, c := range strings.Fields(value) {
+		if keep || language_class_regexp.MatchString(c) {
+			result = append(result, c)
 		}
 	}
+	return strings.Join(result, " ")
+}
 
-	return strings.Join(cleanedDeclarations, "; ")
-}
-
-// 或者如果你希望remainingKeys是固定的，可以这样实现：
-func CleanStyleFixed(style string) string {
-	// 定义需要保留的样式属性
-	remainingKeys := []string{
-		"position",
-		"overflow",
-		"z-index",
-		"outline",
-		"margin",
-		"margin-left",
-		"margin-right",
-		"margin-top",
-		"margin-bottom",
-		"border",
-		"border-color",
-		"border-width",
-		"border-radius",
-		"padding",
-		"padding-left",
-		"padding-right",
-		"padding-top",
-		"padding-bottom",
-		"width",
-		"min-width",
-		"max-width",
-		"height",
-		"min-height",
-		"max-height",
-		"content",
-		"display",
-		"flex",
-		"flex-flow",
-		"gap",
-		"float",
-		"clear",
-		"cursor",
-		"box-sizing",
-		"box-shadow",
-		"color",
-		"line-height",
-		"direction",
-		"font-size",
-		"font-weight",
-		"font-family",
-		"text-align",
-		"text-decoration",
-		"text-overflow",
-		"text-shadow",
-		"line-height",
-		"opacity",
-		"background",
-		"background-color",
-		"background-size",
-		"background-image",
-		"background-position",
-		"background-origin",
-		"background-repeat",
-		"list-style",
-		"shadow",
-		"visibility",
-		"translate",
-		"transform",
-		"transform-origin",
-		"transform-style",
-		"rotate",
-		"zoom",
-	}
-
-	return CleanStyle(style, remainingKeys)
-}
-
-// 更严格的清理版本，只保留最常用的样式
-func CleanRichTextStrict(html string) string {
-	// 只保留最核心的样式属性
-	style_names := []string{
-		"position",
-		"overflow",
-		"z-index",
-		"outline",
-		"margin",
-		"margin-left",
-		"margin-right",
-		"margin-top",
-		"margin-bottom",
-		"border",
-		"border-color",
-		"border-width",
-		"border-radius",
-		"padding",
-		"padding-inline",
-		"padding-left",
-		"padding-right",
-		"padding-top",
-		"padding-bottom",
-		"width",
-		"min-width",
-		"max-width",
-		"height",
-		"min-height",
-		"max-height",
-		"content",
-		"display",
-		"flex",
-		"flex-flow",
-		"gap",
-		"grid-auto-columns",
-		"grid-auto-flow",
-		"grid-auto-rows",
-		"grid-template-areas",
-		"grid-template-columns",
-		"grid-template-rows",
-		"float",
-		"clear",
-		"cursor",
-		"box-sizing",
-		"box-shadow",
-		"color",
-		"line-height",
-		"direction",
-		"font-size",
-		"font-weight",
-		"font-family",
-		"text-align",
-		"text-decoration",
-		"text-overflow",
-		"text-shadow",
-		"white-space",
-		"line-height",
-		"opacity",
-		"background",
-		"background-color",
-		"background-size",
-		"background-image",
-		"background-position",
-		"background-origin",
-		"background-repeat",
-		"list-style",
-		"shadow",
-		"visibility",
-		"translate",
-		"transform",
-		"transform-origin",
-		"transform-style",
-		"rotate",
-		"scale",
-	}
-
-	styleRegex := regexp.MustCompile(`style {0,1}= {0,1}["'][^"']{1,}["']`)
-
-	cleanedHTML := styleRegex.ReplaceAllStringFunc(html, func(match string) string {
-		valueRegex := regexp.MustCompile(`["']([^"']{1,})["']`)
-		matches := valueRegex.FindStringSubmatch(match)
-		if len(matches) < 2 {
-			return `style=""`
+type sanitizer struct {
+	profile *SanitizeProfile
+	b       strings.Builder
+}
+
+func (s *sanitizer) attrs(tag string, n *xhtml.Node) []xhtml.Attribute {
+	var result []xhtml.Attribute
+	seen := make(map[string]bool)
+	for _, attr := range n.Attr {
+		key := strings.ToLower(attr.Key)
+		if attr.Namespace != "" || seen[key] {
+			continue
 		}
+		value := attr.Val
+		switch {
+		case key == "style":
+			value = sanitize_style(value, s.profile.Styles)
+		case key == "class":
+			value = sanitize_class(value, s.profile.KeepClass)
+		case s.profile.Attrs[key] || s.profile.TagAttrs[tag][key]:
+			if sanitize_url_attrs[key] && !safe_url(tag, key, value) {
+				continue
+			}
+		default:
+			continue
+		}
+		if value == "" && key != "alt" {
+			continue
+		}
+		seen[key] = true
+		result = append(result, xhtml.Attribute{Key: key, Val: value})
+	}
+	if tag == "a" {
+		for _, attr := range result {
+			if attr.Key == "href" {
+				result = append(result, xhtml.Attribute{Key: "rel", Val: "noopener noreferrer"})
+				break
+			}
+		}
+	}
+	return result
+}
 
-		styleValue := matches[1]
-		// fmt.Println("before clean", styleValue)
-		cleanedStyle := CleanStyle(styleValue, style_names)
-		// fmt.Println("after CleanStyle", cleanedStyle)
-		if cleanedStyle == "" {
-			return ""
+func (s *sanitizer) node(n *xhtml.Node) {
+	switch n.Type {
+	case xhtml.TextNode:
+		s.b.WriteString(xhtml.EscapeString(n.Data))
+		return
+	case xhtml.DocumentNode:
+		s.children(n)
+		return
+	case xhtml.ElementNode:
+	default:
+		// 注释、doctype 直接去掉
+		return
+	}
+	if n.Namespace != "" {
+		return
+	}
+	tag := strings.ToLower(n.Data)
+	if sanitize_dropped_tags[tag] {
+		return
+	}
+	if s.profile.TextOnly {
+		if sanitize_void_tags[tag] && tag != "br" {
+			return
+		}
+		if tag == "br" {
+			s.b.WriteString("\n")
+			return
+		}
+		block := markdown_block_tags[tag] || tag == "li" || tag == "tr" || tag == "pre" || tag == "blockquote" || (len(tag) == 2 && tag[0] == 'h' && tag[1] >= '1' && tag[1] <= '6')
+		if block {
+			s.newline()
+		}
+		s.children(n)
+		if block {
+			s.newline()
 		}
+		return
+	}
+	if !s.profile.Tags[tag] {
+		s.children(n)
+		return
+	}
+	s.b.WriteString("<" + tag)
+	for _, attr := range s.attrs(tag, n) {
+		s.b.WriteString(" " + attr.Key + `="` + xhtml.EscapeString(attr.Val) + `"`)
+	}
+	s.b.WriteString(">")
+	if sanitize_void_tags[tag] {
+		return
+	}
+	s.children(n)
+	s.b.WriteString("</" + tag + ">")
+}
 
-		return `style="` + cleanedStyle + `;"`
-	})
-	// fmt.Println("cleaned html", cleanedHTML)
-	return cleanedHTML
+func (s *sanitizer) newline() {
+	out := s.b.String()
+	if out != "" && !strings.HasSuffix(out, "\n") {
+		s.b.WriteString("\n")
+	}
+}
+
+func (s *sanitizer) children(n *xhtml.Node) {
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		s.node(c)
+	}
+}
+
+// Sanitize 解析 html 后按白名单重新输出，去掉脚本、事件属性和不安全的链接
+func Sanitize(content string, profile *SanitizeProfile) string {
+	if profile == nil {
+		profile = ProfileKeepLayout
+	}
+	doc, err := xhtml.Parse(strings.NewReader(content))
+	if err != nil {
+		return xhtml.EscapeString(content)
+	}
+	s := &sanitizer{profile: profile}
+	s.node(doc)
+	result := s.b.String()
+	if profile.TextOnly {
+		result = strings.TrimSpace(result)
+	}
+	return result
 }
@@ -0,0 +1,125 @@
+package html
+
+import (
+	"strings"
+	"testing"
+
+	xhtml "golang.org/x/net/html"
+)
+
+func TestSanitize(t *testing.T) {
+	cases := []struct {
+		name    string
+		profile *SanitizeProfile
+		html    string
+		want    string
+	}{
+		{
+			"script and handlers",
+			ProfileStrict,
+			`<p onclick="alert(1)">hi<script>alert(1)</script><img src=x onerror="alert(1)"></p>`,
+			`<p>hi<img src="x"></p>`,
+		},
+		{
+			"javascript urls",
+			ProfileStrict,
+			`<a href=" java&#x09;script:alert(1)">a</a><a href="https://example.com">b</a><img src="data:image/svg+xml;base64,AAAA">`,
+			`<a>a</a><a href="https://example.com" rel="noopener noreferrer">b</a><img>`,
+		},
+		{
+			"styles",
+			ProfileKeepLayout,
+			`<div style="color: red; background: url(javascript:alert(1)); width: 10px; behavior: x" class="box">x</div>`,
+			`<div style="color:red; width:10px" class="box">x</div>`,
+		},
+		{
+			"positioning",
+			ProfileKeepLayout,
+			`<div style="position: fixed; top: 0; left: 0; z-index: 9999; width: 100%">x</div>`,
+			`<div style="width:100%">x</div>`,
+		},
+		{
+			"strict keeps language class only",
+			ProfileStrict,
+			`<pre class="code language-go"><code>a</code></pre><section>b</section>`,
+			`<pre class="language-go"><code>a</code></pre>b`,
+		},
+		{
+			"text only",
+			ProfileTextOnly,
+			`<h1>Title</h1><p>a &lt;b&gt;<br>c</p><ul><li>one</li><li>two</li></ul>`,
+			"Title\na &lt;b&gt;\nc\none\ntwo",
+		},
+		{
+			"drop iframes and forms",
+			ProfileKeepLayout,
+			`<iframe src="https://example.com"></iframe><form action="/x"><input value="1">text</form><!-- comment -->`,
+			`text`,
+		},
+	}
+	for _, c := range cases {
+		if got := Sanitize(c.html, c.profile); got != c.want {
+			t.Errorf("%s: expected\n%s\ngot\n%s", c.name, c.want, got)
+		}
+	}
+}
+
+// check_sanitized 重新解析输出，确认只有白名单中的标签、属性和安全的地址
+func check_sanitized(t *testing.T, profile *SanitizeProfile, input string, output string) {
+	doc, err := xhtml.Parse(strings.NewReader(output))
+	if err != nil {
+		t.Fatalf("parse output failed, %v", err)
+	}
+	var walk func(n *xhtml.Node)
+	walk = func(n *xhtml.Node) {
+		if n.Type == xhtml.ElementNode {
+			tag := n.Data
+			// 解析时自动补上的标签
+			implied := tag == "html" || tag == "head" || tag == "body"
+			if !implied && (profile.TextOnly || !profile.Tags[tag]) {
+				t.Fatalf("input %q: unexpected tag %s in %q", input, tag, output)
+			}
+			for _, attr := range n.Attr {
+				key := attr.Key
+				allowed := key == "style" || key == "class" || (tag == "a" && key == "rel") || profile.Attrs[key] || profile.TagAttrs[tag][key]
+				if !allowed {
+					t.Fatalf("input %q: unexpected attribute %s on %s in %q", input, key, tag, output)
+				}
+				if sanitize_url_attrs[key] && !safe_url(tag, key, attr.Val) {
+					t.Fatalf("input %q: unsafe url %q in %q", input, attr.Val, output)
+				}
+				if key == "style" && unsafe_css_value_regexp.MatchString(attr.Val) {
+					t.Fatalf("input %q: unsafe style %q in %q", input, attr.Val, output)
+				}
+			}
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(doc)
+}
+
+func FuzzSanitize(f *testing.F) {
+	seeds := []string{
+		`<p onclick="alert(1)">hi</p>`,
+		`<a href="javascript:alert(1)">x</a>`,
+		`<img src=x onerror=alert(1)>`,
+		`<svg><script>alert(1)</script></svg>`,
+		`<table><tr><td><math><mi xlink:href="javascript:alert(1)">x</mi></math></td></tr></table>`,
+		`<div style="background:url(javascript:alert(1))">x</div>`,
+		`<noscript><p title="</noscript><img src=x onerror=alert(1)>">`,
+		`<a href="&#106;avascript:alert(1)">x</a>`,
+		`<style><img src=x onerror=alert(1)></style>`,
+		`<<script>script>alert(1)<</script>/script>`,
+	}
+	for _, s := range seeds {
+		f.Add(s)
+	}
+	profiles := []*SanitizeProfile{ProfileStrict, ProfileKeepLayout, ProfileTextOnly}
+	f.Fuzz(func(t *testing.T, input string) {
+		for _, profile := range profiles {
+			check_sanitized(t, profile, input, Sanitize(input, profile))
+		}
+	})
+}
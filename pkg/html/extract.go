@@ -0,0 +1,236 @@
+package html
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"net/url"
+	"strconv"
+	"strings"
+
+	xhtml "golang.org/x/net/html"
+)
+
+type CodeBlock struct {
+	// Language 从 class、data-lang 中读取的语言，例如 go、js
+	Language string `json:"language"`
+	Code     string `json:"code"`
+}
+
+type Table struct {
+	// Header 表头，没有 <th> 时为空
+	Header []string   `json:"header"`
+	Rows   [][]string `json:"rows"`
+}
+
+type Link struct {
+	Text string `json:"text"`
+	URL  string `json:"url"`
+}
+
+// Extracted 从 html 中提取的结构化内容
+type Extracted struct {
+	CodeBlocks []CodeBlock `json:"code_blocks"`
+	Tables     []Table     `json:"tables"`
+	Links      []Link      `json:"links"`
+}
+
+func (t *Table) CSV() string {
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	if len(t.Header) != 0 {
+		w.Write(t.Header)
+	}
+	w.WriteAll(t.Rows)
+	return strings.TrimRight(buf.String(), "\n")
+}
+
+// table_keys 对象的键，空的表头用 column_n 代替，重复的表头加上 _2、_3 后缀
+func table_keys(header []string, n int) []string {
+	keys := make([]string, 0, n)
+	used := make(map[string]bool)
+	for i := 0; i < n; i++ {
+		key := ""
+		if i < len(header) {
+			key = header[i]
+		}
+		if key == "" {
+			key = "column_" + strconv.Itoa(i+1)
+		}
+		base := key
+		for j := 2; used[key]; j++ {
+			key = base + "_" + strconv.Itoa(j)
+		}
+		used[key] = true
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+// table_row 按表头的顺序输出对象的键
+type table_row struct {
+	keys   []string
+	values []string
+}
+
+func (r table_row) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, key := range r.keys {
+		if i != 0 {
+			buf.WriteByte(',')
+		}
+		k, err := json.Marshal(key)
+		if err != nil {
+			return nil, err
+		}
+		v, err := json.Marshal(r.values[i])
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(k)
+		buf.WriteByte(':')
+		buf.Write(v)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// JSON 有表头时输出对象数组，键的顺序和表头一致，否则输出二维数组
+func (t *Table) JSON() string {
+	var v interface{} = t.Rows
+	if len(t.Header) != 0 {
+		n := len(t.Header)
+		for _, row := range t.Rows {
+			n = max(n, len(row))
+		}
+		keys := table_keys(t.Header, n)
+		list := make([]table_row, 0, len(t.Rows))
+		for _, row := range t.Rows {
+			values := make([]string, n)
+			copy(values, row)
+			list = append(list, table_row{keys: keys, values: values})
+		}
+		v = list
+	}
+	b, _ := json.MarshalIndent(v, "", "  ")
+	return string(b)
+}
+
+// cell_text 单元格的文本，多个空白合并为一个空格
+func cell_text(n *xhtml.Node) string {
+	return single_line(text_content(n))
+}
+
+func extract_table(n *xhtml.Node) Table {
+	var t Table
+	var walk func(*xhtml.Node)
+	walk = func(n *xhtml.Node) {
+		for child := n.FirstChild; child != nil; child = child.NextSibling {
+			if child.Type != xhtml.ElementNode {
+				continue
+			}
+			switch child.Data {
+			case "thead", "tbody", "tfoot":
+				walk(child)
+			case "tr":
+				var row []string
+				is_header := child.Parent.Data == "thead"
+				all_th := true
+				for cell := child.FirstChild; cell != nil; cell = cell.NextSibling {
+					if cell.Type != xhtml.ElementNode || (cell.Data != "td" && cell.Data != "th") {
+						continue
+					}
+					if cell.Data != "th" {
+						all_th = false
+					}
+					row = append(row, cell_text(cell))
+				}
+				if len(row) == 0 {
+					continue
+				}
+				if t.Header == nil && len(t.Rows) == 0 && (is_header || all_th) {
+					t.Header = row
+					continue
+				}
+				t.Rows = append(t.Rows, row)
+			}
+		}
+	}
+	walk(n)
+	return t
+}
+
+// Extract 提取代码块、表格和链接，链接按地址去重，base_url 不为空时把相对地址转换为绝对地址
+func Extract(content string, base_url string) (*Extracted, error) {
+	doc, err := xhtml.Parse(strings.NewReader(content))
+	if err != nil {
+		return nil, err
+	}
+	base, err := url.Parse(base_url)
+	if err != nil || base.Host == "" {
+		base = nil
+	}
+	result := &Extracted{}
+	seen_links := make(map[string]bool)
+	var walk func(*xhtml.Node)
+	walk = func(n *xhtml.Node) {
+		if n.Type == xhtml.ElementNode {
+			switch n.Data {
+			case "script", "style", "head", "template":
+				return
+			case "pre":
+				code := strings.Trim(text_content(n), "\n")
+				if strings.TrimSpace(code) != "" {
+					result.CodeBlocks = append(result.CodeBlocks, CodeBlock{
+						Language: code_block_language(n),
+						Code:     code,
+					})
+				}
+				return
+			case "table":
+				t := extract_table(n)
+				if len(t.Rows) != 0 || len(t.Header) != 0 {
+					result.Tables = append(result.Tables, t)
+				}
+				// 表格中的链接也需要提取
+			case "a":
+				href := strings.TrimSpace(get_attr(n, "href"))
+				lower := strings.ToLower(href)
+				if href == "" || strings.HasPrefix(href, "#") || strings.HasPrefix(lower, "javascript:") {
+					break
+				}
+				if base != nil {
+					if u, err := base.Parse(href); err == nil {
+						href = u.String()
+					}
+				}
+				if !seen_links[href] {
+					seen_links[href] = true
+					result.Links = append(result.Links, Link{
+						Text: single_line(text_content(n)),
+						URL:  href,
+					})
+				}
+			}
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(doc)
+	return result, nil
+}
+
+// LinksMarkdown 把链接列表输出为 Markdown 列表
+func (e *Extracted) LinksMarkdown() string {
+	lines := make([]string, 0, len(e.Links))
+	for _, l := range e.Links {
+		text := l.Text
+		if text == "" {
+			text = l.URL
+		}
+		lines = append(lines, "- ["+markdown_escape_replacer.Replace(text)+"]("+markdown_url(l.URL)+")")
+	}
+	return strings.Join(lines, "\n")
+}
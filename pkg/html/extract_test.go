@@ -0,0 +1,87 @@
+package html_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	_html "devboard/pkg/html"
+)
+
+func TestExtract(t *testing.T) {
+	content := `<div class="highlight highlight-source-go"><pre>package main
+</pre></div>
+<pre><code class="language-js">let a = 1;</code></pre>
+<table><thead><tr><th>Name</th><th>Age</th></tr></thead>
+<tbody><tr><td><a href="/u/alice">Alice</a></td><td>30</td></tr><tr><td>Bob, Jr.</td><td>4</td></tr></tbody></table>
+<p><a href="https://example.com">Example</a> <a href="#top">top</a> <a href="javascript:void(0)">x</a> <a href="https://example.com">again</a></p>`
+	r, err := _html.Extract(content, "https://github.com/a/b")
+	if err != nil {
+		t.Fatal(err)
+	}
+	code := []_html.CodeBlock{{Language: "go", Code: "package main"}, {Language: "js", Code: "let a = 1;"}}
+	if !reflect.DeepEqual(r.CodeBlocks, code) {
+		t.Errorf("unexpected code blocks %+v", r.CodeBlocks)
+	}
+	links := []_html.Link{{Text: "Alice", URL: "https://github.com/u/alice"}, {Text: "Example", URL: "https://example.com"}}
+	if !reflect.DeepEqual(r.Links, links) {
+		t.Errorf("unexpected links %+v", r.Links)
+	}
+	if len(r.Tables) != 1 {
+		t.Fatalf("expected 1 table, got %d", len(r.Tables))
+	}
+	table := r.Tables[0]
+	if csv := table.CSV(); csv != "Name,Age\nAlice,30\n\"Bob, Jr.\",4" {
+		t.Errorf("unexpected csv %q", csv)
+	}
+	want_json := `[
+  {
+    "Name": "Alice",
+    "Age": "30"
+  },
+  {
+    "Name": "Bob, Jr.",
+    "Age": "4"
+  }
+]`
+	if j := table.JSON(); j != want_json {
+		t.Errorf("unexpected json %s", j)
+	}
+	if md := r.LinksMarkdown(); md != "- [Alice](https://github.com/u/alice)\n- [Example](https://example.com)" {
+		t.Errorf("unexpected markdown %q", md)
+	}
+}
+
+func TestTableJSON(t *testing.T) {
+	cases := []struct {
+		name  string
+		table _html.Table
+		want  string
+	}{
+		{
+			"duplicate and empty headers",
+			_html.Table{Header: []string{"Name", "", "Name", "Zone"}, Rows: [][]string{{"a", "b", "c", "d"}}},
+			`[{"Name":"a","column_2":"b","Name_2":"c","Zone":"d"}]`,
+		},
+		{
+			"short and long rows",
+			_html.Table{Header: []string{"B", "A"}, Rows: [][]string{{"1"}, {"1", "2", "3"}}},
+			`[{"B":"1","A":"","column_3":""},{"B":"1","A":"2","column_3":"3"}]`,
+		},
+		{
+			"no header",
+			_html.Table{Rows: [][]string{{"1", "2"}}},
+			`[["1","2"]]`,
+		},
+	}
+	for _, c := range cases {
+		var buf bytes.Buffer
+		if err := json.Compact(&buf, []byte(c.table.JSON())); err != nil {
+			t.Fatalf("%s: %v", c.name, err)
+		}
+		if buf.String() != c.want {
+			t.Errorf("%s: got %s, want %s", c.name, buf.String(), c.want)
+		}
+	}
+}
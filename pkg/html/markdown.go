@@ -0,0 +1,516 @@
+package html
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	xhtml "golang.org/x/net/html"
+)
+
+// markdown_writer 输出 Markdown，负责处理块之间的空行和行首空白
+type markdown_writer struct {
+	buf bytes.Buffer
+}
+
+func (w *markdown_writer) last_byte() byte {
+	if w.buf.Len() == 0 {
+		return 0
+	}
+	return w.buf.Bytes()[w.buf.Len()-1]
+}
+
+// write_text 写入普通文本，行首和连续的空格会被忽略
+func (w *markdown_writer) write_text(s string) {
+	if strings.HasPrefix(s, " ") {
+		last := w.last_byte()
+		if last == 0 || last == ' ' || last == '\n' {
+			s = strings.TrimLeft(s, " ")
+		}
+	}
+	w.buf.WriteString(s)
+}
+
+func (w *markdown_writer) write(s string) {
+	w.buf.WriteString(s)
+}
+
+// block 开始一个新的块，保证和前面的内容之间有一个空行
+func (w *markdown_writer) block() {
+	b := w.buf.Bytes()
+	n := len(b)
+	for n > 0 && (b[n-1] == ' ' || b[n-1] == '\t') {
+		n--
+	}
+	w.buf.Truncate(n)
+	if n == 0 {
+		return
+	}
+	newlines := 0
+	for i := n - 1; i >= 0 && b[i] == '\n' && newlines < 2; i-- {
+		newlines++
+	}
+	w.buf.WriteString(strings.Repeat("\n", 2-newlines))
+}
+
+func (w *markdown_writer) String() string {
+	return w.buf.String()
+}
+
+var markdown_whitespace_regexp = regexp.MustCompile(`\s+`)
+var markdown_blank_line_regexp = regexp.MustCompile(`\n{2,}`)
+var markdown_escape_replacer = strings.NewReplacer(
+	`\`, `\\`,
+	"*", `\*`,
+	"_", `\_`,
+	"`", "\\`",
+	"[", `\[`,
+	"]", `\]`,
+)
+
+var markdown_skipped_tags = map[string]bool{
+	"head":     true,
+	"script":   true,
+	"style":    true,
+	"noscript": true,
+	"template": true,
+	"meta":     true,
+	"link":     true,
+	"title":    true,
+	"button":   true,
+	"svg":      true,
+}
+
+var markdown_block_tags = map[string]bool{
+	"p":          true,
+	"div":        true,
+	"section":    true,
+	"article":    true,
+	"header":     true,
+	"footer":     true,
+	"main":       true,
+	"nav":        true,
+	"aside":      true,
+	"figure":     true,
+	"figcaption": true,
+	"dl":         true,
+	"dt":         true,
+	"dd":         true,
+	"address":    true,
+	"details":    true,
+	"summary":    true,
+}
+
+func find_attr(n *xhtml.Node, key string) (string, bool) {
+	for _, attr := range n.Attr {
+		if strings.EqualFold(attr.Key, key) {
+			return attr.Val, true
+		}
+	}
+	return "", false
+}
+
+func get_attr(n *xhtml.Node, key string) string {
+	v, _ := find_attr(n, key)
+	return v
+}
+
+// text_content 返回节点的原始文本，<br> 转换为换行
+func text_content(n *xhtml.Node) string {
+	var b strings.Builder
+	var walk func(*xhtml.Node)
+	walk = func(n *xhtml.Node) {
+		if n.Type == xhtml.TextNode {
+			b.WriteString(n.Data)
+			return
+		}
+		if n.Type == xhtml.ElementNode && n.Data == "br" {
+			b.WriteString("\n")
+			return
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(n)
+	return b.String()
+}
+
+var code_language_regexp = regexp.MustCompile(`(?:^|\s)(?:language|lang|highlight-source|highlight)-([\w+#.-]+)`)
+var brush_regexp = regexp.MustCompile(`brush:\s*([\w+#.-]+)`)
+
+func node_language(n *xhtml.Node) string {
+	if v := get_attr(n, "data-lang"); v != "" {
+		return v
+	}
+	if v := get_attr(n, "data-language"); v != "" {
+		return v
+	}
+	class := get_attr(n, "class")
+	if m := code_language_regexp.FindStringSubmatch(class); m != nil {
+		return m[1]
+	}
+	if m := brush_regexp.FindStringSubmatch(class); m != nil {
+		return m[1]
+	}
+	return ""
+}
+
+// code_block_language 依次从 <pre>、<pre> 下的 <code>、外层元素上查找语言
+func code_block_language(pre *xhtml.Node) string {
+	if lang := node_language(pre); lang != "" {
+		return lang
+	}
+	for c := pre.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type == xhtml.ElementNode && c.Data == "code" {
+			if lang := node_language(c); lang != "" {
+				return lang
+			}
+		}
+	}
+	p := pre.Parent
+	for i := 0; i < 2 && p != nil; i++ {
+		if p.Type == xhtml.ElementNode {
+			if lang := node_language(p); lang != "" {
+				return lang
+			}
+		}
+		p = p.Parent
+	}
+	return ""
+}
+
+// fence 返回比内容中最长的连续反引号更长的围栏
+func fence(content string, min int) string {
+	longest := 0
+	current := 0
+	for _, r := range content {
+		if r == '`' {
+			current++
+			if current > longest {
+				longest = current
+			}
+		} else {
+			current = 0
+		}
+	}
+	if longest+1 > min {
+		min = longest + 1
+	}
+	return strings.Repeat("`", min)
+}
+
+func markdown_url(u string) string {
+	u = strings.TrimSpace(u)
+	u = strings.ReplaceAll(u, " ", "%20")
+	u = strings.ReplaceAll(u, "(", "%28")
+	return strings.ReplaceAll(u, ")", "%29")
+}
+
+type markdown_converter struct{}
+
+func (c *markdown_converter) inline(n *xhtml.Node) string {
+	w := &markdown_writer{}
+	c.children(w, n)
+	return w.String()
+}
+
+// single_line 把内容合并为一行，用于标题和表格单元格
+func single_line(s string) string {
+	return strings.TrimSpace(markdown_whitespace_regexp.ReplaceAllString(s, " "))
+}
+
+func (c *markdown_converter) children(w *markdown_writer, n *xhtml.Node) {
+	for child := n.FirstChild; child != nil; child = child.NextSibling {
+		c.node(w, child)
+	}
+}
+
+// wrap 输出 **粗体** 这类行内格式，空白放在标记外面
+func (c *markdown_converter) wrap(w *markdown_writer, n *xhtml.Node, mark string) {
+	inner := c.inline(n)
+	core := strings.TrimSpace(inner)
+	if core == "" {
+		w.write_text(inner)
+		return
+	}
+	if strings.HasPrefix(inner, " ") || strings.HasPrefix(inner, "\n") {
+		w.write_text(" ")
+	}
+	w.write(mark + core + mark)
+	if strings.HasSuffix(inner, " ") || strings.HasSuffix(inner, "\n") {
+		w.write(" ")
+	}
+}
+
+func (c *markdown_converter) node(w *markdown_writer, n *xhtml.Node) {
+	switch n.Type {
+	case xhtml.TextNode:
+		w.write_text(markdown_escape_replacer.Replace(markdown_whitespace_regexp.ReplaceAllString(n.Data, " ")))
+		return
+	case xhtml.DocumentNode:
+		c.children(w, n)
+		return
+	case xhtml.ElementNode:
+	default:
+		return
+	}
+	tag := strings.ToLower(n.Data)
+	if markdown_skipped_tags[tag] {
+		return
+	}
+	switch tag {
+	case "h1", "h2", "h3", "h4", "h5", "h6":
+		level, _ := strconv.Atoi(tag[1:])
+		text := single_line(c.inline(n))
+		if text == "" {
+			return
+		}
+		w.block()
+		w.write(strings.Repeat("#", level) + " " + text)
+		w.block()
+	case "br":
+		w.write("  \n")
+	case "hr":
+		w.block()
+		w.write("---")
+		w.block()
+	case "strong", "b":
+		c.wrap(w, n, "**")
+	case "em", "i":
+		c.wrap(w, n, "_")
+	case "del", "s", "strike":
+		c.wrap(w, n, "~~")
+	case "code", "kbd", "samp", "tt":
+		text := markdown_whitespace_regexp.ReplaceAllString(text_content(n), " ")
+		if strings.TrimSpace(text) == "" {
+			return
+		}
+		f := fence(text, 1)
+		if strings.HasPrefix(text, "`") || strings.HasSuffix(text, "`") {
+			text = " " + text + " "
+		}
+		w.write(f + text + f)
+	case "pre":
+		content := strings.TrimRight(text_content(n), "\n ")
+		content = strings.TrimLeft(content, "\n")
+		f := fence(content, 3)
+		w.block()
+		w.write(f + code_block_language(n) + "\n" + content + "\n" + f)
+		w.block()
+	case "a":
+		href := get_attr(n, "href")
+		text := strings.TrimSpace(c.inline(n))
+		if href == "" || strings.HasPrefix(strings.ToLower(href), "javascript:") {
+			w.write_text(text)
+			return
+		}
+		if text == "" {
+			return
+		}
+		if text == markdown_escape_replacer.Replace(href) {
+			w.write("<" + href + ">")
+			return
+		}
+		w.write("[" + text + "](" + markdown_url(href) + ")")
+	case "img":
+		src := get_attr(n, "src")
+		alt := markdown_escape_replacer.Replace(get_attr(n, "alt"))
+		// data: 图片直接写在 Markdown 中太大，只保留说明文字
+		if src == "" || strings.HasPrefix(src, "data:") {
+			w.write_text(alt)
+			return
+		}
+		w.write("![" + alt + "](" + markdown_url(src) + ")")
+	case "ul", "ol":
+		c.list(w, n, tag == "ol")
+	case "blockquote":
+		content := strings.TrimSpace(c.block_content(n))
+		if content == "" {
+			return
+		}
+		lines := strings.Split(content, "\n")
+		for i, line := range lines {
+			if line == "" {
+				lines[i] = ">"
+				continue
+			}
+			lines[i] = "> " + line
+		}
+		w.block()
+		w.write(strings.Join(lines, "\n"))
+		w.block()
+	case "table":
+		c.table(w, n)
+	case "input":
+		if strings.EqualFold(get_attr(n, "type"), "checkbox") {
+			if _, checked := find_attr(n, "checked"); checked {
+				w.write("[x] ")
+			} else {
+				w.write("[ ] ")
+			}
+		}
+	default:
+		if markdown_block_tags[tag] {
+			w.block()
+			c.children(w, n)
+			w.block()
+			return
+		}
+		c.children(w, n)
+	}
+}
+
+func has_child_element(n *xhtml.Node, tag string) bool {
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type == xhtml.ElementNode && c.Data == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// block_content 在新的 writer 中渲染子节点，用于列表项、引用这类需要整体缩进的内容
+func (c *markdown_converter) block_content(n *xhtml.Node) string {
+	w := &markdown_writer{}
+	c.children(w, n)
+	return w.String()
+}
+
+func (c *markdown_converter) list(w *markdown_writer, n *xhtml.Node, ordered bool) {
+	index := 1
+	if ordered {
+		if v, err := strconv.Atoi(get_attr(n, "start")); err == nil {
+			index = v
+		}
+	}
+	var items []string
+	for li := n.FirstChild; li != nil; li = li.NextSibling {
+		if li.Type != xhtml.ElementNode || li.Data != "li" {
+			continue
+		}
+		marker := "- "
+		if ordered {
+			marker = fmt.Sprintf("%d. ", index)
+			index++
+		}
+		content := strings.TrimSpace(c.block_content(li))
+		// 没有段落的列表项输出为紧凑列表，嵌套列表不加空行
+		if !has_child_element(li, "p") && !strings.Contains(content, "```") {
+			content = markdown_blank_line_regexp.ReplaceAllString(content, "\n")
+		}
+		indent := strings.Repeat(" ", len(marker))
+		lines := strings.Split(content, "\n")
+		for i := 1; i < len(lines); i++ {
+			if lines[i] != "" {
+				lines[i] = indent + lines[i]
+			}
+		}
+		items = append(items, marker+strings.Join(lines, "\n"))
+	}
+	if len(items) == 0 {
+		return
+	}
+	w.block()
+	w.write(strings.Join(items, "\n"))
+	w.block()
+}
+
+func (c *markdown_converter) table(w *markdown_writer, n *xhtml.Node) {
+	var rows [][]string
+	var aligns []string
+	header_index := -1
+	var collect func(*xhtml.Node)
+	collect = func(n *xhtml.Node) {
+		for child := n.FirstChild; child != nil; child = child.NextSibling {
+			if child.Type != xhtml.ElementNode {
+				continue
+			}
+			switch child.Data {
+			case "thead", "tbody", "tfoot":
+				collect(child)
+			case "tr":
+				var row []string
+				is_header := child.Parent != nil && child.Parent.Data == "thead"
+				for cell := child.FirstChild; cell != nil; cell = cell.NextSibling {
+					if cell.Type != xhtml.ElementNode || (cell.Data != "td" && cell.Data != "th") {
+						continue
+					}
+					if cell.Data == "th" {
+						is_header = true
+					}
+					text := strings.ReplaceAll(single_line(c.inline(cell)), "|", `\|`)
+					row = append(row, text)
+					if len(rows) == 0 {
+						aligns = append(aligns, strings.ToLower(get_attr(cell, "align")))
+					}
+				}
+				if is_header && header_index == -1 && len(rows) == 0 {
+					header_index = 0
+				}
+				rows = append(rows, row)
+			}
+		}
+	}
+	collect(n)
+	if len(rows) == 0 {
+		return
+	}
+	columns := 0
+	for _, row := range rows {
+		if len(row) > columns {
+			columns = len(row)
+		}
+	}
+	if columns == 0 {
+		return
+	}
+	// Markdown 表格必须有表头，没有 <th> 时用空表头
+	if header_index == -1 {
+		rows = append([][]string{make([]string, columns)}, rows...)
+		aligns = nil
+	}
+	format_row := func(row []string) string {
+		cells := make([]string, columns)
+		copy(cells, row)
+		return "| " + strings.Join(cells, " | ") + " |"
+	}
+	separator := make([]string, columns)
+	for i := range separator {
+		align := ""
+		if i < len(aligns) {
+			align = aligns[i]
+		}
+		switch align {
+		case "left":
+			separator[i] = ":---"
+		case "center":
+			separator[i] = ":---:"
+		case "right":
+			separator[i] = "---:"
+		default:
+			separator[i] = "---"
+		}
+	}
+	lines := []string{format_row(rows[0]), "| " + strings.Join(separator, " | ") + " |"}
+	for _, row := range rows[1:] {
+		lines = append(lines, format_row(row))
+	}
+	w.block()
+	w.write(strings.Join(lines, "\n"))
+	w.block()
+}
+
+// ToMarkdown 把 HTML 转换为 Markdown，支持标题、列表、表格、代码块、链接和图片
+func ToMarkdown(content string) (string, error) {
+	doc, err := xhtml.Parse(strings.NewReader(content))
+	if err != nil {
+		return "", err
+	}
+	w := &markdown_writer{}
+	c := &markdown_converter{}
+	c.node(w, doc)
+	return strings.TrimSpace(w.String()), nil
+}
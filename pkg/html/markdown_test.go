@@ -0,0 +1,66 @@
+package html_test
+
+import (
+	"testing"
+
+	_html "devboard/pkg/html"
+)
+
+func TestToMarkdown(t *testing.T) {
+	cases := []struct {
+		name string
+		html string
+		want string
+	}{
+		{
+			"heading and paragraph",
+			`<h1>Title</h1><p>Some <strong>bold</strong> and <em>italic</em> text with <code>a_b</code>.</p>`,
+			"# Title\n\nSome **bold** and _italic_ text with `a_b`.",
+		},
+		{
+			"links and images",
+			`<p><a href="https://example.com/a b">Example</a> <a href="https://example.com">https://example.com</a> <img src="/logo.png" alt="logo"></p>`,
+			"[Example](https://example.com/a%20b) <https://example.com> ![logo](/logo.png)",
+		},
+		{
+			"nested list",
+			`<ul><li>one</li><li>two<ol start="3"><li>three</li><li>four</li></ol></li></ul>`,
+			"- one\n- two\n  3. three\n  4. four",
+		},
+		{
+			"code block with language",
+			"<div class=\"highlight highlight-source-go\"><pre>func main() {\n\tfmt.Println(\"```\")\n}\n</pre></div>",
+			"````go\nfunc main() {\n\tfmt.Println(\"```\")\n}\n````",
+		},
+		{
+			"code block with class on code",
+			`<pre><code class="language-js">let a = 1;</code></pre>`,
+			"```js\nlet a = 1;\n```",
+		},
+		{
+			"table",
+			`<table><thead><tr><th>Name</th><th align="right">Count</th></tr></thead><tbody><tr><td>a|b</td><td>1</td></tr><tr><td>c</td></tr></tbody></table>`,
+			"| Name | Count |\n| --- | ---: |\n| a\\|b | 1 |\n| c |  |",
+		},
+		{
+			"blockquote and escape",
+			`<blockquote><p>quote *not bold*</p><p>second</p></blockquote>`,
+			"> quote \\*not bold\\*\n>\n> second",
+		},
+		{
+			"skip script",
+			`<style>p{}</style><script>alert(1)</script><p>text</p>`,
+			"text",
+		},
+	}
+	for _, c := range cases {
+		got, err := _html.ToMarkdown(c.html)
+		if err != nil {
+			t.Errorf("%s: %v", c.name, err)
+			continue
+		}
+		if got != c.want {
+			t.Errorf("%s: expected\n%q\ngot\n%q", c.name, c.want, got)
+		}
+	}
+}
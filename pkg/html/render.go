@@ -0,0 +1,376 @@
+package html
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+
+	xhtml "golang.org/x/net/html"
+)
+
+// 支持的 Markdown 语法：标题、段落、引用、列表、分割线、代码块，行内的加粗、斜体、删除线、代码、链接和图片。
+// 原始的 html 标签会被转义，输出的 html 再经过 ProfileStrict 清理，不安全的链接会被去掉
+
+var md_heading_regexp = regexp.MustCompile(`^(#{1,6})(?:[ \t]+(.*?))?(?:[ \t]+#+)?[ \t]*$`)
+var md_hr_regexp = regexp.MustCompile(`^ {0,3}(?:(?:\*[ \t]*){3,}|(?:-[ \t]*){3,}|(?:_[ \t]*){3,})$`)
+var md_fence_regexp = regexp.MustCompile("^ {0,3}(`{3,}|~{3,})[ \t]*([^`\\s]*)")
+var md_bullet_regexp = regexp.MustCompile(`^ {0,3}([-*+])[ \t]+`)
+var md_ordered_regexp = regexp.MustCompile(`^ {0,3}(\d{1,9})[.)][ \t]+`)
+var md_quote_regexp = regexp.MustCompile(`^ {0,3}> ?`)
+
+type markdown_renderer struct {
+	b strings.Builder
+}
+
+func is_blank(line string) bool {
+	return strings.TrimSpace(line) == ""
+}
+
+// list_marker 返回列表项标记的长度，不是列表项时返回 0
+func list_marker(line string) (ordered bool, start int, width int) {
+	if m := md_bullet_regexp.FindString(line); m != "" {
+		if md_hr_regexp.MatchString(line) {
+			return false, 0, 0
+		}
+		return false, 0, len(m)
+	}
+	if m := md_ordered_regexp.FindStringSubmatch(line); m != nil {
+		n, _ := strconv.Atoi(m[1])
+		return true, n, len(m[0])
+	}
+	return false, 0, 0
+}
+
+func starts_block(line string) bool {
+	if md_heading_regexp.MatchString(strings.TrimLeft(line, " ")) {
+		return true
+	}
+	if md_hr_regexp.MatchString(line) || md_fence_regexp.MatchString(line) || md_quote_regexp.MatchString(line) {
+		return true
+	}
+	_, _, width := list_marker(line)
+	return width != 0
+}
+
+// dedent 去掉列表项内容前面的缩进
+func dedent(line string, n int) string {
+	i := 0
+	for i < n && i < len(line) && line[i] == ' ' {
+		i += 1
+	}
+	return line[i:]
+}
+
+// blocks tight 为 true 时段落不包裹 p 标签，用于紧凑的列表项
+func (r *markdown_renderer) blocks(lines []string, tight bool) {
+	for i := 0; i < len(lines); {
+		line := strings.TrimRight(lines[i], " \t")
+		if is_blank(line) {
+			i += 1
+			continue
+		}
+		if m := md_fence_regexp.FindStringSubmatch(line); m != nil {
+			indent := len(line) - len(strings.TrimLeft(line, " "))
+			var code []string
+			i += 1
+			for i < len(lines) {
+				l := lines[i]
+				trimmed := strings.TrimSpace(l)
+				if strings.HasPrefix(trimmed, m[1][:1]) && strings.Trim(trimmed, m[1][:1]) == "" && len(trimmed) >= len(m[1]) {
+					i += 1
+					break
+				}
+				code = append(code, dedent(l, indent))
+				i += 1
+			}
+			r.b.WriteString("<pre><code")
+			if m[2] != "" {
+				r.b.WriteString(` class="language-` + xhtml.EscapeString(m[2]) + `"`)
+			}
+			r.b.WriteString(">")
+			if len(code) != 0 {
+				r.b.WriteString(xhtml.EscapeString(strings.Join(code, "\n")) + "\n")
+			}
+			r.b.WriteString("</code></pre>\n")
+			continue
+		}
+		trimmed := strings.TrimLeft(line, " ")
+		if m := md_heading_regexp.FindStringSubmatch(trimmed); m != nil && len(line)-len(trimmed) <= 3 {
+			level := strconv.Itoa(len(m[1]))
+			r.b.WriteString("<h" + level + ">" + render_inline(m[2]) + "</h" + level + ">\n")
+			i += 1
+			continue
+		}
+		if md_hr_regexp.MatchString(line) {
+			r.b.WriteString("<hr>\n")
+			i += 1
+			continue
+		}
+		if md_quote_regexp.MatchString(line) {
+			var quoted []string
+			for i < len(lines) && !is_blank(lines[i]) {
+				l := lines[i]
+				if md_quote_regexp.MatchString(l) {
+					l = md_quote_regexp.ReplaceAllString(l, "")
+				} else if starts_block(l) {
+					break
+				}
+				quoted = append(quoted, l)
+				i += 1
+			}
+			r.b.WriteString("<blockquote>\n")
+			r.blocks(quoted, false)
+			r.b.WriteString("</blockquote>\n")
+			continue
+		}
+		if ordered, start, width := list_marker(line); width != 0 {
+			i = r.list(lines, i, ordered, start)
+			continue
+		}
+		var paragraph []string
+		for i < len(lines) && !is_blank(lines[i]) {
+			if len(paragraph) != 0 && starts_block(lines[i]) {
+				break
+			}
+			paragraph = append(paragraph, strings.TrimSpace(lines[i]))
+			i += 1
+		}
+		// 备注中的换行直接显示为换行
+		content := render_inline(strings.Join(paragraph, "\n"))
+		content = strings.ReplaceAll(content, "\n", "<br>\n")
+		if tight {
+			r.b.WriteString(content + "\n")
+		} else {
+			r.b.WriteString("<p>" + content + "</p>\n")
+		}
+	}
+}
+
+// list 处理从 i 开始的列表，返回列表后的第一行
+func (r *markdown_renderer) list(lines []string, i int, ordered bool, start int) int {
+	tag := "ul"
+	if ordered {
+		tag = "ol"
+	}
+	r.b.WriteString("<" + tag)
+	if ordered && start != 1 {
+		r.b.WriteString(` start="` + strconv.Itoa(start) + `"`)
+	}
+	r.b.WriteString(">\n")
+	var items [][]string
+	tight := true
+	for i < len(lines) {
+		line := lines[i]
+		o, _, width := list_marker(line)
+		if width == 0 || o != ordered {
+			break
+		}
+		item := []string{line[width:]}
+		i += 1
+		for i < len(lines) {
+			l := lines[i]
+			if is_blank(l) {
+				// 空行后面还有缩进的内容时属于同一个列表项
+				if i+1 < len(lines) && !is_blank(lines[i+1]) && strings.HasPrefix(lines[i+1], strings.Repeat(" ", width)) {
+					tight = false
+					item = append(item, "")
+					i += 1
+					continue
+				}
+				break
+			}
+			if strings.HasPrefix(l, " ") && len(l)-len(strings.TrimLeft(l, " ")) >= 2 {
+				item = append(item, dedent(l, width))
+				i += 1
+				continue
+			}
+			if starts_block(l) {
+				break
+			}
+			// 段落的延续行
+			item = append(item, l)
+			i += 1
+		}
+		items = append(items, item)
+		// 列表项之间有空行时不是紧凑的列表
+		if i+1 < len(lines) && is_blank(lines[i]) {
+			if o2, _, w2 := list_marker(lines[i+1]); w2 != 0 && o2 == ordered {
+				tight = false
+				i += 1
+			}
+		}
+	}
+	for _, item := range items {
+		sub := &markdown_renderer{}
+		sub.blocks(item, tight)
+		r.b.WriteString("<li>" + strings.TrimSuffix(sub.b.String(), "\n") + "</li>\n")
+	}
+	r.b.WriteString("</" + tag + ">\n")
+	return i
+}
+
+func is_punct(c byte) bool {
+	return strings.IndexByte("!\"#$%&'()*+,-./:;<=>?@[\\]^_`{|}~", c) != -1
+}
+
+func is_word_char(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c >= 0x80
+}
+
+// link_destination 解析 (url "title") 中的地址，返回地址和 ) 之后的位置，地址中可以包含成对的括号，例如 wiki/Go_(language)
+func link_destination(s string, i int) (string, int, bool) {
+	if i >= len(s) || s[i] != '(' {
+		return "", 0, false
+	}
+	end := closing_paren(s, i)
+	if end == -1 {
+		return "", 0, false
+	}
+	inner := strings.TrimSpace(s[i+1 : end])
+	if strings.HasPrefix(inner, "<") {
+		if j := strings.IndexByte(inner, '>'); j != -1 {
+			inner = inner[1:j]
+		}
+	} else if j := strings.IndexAny(inner, " \t"); j != -1 {
+		inner = inner[:j]
+	}
+	return inner, end + 1, true
+}
+
+// closing_paren 找到和 s[i] 的 ( 对应的 )
+func closing_paren(s string, i int) int {
+	depth := 0
+	for j := i; j < len(s); j++ {
+		switch s[j] {
+		case '\\':
+			j += 1
+		case '(':
+			depth += 1
+		case ')':
+			depth -= 1
+			if depth == 0 {
+				return j
+			}
+		}
+	}
+	return -1
+}
+
+// closing_bracket 找到和 s[i] 的 [ 对应的 ]
+func closing_bracket(s string, i int) int {
+	depth := 0
+	for j := i; j < len(s); j++ {
+		switch s[j] {
+		case '\\':
+			j += 1
+		case '[':
+			depth += 1
+		case ']':
+			depth -= 1
+			if depth == 0 {
+				return j
+			}
+		}
+	}
+	return -1
+}
+
+var md_autolink_regexp = regexp.MustCompile(`^<((?:https?|mailto):[^\s<>]+)>`)
+
+func render_inline(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); {
+		c := s[i]
+		switch {
+		case c == '\\' && i+1 < len(s) && is_punct(s[i+1]):
+			b.WriteString(xhtml.EscapeString(s[i+1 : i+2]))
+			i += 2
+			continue
+		case c == '`':
+			n := 0
+			for i+n < len(s) && s[i+n] == '`' {
+				n += 1
+			}
+			ticks := s[i : i+n]
+			if end := strings.Index(s[i+n:], ticks); end != -1 {
+				code := s[i+n : i+n+end]
+				if len(code) > 2 && code[0] == ' ' && code[len(code)-1] == ' ' {
+					code = code[1 : len(code)-1]
+				}
+				b.WriteString("<code>" + xhtml.EscapeString(code) + "</code>")
+				i += n + end + n
+				continue
+			}
+			b.WriteString(ticks)
+			i += n
+			continue
+		case c == '!' && i+1 < len(s) && s[i+1] == '[':
+			if end := closing_bracket(s, i+1); end != -1 {
+				if dest, next, ok := link_destination(s, end+1); ok {
+					alt := Sanitize(render_inline(s[i+2:end]), ProfileTextOnly)
+					b.WriteString(`<img src="` + xhtml.EscapeString(dest) + `" alt="` + xhtml.EscapeString(alt) + `">`)
+					i = next
+					continue
+				}
+			}
+		case c == '[':
+			if end := closing_bracket(s, i); end != -1 {
+				if dest, next, ok := link_destination(s, end+1); ok {
+					b.WriteString(`<a href="` + xhtml.EscapeString(dest) + `">` + render_inline(s[i+1:end]) + "</a>")
+					i = next
+					continue
+				}
+			}
+		case c == '<':
+			if m := md_autolink_regexp.FindStringSubmatch(s[i:]); m != nil {
+				b.WriteString(`<a href="` + xhtml.EscapeString(m[1]) + `">` + xhtml.EscapeString(m[1]) + "</a>")
+				i += len(m[0])
+				continue
+			}
+		case c == '*' || c == '_' || c == '~':
+			n := 1
+			if i+1 < len(s) && s[i+1] == c {
+				n = 2
+			}
+			if c == '~' && n != 2 {
+				break
+			}
+			// _ 在单词中间时不作为强调，例如 snake_case
+			if c == '_' && i > 0 && is_word_char(s[i-1]) {
+				break
+			}
+			mark := s[i : i+n]
+			if i+n < len(s) && s[i+n] != ' ' {
+				if end := strings.Index(s[i+n:], mark); end > 0 && s[i+n+end-1] != ' ' {
+					after := i + n + end + n
+					if c != '_' || after >= len(s) || !is_word_char(s[after]) {
+						tag := "em"
+						if c == '~' {
+							tag = "del"
+						} else if n == 2 {
+							tag = "strong"
+						}
+						b.WriteString("<" + tag + ">" + render_inline(s[i+n:i+n+end]) + "</" + tag + ">")
+						i = after
+						continue
+					}
+				}
+			}
+			b.WriteString(xhtml.EscapeString(mark))
+			i += n
+			continue
+		}
+		b.WriteString(xhtml.EscapeString(s[i : i+1]))
+		i += 1
+	}
+	return b.String()
+}
+
+// RenderMarkdown 把 Markdown 转换为安全的 html，用于显示备注等用户输入的内容
+func RenderMarkdown(content string) string {
+	content = strings.ReplaceAll(content, "\r\n", "\n")
+	content = strings.ReplaceAll(content, "\t", "    ")
+	r := &markdown_renderer{}
+	r.blocks(strings.Split(content, "\n"), false)
+	return strings.TrimSpace(Sanitize(r.b.String(), ProfileStrict))
+}
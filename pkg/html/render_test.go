@@ -0,0 +1,66 @@
+package html_test
+
+import (
+	"testing"
+
+	_html "devboard/pkg/html"
+)
+
+func TestRenderMarkdown(t *testing.T) {
+	cases := []struct {
+		name     string
+		markdown string
+		want     string
+	}{
+		{
+			"heading and emphasis",
+			"# Title\n\nSome **bold**, *italic*, ~~old~~ and `a<b>` in snake_case_name.",
+			"<h1>Title</h1>\n<p>Some <strong>bold</strong>, <em>italic</em>, <del>old</del> and <code>a&lt;b&gt;</code> in snake_case_name.</p>",
+		},
+		{
+			"line breaks",
+			"first line\nsecond line",
+			"<p>first line<br>\nsecond line</p>",
+		},
+		{
+			"links",
+			"[docs](https://example.com/a \"title\") <https://example.com> ![logo](/logo.png)",
+			"<p><a href=\"https://example.com/a\" rel=\"noopener noreferrer\">docs</a> <a href=\"https://example.com\" rel=\"noopener noreferrer\">https://example.com</a> <img src=\"/logo.png\" alt=\"logo\"></p>",
+		},
+		{
+			"lists",
+			"- one\n- two\n  1. three\n  2. four\n\n3. five",
+			"<ul>\n<li>one</li>\n<li>two\n<ol>\n<li>three</li>\n<li>four</li>\n</ol></li>\n</ul>\n<ol start=\"3\">\n<li>five</li>\n</ol>",
+		},
+		{
+			"code block",
+			"```go\nfmt.Println(\"<b>\")\n```",
+			"<pre><code class=\"language-go\">fmt.Println(&#34;&lt;b&gt;&#34;)\n</code></pre>",
+		},
+		{
+			"quote and rule",
+			"> quoted\n> text\n\n---",
+			"<blockquote>\n<p>quoted<br>\ntext</p>\n</blockquote>\n<hr>",
+		},
+		{
+			"raw html is escaped",
+			"<script>alert(1)</script><img src=x onerror=alert(1)>",
+			"<p>&lt;script&gt;alert(1)&lt;/script&gt;&lt;img src=x onerror=alert(1)&gt;</p>",
+		},
+		{
+			"unsafe link",
+			"[click](javascript:alert(1))",
+			"<p><a>click</a></p>",
+		},
+		{
+			"parentheses in link",
+			"[w](https://en.wikipedia.org/wiki/Go_(language)) and (see [x](https://example.com))",
+			`<p><a href="https://en.wikipedia.org/wiki/Go_(language)" rel="noopener noreferrer">w</a> and (see <a href="https://example.com" rel="noopener noreferrer">x</a>)</p>`,
+		},
+	}
+	for _, c := range cases {
+		if got := _html.RenderMarkdown(c.markdown); got != c.want {
+			t.Errorf("%s:\nexpected %q\ngot      %q", c.name, c.want, got)
+		}
+	}
+}
@@ -0,0 +1,210 @@
+package imaging
+
+import (
+	"bytes"
+	"encoding/binary"
+	"strings"
+)
+
+// EXIF 只读取常用的几个字段
+type EXIF struct {
+	Make     string `json:"make,omitempty"`
+	Model    string `json:"model,omitempty"`
+	Software string `json:"software,omitempty"`
+	// DateTime 拍摄时间，没有时使用修改时间，格式为 2006:01:02 15:04:05
+	DateTime string `json:"date_time,omitempty"`
+	// Orientation 方向 1-8，1 为正常
+	Orientation int `json:"orientation,omitempty"`
+}
+
+const (
+	exif_tag_make               = 0x010f
+	exif_tag_model              = 0x0110
+	exif_tag_orientation        = 0x0112
+	exif_tag_software           = 0x0131
+	exif_tag_date_time          = 0x0132
+	exif_tag_exif_ifd           = 0x8769
+	exif_tag_date_time_original = 0x9003
+)
+
+var png_signature = []byte("\x89PNG\r\n\x1a\n")
+
+// ReadEXIF 从 jpeg 的 APP1 段或 png 的 eXIf 块中读取 EXIF，没有或无法解析时返回 nil
+func ReadEXIF(data []byte) *EXIF {
+	var tiff []byte
+	switch {
+	case len(data) > 2 && data[0] == 0xff && data[1] == 0xd8:
+		tiff = jpeg_exif(data)
+	case bytes.HasPrefix(data, png_signature):
+		tiff = png_exif(data)
+	}
+	if tiff == nil {
+		return nil
+	}
+	return parse_tiff(tiff)
+}
+
+func jpeg_exif(data []byte) []byte {
+	i := 2
+	for i+4 <= len(data) {
+		if data[i] != 0xff {
+			return nil
+		}
+		marker := data[i+1]
+		// 图像数据开始后不会再有 EXIF
+		if marker == 0xda || marker == 0xd9 {
+			return nil
+		}
+		length := int(binary.BigEndian.Uint16(data[i+2:]))
+		if length < 2 || i+2+length > len(data) {
+			return nil
+		}
+		segment := data[i+4 : i+2+length]
+		if marker == 0xe1 && bytes.HasPrefix(segment, []byte("Exif\x00\x00")) {
+			return segment[6:]
+		}
+		i += 2 + length
+	}
+	return nil
+}
+
+func png_exif(data []byte) []byte {
+	i := len(png_signature)
+	for i+8 <= len(data) {
+		length := int(binary.BigEndian.Uint32(data[i:]))
+		typ := string(data[i+4 : i+8])
+		if length < 0 || i+8+length > len(data) {
+			return nil
+		}
+		if typ == "eXIf" {
+			return data[i+8 : i+8+length]
+		}
+		if typ == "IEND" {
+			return nil
+		}
+		// 数据后面还有 4 字节的 crc
+		i += 12 + length
+	}
+	return nil
+}
+
+type tiff_reader struct {
+	data  []byte
+	order binary.ByteOrder
+}
+
+func (r *tiff_reader) u16(offset int) (int, bool) {
+	if offset < 0 || offset+2 > len(r.data) {
+		return 0, false
+	}
+	return int(r.order.Uint16(r.data[offset:])), true
+}
+
+func (r *tiff_reader) u32(offset int) (int, bool) {
+	if offset < 0 || offset+4 > len(r.data) {
+		return 0, false
+	}
+	return int(r.order.Uint32(r.data[offset:])), true
+}
+
+// ifd 读取一个目录中的条目，返回 tag 对应条目的偏移
+func (r *tiff_reader) ifd(offset int) map[int]int {
+	count, ok := r.u16(offset)
+	if !ok {
+		return nil
+	}
+	entries := make(map[int]int, count)
+	for i := 0; i < count; i++ {
+		entry := offset + 2 + i*12
+		tag, ok := r.u16(entry)
+		if !ok || entry+12 > len(r.data) {
+			break
+		}
+		entries[tag] = entry
+	}
+	return entries
+}
+
+func (r *tiff_reader) ascii(entry int) string {
+	typ, _ := r.u16(entry + 2)
+	count, _ := r.u32(entry + 4)
+	if typ != 2 || count <= 0 || count > 1024 {
+		return ""
+	}
+	start := entry + 8
+	if count > 4 {
+		start, _ = r.u32(entry + 8)
+	}
+	if start < 0 || start+count > len(r.data) {
+		return ""
+	}
+	return strings.TrimSpace(strings.TrimRight(string(r.data[start:start+count]), "\x00"))
+}
+
+func (r *tiff_reader) short(entry int) int {
+	typ, _ := r.u16(entry + 2)
+	switch typ {
+	case 3:
+		v, _ := r.u16(entry + 8)
+		return v
+	case 4:
+		v, _ := r.u32(entry + 8)
+		return v
+	}
+	return 0
+}
+
+func parse_tiff(data []byte) *EXIF {
+	if len(data) < 8 {
+		return nil
+	}
+	r := &tiff_reader{data: data}
+	switch string(data[:2]) {
+	case "II":
+		r.order = binary.LittleEndian
+	case "MM":
+		r.order = binary.BigEndian
+	default:
+		return nil
+	}
+	if magic, _ := r.u16(2); magic != 42 {
+		return nil
+	}
+	offset, _ := r.u32(4)
+	entries := r.ifd(offset)
+	if entries == nil {
+		return nil
+	}
+	result := &EXIF{}
+	if e, ok := entries[exif_tag_make]; ok {
+		result.Make = r.ascii(e)
+	}
+	if e, ok := entries[exif_tag_model]; ok {
+		result.Model = r.ascii(e)
+	}
+	if e, ok := entries[exif_tag_software]; ok {
+		result.Software = r.ascii(e)
+	}
+	if e, ok := entries[exif_tag_date_time]; ok {
+		result.DateTime = r.ascii(e)
+	}
+	if e, ok := entries[exif_tag_orientation]; ok {
+		if v := r.short(e); v >= 1 && v <= 8 {
+			result.Orientation = v
+		}
+	}
+	if e, ok := entries[exif_tag_exif_ifd]; ok {
+		sub, _ := r.u32(e + 8)
+		if sub != offset {
+			if e2, ok := r.ifd(sub)[exif_tag_date_time_original]; ok {
+				if v := r.ascii(e2); v != "" {
+					result.DateTime = v
+				}
+			}
+		}
+	}
+	if *result == (EXIF{}) {
+		return nil
+	}
+	return result
+}
@@ -0,0 +1,153 @@
+package imaging
+
+import (
+	"image"
+	"math"
+	"math/bits"
+	"sort"
+	"strconv"
+)
+
+// area_weights 把长度为 n 的一行按面积平均到 m 个格子，返回每个格子覆盖的下标和权重
+func area_weights(n int, m int) [][]struct {
+	i int
+	w float64
+} {
+	result := make([][]struct {
+		i int
+		w float64
+	}, m)
+	scale := float64(n) / float64(m)
+	for j := 0; j < m; j++ {
+		start, end := float64(j)*scale, float64(j+1)*scale
+		for i := int(start); i < n && float64(i) < end; i++ {
+			w := math.Min(end, float64(i+1)) - math.Max(start, float64(i))
+			if w > 0 {
+				result[j] = append(result[j], struct {
+					i int
+					w float64
+				}{i, w / scale})
+			}
+		}
+	}
+	return result
+}
+
+// gray 按面积精确缩放到 w*h 后转换为灰度，同一张图不同尺寸的结果基本一致
+func gray(img image.Image, w int, h int) []float64 {
+	src := to_rgba(img)
+	sw, sh := src.Bounds().Dx(), src.Bounds().Dy()
+	result := make([]float64, w*h)
+	if sw == 0 || sh == 0 {
+		return result
+	}
+	luma := make([]float64, sw*sh)
+	for y := 0; y < sh; y++ {
+		for x := 0; x < sw; x++ {
+			i := src.PixOffset(x, y)
+			// 透明的部分按白色处理，截图的透明背景不影响结果
+			a := float64(src.Pix[i+3])
+			r := float64(src.Pix[i]) + 255 - a
+			g := float64(src.Pix[i+1]) + 255 - a
+			b := float64(src.Pix[i+2]) + 255 - a
+			luma[y*sw+x] = 0.299*r + 0.587*g + 0.114*b
+		}
+	}
+	xw := area_weights(sw, w)
+	yw := area_weights(sh, h)
+	columns := make([]float64, sh*w)
+	for y := 0; y < sh; y++ {
+		for x, weights := range xw {
+			var sum float64
+			for _, v := range weights {
+				sum += luma[y*sw+v.i] * v.w
+			}
+			columns[y*w+x] = sum
+		}
+	}
+	for y, weights := range yw {
+		for x := 0; x < w; x++ {
+			var sum float64
+			for _, v := range weights {
+				sum += columns[v.i*w+x] * v.w
+			}
+			result[y*w+x] = sum
+		}
+	}
+	return result
+}
+
+// DHash 差异哈希，比较相邻像素的亮度，对缩放和轻微的颜色变化不敏感
+func DHash(img image.Image) uint64 {
+	pixels := gray(img, 9, 8)
+	var hash uint64
+	for y := 0; y < 8; y++ {
+		for x := 0; x < 8; x++ {
+			hash <<= 1
+			if pixels[y*9+x] < pixels[y*9+x+1] {
+				hash |= 1
+			}
+		}
+	}
+	return hash
+}
+
+const phash_size = 32
+
+// PHash 感知哈希，取 DCT 变换后的低频部分与中位数比较
+func PHash(img image.Image) uint64 {
+	pixels := gray(img, phash_size, phash_size)
+	var cos [phash_size][phash_size]float64
+	for u := 0; u < phash_size; u++ {
+		for x := 0; x < phash_size; x++ {
+			cos[u][x] = math.Cos(float64(2*x+1) * float64(u) * math.Pi / (2 * phash_size))
+		}
+	}
+	// 只需要左上角 8*8 的系数
+	var rows [8][phash_size]float64
+	for u := 0; u < 8; u++ {
+		for y := 0; y < phash_size; y++ {
+			var sum float64
+			for x := 0; x < phash_size; x++ {
+				sum += pixels[y*phash_size+x] * cos[u][x]
+			}
+			rows[u][y] = sum
+		}
+	}
+	coefficients := make([]float64, 0, 64)
+	for v := 0; v < 8; v++ {
+		for u := 0; u < 8; u++ {
+			var sum float64
+			for y := 0; y < phash_size; y++ {
+				sum += rows[u][y] * cos[v][y]
+			}
+			coefficients = append(coefficients, sum)
+		}
+	}
+	// 直流分量不参与中位数的计算
+	sorted := append([]float64{}, coefficients[1:]...)
+	sort.Float64s(sorted)
+	median := (sorted[len(sorted)/2-1] + sorted[len(sorted)/2]) / 2
+	var hash uint64
+	for _, c := range coefficients {
+		hash <<= 1
+		if c > median {
+			hash |= 1
+		}
+	}
+	return hash
+}
+
+// Distance 两个哈希不同的位数，越小越相似
+func Distance(a uint64, b uint64) int {
+	return bits.OnesCount64(a ^ b)
+}
+
+// ParseHash 解析 FormatHash 的结果
+func ParseHash(s string) (uint64, bool) {
+	v, err := strconv.ParseUint(s, 16, 64)
+	if err != nil {
+		return 0, false
+	}
+	return v, true
+}
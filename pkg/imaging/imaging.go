@@ -0,0 +1,195 @@
+package imaging
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/draw"
+	_ "image/gif"
+	"image/jpeg"
+	"image/png"
+)
+
+// Info 图片的格式信息
+type Info struct {
+	// Format 格式 png、jpeg、gif
+	Format string `json:"format"`
+	Width  int    `json:"width"`
+	Height int    `json:"height"`
+	// ColorModel 颜色模型 rgba、gray、paletted 等
+	ColorModel string `json:"color_model"`
+	EXIF       *EXIF  `json:"exif,omitempty"`
+}
+
+func color_model_name(img image.Image) string {
+	switch img.(type) {
+	case *image.RGBA:
+		return "rgba"
+	case *image.RGBA64:
+		return "rgba64"
+	case *image.NRGBA:
+		return "nrgba"
+	case *image.NRGBA64:
+		return "nrgba64"
+	case *image.Gray:
+		return "gray"
+	case *image.Gray16:
+		return "gray16"
+	case *image.Paletted:
+		return "paletted"
+	case *image.YCbCr:
+		return "ycbcr"
+	case *image.CMYK:
+		return "cmyk"
+	}
+	return "unknown"
+}
+
+// 超过该像素数的图片不解码，避免占用过多内存
+const max_pixels = 64 * 1024 * 1024
+
+// Decode 解码图片并读取格式信息，jpeg 会按 EXIF 中的方向旋转
+func Decode(data []byte) (image.Image, *Info, error) {
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, nil, err
+	}
+	if cfg.Width*cfg.Height > max_pixels {
+		return nil, nil, fmt.Errorf("The image is too large (%dx%d)", cfg.Width, cfg.Height)
+	}
+	img, format, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, nil, err
+	}
+	b := img.Bounds()
+	info := &Info{
+		Format:     format,
+		Width:      b.Dx(),
+		Height:     b.Dy(),
+		ColorModel: color_model_name(img),
+		EXIF:       ReadEXIF(data),
+	}
+	if info.EXIF != nil && info.EXIF.Orientation > 1 {
+		img = Orient(img, info.EXIF.Orientation)
+	}
+	return img, info, nil
+}
+
+func to_rgba(img image.Image) *image.RGBA {
+	if v, ok := img.(*image.RGBA); ok && v.Bounds().Min == (image.Point{}) {
+		return v
+	}
+	b := img.Bounds()
+	dst := image.NewRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+	draw.Draw(dst, dst.Bounds(), img, b.Min, draw.Src)
+	return dst
+}
+
+// Orient 按 EXIF 的方向（1-8）旋转、翻转图片
+func Orient(img image.Image, orientation int) image.Image {
+	if orientation < 2 || orientation > 8 {
+		return img
+	}
+	src := to_rgba(img)
+	w, h := src.Bounds().Dx(), src.Bounds().Dy()
+	dw, dh := w, h
+	if orientation >= 5 {
+		dw, dh = h, w
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, dw, dh))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			var dx, dy int
+			switch orientation {
+			case 2:
+				dx, dy = w-1-x, y
+			case 3:
+				dx, dy = w-1-x, h-1-y
+			case 4:
+				dx, dy = x, h-1-y
+			case 5:
+				dx, dy = y, x
+			case 6:
+				dx, dy = h-1-y, x
+			case 7:
+				dx, dy = h-1-y, w-1-x
+			case 8:
+				dx, dy = y, w-1-x
+			}
+			copy(dst.Pix[dst.PixOffset(dx, dy):dst.PixOffset(dx, dy)+4], src.Pix[src.PixOffset(x, y):src.PixOffset(x, y)+4])
+		}
+	}
+	return dst
+}
+
+// resize 按区域取平均值缩放，缩小截图时文字不会出现明显的锯齿
+func resize(src *image.RGBA, w int, h int) *image.RGBA {
+	sw, sh := src.Bounds().Dx(), src.Bounds().Dy()
+	dst := image.NewRGBA(image.Rect(0, 0, w, h))
+	if sw == 0 || sh == 0 {
+		return dst
+	}
+	for y := 0; y < h; y++ {
+		sy0 := y * sh / h
+		sy1 := max((y+1)*sh/h, sy0+1)
+		for x := 0; x < w; x++ {
+			sx0 := x * sw / w
+			sx1 := max((x+1)*sw/w, sx0+1)
+			var r, g, b, a, n int
+			for sy := sy0; sy < sy1; sy++ {
+				i := src.PixOffset(sx0, sy)
+				for sx := sx0; sx < sx1; sx++ {
+					r += int(src.Pix[i])
+					g += int(src.Pix[i+1])
+					b += int(src.Pix[i+2])
+					a += int(src.Pix[i+3])
+					i += 4
+					n++
+				}
+			}
+			i := dst.PixOffset(x, y)
+			dst.Pix[i] = uint8(r / n)
+			dst.Pix[i+1] = uint8(g / n)
+			dst.Pix[i+2] = uint8(b / n)
+			dst.Pix[i+3] = uint8(a / n)
+		}
+	}
+	return dst
+}
+
+// Thumbnail 等比缩放到最长边不超过 size，图片本身更小时不放大
+func Thumbnail(img image.Image, size int) image.Image {
+	src := to_rgba(img)
+	w, h := src.Bounds().Dx(), src.Bounds().Dy()
+	if w <= size && h <= size {
+		return src
+	}
+	if w >= h {
+		h = max(h*size/w, 1)
+		w = size
+	} else {
+		w = max(w*size/h, 1)
+		h = size
+	}
+	return resize(src, w, h)
+}
+
+// Encode 不透明的图片使用 jpeg 以减小体积，否则使用 png，返回内容和 mime 类型
+func Encode(img image.Image) ([]byte, string, error) {
+	var buf bytes.Buffer
+	if o, ok := img.(interface{ Opaque() bool }); ok && o.Opaque() {
+		if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: 85}); err != nil {
+			return nil, "", err
+		}
+		return buf.Bytes(), "image/jpeg", nil
+	}
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, "", err
+	}
+	return buf.Bytes(), "image/png", nil
+}
+
+// FormatHash 以 16 位十六进制保存感知哈希
+func FormatHash(hash uint64) string {
+	return fmt.Sprintf("%016x", hash)
+}
@@ -0,0 +1,133 @@
+package imaging_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"testing"
+
+	"devboard/pkg/imaging"
+)
+
+// screenshot 模拟一张有文字行的截图
+func screenshot(w int, h int, shift int) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			// 背景带有渐变，避免相邻区域的亮度完全相同
+			v := uint8(180 + x*70/w)
+			c := color.RGBA{v, v, uint8(180 + y*70/h), 255}
+			if (y/12)%3 == 0 && (x+shift)%40 < 30 && x < w*2/3 {
+				c = color.RGBA{30, 30, 30, 255}
+			}
+			if x > w*3/4 && y < h/3 {
+				c = color.RGBA{40, 120, 220, 255}
+			}
+			img.Set(x, y, c)
+		}
+	}
+	return img
+}
+
+func TestThumbnail(t *testing.T) {
+	img := screenshot(800, 400, 0)
+	thumb := imaging.Thumbnail(img, 160)
+	if b := thumb.Bounds(); b.Dx() != 160 || b.Dy() != 80 {
+		t.Errorf("unexpected thumbnail size %v", b)
+	}
+	small := imaging.Thumbnail(screenshot(100, 50, 0), 160)
+	if b := small.Bounds(); b.Dx() != 100 || b.Dy() != 50 {
+		t.Errorf("small image should not be enlarged, got %v", b)
+	}
+	data, mime_type, err := imaging.Encode(thumb)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mime_type != "image/jpeg" {
+		t.Errorf("opaque thumbnail should be jpeg, got %s", mime_type)
+	}
+	if _, info, err := imaging.Decode(data); err != nil || info.Format != "jpeg" || info.Width != 160 {
+		t.Errorf("unexpected decoded thumbnail %+v %v", info, err)
+	}
+}
+
+func TestPerceptualHash(t *testing.T) {
+	a := screenshot(800, 400, 0)
+	// 同一张截图缩小后哈希应该几乎相同
+	resized := imaging.Thumbnail(a, 400)
+	different := image.NewRGBA(image.Rect(0, 0, 800, 400))
+	for y := 0; y < 400; y++ {
+		for x := 0; x < 800; x++ {
+			v := uint8((x * 255 / 800) ^ (y * 255 / 400))
+			different.Set(x, y, color.RGBA{v, 255 - v, v / 2, 255})
+		}
+	}
+	if d := imaging.Distance(imaging.DHash(a), imaging.DHash(resized)); d > 4 {
+		t.Errorf("dhash distance of resized image is %d", d)
+	}
+	if d := imaging.Distance(imaging.PHash(a), imaging.PHash(resized)); d > 4 {
+		t.Errorf("phash distance of resized image is %d", d)
+	}
+	if d := imaging.Distance(imaging.PHash(a), imaging.PHash(different)); d < 16 {
+		t.Errorf("phash distance of different image is only %d", d)
+	}
+	hash := imaging.DHash(a)
+	if v, ok := imaging.ParseHash(imaging.FormatHash(hash)); !ok || v != hash {
+		t.Errorf("failed to parse formatted hash")
+	}
+}
+
+// exif_segment 构造只包含 Make 和 Orientation 的 EXIF
+func exif_segment(orientation uint16) []byte {
+	var tiff bytes.Buffer
+	tiff.WriteString("II")
+	binary.Write(&tiff, binary.LittleEndian, uint16(42))
+	binary.Write(&tiff, binary.LittleEndian, uint32(8))
+	binary.Write(&tiff, binary.LittleEndian, uint16(2))
+	// Make，ASCII，长度 6，值在目录后面
+	binary.Write(&tiff, binary.LittleEndian, []uint16{0x010f, 2})
+	binary.Write(&tiff, binary.LittleEndian, []uint32{6, 8 + 2 + 2*12 + 4})
+	// Orientation，SHORT
+	binary.Write(&tiff, binary.LittleEndian, []uint16{0x0112, 3})
+	binary.Write(&tiff, binary.LittleEndian, uint32(1))
+	binary.Write(&tiff, binary.LittleEndian, []uint16{orientation, 0})
+	binary.Write(&tiff, binary.LittleEndian, uint32(0))
+	tiff.WriteString("Canon\x00")
+	segment := append([]byte("Exif\x00\x00"), tiff.Bytes()...)
+	var buf bytes.Buffer
+	buf.Write([]byte{0xff, 0xe1})
+	binary.Write(&buf, binary.BigEndian, uint16(len(segment)+2))
+	buf.Write(segment)
+	return buf.Bytes()
+}
+
+func TestDecodeEXIF(t *testing.T) {
+	var encoded bytes.Buffer
+	if err := jpeg.Encode(&encoded, screenshot(60, 20, 0), nil); err != nil {
+		t.Fatal(err)
+	}
+	raw := encoded.Bytes()
+	data := append(append([]byte{0xff, 0xd8}, exif_segment(6)...), raw[2:]...)
+	img, info, err := imaging.Decode(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.EXIF == nil || info.EXIF.Make != "Canon" || info.EXIF.Orientation != 6 {
+		t.Fatalf("unexpected exif %+v", info.EXIF)
+	}
+	// 方向为 6 时需要顺时针旋转 90 度
+	if b := img.Bounds(); b.Dx() != 20 || b.Dy() != 60 {
+		t.Errorf("image should be rotated, got %v", b)
+	}
+	var p bytes.Buffer
+	png.Encode(&p, screenshot(10, 10, 0))
+	if imaging.ReadEXIF(p.Bytes()) != nil {
+		t.Errorf("png without eXIf chunk should have no exif")
+	}
+	if imaging.ReadEXIF([]byte{0xff, 0xd8, 0xff, 0xe1, 0xff, 0xff}) != nil {
+		t.Errorf("truncated exif should be ignored")
+	}
+}
@@ -0,0 +1,288 @@
+package llm
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Provider 一个 OpenAI 兼容的 LLM 服务配置，本地的 Ollama、LM Studio 等也按这种方式接入
+type Provider struct {
+	Name        string  `json:"name"`
+	BaseURL     string  `json:"base_url"` // 如 https://api.openai.com/v1、http://127.0.0.1:11434/v1
+	APIKey      string  `json:"api_key"`
+	Model       string  `json:"model"`
+	Temperature float64 `json:"temperature"`
+	Timeout     int     `json:"timeout"` // 秒
+	// EmbeddingModel 用于语义检索的向量模型，为空表示该厂商不支持
+	EmbeddingModel string `json:"embedding_model"`
+}
+
+// ProviderResp 返回给前端的厂商信息，不包含 api key
+type ProviderResp struct {
+	Name        string  `json:"name"`
+	BaseURL     string  `json:"base_url"`
+	HasAPIKey   bool    `json:"has_api_key"`
+	Model       string  `json:"model"`
+	Temperature float64 `json:"temperature"`
+	Timeout     int     `json:"timeout"`
+	IsDefault   bool    `json:"is_default"`
+
+	EmbeddingModel string `json:"embedding_model"`
+}
+
+const default_timeout = 60
+
+func (p *Provider) Public(is_default bool) ProviderResp {
+	return ProviderResp{
+		Name:        p.Name,
+		BaseURL:     p.BaseURL,
+		HasAPIKey:   p.APIKey != "",
+		Model:       p.Model,
+		Temperature: p.Temperature,
+		Timeout:     p.Timeout,
+		IsDefault:   is_default,
+
+		EmbeddingModel: p.EmbeddingModel,
+	}
+}
+
+func (p *Provider) timeout() time.Duration {
+	if p.Timeout <= 0 {
+		return default_timeout * time.Second
+	}
+	return time.Duration(p.Timeout) * time.Second
+}
+
+func (p *Provider) endpoint(path string) string {
+	return strings.TrimRight(p.BaseURL, "/") + path
+}
+
+func (p *Provider) new_request(ctx context.Context, method string, path string, body io.Reader) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, method, p.endpoint(path), body)
+	if err != nil {
+		return nil, fmt.Errorf("error creating request: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	// 本地服务一般不需要 key
+	if p.APIKey != "" {
+		req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", p.APIKey))
+	}
+	return req, nil
+}
+
+type ChatOptions struct {
+	Model       string
+	Temperature *float64
+	MaxTokens   int
+}
+
+type chat_completion_resp struct {
+	Choices []struct {
+		Message LLMChatMessage `json:"message"`
+	} `json:"choices"`
+}
+
+// Chat 调用 /chat/completions 并返回第一条回复的内容
+func (p *Provider) Chat(ctx context.Context, messages []LLMChatMessage, opts ChatOptions) (string, error) {
+	model := opts.Model
+	if model == "" {
+		model = p.Model
+	}
+	if model == "" {
+		return "", fmt.Errorf("the provider %s has no model", p.Name)
+	}
+	temperature := p.Temperature
+	if opts.Temperature != nil {
+		temperature = *opts.Temperature
+	}
+	request_body := map[string]interface{}{
+		"messages":    messages,
+		"model":       model,
+		"stream":      false,
+		"temperature": temperature,
+	}
+	if opts.MaxTokens > 0 {
+		request_body["max_tokens"] = opts.MaxTokens
+	}
+	json_body, err := json.Marshal(request_body)
+	if err != nil {
+		return "", fmt.Errorf("error marshaling request body: %v", err)
+	}
+	ctx, cancel := context.WithTimeout(ctx, p.timeout())
+	defer cancel()
+	req, err := p.new_request(ctx, http.MethodPost, "/chat/completions", bytes.NewReader(json_body))
+	if err != nil {
+		return "", err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	resp_body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", fmt.Errorf("error reading API response: %v", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("LLM API error: %s", string(resp_body))
+	}
+	var r chat_completion_resp
+	if err := json.Unmarshal(resp_body, &r); err != nil {
+		return "", err
+	}
+	if len(r.Choices) == 0 {
+		return "", fmt.Errorf("LLM API returned no choices")
+	}
+	return r.Choices[0].Message.Content, nil
+}
+
+type embedding_resp struct {
+	Data []struct {
+		Index     int       `json:"index"`
+		Embedding []float64 `json:"embedding"`
+	} `json:"data"`
+}
+
+// Embed 调用 /embeddings 获取文本向量，返回结果与 inputs 顺序一致
+func (p *Provider) Embed(ctx context.Context, inputs []string) ([][]float64, error) {
+	if p.EmbeddingModel == "" {
+		return nil, fmt.Errorf("the provider %s has no embedding model", p.Name)
+	}
+	json_body, err := json.Marshal(map[string]interface{}{
+		"model": p.EmbeddingModel,
+		"input": inputs,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error marshaling request body: %v", err)
+	}
+	ctx, cancel := context.WithTimeout(ctx, p.timeout())
+	defer cancel()
+	req, err := p.new_request(ctx, http.MethodPost, "/embeddings", bytes.NewReader(json_body))
+	if err != nil {
+		return nil, err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	resp_body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("error reading API response: %v", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("LLM API error: %s", string(resp_body))
+	}
+	var r embedding_resp
+	if err := json.Unmarshal(resp_body, &r); err != nil {
+		return nil, err
+	}
+	result := make([][]float64, len(inputs))
+	for _, d := range r.Data {
+		if d.Index >= 0 && d.Index < len(result) {
+			result[d.Index] = d.Embedding
+		}
+	}
+	return result, nil
+}
+
+type PingResult struct {
+	Latency int64    `json:"latency"` // 毫秒
+	Models  []string `json:"models"`
+}
+
+// Ping 请求 /models 检查地址和 key 是否可用
+func (p *Provider) Ping(ctx context.Context) (*PingResult, error) {
+	ctx, cancel := context.WithTimeout(ctx, p.timeout())
+	defer cancel()
+	req, err := p.new_request(ctx, http.MethodGet, "/models", nil)
+	if err != nil {
+		return nil, err
+	}
+	start := time.Now()
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	resp_body, err := io.ReadAll(io.LimitReader(resp.Body, 1024*1024))
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("LLM API error: %s", string(resp_body))
+	}
+	result := &PingResult{
+		Latency: time.Since(start).Milliseconds(),
+		Models:  []string{},
+	}
+	var r struct {
+		Data []struct {
+			Id string `json:"id"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(resp_body, &r); err == nil {
+		for _, m := range r.Data {
+			result.Models = append(result.Models, m.Id)
+		}
+	}
+	return result, nil
+}
+
+// Registry 按名称查找厂商配置，配置通过 loader 实时读取，所以用户修改设置后无需重新创建
+type Registry struct {
+	loader func() ([]Provider, string)
+}
+
+func NewRegistry(loader func() ([]Provider, string)) *Registry {
+	return &Registry{
+		loader: loader,
+	}
+}
+
+func (r *Registry) List() ([]Provider, string) {
+	if r.loader == nil {
+		return nil, ""
+	}
+	return r.loader()
+}
+
+// Resolve 根据名称查找厂商，名称为空时使用默认厂商
+func (r *Registry) Resolve(name string) (*Provider, error) {
+	providers, default_name := r.List()
+	if name == "" {
+		name = default_name
+	}
+	if name == "" && len(providers) == 1 {
+		name = providers[0].Name
+	}
+	if name == "" {
+		return nil, fmt.Errorf("There's no default LLM provider")
+	}
+	for i := range providers {
+		if providers[i].Name == name {
+			p := providers[i]
+			return &p, nil
+		}
+	}
+	return nil, fmt.Errorf("LLM provider %s not found", name)
+}
+
+// ExtractJSON 从模型回复中取出 JSON 并解析，兼容回复被 ``` 代码块包裹或前后带有说明文字的情况
+func ExtractJSON(content string, v interface{}) error {
+	start := strings.IndexAny(content, "{[")
+	if start == -1 {
+		return fmt.Errorf("there's no json in the content")
+	}
+	end := strings.LastIndexAny(content, "}]")
+	if end < start {
+		return fmt.Errorf("there's no json in the content")
+	}
+	return json.Unmarshal([]byte(content[start:end+1]), v)
+}
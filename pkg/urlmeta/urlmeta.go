@@ -0,0 +1,291 @@
+package urlmeta
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"syscall"
+	"time"
+
+	"golang.org/x/net/html"
+)
+
+const user_agent = "Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36"
+
+// 页面只读取前面这部分，<head> 一般都在里面
+const max_page_size = 512 * 1024
+
+type Meta struct {
+	Title        string `json:"title"`
+	Description  string `json:"description"`
+	SiteName     string `json:"site_name"`
+	Icon         string `json:"icon"`
+	Image        string `json:"image"`
+	URL          string `json:"url"`
+	CanonicalURL string `json:"canonical_url"`
+}
+
+// StatusError 服务端返回了非 2xx 的状态码
+type StatusError struct {
+	Code int
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("Unexpected status code %d", e.Code)
+}
+
+// ErrPrivateAddress 地址指向本机或局域网，不会去请求
+var ErrPrivateAddress = errors.New("The host resolves to a private address")
+
+func is_private_ip(ip net.IP) bool {
+	return ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsInterfaceLocalMulticast()
+}
+
+// block_private_control 在连接前检查实际连接的地址，不单独解析域名，避免解析和连接之间域名指向被修改
+func block_private_control(network string, address string, c syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip := net.ParseIP(host)
+	if ip == nil || is_private_ip(ip) {
+		return ErrPrivateAddress
+	}
+	return nil
+}
+
+// new_client block_private 为 true 时拒绝连接本机、内网和链路本地地址，跳转后的地址同样会检查
+func new_client(block_private bool) *http.Client {
+	client := &http.Client{
+		Timeout: 10 * time.Second,
+	}
+	if block_private {
+		dialer := &net.Dialer{
+			Timeout:   10 * time.Second,
+			KeepAlive: 30 * time.Second,
+			Control:   block_private_control,
+		}
+		transport := http.DefaultTransport.(*http.Transport).Clone()
+		transport.DialContext = dialer.DialContext
+		// 使用代理时连接的是代理服务器，无法检查目标地址
+		transport.Proxy = nil
+		client.Transport = transport
+	}
+	return client
+}
+
+// IsOffline 判断错误是否由网络不可用引起，这种情况下应该等网络恢复后再重试
+func IsOffline(err error) bool {
+	if errors.Is(err, ErrPrivateAddress) {
+		return false
+	}
+	var dns_err *net.DNSError
+	if errors.As(err, &dns_err) {
+		return !dns_err.IsNotFound
+	}
+	var op_err *net.OpError
+	if errors.As(err, &op_err) {
+		return op_err.Op == "dial"
+	}
+	return false
+}
+
+// IsPermanent 判断错误重试也不会成功，例如 404、域名不存在
+func IsPermanent(err error) bool {
+	if errors.Is(err, ErrPrivateAddress) {
+		return true
+	}
+	var status_err *StatusError
+	if errors.As(err, &status_err) {
+		return status_err.Code == http.StatusNotFound || status_err.Code == http.StatusGone
+	}
+	var dns_err *net.DNSError
+	if errors.As(err, &dns_err) {
+		return dns_err.IsNotFound
+	}
+	return false
+}
+
+// Normalize 补全协议并校验地址
+func Normalize(raw string) (*url.URL, error) {
+	parsed, err := url.Parse(strings.TrimSpace(raw))
+	if err != nil {
+		return nil, err
+	}
+	if parsed.Scheme == "" {
+		parsed.Scheme = "https"
+	}
+	if parsed.Scheme != "http" && parsed.Scheme != "https" {
+		return nil, fmt.Errorf("Unsupported scheme %s", parsed.Scheme)
+	}
+	if parsed.Host == "" {
+		return nil, fmt.Errorf("Invalid url")
+	}
+	return parsed, nil
+}
+
+func get(ctx context.Context, u string, limit int64, block_private bool) ([]byte, string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, "", err
+	}
+	req.Header.Set("User-Agent", user_agent)
+	client := new_client(block_private)
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, "", &StatusError{Code: resp.StatusCode}
+	}
+	buf, err := io.ReadAll(io.LimitReader(resp.Body, limit))
+	if err != nil {
+		return nil, "", err
+	}
+	return buf, resp.Header.Get("Content-Type"), nil
+}
+
+// Fetch 请求页面并解析 Open Graph 等信息
+// block_private 为 true 时不请求本机和局域网的地址，返回 ErrPrivateAddress，用于后台自动获取
+func Fetch(ctx context.Context, raw string, block_private bool) (*Meta, error) {
+	parsed, err := Normalize(raw)
+	if err != nil {
+		return nil, err
+	}
+	buf, _, err := get(ctx, parsed.String(), max_page_size, block_private)
+	if err != nil {
+		return nil, err
+	}
+	doc, err := html.Parse(bytes.NewReader(buf))
+	if err != nil {
+		return nil, err
+	}
+	meta := Extract(doc, parsed)
+	return &meta, nil
+}
+
+// FetchAsset 下载图标、图片，超过 max_size 时返回错误
+func FetchAsset(ctx context.Context, u string, max_size int64, block_private bool) ([]byte, string, error) {
+	buf, content_type, err := get(ctx, u, max_size+1, block_private)
+	if err != nil {
+		return nil, "", err
+	}
+	if int64(len(buf)) > max_size {
+		return nil, "", fmt.Errorf("The asset is larger than %d bytes", max_size)
+	}
+	if content_type == "" || strings.HasPrefix(content_type, "application/octet-stream") {
+		content_type = http.DetectContentType(buf)
+	}
+	if !strings.HasPrefix(content_type, "image/") {
+		return nil, "", fmt.Errorf("The asset is not an image, %s", content_type)
+	}
+	return buf, content_type, nil
+}
+
+func Extract(doc *html.Node, base_url *url.URL) Meta {
+	meta := Meta{
+		URL: base_url.String(),
+	}
+	var walk func(*html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.ElementNode {
+			switch strings.ToLower(n.Data) {
+			case "title":
+				if meta.Title == "" && n.FirstChild != nil {
+					meta.Title = strings.TrimSpace(n.FirstChild.Data)
+				}
+			case "meta":
+				var name string
+				var property string
+				var content string
+				for _, attr := range n.Attr {
+					key := strings.ToLower(attr.Key)
+					switch key {
+					case "name":
+						name = strings.ToLower(attr.Val)
+					case "property":
+						property = strings.ToLower(attr.Val)
+					case "content":
+						content = attr.Val
+					}
+				}
+				if content == "" {
+					break
+				}
+				switch {
+				case property == "og:title" && meta.Title == "":
+					meta.Title = strings.TrimSpace(content)
+				case property == "og:description" && meta.Description == "":
+					meta.Description = strings.TrimSpace(content)
+				case property == "og:site_name" && meta.SiteName == "":
+					meta.SiteName = strings.TrimSpace(content)
+				case property == "og:image" && meta.Image == "":
+					meta.Image = resolve_url(base_url, content)
+				case property == "og:url" && meta.CanonicalURL == "":
+					meta.CanonicalURL = resolve_url(base_url, content)
+				case name == "description" && meta.Description == "":
+					meta.Description = strings.TrimSpace(content)
+				}
+			case "link":
+				var rel string
+				var href string
+				for _, attr := range n.Attr {
+					key := strings.ToLower(attr.Key)
+					switch key {
+					case "rel":
+						rel = strings.ToLower(attr.Val)
+					case "href":
+						href = attr.Val
+					}
+				}
+				if href == "" {
+					break
+				}
+				// <link rel="canonical"> 优先于 og:url
+				if rel == "canonical" {
+					meta.CanonicalURL = resolve_url(base_url, href)
+					break
+				}
+				if meta.Icon == "" && strings.Contains(rel, "icon") {
+					meta.Icon = resolve_url(base_url, href)
+				}
+			}
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(doc)
+	if meta.Title == "" {
+		meta.Title = base_url.Host
+	}
+	if meta.Description == "" {
+		meta.Description = base_url.String()
+	}
+	if meta.SiteName == "" {
+		meta.SiteName = base_url.Hostname()
+	}
+	if meta.Icon == "" {
+		meta.Icon = resolve_url(base_url, "/favicon.ico")
+	}
+	if meta.CanonicalURL == "" {
+		meta.CanonicalURL = base_url.String()
+	}
+	return meta
+}
+
+func resolve_url(base_url *url.URL, ref string) string {
+	u, err := base_url.Parse(ref)
+	if err != nil {
+		return ref
+	}
+	return u.String()
+}
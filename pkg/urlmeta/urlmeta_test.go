@@ -0,0 +1,102 @@
+package urlmeta_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"golang.org/x/net/html"
+
+	"devboard/pkg/urlmeta"
+)
+
+func TestExtract(t *testing.T) {
+	page := `<html><head>
+<title>Example</title>
+<meta property="og:description" content="An example page">
+<meta property="og:url" content="https://example.com/og">
+<meta property="og:image" content="/cover.png">
+<link rel="canonical" href="/post/1">
+<link rel="shortcut icon" href="/static/icon.png">
+</head><body></body></html>`
+	doc, err := html.Parse(strings.NewReader(page))
+	if err != nil {
+		t.Fatal(err)
+	}
+	base, _ := url.Parse("https://example.com/post/1?utm_source=x")
+	meta := urlmeta.Extract(doc, base)
+	expected := urlmeta.Meta{
+		Title:        "Example",
+		Description:  "An example page",
+		SiteName:     "example.com",
+		Icon:         "https://example.com/static/icon.png",
+		Image:        "https://example.com/cover.png",
+		URL:          "https://example.com/post/1?utm_source=x",
+		CanonicalURL: "https://example.com/post/1",
+	}
+	if meta != expected {
+		t.Errorf("expected %+v, got %+v", expected, meta)
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	if u, err := urlmeta.Normalize("example.com/a"); err == nil {
+		t.Errorf("expected error for url without scheme, got %s", u)
+	}
+	if _, err := urlmeta.Normalize("ftp://example.com"); err == nil {
+		t.Error("expected error for ftp url")
+	}
+	u, err := urlmeta.Normalize(" https://example.com/a ")
+	if err != nil || u.String() != "https://example.com/a" {
+		t.Errorf("unexpected result %v %v", u, err)
+	}
+}
+
+func TestFetchPrivateAddress(t *testing.T) {
+	for _, u := range []string{
+		"http://127.0.0.1:8080/admin",
+		"http://localhost/",
+		"http://[::1]/",
+		"http://10.0.0.1/",
+		"http://192.168.1.1/",
+		"http://169.254.169.254/latest/meta-data/",
+		"http://0.0.0.0/",
+	} {
+		_, err := urlmeta.Fetch(context.Background(), u, true)
+		if !errors.Is(err, urlmeta.ErrPrivateAddress) {
+			t.Errorf("%s: expected ErrPrivateAddress, got %v", u, err)
+			continue
+		}
+		if !urlmeta.IsPermanent(err) || urlmeta.IsOffline(err) {
+			t.Errorf("%s: the error should be permanent", u)
+		}
+	}
+}
+
+func TestFetchLocalServer(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/old" {
+			http.Redirect(w, r, "/", http.StatusFound)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(`<html><head><title>Intranet</title></head></html>`))
+	}))
+	defer server.Close()
+	// 用户主动获取时可以请求内网的地址
+	meta, err := urlmeta.Fetch(context.Background(), server.URL+"/old", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if meta.Title != "Intranet" {
+		t.Errorf("unexpected title %q", meta.Title)
+	}
+	// 后台获取时在连接时拒绝
+	if _, err := urlmeta.Fetch(context.Background(), server.URL, true); !errors.Is(err, urlmeta.ErrPrivateAddress) {
+		t.Errorf("expected ErrPrivateAddress, got %v", err)
+	}
+}